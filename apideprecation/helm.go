@@ -0,0 +1,62 @@
+package apideprecation
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ChartRenderer renders the manifests a helm release installs from its chart and values
+type ChartRenderer interface {
+	Render(release, chart, version string, values []byte) ([]byte, error)
+}
+
+// HelmChartRenderer renders charts with the helm v2 binary at Path, fetching each chart from the repositories configured for helm
+type HelmChartRenderer struct {
+	Path string
+}
+
+func (r HelmChartRenderer) Render(release, chart, version string, values []byte) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "kube-aws-chart")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	valuesFile := filepath.Join(dir, "values.yaml")
+	if err := ioutil.WriteFile(valuesFile, values, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write values of helm release %s: %v", release, err)
+	}
+
+	chartsDir := filepath.Join(dir, "charts")
+	fetch := []string{"fetch", chart, "--untar", "--untardir", chartsDir}
+	if version != "" {
+		fetch = append(fetch, "--version", version)
+	}
+	if _, err := r.run(fetch...); err != nil {
+		return nil, fmt.Errorf("failed to fetch chart %s of helm release %s: %v", chart, release, err)
+	}
+	// The chart is untarred into the directory named after the chart without the repository name
+	chartDir := filepath.Join(chartsDir, chart[strings.LastIndex(chart, "/")+1:])
+
+	out, err := r.run("template", chartDir, "--name", release, "--values", valuesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s of helm release %s: %v", chart, release, err)
+	}
+	return out, nil
+}
+
+func (r HelmChartRenderer) run(args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(r.Path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s: %v: %s", r.Path, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
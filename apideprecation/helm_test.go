@@ -0,0 +1,44 @@
+package apideprecation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHelm is a helm binary which untars an empty chart on `fetch` and prints the arguments and values of `template`
+const fakeHelm = `#!/bin/sh
+case "$1" in
+fetch)
+  mkdir -p "$5/$(basename $2)"
+  ;;
+template)
+  echo "# $@"
+  cat "$6"
+  ;;
+esac
+`
+
+func TestHelmChartRenderer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fake-helm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	helm := filepath.Join(dir, "helm")
+	require.NoError(t, ioutil.WriteFile(helm, []byte(fakeHelm), 0700))
+
+	out, err := HelmChartRenderer{Path: helm}.Render("web", "stable/nginx", "1.2.3", []byte(`{"replicas":2}`))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^# template .+/charts/nginx --name web --values .+/values.yaml$`, lines[0])
+	assert.Equal(t, `{"replicas":2}`, lines[1])
+
+	_, err = HelmChartRenderer{Path: filepath.Join(dir, "missing")}.Render("web", "stable/nginx", "1.2.3", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,309 @@
+package apideprecation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Masterminds/semver"
+	"github.com/go-yaml/yaml"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+)
+
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Finding is an object which uses an API version deprecated or removed in the target Kubernetes version
+type Finding struct {
+	// Source is where the object was found e.g. the path to a plugin manifest or `cluster`
+	Source     string
+	Object     string
+	APIVersion string
+	Kind       string
+	Status     Status
+	Rule       *Rule
+}
+
+func (f Finding) String() string {
+	msg := fmt.Sprintf("%s: %s %s uses %s which is %s in %s", f.Source, f.Kind, f.Object, f.APIVersion, f.Status, f.removedOrDeprecatedIn())
+	if f.Rule.Replacement != "" {
+		msg += fmt.Sprintf(". Migrate to %s", f.Rule.Replacement)
+	}
+	return msg
+}
+
+func (f Finding) removedOrDeprecatedIn() string {
+	if f.Status == StatusRemoved {
+		return f.Rule.RemovedIn
+	}
+	return f.Rule.DeprecatedIn
+}
+
+// Scanner looks for objects using API versions deprecated or removed in the Kubernetes version it targets
+type Scanner struct {
+	table  Table
+	target *semver.Version
+}
+
+func NewScanner(table Table, targetVersion string) (*Scanner, error) {
+	v, err := semver.NewVersion(targetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("target kubernetes version \"%s\" is not a valid version: %v", targetVersion, err)
+	}
+	return &Scanner{table: table, target: v}, nil
+}
+
+func (s *Scanner) check(source, object, apiVersion, kind string) *Finding {
+	r := s.table.Find(apiVersion, kind)
+	if r == nil {
+		return nil
+	}
+	status := r.StatusIn(s.target)
+	if status == StatusOK {
+		return nil
+	}
+	return &Finding{
+		Source:     source,
+		Object:     object,
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Status:     status,
+		Rule:       r,
+	}
+}
+
+// ScanManifest checks every object in the possibly multi-document YAML or JSON manifest
+func (s *Scanner) ScanManifest(source string, content []byte) ([]Finding, error) {
+	findings := []Finding{}
+
+	d := yaml.NewDecoder(bytes.NewReader(content))
+	for i := 0; ; i++ {
+		var doc interface{}
+		err := d.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return findings, fmt.Errorf("failed to parse document #%d of %s: %v", i, source, err)
+		}
+		findings = append(findings, s.ScanValues(source, doc)...)
+	}
+
+	return findings, nil
+}
+
+// ScanValues walks arbitrary YAML or JSON data such as helm release values and checks every embedded object
+// that looks like a Kubernetes object i.e. has both `apiVersion` and `kind`
+func (s *Scanner) ScanValues(source string, values interface{}) []Finding {
+	findings := []Finding{}
+
+	switch v := values.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprintf("%v", k)] = e
+		}
+		return s.ScanValues(source, m)
+	case map[string]interface{}:
+		apiVersion, _ := v["apiVersion"].(string)
+		kind, _ := v["kind"].(string)
+		if apiVersion != "" && kind != "" {
+			if f := s.check(source, objectName(v), apiVersion, kind); f != nil {
+				findings = append(findings, *f)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			findings = append(findings, s.ScanValues(source, v[k])...)
+		}
+	case []interface{}:
+		for _, e := range v {
+			findings = append(findings, s.ScanValues(source, e)...)
+		}
+	}
+
+	return findings
+}
+
+func objectName(obj map[string]interface{}) string {
+	var metadata map[string]interface{}
+	switch m := obj["metadata"].(type) {
+	case map[string]interface{}:
+		metadata = m
+	case map[interface{}]interface{}:
+		metadata = map[string]interface{}{}
+		for k, v := range m {
+			metadata[fmt.Sprintf("%v", k)] = v
+		}
+	}
+	name, _ := metadata["name"].(string)
+	if ns, _ := metadata["namespace"].(string); ns != "" {
+		return ns + "/" + name
+	}
+	if name == "" {
+		return "<unnamed>"
+	}
+	return name
+}
+
+type apiVersions struct {
+	Versions []string `json:"versions"`
+}
+
+type apiGroupList struct {
+	Groups []struct {
+		Versions []struct {
+			GroupVersion string `json:"groupVersion"`
+		} `json:"versions"`
+	} `json:"groups"`
+}
+
+type objectList struct {
+	Items []struct {
+		Metadata objectMetadata `json:"metadata"`
+	} `json:"items"`
+}
+
+type objectMetadata struct {
+	Name          string            `json:"name"`
+	Namespace     string            `json:"namespace"`
+	Annotations   map[string]string `json:"annotations"`
+	ManagedFields []struct {
+		Manager    string `json:"manager"`
+		APIVersion string `json:"apiVersion"`
+	} `json:"managedFields"`
+}
+
+// writtenWith returns the API versions the object has been written with.
+// The API server records the API version of every write in managedFields. The last-applied-configuration annotation is
+// consulted only for objects without managedFields, which were last written before the API server recorded them.
+// It returns nil when neither is available
+func (m objectMetadata) writtenWith(kind string) []string {
+	if len(m.ManagedFields) > 0 {
+		versions := []string{}
+		for _, f := range m.ManagedFields {
+			versions = append(versions, f.APIVersion)
+		}
+		return versions
+	}
+	if lastApplied := m.Annotations[lastAppliedConfigAnnotation]; lastApplied != "" {
+		applied := struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}{}
+		if err := json.Unmarshal([]byte(lastApplied), &applied); err != nil {
+			logger.Debugf("ignoring malformed %s annotation of %s %s: %v", lastAppliedConfigAnnotation, kind, m.Name, err)
+			return nil
+		}
+		if applied.Kind == kind {
+			return []string{applied.APIVersion}
+		}
+	}
+	return nil
+}
+
+// apiPath returns the API path to list the resource in the group version. The core group is served under `/api` rather than `/apis`
+func apiPath(groupVersion, resource string) string {
+	if groupOf(groupVersion) == "" {
+		return fmt.Sprintf("/api/%s/%s", groupVersion, resource)
+	}
+	return fmt.Sprintf("/apis/%s/%s", groupVersion, resource)
+}
+
+// ScanCluster checks objects stored in a live cluster.
+// As the API server converts objects to whichever version they are read with, the API versions each object has been
+// written with are taken from its managedFields, or its last-applied-configuration annotation when it has no managedFields.
+// Objects of kinds whose replacement API version isn't served yet are reported as a whole, because they can only be
+// managed through the deprecated API.
+func (s *Scanner) ScanCluster(client kubeclient.Interface) ([]Finding, error) {
+	served := map[string]bool{}
+	core := apiVersions{}
+	if err := client.Get("/api", &core); err != nil {
+		return nil, fmt.Errorf("failed to discover served api versions: %v", err)
+	}
+	for _, v := range core.Versions {
+		served[v] = true
+	}
+	groups := apiGroupList{}
+	if err := client.Get("/apis", &groups); err != nil {
+		return nil, fmt.Errorf("failed to discover served api versions: %v", err)
+	}
+	for _, g := range groups.Groups {
+		for _, v := range g.Versions {
+			served[v.GroupVersion] = true
+		}
+	}
+
+	findings := []Finding{}
+	lists := map[string]*objectList{}
+	warned := map[string]bool{}
+	for _, r := range s.table {
+		if r.StatusIn(s.target) == StatusOK || r.Resource == "" {
+			continue
+		}
+
+		listVersion := r.Replacement
+		onlyDeprecatedServed := false
+		if !served[listVersion] {
+			if !served[r.APIVersion] {
+				continue
+			}
+			listVersion = r.APIVersion
+			onlyDeprecatedServed = true
+		}
+
+		path := apiPath(listVersion, r.Resource)
+		list, ok := lists[path]
+		if !ok {
+			list = &objectList{}
+			if err := client.Get(path, list); err != nil {
+				if kubeclient.IsNotFound(err) {
+					logger.Debugf("skipping %s as it isn't served: %v", path, err)
+					continue
+				}
+				return findings, fmt.Errorf("failed to list %s: %v", path, err)
+			}
+			lists[path] = list
+		}
+
+		unknown := 0
+		for _, item := range list.Items {
+			name := item.Metadata.Name
+			if item.Metadata.Namespace != "" {
+				name = item.Metadata.Namespace + "/" + name
+			}
+
+			deprecated := onlyDeprecatedServed
+			if !deprecated {
+				versions := item.Metadata.writtenWith(r.Kind)
+				if versions == nil {
+					unknown++
+				}
+				for _, v := range versions {
+					if v == r.APIVersion {
+						deprecated = true
+					}
+				}
+			}
+			if !deprecated {
+				continue
+			}
+			if f := s.check("cluster", name, r.APIVersion, r.Kind); f != nil {
+				findings = append(findings, *f)
+			}
+		}
+		// Rules for the same kind share the list, so that objects of unknown API versions are warned once
+		if unknown > 0 && !warned[path] {
+			warned[path] = true
+			logger.Warnf("Skipped %d %s object(s) as the API versions they were written with are unknown. Check they aren't managed via deprecated APIs\n", unknown, r.Kind)
+		}
+	}
+
+	return findings, nil
+}
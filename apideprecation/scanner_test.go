@@ -0,0 +1,238 @@
+package apideprecation
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinTable(t *testing.T) {
+	table, err := BuiltinTable()
+	require.NoError(t, err)
+	require.NotEmpty(t, table)
+
+	r := table.Find("extensions/v1beta1", "Deployment")
+	require.NotNil(t, r)
+	assert.Equal(t, "apps/v1", r.Replacement)
+	assert.Equal(t, "extensions", r.Group())
+	assert.Nil(t, table.Find("apps/v1", "Deployment"))
+}
+
+func TestScanManifest(t *testing.T) {
+	table, err := BuiltinTable()
+	require.NoError(t, err)
+
+	manifest := `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1beta1
+kind: ClusterRole
+metadata:
+  name: bar
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1beta2
+  kind: DaemonSet
+  metadata:
+    name: baz
+- apiVersion: apps/v1
+  kind: DaemonSet
+  metadata:
+    name: qux
+`
+
+	testCases := []struct {
+		target   string
+		expected []string
+	}{
+		{
+			target:   "v1.15",
+			expected: []string{"Deployment kube-system/foo deprecated", "DaemonSet baz deprecated"},
+		},
+		{
+			target:   "v1.16.0-rc.1",
+			expected: []string{"Deployment kube-system/foo removed", "DaemonSet baz removed"},
+		},
+		{
+			target:   "v1.17.0",
+			expected: []string{"Deployment kube-system/foo removed", "ClusterRole bar deprecated", "DaemonSet baz removed"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.target, func(t *testing.T) {
+			s, err := NewScanner(table, tc.target)
+			require.NoError(t, err)
+
+			findings, err := s.ScanManifest("plugins/foo/manifest.yaml", []byte(manifest))
+			require.NoError(t, err)
+
+			actual := []string{}
+			for _, f := range findings {
+				assert.Equal(t, "plugins/foo/manifest.yaml", f.Source)
+				actual = append(actual, fmt.Sprintf("%s %s %s", f.Kind, f.Object, f.Status))
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	s, err := NewScanner(table, "v1.16")
+	require.NoError(t, err)
+	_, err = s.ScanManifest("broken.yaml", []byte("apiVersion: [v1"))
+	assert.Error(t, err)
+}
+
+func TestScanValues(t *testing.T) {
+	table, err := BuiltinTable()
+	require.NoError(t, err)
+	s, err := NewScanner(table, "v1.22.0")
+	require.NoError(t, err)
+
+	values := map[string]interface{}{
+		"replicas": 2,
+		"extraObjects": []interface{}{
+			map[string]interface{}{
+				"apiVersion": "networking.k8s.io/v1beta1",
+				"kind":       "Ingress",
+				"metadata":   map[string]interface{}{"name": "web"},
+			},
+		},
+	}
+
+	findings := s.ScanValues("helm release web", values)
+	require.Len(t, findings, 1)
+	assert.Equal(t, StatusRemoved, findings[0].Status)
+	assert.Equal(t, "helm release web: Ingress web uses networking.k8s.io/v1beta1 which is removed in v1.22. Migrate to networking.k8s.io/v1", findings[0].String())
+}
+
+type fakeKubeClient struct {
+	responses map[string]interface{}
+}
+
+func (c fakeKubeClient) Get(path string, out interface{}) error {
+	r, ok := c.responses[path]
+	if !ok {
+		return &kubeclient.StatusError{Method: "GET", Path: path, Code: 404}
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func item(namespace, name, lastAppliedAPIVersion, kind string, managedAPIVersions ...string) map[string]interface{} {
+	metadata := map[string]interface{}{"name": name, "namespace": namespace}
+	if lastAppliedAPIVersion != "" {
+		metadata["annotations"] = map[string]string{
+			lastAppliedConfigAnnotation: fmt.Sprintf(`{"apiVersion":"%s","kind":"%s"}`, lastAppliedAPIVersion, kind),
+		}
+	}
+	managedFields := []interface{}{}
+	for _, v := range managedAPIVersions {
+		managedFields = append(managedFields, map[string]string{"manager": "kubectl", "apiVersion": v})
+	}
+	if len(managedFields) > 0 {
+		metadata["managedFields"] = managedFields
+	}
+	return map[string]interface{}{"metadata": metadata}
+}
+
+func TestScanCluster(t *testing.T) {
+	table, err := BuiltinTable()
+	require.NoError(t, err)
+	s, err := NewScanner(table, "v1.16.2")
+	require.NoError(t, err)
+
+	client := fakeKubeClient{
+		responses: map[string]interface{}{
+			"/api": map[string]interface{}{"versions": []string{"v1"}},
+			"/apis": map[string]interface{}{
+				"groups": []interface{}{
+					map[string]interface{}{"versions": []interface{}{
+						map[string]string{"groupVersion": "apps/v1"},
+						map[string]string{"groupVersion": "apps/v1beta2"},
+					}},
+					map[string]interface{}{"versions": []interface{}{
+						map[string]string{"groupVersion": "extensions/v1beta1"},
+					}},
+				},
+			},
+			"/apis/apps/v1/deployments": map[string]interface{}{
+				"items": []interface{}{
+					item("default", "legacy", "extensions/v1beta1", "Deployment"),
+					item("default", "current", "apps/v1", "Deployment"),
+					item("default", "unmanaged", "", ""),
+					// Written by helm via the deprecated API without the last-applied-configuration annotation
+					item("default", "helm", "", "", "apps/v1", "extensions/v1beta1"),
+					// managedFields take precedence over the annotation left by an older apply
+					item("default", "migrated", "extensions/v1beta1", "Deployment", "apps/v1"),
+				},
+			},
+			"/apis/apps/v1/daemonsets": map[string]interface{}{
+				"items": []interface{}{
+					item("kube-system", "proxy", "apps/v1beta2", "DaemonSet"),
+				},
+			},
+			// networking.k8s.io/v1 isn't served so that every network policy is managed via the deprecated API
+			"/apis/extensions/v1beta1/networkpolicies": map[string]interface{}{
+				"items": []interface{}{
+					item("default", "deny-all", "", ""),
+				},
+			},
+		},
+	}
+
+	findings, err := s.ScanCluster(client)
+	require.NoError(t, err)
+
+	actual := []string{}
+	for _, f := range findings {
+		assert.Equal(t, "cluster", f.Source)
+		actual = append(actual, fmt.Sprintf("%s %s %s %s", f.APIVersion, f.Kind, f.Object, f.Status))
+	}
+	assert.Equal(t, []string{
+		"extensions/v1beta1 Deployment default/legacy removed",
+		"extensions/v1beta1 Deployment default/helm removed",
+		"extensions/v1beta1 NetworkPolicy default/deny-all removed",
+		"apps/v1beta2 DaemonSet kube-system/proxy removed",
+	}, actual)
+}
+
+func TestScanClusterCoreGroup(t *testing.T) {
+	table, err := TableFromBytes([]byte(`
+- apiVersion: v1
+  kind: ComponentStatus
+  resource: componentstatuses
+  deprecatedIn: v1.19
+  removedIn: v1.99
+`))
+	require.NoError(t, err)
+	s, err := NewScanner(table, "v1.19.0")
+	require.NoError(t, err)
+
+	// Core group resources are listed from /api rather than /apis
+	client := fakeKubeClient{
+		responses: map[string]interface{}{
+			"/api":  map[string]interface{}{"versions": []string{"v1"}},
+			"/apis": map[string]interface{}{"groups": []interface{}{}},
+			"/api/v1/componentstatuses": map[string]interface{}{
+				"items": []interface{}{item("", "scheduler", "", "")},
+			},
+		},
+	}
+
+	findings, err := s.ScanCluster(client)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "cluster: ComponentStatus scheduler uses v1 which is deprecated in v1.19", findings[0].String())
+}
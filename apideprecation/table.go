@@ -0,0 +1,108 @@
+package apideprecation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/go-yaml/yaml"
+	"github.com/kubernetes-incubator/kube-aws/builtin"
+)
+
+const builtinTableFile = "deprecated-apis.yaml"
+
+// Rule describes a single deprecated Kubernetes API version of a kind
+type Rule struct {
+	APIVersion   string `yaml:"apiVersion"`
+	Kind         string `yaml:"kind"`
+	Resource     string `yaml:"resource"`
+	DeprecatedIn string `yaml:"deprecatedIn"`
+	RemovedIn    string `yaml:"removedIn"`
+	Replacement  string `yaml:"replacement,omitempty"`
+
+	deprecatedIn *semver.Version
+	removedIn    *semver.Version
+}
+
+// Table is the list of deprecation rules a scan checks against
+type Table []*Rule
+
+// BuiltinTable returns the deprecation table embedded into kube-aws
+func BuiltinTable() (Table, error) {
+	return TableFromBytes(builtin.Bytes(builtinTableFile))
+}
+
+func TableFromBytes(data []byte) (Table, error) {
+	t := Table{}
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse deprecation table: %v", err)
+	}
+	for i, r := range t {
+		if err := r.load(); err != nil {
+			return nil, fmt.Errorf("invalid deprecation rule at index %d: %v", i, err)
+		}
+	}
+	return t, nil
+}
+
+func (r *Rule) load() error {
+	if r.APIVersion == "" || r.Kind == "" {
+		return fmt.Errorf("both `apiVersion` and `kind` must be specified")
+	}
+	var err error
+	if r.deprecatedIn, err = semver.NewVersion(r.DeprecatedIn); err != nil {
+		return fmt.Errorf("`deprecatedIn` of %s %s is not a valid version: %v", r.APIVersion, r.Kind, err)
+	}
+	if r.removedIn, err = semver.NewVersion(r.RemovedIn); err != nil {
+		return fmt.Errorf("`removedIn` of %s %s is not a valid version: %v", r.APIVersion, r.Kind, err)
+	}
+	return nil
+}
+
+// Group returns the API group of the deprecated API version. It is empty for the core group.
+func (r *Rule) Group() string {
+	return groupOf(r.APIVersion)
+}
+
+func groupOf(apiVersion string) string {
+	if i := strings.Index(apiVersion, "/"); i >= 0 {
+		return apiVersion[:i]
+	}
+	return ""
+}
+
+// Find returns the rule for the apiVersion and kind, or nil when the combination isn't deprecated at all
+func (t Table) Find(apiVersion, kind string) *Rule {
+	for _, r := range t {
+		if r.APIVersion == apiVersion && r.Kind == kind {
+			return r
+		}
+	}
+	return nil
+}
+
+type Status string
+
+const (
+	StatusOK         Status = ""
+	StatusDeprecated Status = "deprecated"
+	StatusRemoved    Status = "removed"
+)
+
+// StatusIn tells whether the API version is deprecated or already removed in the Kubernetes version
+func (r *Rule) StatusIn(version *semver.Version) Status {
+	v := withoutPrerelease(version)
+	if !v.LessThan(r.removedIn) {
+		return StatusRemoved
+	}
+	if !v.LessThan(r.deprecatedIn) {
+		return StatusDeprecated
+	}
+	return StatusOK
+}
+
+// withoutPrerelease makes e.g. v1.16.0-beta.1 compare as v1.16.0 so that removals take effect for pre-releases too
+func withoutPrerelease(v *semver.Version) *semver.Version {
+	r, _ := semver.NewVersion(fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch()))
+	return r
+}
@@ -0,0 +1,10 @@
+#!/usr/bin/env bash
+
+set -e
+
+# This script publishes the kube-aws image of the tag being built to quay.io.
+# It requires the credentials of the robot account, set in the `QUAY_USERNAME` and `QUAY_PASSWORD` env vars of the Travis CI repository settings
+
+echo "$QUAY_PASSWORD" | docker login -u "$QUAY_USERNAME" --password-stdin quay.io
+
+IMAGE_TAG="$TRAVIS_TAG" make publish-image
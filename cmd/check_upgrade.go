@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/apideprecation"
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdCheckUpgrade = &cobra.Command{
+		Use:   "check-upgrade",
+		Short: "Find usages of Kubernetes APIs deprecated or removed in the version to upgrade to",
+		Long: `Find usages of Kubernetes APIs deprecated or removed in the version specified with --to.
+
+Kubernetes manifests contributed by plugins are scanned offline. Charts of helm releases contributed by plugins are rendered
+with the helm v2 binary specified with --helm, which fetches them from the chart repositories configured for it.
+When --kubeconfig is specified, objects in the cluster reachable with it are scanned, too.
+Exits with status code 2 when any API removed in the target version is in use.`,
+		RunE:         runCmdCheckUpgrade,
+		SilenceUsage: true,
+	}
+
+	checkUpgradeOpts = struct {
+		awsDebug   bool
+		profile    string
+		to         string
+		kubeconfig string
+		context    string
+		helm       string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdCheckUpgrade)
+	cmdCheckUpgrade.Flags().StringVar(&checkUpgradeOpts.to, "to", "", "The Kubernetes version to upgrade to e.g. v1.16")
+	cmdCheckUpgrade.Flags().StringVar(&checkUpgradeOpts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig used to scan objects in the cluster. The cluster isn't scanned when omitted")
+	cmdCheckUpgrade.Flags().StringVar(&checkUpgradeOpts.context, "context", "", "The kubeconfig context to use. Defaults to the current context")
+	cmdCheckUpgrade.Flags().StringVar(&checkUpgradeOpts.helm, "helm", "helm", "Path to the helm v2 binary used to render charts of helm releases")
+	cmdCheckUpgrade.Flags().BoolVar(&checkUpgradeOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdCheckUpgrade.Flags().StringVar(&checkUpgradeOpts.profile, "profile", "", "The AWS profile to use from credentials file")
+}
+
+func runCmdCheckUpgrade(c *cobra.Command, _ []string) error {
+	if err := validateRequired(flag{"--to", checkUpgradeOpts.to}); err != nil {
+		return err
+	}
+
+	table, err := apideprecation.BuiltinTable()
+	if err != nil {
+		return err
+	}
+	scanner, err := apideprecation.NewScanner(table, checkUpgradeOpts.to)
+	if err != nil {
+		return err
+	}
+
+	opts := root.NewOptions(false, false, checkUpgradeOpts.profile)
+	cluster, err := root.LoadClusterFromFile(configPath, opts, checkUpgradeOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %v", err)
+	}
+
+	findings, err := cluster.ScanDeprecatedAPIs(scanner, apideprecation.HelmChartRenderer{Path: checkUpgradeOpts.helm})
+	if err != nil {
+		return fmt.Errorf("failed to scan plugin manifests: %v", err)
+	}
+
+	if checkUpgradeOpts.kubeconfig != "" {
+		client, err := kubeclient.NewFromKubeconfig(checkUpgradeOpts.kubeconfig, checkUpgradeOpts.context)
+		if err != nil {
+			return err
+		}
+		fs, err := scanner.ScanCluster(client)
+		if err != nil {
+			return fmt.Errorf("failed to scan cluster: %v", err)
+		}
+		findings = append(findings, fs...)
+	}
+
+	removed := 0
+	for _, f := range findings {
+		if f.Status == apideprecation.StatusRemoved {
+			removed++
+			logger.Errorf("%s\n", f.String())
+		} else {
+			logger.Warnf("%s\n", f.String())
+		}
+	}
+
+	if removed > 0 {
+		c.SilenceErrors = true
+		return &ExitError{fmt.Sprintf("Found %d object(s) using APIs removed in %s", removed, checkUpgradeOpts.to), 2}
+	}
+
+	logger.Infof("No usages of APIs removed in %s found\n", checkUpgradeOpts.to)
+	return nil
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/kubernetes-incubator/kube-aws/credential"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdCredentials = &cobra.Command{
+		Use:          "credentials",
+		Short:        "Manage credentials of users accessing the cluster",
+		Long:         ``,
+		SilenceUsage: true,
+	}
+
+	cmdCredentialsIssueUser = &cobra.Command{
+		Use:   "issue-user",
+		Short: "Issue a short-lived client certificate and a kubeconfig for a user",
+		Long: `Issue a client certificate for a user signed by the cluster CA and write a personal kubeconfig embedding it.
+
+The username is set to the common name of the certificate. Each group is mapped to the organizations of the certificate,
+which are the Kubernetes groups of the user, by kubernetes.authentication.userCertificates.organizations in cluster.yaml.
+The certificate and the key are written to credentials/users/. The certificate is recorded in the issuance ledger encrypted with the KMS key,
+and its serial in the plaintext audit log credentials/issued-users.log.`,
+		RunE:         runCmdCredentialsIssueUser,
+		SilenceUsage: true,
+	}
+
+	cmdCredentialsRevoke = &cobra.Command{
+		Use:   "revoke <serial>",
+		Short: "Revoke a certificate signed by kube-aws and regenerate the CRL",
+		Long: `Mark the certificate with the serial as revoked in the issuance ledger and regenerate credentials/crl.pem signed by the cluster CA.
+
+Run "kube-aws apply" afterwards to deliver the CRL to etcd nodes, which reject revoked client and peer certificates.
+Note that kube-apiserver doesn't support CRLs. Revoked certificates remain valid for the Kubernetes API until they expire.`,
+		Args:         cobra.ExactArgs(1),
+		RunE:         runCmdCredentialsRevoke,
+		SilenceUsage: true,
+	}
+
+	issueUserOpts = struct {
+		name       string
+		groups     []string
+		ttl        time.Duration
+		caCertPath string
+		caKeyPath  string
+		output     string
+		awsDebug   bool
+		profile    string
+	}{}
+
+	revokeOpts = struct {
+		reason      string
+		crlValidity time.Duration
+		caCertPath  string
+		caKeyPath   string
+		awsDebug    bool
+		profile     string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdCredentials)
+	cmdCredentials.AddCommand(cmdCredentialsIssueUser)
+	cmdCredentials.AddCommand(cmdCredentialsRevoke)
+
+	cmdCredentialsIssueUser.Flags().StringVar(&issueUserOpts.name, "name", "", "The username, which is set to the common name of the certificate")
+	cmdCredentialsIssueUser.Flags().StringSliceVar(&issueUserOpts.groups, "groups", []string{}, "Comma-separated groups of the user, which are mapped to the organizations of the certificate by kubernetes.authentication.userCertificates.organizations in cluster.yaml")
+	cmdCredentialsIssueUser.Flags().DurationVar(&issueUserOpts.ttl, "ttl", 8*time.Hour, "How long the certificate is valid for")
+	cmdCredentialsIssueUser.Flags().StringVar(&issueUserOpts.caCertPath, "ca-cert-path", "./credentials/ca.pem", "path to pem-encoded CA x509 certificate")
+	cmdCredentialsIssueUser.Flags().StringVar(&issueUserOpts.caKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA key")
+	cmdCredentialsIssueUser.Flags().StringVar(&issueUserOpts.output, "output", "", "Path to write the personal kubeconfig to. Defaults to kubeconfig-<name>")
+	cmdCredentialsIssueUser.Flags().BoolVar(&issueUserOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdCredentialsIssueUser.Flags().StringVar(&issueUserOpts.profile, "profile", "", "The AWS profile to use from credentials file")
+
+	cmdCredentialsRevoke.Flags().StringVar(&revokeOpts.reason, "reason", "", "Why the certificate is revoked, which is recorded in the issuance ledger")
+	cmdCredentialsRevoke.Flags().DurationVar(&revokeOpts.crlValidity, "crl-validity", 365*24*time.Hour, "How long the regenerated CRL is valid for")
+	cmdCredentialsRevoke.Flags().StringVar(&revokeOpts.caCertPath, "ca-cert-path", "./credentials/ca.pem", "path to pem-encoded CA x509 certificate")
+	cmdCredentialsRevoke.Flags().StringVar(&revokeOpts.caKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA key")
+	cmdCredentialsRevoke.Flags().BoolVar(&revokeOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdCredentialsRevoke.Flags().StringVar(&revokeOpts.profile, "profile", "", "The AWS profile to use from credentials file")
+}
+
+func runCmdCredentialsIssueUser(_ *cobra.Command, _ []string) error {
+	if err := validateRequired(flag{"--name", issueUserOpts.name}); err != nil {
+		return err
+	}
+
+	output := issueUserOpts.output
+	if output == "" {
+		output = fmt.Sprintf("kubeconfig-%s", issueUserOpts.name)
+	}
+
+	opts := root.NewOptions(false, false, issueUserOpts.profile)
+	cluster, err := root.LoadClusterFromFile(configPath, opts, issueUserOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %v", err)
+	}
+
+	record, err := cluster.IssueUserCredentials(root.IssueUserOptions{
+		Name:       issueUserOpts.name,
+		Groups:     issueUserOpts.groups,
+		TTL:        issueUserOpts.ttl,
+		CaCertPath: issueUserOpts.caCertPath,
+		CaKeyPath:  issueUserOpts.caKeyPath,
+		Output:     output,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to issue credentials for %s: %v", issueUserOpts.name, err)
+	}
+
+	for _, o := range record.Organizations {
+		if o == "system:masters" {
+			logger.Warnf("%s is issued a certificate in system:masters, which bypasses RBAC until the certificate expires\n", record.CommonName)
+		}
+	}
+	logger.Infof("Issued a certificate with serial %s for %s in groups [%s], valid until %s\n", record.Serial, record.CommonName, strings.Join(record.Organizations, ", "), record.NotAfter.Format(time.RFC3339))
+	logger.Infof("Wrote the kubeconfig for %s to %s\n", record.CommonName, output)
+	return nil
+}
+
+func runCmdCredentialsRevoke(_ *cobra.Command, args []string) error {
+	opts := root.NewOptions(false, false, revokeOpts.profile)
+	cluster, err := root.LoadClusterFromFile(configPath, opts, revokeOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %v", err)
+	}
+
+	entry, err := cluster.RevokeCertificate(root.RevokeOptions{
+		Serial:      args[0],
+		Reason:      revokeOpts.reason,
+		CaCertPath:  revokeOpts.caCertPath,
+		CaKeyPath:   revokeOpts.caKeyPath,
+		CRLValidity: revokeOpts.crlValidity,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke certificate %s: %v", args[0], err)
+	}
+
+	logger.Infof("Revoked the certificate with serial %s issued for %s by %s\n", entry.Serial, entry.CommonName, entry.Source)
+	logger.Infof("Regenerated credentials/%s. Run `kube-aws apply` to deliver it to etcd nodes\n", credential.CRLFile)
+	logger.Warnf("kube-apiserver doesn't support CRLs. Remove RBAC bindings for %s to revoke its access to the Kubernetes API before the certificate expires at %s\n", entry.CommonName, entry.NotAfter.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
+	"github.com/kubernetes-incubator/kube-aws/credential"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdDecryptEnvelopeAssets = &cobra.Command{
+		Use:   "decrypt-envelope-assets <list>",
+		Short: "Decrypt envelope-encrypted assets on nodes",
+		Long: `Decrypt the envelope-encrypted assets listed in the file and remove the list.
+
+Each line of the list is "<encrypted file> <decrypted file> [<permissions>]".
+The data key of each asset is unwrapped with KMS and kept in memory only, and the AES-GCM authentication tag
+of each asset is verified before the decrypted file is written.
+This runs on nodes while they boot, and isn't meant to be run by hand.`,
+		Args:         cobra.ExactArgs(1),
+		RunE:         runCmdDecryptEnvelopeAssets,
+		SilenceUsage: true,
+		Hidden:       true,
+	}
+
+	decryptEnvelopeAssetsOpts = struct {
+		region string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdDecryptEnvelopeAssets)
+	cmdDecryptEnvelopeAssets.Flags().StringVar(&decryptEnvelopeAssetsOpts.region, "region", "", "The AWS region of the KMS key")
+}
+
+func runCmdDecryptEnvelopeAssets(_ *cobra.Command, args []string) error {
+	if err := validateRequired(
+		flag{"--region", decryptEnvelopeAssetsOpts.region},
+	); err != nil {
+		return err
+	}
+
+	session, err := awsconn.NewSessionFromRegion(api.RegionForName(decryptEnvelopeAssetsOpts.region), false, "")
+	if err != nil {
+		return err
+	}
+	if err := credential.NewEnvelopeAssetsDecryptor(kms.New(session)).DecryptList(args[0]); err != nil {
+		return fmt.Errorf("failed to decrypt assets: %v", err)
+	}
+	return nil
+}
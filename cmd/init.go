@@ -9,6 +9,7 @@ import (
 	"github.com/kubernetes-incubator/kube-aws/filegen"
 	"github.com/kubernetes-incubator/kube-aws/flatcar/amiregistry"
 	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +23,12 @@ var (
 	}
 
 	initOpts = config.InitialConfig{}
+
+	initSubnetOpts = struct {
+		availabilityZones []string
+		tiers             []string
+		reservedPerTier   int
+	}{}
 )
 
 const (
@@ -40,6 +47,10 @@ func init() {
 	cmdInit.Flags().StringVar(&initOpts.KMSKeyARN, "kms-key-arn", "", "The ARN of the AWS KMS key for encrypting TLS assets")
 	cmdInit.Flags().StringVar(&initOpts.AmiId, "ami-id", "", "The AMI ID of CoreOS. Last CoreOS Stable Channel selected by default if empty")
 	cmdInit.Flags().BoolVar(&initOpts.NoRecordSet, "no-record-set", false, "Instruct kube-aws to not manage Route53 record sets for your K8S API endpoints")
+	cmdInit.Flags().StringSliceVar(&initSubnetOpts.availabilityZones, "availability-zones", nil, "The AWS availability-zones to deploy to. Subnets are planned for each of them out of --vpc-cidr instead of using --availability-zone")
+	cmdInit.Flags().StringVar(&initOpts.VPCCIDR, "vpc-cidr", "10.0.0.0/16", "The CIDR of the VPC subnets are planned out of. Used with --availability-zones")
+	cmdInit.Flags().StringSliceVar(&initSubnetOpts.tiers, "subnet-tiers", []string{"public:24"}, "The subnets to plan per availability zone in the `[<name>=]<public|private>:<prefix length>` notation. Used with --availability-zones")
+	cmdInit.Flags().IntVar(&initSubnetOpts.reservedPerTier, "reserved-subnets-per-tier", 1, "The number of CIDR blocks reserved per subnet tier for future node pools or availability zones. Used with --availability-zones")
 }
 
 func runCmdInit(_ *cobra.Command, _ []string) error {
@@ -49,11 +60,25 @@ func runCmdInit(_ *cobra.Command, _ []string) error {
 		flag{"--cluster-name", initOpts.ClusterName},
 		flag{"--external-dns-name", initOpts.ExternalDNSName},
 		flag{"--region", initOpts.Region.Name},
-		flag{"--availability-zone", initOpts.AvailabilityZone},
 	); err != nil {
 		return err
 	}
 
+	if len(initSubnetOpts.availabilityZones) > 0 {
+		if initOpts.AvailabilityZone != "" {
+			return errors.New("--availability-zone and --availability-zones can't be specified at the same time")
+		}
+		if err := planInitialSubnets(); err != nil {
+			return err
+		}
+	} else {
+		if err := validateRequired(flag{"--availability-zone", initOpts.AvailabilityZone}); err != nil {
+			return err
+		}
+		// --vpc-cidr is effective only when subnets are planned
+		initOpts.VPCCIDR = ""
+	}
+
 	if initOpts.AmiId == "" {
 		amiID, err := amiregistry.GetAMI(initOpts.Region.Name, defaultReleaseChannel)
 		initOpts.AmiId = amiID
@@ -80,3 +105,32 @@ Next steps:
 	logger.Infof(successMsg, configPath, configPath)
 	return nil
 }
+
+func planInitialSubnets() error {
+	tiers := []api.SubnetTier{}
+	for _, t := range initSubnetOpts.tiers {
+		tier, err := api.ParseSubnetTier(t)
+		if err != nil {
+			return err
+		}
+		tiers = append(tiers, tier)
+	}
+
+	plan := api.SubnetPlan{
+		VPCCIDR:           initOpts.VPCCIDR,
+		AvailabilityZones: initSubnetOpts.availabilityZones,
+		Tiers:             tiers,
+		ReservedPerTier:   initSubnetOpts.reservedPerTier,
+	}
+	allocation, err := plan.Allocate(api.Subnets{})
+	if err != nil {
+		return fmt.Errorf("failed to plan subnets: %v", err)
+	}
+
+	initOpts.SubnetsYAML, err = config.RenderSubnets(allocation.Subnets, allocation.Reserved)
+	if err != nil {
+		return err
+	}
+	logSubnetAllocation(allocation)
+	return nil
+}
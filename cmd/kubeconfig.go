@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/kubernetes-incubator/kube-aws/pkg/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdKubeconfig = &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Generate a kubeconfig to access the cluster",
+		Long: `Generate a kubeconfig containing a context for each API endpoint in cluster.yaml.
+
+By default the kubeconfig authenticates with the admin client certificate in the credentials directory.
+Use --auth=exec to obtain tokens via aws-iam-authenticator or "aws eks get-token" when kubernetes.authentication.awsIAM is enabled,
+or --auth=oidc to authenticate via the OIDC provider configured in experimental.oidc.
+With --merge, the entries are merged into the kubeconfig kubectl reads by default instead of written to --output.`,
+		RunE:         runCmdKubeconfig,
+		SilenceUsage: true,
+	}
+
+	kubeconfigOpts = struct {
+		output           string
+		merge            bool
+		auth             string
+		execCommand      string
+		profile          string
+		oidcClientSecret string
+		embedCerts       bool
+		context          string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdKubeconfig)
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.output, "output", "kubeconfig", "Path to write the kubeconfig to. Ignored when --merge is specified")
+	cmdKubeconfig.Flags().BoolVar(&kubeconfigOpts.merge, "merge", false, "Merge into the kubeconfig at $KUBECONFIG or ~/.kube/config, replacing entries with the same names")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.auth, "auth", root.KubeconfigAuthCert, "How kubectl authenticates to the cluster. One of cert, exec or oidc")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.execCommand, "exec-command", root.KubeconfigExecAWSIAMAuthenticator, "The command to obtain tokens with when --auth=exec. Either aws-iam-authenticator or aws")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.profile, "profile", "", "The AWS profile the exec command obtains tokens with")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.oidcClientSecret, "oidc-client-secret", "", "The client secret written to the oidc auth-provider when --auth=oidc")
+	cmdKubeconfig.Flags().BoolVar(&kubeconfigOpts.embedCerts, "embed-certs", false, "Embed the CA certificate and the admin credentials into the kubeconfig rather than referencing the files")
+	cmdKubeconfig.Flags().StringVar(&kubeconfigOpts.context, "context", "", "The name of the API endpoint whose context is set as the current context. Defaults to adminAPIEndpointName")
+}
+
+func runCmdKubeconfig(_ *cobra.Command, _ []string) error {
+	c, err := model.ClusterFromFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %v", err)
+	}
+	cfg, err := model.Compile(c, api.ClusterOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to compile cluster config: %v", err)
+	}
+
+	kubeconfig, err := root.GenerateKubeconfig(cfg, root.KubeconfigOptions{
+		Auth:             kubeconfigOpts.auth,
+		ExecCommand:      kubeconfigOpts.execCommand,
+		AWSProfile:       kubeconfigOpts.profile,
+		OIDCClientSecret: kubeconfigOpts.oidcClientSecret,
+		EmbedCerts:       kubeconfigOpts.embedCerts,
+		// Relative paths in the merged kubeconfig would be resolved against its directory rather than the working directory
+		AbsolutePaths: kubeconfigOpts.merge,
+	})
+	if err != nil {
+		return err
+	}
+
+	if kubeconfigOpts.context != "" {
+		if _, err := cfg.APIEndpoints.FindByName(kubeconfigOpts.context); err != nil {
+			return fmt.Errorf("invalid --context: %v", err)
+		}
+		kubeconfig.CurrentContext = root.KubeconfigContextName(cfg.ClusterName, kubeconfigOpts.context)
+	}
+
+	if kubeconfigOpts.merge {
+		path := kubeclient.DefaultKubeconfigPath()
+		if err := kubeconfig.MergeIntoFile(path); err != nil {
+			return err
+		}
+		logger.Infof("Merged %d context(s) into %s. The current context is now %s\n", len(kubeconfig.Contexts), path, kubeconfig.CurrentContext)
+		return nil
+	}
+
+	if err := kubeconfig.WriteToFile(kubeconfigOpts.output); err != nil {
+		return err
+	}
+	logger.Infof("Wrote %d context(s) to %s. The current context is %s\n", len(kubeconfig.Contexts), kubeconfigOpts.output, kubeconfig.CurrentContext)
+	return nil
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdMigrate = &cobra.Command{
+		Use:          "migrate",
+		Short:        "Migrate resources of your cluster to their replacements",
+		Long:         ``,
+		SilenceUsage: true,
+	}
+
+	cmdMigrateSpotFleet = &cobra.Command{
+		Use:   "spotfleet",
+		Short: "Replace the spot fleet of a node pool with an auto scaling group of mixed instances",
+		Long: `Replace the deprecated spot fleet of a node pool with an auto scaling group of spot instances in two steps:
+
+  1. While the node pool still has spotFleet in cluster.yaml, the settings replacing it are printed.
+     Launch specifications are translated into mixedInstances overrides with their weighted capacities,
+     the capacity-optimized allocation strategy and a root volume large enough for every instance type.
+     Put them into cluster.yaml and run the command again. Keep migratedFromSpotFleet: true in cluster.yaml permanently,
+     as removing it replaces the auto scaling group.
+  2. The auto scaling group is created alongside the spot fleet still deployed. Once all its nodes are Ready,
+     nodes of the fleet are drained and the fleet is removed.
+
+Steps already done according to the deployed node pool stack are skipped. Run the command again to resume an interrupted migration.`,
+		RunE:         runCmdMigrateSpotFleet,
+		SilenceUsage: true,
+	}
+
+	migrateSpotFleetOpts = struct {
+		pool         string
+		kubeconfig   string
+		context      string
+		readyTimeout time.Duration
+		drainTimeout time.Duration
+		force        bool
+		awsDebug     bool
+		profile      string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdMigrate)
+	cmdMigrate.AddCommand(cmdMigrateSpotFleet)
+
+	cmdMigrateSpotFleet.Flags().StringVar(&migrateSpotFleetOpts.pool, "pool", "", "The name of the node pool to migrate")
+	cmdMigrateSpotFleet.Flags().StringVar(&migrateSpotFleetOpts.kubeconfig, "kubeconfig", "kubeconfig", "Path to the kubeconfig used to check and drain nodes")
+	cmdMigrateSpotFleet.Flags().StringVar(&migrateSpotFleetOpts.context, "context", "", "The kubeconfig context to use. Defaults to the current context")
+	cmdMigrateSpotFleet.Flags().DurationVar(&migrateSpotFleetOpts.readyTimeout, "ready-timeout", 20*time.Minute, "How long to wait for nodes of the auto scaling group to be Ready")
+	cmdMigrateSpotFleet.Flags().DurationVar(&migrateSpotFleetOpts.drainTimeout, "drain-timeout", 5*time.Minute, "Maximum time to wait for each node of the spot fleet to be drained")
+	cmdMigrateSpotFleet.Flags().BoolVar(&migrateSpotFleetOpts.force, "force", false, "Don't ask for confirmation")
+	cmdMigrateSpotFleet.Flags().BoolVar(&migrateSpotFleetOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdMigrateSpotFleet.Flags().StringVar(&migrateSpotFleetOpts.profile, "profile", "", "The AWS profile to use from credentials file")
+}
+
+func runCmdMigrateSpotFleet(_ *cobra.Command, _ []string) error {
+	if err := validateRequired(flag{"--pool", migrateSpotFleetOpts.pool}); err != nil {
+		return err
+	}
+	poolName := migrateSpotFleetOpts.pool
+
+	opts := root.NewOptions(false, false, migrateSpotFleetOpts.profile)
+	cluster, err := root.LoadClusterFromFile(configPath, opts, migrateSpotFleetOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %v", err)
+	}
+
+	for _, np := range cluster.Cfg.NodePools {
+		if np.NodePoolName != poolName || !np.SpotFleet.Enabled() {
+			continue
+		}
+		r, err := np.WorkerNodePool.SpotFleetReplacement()
+		if err != nil {
+			return err
+		}
+		rendered, err := config.RenderSpotFleetReplacement(r)
+		if err != nil {
+			return err
+		}
+		fmt.Print(rendered)
+		logger.Infof("Update node pool %s in %s as above and run `kube-aws migrate spotfleet --pool %s` again\n", poolName, configPath, poolName)
+		return nil
+	}
+
+	if !migrateSpotFleetOpts.force && !migrateSpotFleetConfirmation(poolName) {
+		logger.Info("Operation cancelled")
+		return nil
+	}
+
+	kube, err := kubeclient.NewFromKubeconfig(migrateSpotFleetOpts.kubeconfig, migrateSpotFleetOpts.context)
+	if err != nil {
+		return err
+	}
+
+	if err := cluster.MigrateSpotFleet(poolName, kube, migrateSpotFleetOpts.readyTimeout, migrateSpotFleetOpts.drainTimeout); err != nil {
+		return fmt.Errorf("failed to migrate spot fleet of node pool %s: %v. Run the same command again to resume the migration", poolName, err)
+	}
+	logger.Infof("Migrated node pool %s from the spot fleet to the auto scaling group\n", poolName)
+	return nil
+}
+
+func migrateSpotFleetConfirmation(poolName string) bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("This operation will update node pool %s twice, draining and terminating all the nodes of its spot fleet. Are you sure? [y,n]: ", poolName)
+	text, _ := reader.ReadString('\n')
+	text = strings.TrimSuffix(strings.ToLower(text), "\n")
+
+	return text == "y" || text == "yes"
+}
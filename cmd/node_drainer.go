@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/kubernetes-incubator/kube-aws/awsconn"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/nodedrainer"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdNodeDrainer = &cobra.Command{
+		Use:   "node-drainer",
+		Short: "Drain nodes being terminated by auto scaling groups or spot interruptions",
+		Long: `Drain nodes being terminated by auto scaling groups or spot interruptions.
+
+Auto scaling lifecycle actions and spot instance interruption warnings are received from the SQS queue the node drainer
+rules of the control plane stack send them to. Each node is cordoned and drained with the eviction API so that
+PodDisruptionBudgets are respected, and then the lifecycle action is completed.
+This runs inside the cluster as the kube-node-drainer deployment, and isn't meant to be run by hand.`,
+		RunE:         runCmdNodeDrainer,
+		SilenceUsage: true,
+		Hidden:       true,
+	}
+
+	nodeDrainerOpts = struct {
+		region       string
+		clusterName  string
+		queueName    string
+		drainTimeout time.Duration
+		kubeconfig   string
+		context      string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdNodeDrainer)
+	cmdNodeDrainer.Flags().StringVar(&nodeDrainerOpts.region, "region", "", "The AWS region of the cluster")
+	cmdNodeDrainer.Flags().StringVar(&nodeDrainerOpts.clusterName, "cluster-name", "", "The name of the cluster. Lifecycle actions of auto scaling groups not tagged with kubernetes.io/cluster/<cluster-name> are ignored")
+	cmdNodeDrainer.Flags().StringVar(&nodeDrainerOpts.queueName, "queue-name", "", "The name of the SQS queue to receive lifecycle actions and spot interruption warnings from")
+	cmdNodeDrainer.Flags().DurationVar(&nodeDrainerOpts.drainTimeout, "drain-timeout", 5*time.Minute, "Maximum time to wait for a node to be drained")
+	cmdNodeDrainer.Flags().StringVar(&nodeDrainerOpts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig. The service account of the pod is used when omitted")
+	cmdNodeDrainer.Flags().StringVar(&nodeDrainerOpts.context, "context", "", "The kubeconfig context to use. Defaults to the current context")
+}
+
+func runCmdNodeDrainer(_ *cobra.Command, _ []string) error {
+	if err := validateRequired(
+		flag{"--region", nodeDrainerOpts.region},
+		flag{"--cluster-name", nodeDrainerOpts.clusterName},
+		flag{"--queue-name", nodeDrainerOpts.queueName},
+	); err != nil {
+		return err
+	}
+
+	var kube *kubeclient.Client
+	var err error
+	if nodeDrainerOpts.kubeconfig != "" {
+		kube, err = kubeclient.NewFromKubeconfig(nodeDrainerOpts.kubeconfig, nodeDrainerOpts.context)
+	} else {
+		kube, err = kubeclient.NewInCluster()
+	}
+	if err != nil {
+		return err
+	}
+
+	session, err := awsconn.NewSessionFromRegion(api.RegionForName(nodeDrainerOpts.region), false, "")
+	if err != nil {
+		return err
+	}
+	sqsSvc := sqs.New(session)
+	queue, err := sqsSvc.GetQueueUrl(&sqs.GetQueueUrlInput{QueueName: aws.String(nodeDrainerOpts.queueName)})
+	if err != nil {
+		return fmt.Errorf("failed to get the url of queue %s: %v", nodeDrainerOpts.queueName, err)
+	}
+
+	drainer := nodedrainer.New(
+		nodedrainer.Config{QueueURL: aws.StringValue(queue.QueueUrl), ClusterName: nodeDrainerOpts.clusterName, DrainTimeout: nodeDrainerOpts.drainTimeout},
+		kube,
+		sqsSvc,
+		autoscaling.New(session),
+	)
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		logger.Info("stopping after the messages being handled are done")
+		close(stop)
+	}()
+
+	logger.Infof("receiving messages from %s", aws.StringValue(queue.QueueUrl))
+	return drainer.Run(stop)
+}
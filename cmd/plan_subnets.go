@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root/config"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdPlanSubnets = &cobra.Command{
+		Use:   "plan-subnets",
+		Short: "Compute non-overlapping subnet CIDRs out of the VPC CIDR",
+		Long: `Compute non-overlapping subnet CIDRs for each availability zone and subnet tier out of the VPC CIDR.
+Subnets already defined in cluster.yaml are kept as-is and CIDR blocks reserved by the previous plan are used first.
+The planned subnets are printed, or written into cluster.yaml when --write is specified.`,
+		RunE:         runCmdPlanSubnets,
+		SilenceUsage: true,
+	}
+
+	planSubnetsOpts = struct {
+		vpcCIDR           string
+		availabilityZones []string
+		tiers             []string
+		reservedPerTier   int
+		write             bool
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdPlanSubnets)
+	cmdPlanSubnets.Flags().StringVar(&planSubnetsOpts.vpcCIDR, "vpc-cidr", "", "The CIDR of the VPC. Defaults to vpcCIDR in cluster.yaml")
+	cmdPlanSubnets.Flags().StringSliceVar(&planSubnetsOpts.availabilityZones, "availability-zones", nil, "The AWS availability-zones to plan subnets for")
+	cmdPlanSubnets.Flags().StringSliceVar(&planSubnetsOpts.tiers, "tiers", []string{"public:24"}, "The subnets to plan per availability zone in the `[<name>=]<public|private>:<prefix length>` notation e.g. public:24,private:20")
+	cmdPlanSubnets.Flags().IntVar(&planSubnetsOpts.reservedPerTier, "reserved-per-tier", 1, "The number of CIDR blocks kept reserved per tier for future node pools or availability zones")
+	cmdPlanSubnets.Flags().BoolVar(&planSubnetsOpts.write, "write", false, "Write the planned subnets into cluster.yaml")
+}
+
+func runCmdPlanSubnets(_ *cobra.Command, _ []string) error {
+	if len(planSubnetsOpts.availabilityZones) == 0 {
+		return fmt.Errorf("missing required flag: --availability-zones")
+	}
+
+	inputs := &config.SubnetPlanInputs{}
+	if _, err := os.Stat(configPath); err == nil {
+		if inputs, err = config.SubnetPlanInputsFromFile(configPath); err != nil {
+			return err
+		}
+	} else if planSubnetsOpts.write {
+		return fmt.Errorf("--write requires %s to exist: %v", configPath, err)
+	}
+
+	vpcCIDR := planSubnetsOpts.vpcCIDR
+	if vpcCIDR == "" {
+		vpcCIDR = inputs.VPCCIDR
+	}
+	if vpcCIDR == "" {
+		vpcCIDR = api.NewDefaultCluster().VPCCIDR
+	}
+
+	tiers := []api.SubnetTier{}
+	for _, t := range planSubnetsOpts.tiers {
+		tier, err := api.ParseSubnetTier(t)
+		if err != nil {
+			return err
+		}
+		tiers = append(tiers, tier)
+	}
+
+	// The subnet created from the top-level instanceCIDR is kept by reserving its CIDR for the first public tier, which reuses it when the sizes match
+	reserved, err := config.ReserveInstanceCIDR(inputs.Reserved, inputs.InstanceCIDR, inputs.Subnets, instanceCIDRTier(tiers))
+	if err != nil {
+		return err
+	}
+
+	plan := api.SubnetPlan{
+		VPCCIDR:           vpcCIDR,
+		AvailabilityZones: planSubnetsOpts.availabilityZones,
+		Tiers:             tiers,
+		ReservedPerTier:   planSubnetsOpts.reservedPerTier,
+		Reserved:          reserved,
+	}
+	allocation, err := plan.Allocate(inputs.Subnets)
+	if err != nil {
+		return fmt.Errorf("failed to plan subnets: %v", err)
+	}
+
+	subnets := append(append(api.Subnets{}, inputs.Subnets...), allocation.Subnets...)
+
+	if !planSubnetsOpts.write {
+		rendered, err := config.RenderSubnets(subnets, allocation.Reserved)
+		if err != nil {
+			return err
+		}
+		logSubnetAllocation(allocation)
+		fmt.Printf("vpcCIDR: \"%s\"\n\n%s", vpcCIDR, rendered)
+		return nil
+	}
+
+	if err := config.WriteSubnets(configPath, vpcCIDR, subnets, allocation.Reserved); err != nil {
+		return err
+	}
+	logSubnetAllocation(allocation)
+	logger.Infof("Wrote %d planned subnet(s) into %s\n", len(allocation.Subnets), configPath)
+	return nil
+}
+
+func instanceCIDRTier(tiers []api.SubnetTier) string {
+	for _, t := range tiers {
+		if !t.Private {
+			return t.Name
+		}
+	}
+	if len(tiers) == 0 {
+		return "Public"
+	}
+	return tiers[0].Name
+}
+
+func logSubnetAllocation(allocation *api.SubnetAllocation) {
+	logger.Heading("Planned subnets\n")
+	for _, s := range allocation.Subnets {
+		visibility := "public"
+		if s.Private {
+			visibility = "private"
+		}
+		logger.Infof("  %s\t%s\t%s\t%s\n", s.Name, visibility, s.AvailabilityZone, s.InstanceCIDR)
+	}
+
+	tiers := []string{}
+	for t := range allocation.Reserved {
+		tiers = append(tiers, t)
+	}
+	sort.Strings(tiers)
+	for _, t := range tiers {
+		logger.Infof("  reserved for %s: %s\n", t, strings.Join(allocation.Reserved[t], ", "))
+	}
+	logger.Infof("  free: %s\n", strings.Join(allocation.Free, ", "))
+}
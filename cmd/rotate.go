@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdRotate = &cobra.Command{
+		Use:          "rotate",
+		Short:        "Rotate keys of the cluster",
+		Long:         ``,
+		SilenceUsage: true,
+	}
+
+	cmdRotateEncryptionKey = &cobra.Command{
+		Use:   "encryption-key",
+		Short: "Rotate the key secrets are encrypted at rest with",
+		Long: `Rotate the key kube-apiserver encrypts secrets at rest with in the following steps:
+
+  1. add-key:         add a new key to the encryption config as a secondary key and roll controller nodes
+  2. promote-key:     make the new key the primary key and roll controller nodes
+  3. rewrite-secrets: rewrite all the secrets with the kubeconfig so that they are re-encrypted with the new key
+  4. remove-old-keys: remove the old keys from the encryption config and roll controller nodes
+
+The progress is recorded in credentials/encryption-key-rotation.json. Run the command again to resume an interrupted rotation.
+Note that each rolling update of controller nodes applies any other pending change to the control plane as well.`,
+		RunE:         runCmdRotateEncryptionKey,
+		SilenceUsage: true,
+	}
+
+	rotateEncryptionKeyOpts = struct {
+		provider   string
+		kubeconfig string
+		context    string
+		force      bool
+		awsDebug   bool
+		profile    string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdRotate)
+	cmdRotate.AddCommand(cmdRotateEncryptionKey)
+
+	cmdRotateEncryptionKey.Flags().StringVar(&rotateEncryptionKeyOpts.provider, "provider", "", "The type of the new key. Either aescbc or kms. Defaults to kms when kubernetes.encryptionAtRest.kms is enabled, or aescbc otherwise")
+	cmdRotateEncryptionKey.Flags().StringVar(&rotateEncryptionKeyOpts.kubeconfig, "kubeconfig", "kubeconfig", "Path to the kubeconfig used to rewrite secrets")
+	cmdRotateEncryptionKey.Flags().StringVar(&rotateEncryptionKeyOpts.context, "context", "", "The kubeconfig context to use. Defaults to the current context")
+	cmdRotateEncryptionKey.Flags().BoolVar(&rotateEncryptionKeyOpts.force, "force", false, "Don't ask for confirmation")
+	cmdRotateEncryptionKey.Flags().BoolVar(&rotateEncryptionKeyOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdRotateEncryptionKey.Flags().StringVar(&rotateEncryptionKeyOpts.profile, "profile", "", "The AWS profile to use from credentials file")
+}
+
+func runCmdRotateEncryptionKey(_ *cobra.Command, _ []string) error {
+	if !rotateEncryptionKeyOpts.force && !rotateEncryptionKeyConfirmation() {
+		logger.Info("Operation cancelled")
+		return nil
+	}
+
+	// Each step must wait for all the controller nodes to be replaced before proceeding to the next one
+	opts := root.NewOptions(false, false, rotateEncryptionKeyOpts.profile)
+
+	err := root.RotateEncryptionKey(configPath, opts, rotateEncryptionKeyOpts.awsDebug, root.RotateEncryptionKeyOptions{
+		Provider:   rotateEncryptionKeyOpts.provider,
+		Kubeconfig: rotateEncryptionKeyOpts.kubeconfig,
+		Context:    rotateEncryptionKeyOpts.context,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rotate encryption key: %v", err)
+	}
+	return nil
+}
+
+func rotateEncryptionKeyConfirmation() bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("This operation will update the control plane up to three times. Are you sure? [y,n]: ")
+	text, _ := reader.ReadString('\n')
+	text = strings.TrimSuffix(strings.ToLower(text), "\n")
+
+	return text == "y" || text == "yes"
+}
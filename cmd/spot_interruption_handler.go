@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/nodedrainer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdSpotInterruptionHandler = &cobra.Command{
+		Use:   "spot-interruption-handler",
+		Short: "Drain the node on spot instance interruption notices",
+		Long: `Drain the node on spot instance interruption notices.
+
+The instance metadata is polled for spot instance interruption notices and EC2 instance rebalance recommendations.
+The node is cordoned and drained with the eviction API on an interruption notice, and optionally on a rebalance recommendation.
+Both are recorded as events of the node and exposed as Prometheus metrics.
+This runs inside the cluster as the kube-spot-interruption-handler daemonsets with the kubelet credentials of the node,
+so that it can act on no other nodes and pods, and isn't meant to be run by hand.`,
+		RunE:         runCmdSpotInterruptionHandler,
+		SilenceUsage: true,
+		Hidden:       true,
+	}
+
+	spotInterruptionHandlerOpts = struct {
+		nodeName                       string
+		drainTimeout                   time.Duration
+		pollInterval                   time.Duration
+		drainOnRebalanceRecommendation bool
+		metricsAddress                 string
+		kubeconfig                     string
+		context                        string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdSpotInterruptionHandler)
+	cmdSpotInterruptionHandler.Flags().StringVar(&spotInterruptionHandlerOpts.nodeName, "node-name", "", "The name of the node this runs on")
+	cmdSpotInterruptionHandler.Flags().DurationVar(&spotInterruptionHandlerOpts.drainTimeout, "drain-timeout", nodedrainer.DefaultSpotDrainTimeout, "Maximum time to wait for the node to be drained")
+	cmdSpotInterruptionHandler.Flags().DurationVar(&spotInterruptionHandlerOpts.pollInterval, "poll-interval", nodedrainer.DefaultSpotPollInterval, "Interval of polling the instance metadata")
+	cmdSpotInterruptionHandler.Flags().BoolVar(&spotInterruptionHandlerOpts.drainOnRebalanceRecommendation, "drain-on-rebalance-recommendation", false, "Drain the node on EC2 instance rebalance recommendations, too")
+	cmdSpotInterruptionHandler.Flags().StringVar(&spotInterruptionHandlerOpts.metricsAddress, "metrics-address", ":9253", "The address to serve Prometheus metrics on")
+	cmdSpotInterruptionHandler.Flags().StringVar(&spotInterruptionHandlerOpts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig. The service account of the pod is used when omitted")
+	cmdSpotInterruptionHandler.Flags().StringVar(&spotInterruptionHandlerOpts.context, "context", "", "The kubeconfig context to use. Defaults to the current context")
+}
+
+func runCmdSpotInterruptionHandler(_ *cobra.Command, _ []string) error {
+	if err := validateRequired(
+		flag{"--node-name", spotInterruptionHandlerOpts.nodeName},
+	); err != nil {
+		return err
+	}
+
+	var kube *kubeclient.Client
+	var err error
+	if spotInterruptionHandlerOpts.kubeconfig != "" {
+		kube, err = kubeclient.NewFromKubeconfig(spotInterruptionHandlerOpts.kubeconfig, spotInterruptionHandlerOpts.context)
+	} else {
+		kube, err = kubeclient.NewInCluster()
+	}
+	if err != nil {
+		return err
+	}
+
+	handler := nodedrainer.NewSpotHandler(
+		nodedrainer.SpotHandlerConfig{
+			NodeName:                       spotInterruptionHandlerOpts.nodeName,
+			DrainTimeout:                   spotInterruptionHandlerOpts.drainTimeout,
+			PollInterval:                   spotInterruptionHandlerOpts.pollInterval,
+			DrainOnRebalanceRecommendation: spotInterruptionHandlerOpts.drainOnRebalanceRecommendation,
+		},
+		kube,
+		nodedrainer.NewIMDSClient(),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler.Metrics)
+	go func() {
+		if err := http.ListenAndServe(spotInterruptionHandlerOpts.metricsAddress, mux); err != nil {
+			logger.Errorf("failed to serve metrics on %s: %v", spotInterruptionHandlerOpts.metricsAddress, err)
+		}
+	}()
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	logger.Infof("polling the instance metadata of node %s", spotInterruptionHandlerOpts.nodeName)
+	return handler.Run(stop)
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root"
+	"github.com/kubernetes-incubator/kube-aws/core/root/defaults"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdUpgrade = &cobra.Command{
+		Use:          "upgrade",
+		Short:        "Upgrade components of your cluster",
+		Long:         ``,
+		SilenceUsage: true,
+	}
+
+	cmdUpgradeKubernetes = &cobra.Command{
+		Use:   "kubernetes",
+		Short: "Upgrade Kubernetes of your cluster to the specified version",
+		Long: `Upgrade Kubernetes of your cluster to the version specified with --to.
+
+The control plane is upgraded first. Once the API server reports healthy, node pools are rolled in the order defined by their nodePoolRollingStrategy.
+Progress is recorded in a state file so that an interrupted upgrade can be resumed by running the same command again.`,
+		RunE:         runCmdUpgradeKubernetes,
+		SilenceUsage: true,
+	}
+
+	upgradeKubernetesOpts = struct {
+		awsDebug, prettyPrint bool
+		force                 bool
+		profile               string
+		to                    string
+		stateFile             string
+		apiHealthTimeout      time.Duration
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdUpgrade)
+	cmdUpgrade.AddCommand(cmdUpgradeKubernetes)
+
+	cmdUpgradeKubernetes.Flags().StringVar(&upgradeKubernetesOpts.to, "to", "", "The Kubernetes version to upgrade to e.g. v1.15.5")
+	cmdUpgradeKubernetes.Flags().StringVar(&upgradeKubernetesOpts.stateFile, "state-file", defaults.UpgradeStateFile, "Path to the file recording the progress of the upgrade")
+	cmdUpgradeKubernetes.Flags().DurationVar(&upgradeKubernetesOpts.apiHealthTimeout, "api-health-timeout", 10*time.Minute, "How long to wait for the API server to become healthy after upgrading the control plane")
+	cmdUpgradeKubernetes.Flags().BoolVar(&upgradeKubernetesOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdUpgradeKubernetes.Flags().BoolVar(&upgradeKubernetesOpts.prettyPrint, "pretty-print", false, "Pretty print the resulting CloudFormation")
+	cmdUpgradeKubernetes.Flags().BoolVar(&upgradeKubernetesOpts.force, "force", false, "Don't ask for confirmation")
+	cmdUpgradeKubernetes.Flags().StringVar(&upgradeKubernetesOpts.profile, "profile", "", "The AWS profile to use from credentials file")
+}
+
+func runCmdUpgradeKubernetes(_ *cobra.Command, _ []string) error {
+	if err := validateRequired(flag{"--to", upgradeKubernetesOpts.to}); err != nil {
+		return err
+	}
+	to := upgradeKubernetesOpts.to
+
+	opts := root.NewOptions(upgradeKubernetesOpts.prettyPrint, false, upgradeKubernetesOpts.profile)
+
+	state, err := root.LoadUpgradeState(upgradeKubernetesOpts.stateFile)
+	if err != nil {
+		return err
+	}
+
+	if state != nil {
+		if state.To != to {
+			return fmt.Errorf("an upgrade from %s to %s is already in progress according to %s. Finish it first, or remove the file to abandon it", state.From, state.To, upgradeKubernetesOpts.stateFile)
+		}
+		logger.Infof("Resuming the upgrade from kubernetes %s to %s started at %s\n", state.From, state.To, state.StartedAt.Format(time.RFC3339))
+	} else {
+		cluster, err := root.LoadClusterFromFile(configPath, opts, upgradeKubernetesOpts.awsDebug)
+		if err != nil {
+			return fmt.Errorf("failed to read cluster config: %v", err)
+		}
+		from := cluster.Cfg.K8sVer
+
+		if err := root.CheckKubernetesVersionSkew(from, to, cluster.Cfg.Etcd.Version()); err != nil {
+			return fmt.Errorf("refusing to upgrade: %v", err)
+		}
+
+		if !upgradeKubernetesOpts.force && !upgradeKubernetesConfirmation(from, to) {
+			logger.Info("Operation cancelled")
+			return nil
+		}
+
+		state = root.NewUpgradeState(upgradeKubernetesOpts.stateFile, from, to)
+		if err := state.Save(); err != nil {
+			return err
+		}
+
+		if err := root.SetKubernetesVersion(configPath, to); err != nil {
+			return err
+		}
+		logger.Infof("Updated kubernetesVersion in %s to %s\n", configPath, to)
+	}
+
+	cluster, err := root.LoadClusterFromFile(configPath, opts, upgradeKubernetesOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %v", err)
+	}
+
+	if _, err := cluster.ValidateStack(); err != nil {
+		return err
+	}
+
+	if err := cluster.UpgradeKubernetes(state, upgradeKubernetesOpts.apiHealthTimeout); err != nil {
+		return fmt.Errorf("error upgrading cluster: %v. Run the same command again to resume the upgrade", err)
+	}
+
+	if err := state.Remove(); err != nil {
+		return err
+	}
+
+	logger.Infof("Success! Your cluster has been upgraded to kubernetes %s\n", to)
+	return nil
+}
+
+func upgradeKubernetesConfirmation(from, to string) bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("This operation will upgrade kubernetes of the cluster from %s to %s. Are you sure? [y,n]: ", from, to)
+	text, _ := reader.ReadString('\n')
+	text = strings.TrimSuffix(strings.ToLower(text), "\n")
+
+	return text == "y" || text == "yes"
+}
@@ -48,6 +48,12 @@ func runCmdValidate(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to initialize cluster driver: %v", err)
 	}
 
+	if usage, err := cluster.Cfg.SubnetUsage(); err != nil {
+		return fmt.Errorf("invalid subnets: %v", err)
+	} else {
+		logger.Infof("Subnet allocation: %s\n", usage)
+	}
+
 	logger.Info("Validating UserData and stack template...\n")
 
 	targets := root.OperationTargetsFromStringSlice(validateOpts.targets)
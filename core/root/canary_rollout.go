@@ -0,0 +1,236 @@
+package root
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/kubernetes-incubator/kube-aws/cfnstack"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/kubernetes-incubator/kube-aws/pkg/model"
+)
+
+// The templates of the stacks rolled along with the canary node pool before the rollout are uploaded next to the current ones, so that the stacks can be rolled back to them
+const canaryRollbackTemplateFilename = "stack.rollback.json"
+
+type canaryHealthCheckFunc func(api.CanaryHealthCheck) error
+
+// canaryNodePool returns the targeted node pool with the "Canary" NodePoolRollingStrategy, if any
+func (cl *Cluster) canaryNodePool(targets OperationTargets) *model.Stack {
+	for _, np := range cl.nodePoolStacks {
+		if np.NodePoolConfig.NodePoolRollingStrategy == "Canary" && targets.IncludeWorker(np.StackName) {
+			return np
+		}
+	}
+	return nil
+}
+
+// canaryRollback is what the stacks rolled along with the canary node pool are rolled back to when the canary fails its health checks
+type canaryRollback struct {
+	rootTemplate string
+	// templates are the templates of the nested stacks before the rollout, by stack name
+	templates map[string]string
+}
+
+// updateWithCanary rolls the canary node pool along with the targeted stacks other than node pools, and then the other node pools
+// only after all the health checks of the canary node pool pass.
+// When they don't pass, the root stack and all the stacks rolled along with the canary node pool are rolled back to the templates they had
+// before the rollout and the other node pools are left as is
+func (cl *Cluster) updateWithCanary(cfSvc *cloudformation.CloudFormation, targets OperationTargets, canary *model.Stack) (string, error) {
+	name := canary.NodePoolConfig.NodePoolName
+	settings := canary.NodePoolConfig.Canary
+
+	if _, err := getNestedStackName(cfSvc, cl.stackName(), canary.NestedStackName()); err != nil {
+		logger.Infof("Canary node pool %s doesn't exist yet. Rolling all the node pools at once\n", name)
+		return cl.updateStack(cfSvc, targets)
+	}
+
+	first := OperationTargets{}
+	others := OperationTargets{}
+	for _, t := range targets {
+		if t != canary.StackName && cl.isNodePoolStack(t) {
+			others = append(others, t)
+			continue
+		}
+		first = append(first, t)
+	}
+
+	rollback, err := cl.currentTemplates(cfSvc, first)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the templates to roll back canary node pool %s to: %v", name, err)
+	}
+
+	logger.Headingf("Rolling canary node pool %s along with stack(s) %s", name, first.String())
+	report, err := cl.updateStack(cfSvc, first)
+	if err != nil {
+		return "", fmt.Errorf("failed to roll canary node pool %s. Aborted rolling node pool(s) %s: %v", name, others.String(), err)
+	}
+
+	logger.Headingf("Running health checks of canary node pool %s", name)
+	timeout := time.Duration(settings.TimeoutOrDefault()) * time.Second
+	interval := time.Duration(settings.IntervalOrDefault()) * time.Second
+	if err := waitForCanaryHealthy(settings.HealthChecks, runCanaryHealthCheck, timeout, interval); err != nil {
+		if settings.DisableRollback {
+			return "", fmt.Errorf("canary node pool %s failed its health checks. Aborted rolling node pool(s) %s and kept stack(s) %s updated: %v", name, others.String(), first.String(), err)
+		}
+		logger.Headingf("Rolling back stack(s) %s", first.String())
+		if rerr := cl.rollbackStacks(cfSvc, rollback); rerr != nil {
+			return "", fmt.Errorf("canary node pool %s failed its health checks: %v. Aborted rolling node pool(s) %s but failed to roll back stack(s) %s, which are left updated: %v", name, err, others.String(), first.String(), rerr)
+		}
+		return "", fmt.Errorf("canary node pool %s failed its health checks. Rolled back stack(s) %s and aborted rolling node pool(s) %s: %v", name, first.String(), others.String(), err)
+	}
+	logger.Infof("Canary node pool %s passed all the health checks\n", name)
+
+	if len(others) == 0 {
+		return report, nil
+	}
+	logger.Headingf("Rolling node pool(s) %s", others.String())
+	return cl.updateStack(cfSvc, targets)
+}
+
+func (cl *Cluster) isNodePoolStack(stackName string) bool {
+	for _, np := range cl.nodePoolStacks {
+		if np.StackName == stackName {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedStack returns the nested stack of the name, or nil when there's no such stack
+func (cl *Cluster) nestedStack(stackName string) *model.Stack {
+	stacks := append([]*model.Stack{cl.networkStack, cl.controlPlaneStack, cl.etcdStack}, cl.nodePoolStacks...)
+	for _, s := range stacks {
+		if s.StackName == stackName {
+			return s
+		}
+	}
+	return nil
+}
+
+// currentTemplates returns the current templates of the root stack and the nested stacks
+func (cl *Cluster) currentTemplates(cfSvc *cloudformation.CloudFormation, stackNames OperationTargets) (*canaryRollback, error) {
+	rootTemplate, err := cl.getCurrentRootStackTemplate()
+	if err != nil {
+		return nil, err
+	}
+	rollback := &canaryRollback{rootTemplate: rootTemplate, templates: map[string]string{}}
+	for _, stackName := range stackNames {
+		s := cl.nestedStack(stackName)
+		if s == nil {
+			return nil, fmt.Errorf("unknown stack %s", stackName)
+		}
+		nestedStackName, err := getNestedStackName(cfSvc, cl.stackName(), s.NestedStackName())
+		if err != nil {
+			return nil, err
+		}
+		template, err := getStackTemplate(cfSvc, nestedStackName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the template of stack %s: %v", stackName, err)
+		}
+		rollback.templates[stackName] = template
+	}
+	return rollback, nil
+}
+
+// rollbackStacks updates the root stack back to its previous template, which points the nested stacks to their previous templates.
+// The previous templates are uploaded next to the current ones as the current ones are overwritten by the rollout
+func (cl *Cluster) rollbackStacks(cfSvc *cloudformation.CloudFormation, rollback *canaryRollback) error {
+	rootTemplate := rollback.rootTemplate
+	var assets cfnstack.Assets = cfnstack.EmptyAssets()
+	for stackName, template := range rollback.templates {
+		s := cl.nestedStack(stackName)
+		builder, err := cfnstack.NewAssetsBuilder(s.StackName, s.ClusterExportedStacksS3URI(), s.Region)
+		if err != nil {
+			return err
+		}
+		a, err := builder.Add(canaryRollbackTemplateFilename, template)
+		if err != nil {
+			return err
+		}
+		url, err := a.URL()
+		if err != nil {
+			return err
+		}
+		if rootTemplate, err = cl.setNestedStackTemplateURL(rootTemplate, stackName, url); err != nil {
+			return fmt.Errorf("failed to update stack template: %v", err)
+		}
+		assets = assets.Merge(builder.Build())
+	}
+
+	rootBuilder, err := cfnstack.NewAssetsBuilder(cl.stackName(), cl.exportedStacksS3URI(), cl.controlPlaneStack.Region)
+	if err != nil {
+		return err
+	}
+	if _, err := rootBuilder.Add(REMOTE_STACK_TEMPLATE_FILENAME, rootTemplate); err != nil {
+		return err
+	}
+
+	assets = assets.Merge(rootBuilder.Build())
+	if err := cl.uploadAssets(assets); err != nil {
+		return err
+	}
+	templateURL, err := cl.extractRootStackTemplateURL(assets)
+	if err != nil {
+		return err
+	}
+
+	_, err = cl.stackProvisioner().UpdateStackAtURLAndWait(cfSvc, templateURL)
+	return err
+}
+
+// waitForCanaryHealthy runs all the health checks every interval until they pass at once or the timeout expires.
+// Results are reported only when they change from the previous attempt, not to flood the output
+func waitForCanaryHealthy(checks []api.CanaryHealthCheck, check canaryHealthCheckFunc, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	results := map[string]string{}
+	for {
+		failures := []string{}
+		for _, h := range checks {
+			result := "passed"
+			if err := check(h); err != nil {
+				result = fmt.Sprintf("failed: %v", err)
+				failures = append(failures, fmt.Sprintf("%s: %v", h.Name, err))
+			}
+			if results[h.Name] != result {
+				logger.Infof("Health check %s %s\n", h.Name, result)
+				results[h.Name] = result
+			}
+		}
+		if len(failures) == 0 {
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("timed out waiting for the health checks to pass: %s", strings.Join(failures, ", "))
+		}
+		time.Sleep(interval)
+	}
+}
+
+func runCanaryHealthCheck(h api.CanaryHealthCheck) error {
+	if h.Command != "" {
+		out, err := exec.Command("sh", "-c", h.Command).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(h.HTTPGet.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if expected := h.HTTPGet.ExpectedStatusOrDefault(); resp.StatusCode != expected {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("expected status %d but got %d %s", expected, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
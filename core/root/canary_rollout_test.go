@@ -0,0 +1,83 @@
+package root
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/kubernetes-incubator/kube-aws/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForCanaryHealthy(t *testing.T) {
+	checks := []api.CanaryHealthCheck{{Name: "nodes-ready"}, {Name: "ingress"}}
+
+	attempts := map[string]int{}
+	check := func(h api.CanaryHealthCheck) error {
+		attempts[h.Name]++
+		if h.Name == "ingress" && attempts[h.Name] < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	require.NoError(t, waitForCanaryHealthy(checks, check, time.Second, time.Millisecond))
+	assert.Equal(t, map[string]int{"nodes-ready": 3, "ingress": 3}, attempts, "expected all the health checks to be retried until they pass at once")
+}
+
+func TestWaitForCanaryHealthyTimeout(t *testing.T) {
+	checks := []api.CanaryHealthCheck{{Name: "nodes-ready"}, {Name: "ingress"}}
+	check := func(h api.CanaryHealthCheck) error {
+		if h.Name == "ingress" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	err := waitForCanaryHealthy(checks, check, 10*time.Millisecond, time.Millisecond)
+	require.EqualError(t, err, "timed out waiting for the health checks to pass: ingress: connection refused")
+}
+
+func TestRunCanaryHealthCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			w.Write([]byte("ok"))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("not ready"))
+	}))
+	defer server.Close()
+
+	assert.NoError(t, runCanaryHealthCheck(api.CanaryHealthCheck{Name: "cmd", Command: "test 1 -eq 1"}))
+	assert.EqualError(t, runCanaryHealthCheck(api.CanaryHealthCheck{Name: "cmd", Command: "echo unhealthy; exit 3"}), "exit status 3: unhealthy")
+
+	assert.NoError(t, runCanaryHealthCheck(api.CanaryHealthCheck{Name: "http", HTTPGet: &api.HTTPGetHealthCheck{URL: server.URL + "/healthz"}}))
+	assert.EqualError(t, runCanaryHealthCheck(api.CanaryHealthCheck{Name: "http", HTTPGet: &api.HTTPGetHealthCheck{URL: server.URL + "/ready"}}),
+		"expected status 200 but got 503 not ready")
+	assert.NoError(t, runCanaryHealthCheck(api.CanaryHealthCheck{Name: "http", HTTPGet: &api.HTTPGetHealthCheck{URL: server.URL + "/ready", ExpectedStatus: 503}}))
+}
+
+func TestNodePoolRolloutBatches(t *testing.T) {
+	pool := func(stackName, strategy string) *model.Stack {
+		np := api.NewDefaultNodePoolConfig()
+		np.NodePoolName = stackName
+		np.NodePoolRollingStrategy = strategy
+		return &model.Stack{StackName: stackName, NodePoolConfig: &model.NodePoolConfig{WorkerNodePool: np}}
+	}
+	c := Cluster{nodePoolStacks: []*model.Stack{
+		pool("pool1", "Sequential"),
+		pool("pool2", "Surge"),
+		pool("pool3", "Canary"),
+		pool("pool4", "Parallel"),
+		pool("pool5", "Sequential"),
+	}}
+
+	batches, err := c.nodePoolRolloutBatches()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"pool3"}, {"pool2", "pool4"}, {"pool1"}, {"pool5"}}, batches)
+}
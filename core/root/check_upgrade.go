@@ -0,0 +1,64 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/kubernetes-incubator/kube-aws/apideprecation"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+)
+
+// ScanDeprecatedAPIs renders the kubernetes manifests and the charts of helm releases contributed by plugins and reports
+// the objects in them that use API versions deprecated or removed in the version the scanner targets.
+// This doesn't require access to AWS or the cluster, but to the chart repositories of the helm releases.
+func (cl *Cluster) ScanDeprecatedAPIs(s *apideprecation.Scanner, charts apideprecation.ChartRenderer) ([]apideprecation.Finding, error) {
+	extraController, err := cl.extras.Controller(cl.Cfg.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render plugin manifests: %v", err)
+	}
+
+	findings := []apideprecation.Finding{}
+
+	for _, m := range extraController.KubernetesManifestFiles {
+		content, err := m.RenderContent(cl.Cfg.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render manifest %s: %v", m.Path, err)
+		}
+		if content == "" {
+			logger.Warnf("Skipped %s as its content is resolved by CloudFormation at deployment time\n", m.Path)
+			continue
+		}
+		fs, err := s.ScanManifest(m.Path, []byte(content))
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, fs...)
+	}
+
+	for _, r := range extraController.HelmReleaseFilesets {
+		release := struct {
+			Chart struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"chart"`
+		}{}
+		if err := json.Unmarshal([]byte(r.ReleaseFile.Content.String()), &release); err != nil {
+			return nil, fmt.Errorf("failed to parse helm release %s: %v", r.ReleaseFile.Path, err)
+		}
+		// Release files are written to the directory named after the release
+		name := filepath.Base(filepath.Dir(r.ReleaseFile.Path))
+		rendered, err := charts.Render(name, release.Chart.Name, release.Chart.Version, []byte(r.ValuesFile.Content.String()))
+		if err != nil {
+			return nil, err
+		}
+		source := fmt.Sprintf("helm release %s (chart %s %s)", name, release.Chart.Name, release.Chart.Version)
+		fs, err := s.ScanManifest(source, rendered)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, fs...)
+	}
+
+	return findings, nil
+}
@@ -258,10 +258,13 @@ func (cl *Cluster) ensureNestedStacksLoaded() error {
 }
 
 func (cl *Cluster) GenerateAssetsOnDisk(dir string, opts credential.GeneratorOptions) (*credential.RawAssetsOnDisk, error) {
-	a, err := model.GenerateAssetsOnDisk(cl.session, cl.Cfg.Config, dir, opts)
+	a, err := cl.context().GenerateAssetsOnDisk(cl.Cfg.Config, dir, opts)
 	if err != nil {
 		return nil, err
 	}
+	if err := cl.RecordSignedCertificates(dir, a); err != nil {
+		return nil, fmt.Errorf("failed to record certificates to the issuance ledger: %v", err)
+	}
 	kmsConfig := credential.NewKMSConfig(cl.Cfg.KMSKeyARN, nil, cl.session)
 	enc := kmsConfig.Encryptor()
 	p := credential.NewProtectedPKI(enc)
@@ -323,10 +326,11 @@ type renderer interface {
 }
 
 type diffSetting struct {
-	stackName      string
-	renderer       renderer
-	userdata       *api.UserData
-	launchConfName string
+	stackName string
+	renderer  renderer
+	userdata  *api.UserData
+	// launchNames are the logical names of the launch template and the legacy launch configuration the userdata is read from
+	launchNames []string
 }
 
 func (cl *Cluster) Diff(opts OperationTargets, context int) ([]*DiffResult, error) {
@@ -345,7 +349,7 @@ func (cl *Cluster) Diff(opts OperationTargets, context int) ([]*DiffResult, erro
 		includeAll = includeAll && opts.IncludeWorker(np.StackName)
 	}
 	if isAll || includeAll {
-		mappings["root"] = diffSetting{cl.stackName(), cl, nil, ""}
+		mappings["root"] = diffSetting{cl.stackName(), cl, nil, nil}
 	}
 
 	if isAll || opts.IncludeNetwork(cl.networkStack.Config.NetworkStackName()) {
@@ -353,7 +357,7 @@ func (cl *Cluster) Diff(opts OperationTargets, context int) ([]*DiffResult, erro
 		if err != nil {
 			return nil, err
 		}
-		mappings["network"] = diffSetting{stackName, cl.networkStack, nil, ""}
+		mappings["network"] = diffSetting{stackName, cl.networkStack, nil, nil}
 	}
 
 	staticEtcdIndex := 0
@@ -362,7 +366,8 @@ func (cl *Cluster) Diff(opts OperationTargets, context int) ([]*DiffResult, erro
 		if err != nil {
 			return nil, err
 		}
-		mappings["etcd"] = diffSetting{stackName, cl.etcdStack, cl.etcdStack.GetUserData("Etcd"), cl.etcdStack.Config.EtcdNodes[staticEtcdIndex].LaunchConfigurationLogicalName()}
+		etcdNode := cl.etcdStack.Config.EtcdNodes[staticEtcdIndex]
+		mappings["etcd"] = diffSetting{stackName, cl.etcdStack, cl.etcdStack.GetUserData("Etcd"), []string{etcdNode.LaunchTemplateLogicalName(), etcdNode.LaunchConfigurationLogicalName()}}
 	}
 
 	if isAll || opts.IncludeControlPlane(cl.controlPlaneStack.Config.ControlPlaneStackName()) {
@@ -370,7 +375,8 @@ func (cl *Cluster) Diff(opts OperationTargets, context int) ([]*DiffResult, erro
 		if err != nil {
 			return nil, err
 		}
-		mappings["controller"] = diffSetting{stackName, cl.controlPlaneStack, cl.controlPlaneStack.GetUserData("Controller"), cl.controlPlaneStack.Config.Controller.LaunchConfigurationLogicalName()}
+		controller := cl.controlPlaneStack.Config.Controller
+		mappings["controller"] = diffSetting{stackName, cl.controlPlaneStack, cl.controlPlaneStack.GetUserData("Controller"), []string{controller.LaunchTemplateLogicalName(), controller.LaunchConfigurationLogicalName()}}
 	}
 
 	for _, np := range cl.nodePoolStacks {
@@ -380,7 +386,8 @@ func (cl *Cluster) Diff(opts OperationTargets, context int) ([]*DiffResult, erro
 				return nil, err
 			}
 			id := fmt.Sprintf("worker-%s", np.StackName)
-			mappings[id] = diffSetting{stackName, np, np.GetUserData("Worker"), np.NodePoolConfig.WorkerNodePool.LaunchConfigurationLogicalName()}
+			pool := np.NodePoolConfig.WorkerNodePool
+			mappings[id] = diffSetting{stackName, np, np.GetUserData("Worker"), []string{pool.LaunchTemplateLogicalName(), pool.LaunchConfigurationLogicalName()}}
 		}
 	}
 
@@ -404,7 +411,7 @@ func (cl *Cluster) Diff(opts OperationTargets, context int) ([]*DiffResult, erro
 		diffResults = append(diffResults, stackDiffSummary)
 
 		if len(stackDiffOutput) > 0 && setting.userdata != nil {
-			currentInsScriptUserdata, err := getInstanceScriptUserdata(currentStack, setting.launchConfName)
+			currentInsScriptUserdata, err := getInstanceScriptUserdata(currentStack, setting.launchNames)
 			if err != nil {
 				return nil, fmt.Errorf("failed to obtain %s instance userdata template: %v", id, err)
 			}
@@ -420,7 +427,7 @@ func (cl *Cluster) Diff(opts OperationTargets, context int) ([]*DiffResult, erro
 			insScriptUserdataDiffSummary := &DiffResult{fmt.Sprintf("%s-userdata-instance-script", id), insScriptUserdataDiffOutput}
 			diffResults = append(diffResults, insScriptUserdataDiffSummary)
 
-			currentInsUserdata, err := getInstanceUserdataJson(currentStack, setting.launchConfName)
+			currentInsUserdata, err := getInstanceUserdataJson(currentStack, setting.launchNames)
 			if err != nil {
 				return nil, fmt.Errorf("failed to obtain %s instance userdata template: %v", id, err)
 			}
@@ -542,11 +549,7 @@ func (cl *Cluster) generateAssets(targets OperationTargets) (cfnstack.Assets, er
 		Merge(etcdAssets).
 		Merge(wAssets)
 
-	s3URI := fmt.Sprintf("%s/kube-aws/clusters/%s/exported/stacks",
-		strings.TrimSuffix(cl.s3URI(), "/"),
-		cl.controlPlaneStack.ClusterName,
-	)
-	rootStackAssetsBuilder, err := cfnstack.NewAssetsBuilder(cl.stackName(), s3URI, cl.controlPlaneStack.Region)
+	rootStackAssetsBuilder, err := cfnstack.NewAssetsBuilder(cl.stackName(), cl.exportedStacksS3URI(), cl.controlPlaneStack.Region)
 	if err != nil {
 		return nil, err
 	}
@@ -564,14 +567,15 @@ func (cl *Cluster) generateAssets(targets OperationTargets) (cfnstack.Assets, er
 		}
 		stackTemplate = renderedTemplate
 	} else {
+		rootStackTemplate, err := cl.getCurrentRootStackTemplate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template : %v", err)
+		}
+		stackTemplate = rootStackTemplate
+
 		for _, target := range targets {
 			logger.Infof("updating template url of %s\n", target)
 
-			rootStackTemplate, err := cl.getCurrentRootStackTemplate()
-			if err != nil {
-				return nil, fmt.Errorf("failed to render template : %v", err)
-			}
-
 			a, err := nestedStacksAssets.FindAssetByStackAndFileName(target, REMOTE_STACK_TEMPLATE_FILENAME)
 			if err != nil {
 				return nil, fmt.Errorf("failed to find assets for stack %s: %v", target, err)
@@ -582,7 +586,7 @@ func (cl *Cluster) generateAssets(targets OperationTargets) (cfnstack.Assets, er
 				return nil, fmt.Errorf("failed to locate %s stack template url: %v", target, err)
 			}
 
-			stackTemplate, err = cl.setNestedStackTemplateURL(rootStackTemplate, target, nestedStackTemplateURL)
+			stackTemplate, err = cl.setNestedStackTemplateURL(stackTemplate, target, nestedStackTemplateURL)
 			if err != nil {
 				return nil, fmt.Errorf("failed to update stack template: %v", err)
 			}
@@ -595,6 +599,13 @@ func (cl *Cluster) generateAssets(targets OperationTargets) (cfnstack.Assets, er
 	return nestedStacksAssets.Merge(rootStackAssets), nil
 }
 
+func (cl *Cluster) exportedStacksS3URI() string {
+	return fmt.Sprintf("%s/kube-aws/clusters/%s/exported/stacks",
+		strings.TrimSuffix(cl.s3URI(), "/"),
+		cl.controlPlaneStack.ClusterName,
+	)
+}
+
 func (cl *Cluster) setNestedStackTemplateURL(template, stack string, url string) (string, error) {
 	path := fmt.Sprintf("Resources.%s.Properties.TemplateURL", naming.FromStackToCfnResource(stack))
 	return sjson.Set(template, path, url)
@@ -722,8 +733,16 @@ func (cl *Cluster) LegacyUpdate(targets OperationTargets) (string, error) {
 }
 
 func (cl *Cluster) update(cfSvc *cloudformation.CloudFormation, targets OperationTargets) (string, error) {
+	targets = cl.operationTargetsFromUserInput([]OperationTargets{targets})
 
-	assets, err := cl.generateAssets(cl.operationTargetsFromUserInput([]OperationTargets{targets}))
+	if canary := cl.canaryNodePool(targets); canary != nil {
+		return cl.updateWithCanary(cfSvc, targets, canary)
+	}
+	return cl.updateStack(cfSvc, targets)
+}
+
+func (cl *Cluster) updateStack(cfSvc *cloudformation.CloudFormation, targets OperationTargets) (string, error) {
+	assets, err := cl.generateAssets(targets)
 	if err != nil {
 		return "", err
 	}
@@ -751,7 +770,21 @@ func (cl *Cluster) update(cfSvc *cloudformation.CloudFormation, targets Operatio
 		go streamStackEvents(cl, cfSvc, q)
 	}
 
-	return cl.stackProvisioner().UpdateStackAtURLAndWait(cfSvc, templateUrl)
+	surges := cl.reportSurgeNodePools(targets)
+
+	report, err := cl.stackProvisioner().UpdateStackAtURLAndWait(cfSvc, templateUrl)
+	if err != nil {
+		if len(surges) > 0 {
+			return "", fmt.Errorf("%v. CloudFormation keeps the current auto scaling groups of node pool(s) %s when their replacements fail to signal", err, surges.String())
+		}
+		return "", err
+	}
+
+	if err := cl.refreshInstances(targets); err != nil {
+		return "", err
+	}
+
+	return report, nil
 }
 
 func (cl *Cluster) ValidateTemplates() error {
@@ -23,6 +23,9 @@ type InitialConfig struct {
 	NoRecordSet      bool
 	Region           api.Region
 	S3URI            string
+	// VPCCIDR and SubnetsYAML are set when subnets are planned for multiple availability zones
+	VPCCIDR     string
+	SubnetsYAML string
 }
 
 type UnmarshalledConfig struct {
@@ -75,8 +78,6 @@ func ConfigFromBytes(data []byte, plugins []*api.Plugin) (*Config, error) {
 		return nil, err
 	}
 
-	extras := clusterextension.NewExtrasFromPlugins(plugins, c.PluginConfigs)
-
 	opts := api.ClusterOptions{
 		S3URI: c.S3URI,
 		// TODO
@@ -100,14 +101,45 @@ func ConfigFromBytes(data []byte, plugins []*api.Plugin) (*Config, error) {
 		if err := failFastWhenUnknownKeysFound([]unknownKeyValidation{
 			{np, fmt.Sprintf("worker.nodePools[%d]", i)},
 			{np.AutoScalingGroup, fmt.Sprintf("worker.nodePools[%d].autoScalingGroup", i)},
+			{np.AutoScalingGroup.WarmPool, fmt.Sprintf("worker.nodePools[%d].autoScalingGroup.warmPool", i)},
+			{np.AutoScalingGroup.InstanceRefresh, fmt.Sprintf("worker.nodePools[%d].autoScalingGroup.instanceRefresh", i)},
 			{np.SpotFleet, fmt.Sprintf("worker.nodePools[%d].spotFleet", i)},
+			{np.Canary, fmt.Sprintf("worker.nodePools[%d].canary", i)},
 		}); err != nil {
 			return nil, err
 		}
 
+		for j, h := range np.Canary.HealthChecks {
+			if err := failFastWhenUnknownKeysFound([]unknownKeyValidation{
+				{h, fmt.Sprintf("worker.nodePools[%d].canary.healthChecks[%d]", i, j)},
+			}); err != nil {
+				return nil, err
+			}
+		}
+		for j, a := range np.AutoScalingGroup.ScheduledActions {
+			if err := failFastWhenUnknownKeysFound([]unknownKeyValidation{
+				{a, fmt.Sprintf("worker.nodePools[%d].autoScalingGroup.scheduledActions[%d]", i, j)},
+			}); err != nil {
+				return nil, err
+			}
+		}
+		for j, p := range np.AutoScalingGroup.TargetTrackingPolicies {
+			if err := failFastWhenUnknownKeysFound([]unknownKeyValidation{
+				{p, fmt.Sprintf("worker.nodePools[%d].autoScalingGroup.targetTrackingPolicies[%d]", i, j)},
+			}); err != nil {
+				return nil, err
+			}
+		}
+
 		nps = append(nps, npConf)
 	}
 
+	if err := cpConfig.ValidateClusterAutoscalerConflicts(nps); err != nil {
+		return nil, err
+	}
+	cpConfig.SetClusterAutoscalerValues(nps)
+	extras := clusterextension.NewExtrasFromPlugins(plugins, cpConfig.PluginConfigs)
+
 	cfg := &Config{Config: cpConfig, NodePools: nps}
 
 	validations := []unknownKeyValidation{
@@ -116,18 +148,29 @@ func ConfigFromBytes(data []byte, plugins []*api.Plugin) (*Config, error) {
 		{c.Etcd, "etcd"},
 		{c.Etcd.RootVolume, "etcd.rootVolume"},
 		{c.Etcd.DataVolume, "etcd.dataVolume"},
+		{c.Etcd.MetadataOptions, "etcd.metadataOptions"},
 		{c.Controller, "controller"},
 		{c.Controller.AutoScalingGroup, "controller.autoScalingGroup"},
 		{c.Controller.RootVolume, "controller.rootVolume"},
+		{c.Controller.MetadataOptions, "controller.metadataOptions"},
 		{c.Experimental, "experimental"},
 		{c.Addons, "addons"},
 		{c.Addons.Rescheduler, "addons.rescheduler"},
 		{c.Addons.MetricsServer, "addons.metricsServer"},
+		{c.IPv6, "ipv6"},
+		{c.PrivateLinks, "privateLinks"},
+		{c.PrivateHostedZone, "privateHostedZone"},
+		{c.SecretBackend, "secretBackend"},
+		{c.SecretBackend.Vault, "secretBackend.vault"},
 	}
 
 	for i, np := range c.Worker.NodePools {
 		validations = append(validations, unknownKeyValidation{np, fmt.Sprintf("worker.nodePools[%d]", i)})
 		validations = append(validations, unknownKeyValidation{np.RootVolume, fmt.Sprintf("worker.nodePools[%d].rootVolume", i)})
+		validations = append(validations, unknownKeyValidation{np.MetadataOptions, fmt.Sprintf("worker.nodePools[%d].metadataOptions", i)})
+		validations = append(validations, unknownKeyValidation{np.SpotInterruptionHandling, fmt.Sprintf("worker.nodePools[%d].spotInterruptionHandling", i)})
+		validations = append(validations, unknownKeyValidation{np.SpotInterruptionHandling.RebalanceRecommendation, fmt.Sprintf("worker.nodePools[%d].spotInterruptionHandling.rebalanceRecommendation", i)})
+		validations = append(validations, unknownKeyValidation{np.ClusterAutoscaler, fmt.Sprintf("worker.nodePools[%d].clusterAutoscaler", i)})
 
 	}
 
@@ -135,6 +178,10 @@ func ConfigFromBytes(data []byte, plugins []*api.Plugin) (*Config, error) {
 		validations = append(validations, unknownKeyValidation{endpoint, fmt.Sprintf("apiEndpoints[%d]", i)})
 	}
 
+	for i, vpc := range c.PrivateHostedZone.AdditionalVPCs {
+		validations = append(validations, unknownKeyValidation{vpc, fmt.Sprintf("privateHostedZone.additionalVPCs[%d]", i)})
+	}
+
 	if err := failFastWhenUnknownKeysFound(validations); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+)
+
+// RenderSpotFleetReplacement renders the node pool settings replacing a spot fleet as an item of `worker.nodePools`,
+// preceded by comments explaining how the auto scaling group differs from the fleet
+func RenderSpotFleetReplacement(r *api.SpotFleetReplacement) (string, error) {
+	data, err := yaml.Marshal([]*api.SpotFleetReplacement{r})
+	if err != nil {
+		return "", fmt.Errorf("failed to render node pool %s: %v", r.Name, err)
+	}
+
+	lines := []string{fmt.Sprintf("# Remove `spotFleet` from node pool %s and merge the settings below into it. The other settings of the node pool are kept as they are.", r.Name)}
+	for _, n := range r.Notes {
+		lines = append(lines, "# NOTE: "+n)
+	}
+	lines = append(lines, "worker:", "  nodePools:")
+	for _, l := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		lines = append(lines, "  "+l)
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/go-yaml/yaml"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderSpotFleetReplacement(t *testing.T) {
+	r := &api.SpotFleetReplacement{
+		Name:                  "spotty",
+		MigratedFromSpotFleet: true,
+		Count:                 3,
+		InstanceType:          "c4.large",
+		RootVolume:            api.RootVolume{Size: 60, Type: "gp2"},
+		Notes:                 []string{"`count` is 3 units of capacity rather than instances"},
+	}
+	r.AutoScalingGroup.MixedInstances = api.MixedInstances{
+		Enabled:                true,
+		SpotAllocationStrategy: "capacity-optimized",
+		Overrides:              []api.InstanceTypeOverride{{InstanceType: "c4.large", WeightedCapacity: 1}, {InstanceType: "c4.xlarge", WeightedCapacity: 2}},
+	}
+
+	rendered, err := RenderSpotFleetReplacement(r)
+	require.NoError(t, err)
+	assert.Contains(t, rendered, "# NOTE: `count` is 3 units of capacity rather than instances\nworker:\n  nodePools:\n  - name: spotty\n    migratedFromSpotFleet: true\n")
+
+	parsed := struct {
+		Worker struct {
+			NodePools []api.WorkerNodePool `yaml:"nodePools"`
+		} `yaml:"worker"`
+	}{}
+	require.NoError(t, yaml.Unmarshal([]byte(rendered), &parsed))
+	require.Len(t, parsed.Worker.NodePools, 1)
+	pool := parsed.Worker.NodePools[0]
+	assert.Equal(t, "spotty", pool.NodePoolName)
+	assert.Equal(t, 3, pool.Count)
+	assert.Equal(t, r.AutoScalingGroup.MixedInstances, pool.AutoScalingGroup.MixedInstances)
+	assert.Empty(t, pool.UnknownKeys)
+}
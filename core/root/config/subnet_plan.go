@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+)
+
+const (
+	subnetPlanHeader   = "# Subnets planned by `kube-aws plan-subnets`."
+	reservedCIDRsKey   = "# reservedSubnetCIDRs:"
+	reservedCIDRIndent = "#   "
+
+	// defaultInstanceCIDRTier is the tier the top-level `instanceCIDR` is reserved for when no tier is known,
+	// which is the one `kube-aws plan-subnets` plans by default
+	defaultInstanceCIDRTier = "Public"
+)
+
+var (
+	vpcCIDRKey            = regexp.MustCompile(`(?m)^vpcCIDR:.*$`)
+	singleSubnetKeys      = regexp.MustCompile(`(?m)^(availabilityZone|instanceCIDR):`)
+	reservedCIDRsEntry    = regexp.MustCompile(`^#   ([a-zA-Z][a-zA-Z0-9]*): (.+)$`)
+	topLevelKeyOrComment  = regexp.MustCompile(`^[^\s-]`)
+	subnetsKeyOnItsOwn    = regexp.MustCompile(`^subnets:\s*$`)
+	subnetPlanCommentLine = regexp.MustCompile(`^#( |$)`)
+)
+
+// SubnetPlanInputs is the part of cluster.yaml a subnet plan builds on
+type SubnetPlanInputs struct {
+	VPCCIDR string      `yaml:"vpcCIDR,omitempty"`
+	Subnets api.Subnets `yaml:"subnets,omitempty"`
+	// InstanceCIDR is the top-level `instanceCIDR` of the single subnet created when `subnets` is omitted
+	InstanceCIDR string `yaml:"instanceCIDR,omitempty"`
+	// Reserved are the CIDR blocks reserved by the previous plan, recorded as a comment in cluster.yaml
+	Reserved map[string][]string `yaml:"-"`
+}
+
+// SubnetPlanInputsFromFile reads the VPC CIDR, the subnets and the CIDR blocks reserved by the previous plan from cluster.yaml
+func SubnetPlanInputsFromFile(configPath string) (*SubnetPlanInputs, error) {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", configPath, err)
+	}
+	return subnetPlanInputsFromBytes(data)
+}
+
+func subnetPlanInputsFromBytes(data []byte) (*SubnetPlanInputs, error) {
+	inputs := &SubnetPlanInputs{}
+	if err := yaml.Unmarshal(data, inputs); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+
+	inputs.Reserved = map[string][]string{}
+	inReserved := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == reservedCIDRsKey {
+			inReserved = true
+			continue
+		}
+		if !inReserved {
+			continue
+		}
+		m := reservedCIDRsEntry.FindStringSubmatch(line)
+		if m == nil {
+			inReserved = false
+			continue
+		}
+		for _, c := range strings.Split(m[2], ",") {
+			inputs.Reserved[m[1]] = append(inputs.Reserved[m[1]], strings.TrimSpace(c))
+		}
+	}
+
+	return inputs, nil
+}
+
+// RenderSubnets renders the `subnets` key of cluster.yaml preceded by comments recording the reserved CIDR blocks
+func RenderSubnets(subnets api.Subnets, reserved map[string][]string) (string, error) {
+	data, err := yaml.Marshal(struct {
+		Subnets api.Subnets `yaml:"subnets"`
+	}{subnets})
+	if err != nil {
+		return "", fmt.Errorf("failed to render subnets: %v", err)
+	}
+
+	lines := []string{subnetPlanHeader}
+	if len(reserved) > 0 {
+		lines = append(lines,
+			"# The CIDR blocks below are reserved for future subnets. They are used first when subnets are planned again.",
+			reservedCIDRsKey,
+		)
+		tiers := []string{}
+		for t := range reserved {
+			tiers = append(tiers, t)
+		}
+		sort.Strings(tiers)
+		for _, t := range tiers {
+			lines = append(lines, fmt.Sprintf("%s%s: %s", reservedCIDRIndent, t, strings.Join(reserved[t], ", ")))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n" + string(data), nil
+}
+
+// WriteSubnets updates cluster.yaml to use the VPC CIDR and the subnets.
+// The top-level `availabilityZone` and `instanceCIDR` are commented out as they can't be used together with `subnets`.
+func WriteSubnets(configPath string, vpcCIDR string, subnets api.Subnets, reserved map[string][]string) error {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", configPath, err)
+	}
+	updated, err := writeSubnets(data, vpcCIDR, subnets, reserved)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(configPath, updated, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %v", configPath, err)
+	}
+	return nil
+}
+
+func writeSubnets(data []byte, vpcCIDR string, subnets api.Subnets, reserved map[string][]string) ([]byte, error) {
+	inputs, err := subnetPlanInputsFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err = ReserveInstanceCIDR(reserved, inputs.InstanceCIDR, subnets, defaultInstanceCIDRTier)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := RenderSubnets(subnets, reserved)
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(data)
+	s = singleSubnetKeys.ReplaceAllString(s, "# $1:")
+
+	vpcCIDRLine := fmt.Sprintf(`vpcCIDR: "%s"`, vpcCIDR)
+	if vpcCIDRKey.MatchString(s) {
+		s = vpcCIDRKey.ReplaceAllLiteralString(s, vpcCIDRLine)
+	} else {
+		block = vpcCIDRLine + "\n\n" + block
+	}
+
+	lines := strings.Split(s, "\n")
+	start := -1
+	for i, l := range lines {
+		if subnetsKeyOnItsOwn.MatchString(l) {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		if s != "" && !strings.HasSuffix(s, "\n") {
+			s += "\n"
+		}
+		return []byte(s + "\n" + block), nil
+	}
+
+	// The block ends right before the next top-level key or comment
+	end := start + 1
+	for end < len(lines) && !topLevelKeyOrComment.MatchString(lines[end]) {
+		end++
+	}
+	for end > start+1 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+
+	// Replace the comments written by the previous plan too
+	for start > 0 && subnetPlanCommentLine.MatchString(lines[start-1]) && isSubnetPlanComment(lines[:start]) {
+		start--
+	}
+
+	result := append([]string{}, lines[:start]...)
+	result = append(result, strings.TrimSuffix(block, "\n"))
+	result = append(result, lines[end:]...)
+	return []byte(strings.Join(result, "\n")), nil
+}
+
+// ReserveInstanceCIDR returns the reserved CIDR blocks plus the top-level `instanceCIDR` reserved for the tier.
+// The subnet created from it may still exist after `instanceCIDR` is commented out, so its address space must not be allocated to other subnets.
+// The CIDR is left as is when it overlaps one of the subnets or is already reserved
+func ReserveInstanceCIDR(reserved map[string][]string, instanceCIDR string, subnets api.Subnets, tier string) (map[string][]string, error) {
+	if instanceCIDR == "" {
+		return reserved, nil
+	}
+	_, cidr, err := net.ParseCIDR(instanceCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instanceCIDR: %v", err)
+	}
+
+	for _, s := range subnets {
+		if s.InstanceCIDR == "" {
+			continue
+		}
+		if _, other, err := net.ParseCIDR(s.InstanceCIDR); err == nil && cidrsOverlap(cidr, other) {
+			return reserved, nil
+		}
+	}
+
+	result := map[string][]string{}
+	for t, cidrs := range reserved {
+		for _, c := range cidrs {
+			if _, other, err := net.ParseCIDR(c); err == nil && cidrsOverlap(cidr, other) {
+				return reserved, nil
+			}
+		}
+		result[t] = append([]string{}, cidrs...)
+	}
+	result[tier] = append(result[tier], cidr.String())
+	return result, nil
+}
+
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// isSubnetPlanComment returns true if the comment lines right before the end of lines are written by a previous plan
+func isSubnetPlanComment(lines []string) bool {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !subnetPlanCommentLine.MatchString(lines[i]) {
+			return false
+		}
+		if lines[i] == subnetPlanHeader {
+			return true
+		}
+	}
+	return false
+}
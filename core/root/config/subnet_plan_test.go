@@ -0,0 +1,127 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSubnets(t *testing.T) {
+	original := `clusterName: test
+# Availability Zone
+availabilityZone: us-west-2a
+region: us-west-2
+
+# vpcCIDR: "10.0.0.0/16"
+
+# Kubernetes subnets
+# subnets:
+#   - name: Example
+kmsKeyArn: "arn"
+`
+
+	public := api.NewPublicSubnet("us-west-2a", "10.0.0.0/24")
+	public.Name = "Public1"
+	private := api.NewPrivateSubnet("us-west-2a", "10.0.16.0/20")
+	private.Name = "Private1"
+
+	written, err := writeSubnets([]byte(original), "10.0.0.0/16", api.Subnets{public}, map[string][]string{"Public": {"10.0.1.0/24"}})
+	require.NoError(t, err)
+	assert.Equal(t, `clusterName: test
+# Availability Zone
+# availabilityZone: us-west-2a
+region: us-west-2
+
+# vpcCIDR: "10.0.0.0/16"
+
+# Kubernetes subnets
+# subnets:
+#   - name: Example
+kmsKeyArn: "arn"
+
+vpcCIDR: "10.0.0.0/16"
+
+# Subnets planned by `+"`kube-aws plan-subnets`"+`.
+# The CIDR blocks below are reserved for future subnets. They are used first when subnets are planned again.
+# reservedSubnetCIDRs:
+#   Public: 10.0.1.0/24
+subnets:
+- availabilityZone: us-west-2a
+  name: Public1
+  instanceCIDR: 10.0.0.0/24
+`, string(written))
+
+	inputs, err := subnetPlanInputsFromBytes(written)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/16", inputs.VPCCIDR)
+	assert.Equal(t, map[string][]string{"Public": {"10.0.1.0/24"}}, inputs.Reserved)
+	require.Len(t, inputs.Subnets, 1)
+	assert.Equal(t, "Public1", inputs.Subnets[0].Name)
+
+	// Planning again replaces the previous plan in place
+	rewritten, err := writeSubnets([]byte(string(written)+"etcd:\n  count: 3\n"), "10.0.0.0/16", api.Subnets{public, private}, map[string][]string{"Private": {"10.0.32.0/20", "10.0.48.0/20"}})
+	require.NoError(t, err)
+	assert.Equal(t, `clusterName: test
+# Availability Zone
+# availabilityZone: us-west-2a
+region: us-west-2
+
+# vpcCIDR: "10.0.0.0/16"
+
+# Kubernetes subnets
+# subnets:
+#   - name: Example
+kmsKeyArn: "arn"
+
+vpcCIDR: "10.0.0.0/16"
+
+# Subnets planned by `+"`kube-aws plan-subnets`"+`.
+# The CIDR blocks below are reserved for future subnets. They are used first when subnets are planned again.
+# reservedSubnetCIDRs:
+#   Private: 10.0.32.0/20, 10.0.48.0/20
+subnets:
+- availabilityZone: us-west-2a
+  name: Public1
+  instanceCIDR: 10.0.0.0/24
+- availabilityZone: us-west-2a
+  name: Private1
+  instanceCIDR: 10.0.16.0/20
+  private: true
+etcd:
+  count: 3
+`, string(rewritten))
+
+	inputs, err = subnetPlanInputsFromBytes(rewritten)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"Private": {"10.0.32.0/20", "10.0.48.0/20"}}, inputs.Reserved)
+}
+
+func TestWriteSubnetsReservesInstanceCIDR(t *testing.T) {
+	original := `clusterName: test
+availabilityZone: us-west-2a
+instanceCIDR: "10.0.0.0/24"
+`
+
+	public := api.NewPublicSubnet("us-west-2a", "10.0.1.0/24")
+	public.Name = "Public1"
+
+	written, err := writeSubnets([]byte(original), "10.0.0.0/16", api.Subnets{public}, map[string][]string{"Public": {"10.0.2.0/24"}})
+	require.NoError(t, err)
+
+	inputs, err := subnetPlanInputsFromBytes(written)
+	require.NoError(t, err)
+	assert.Empty(t, inputs.InstanceCIDR)
+	assert.Equal(t, map[string][]string{"Public": {"10.0.2.0/24", "10.0.0.0/24"}}, inputs.Reserved)
+
+	// Nothing is reserved when a subnet reuses the CIDR
+	reused := api.NewPublicSubnet("us-west-2a", "10.0.0.0/24")
+	reused.Name = "Public1"
+	written, err = writeSubnets([]byte(original), "10.0.0.0/16", api.Subnets{reused}, map[string][]string{})
+	require.NoError(t, err)
+
+	inputs, err = subnetPlanInputsFromBytes(written)
+	require.NoError(t, err)
+	assert.Empty(t, inputs.Reserved)
+}
@@ -1,15 +1,20 @@
 package root
 
 import (
+	"errors"
 	"fmt"
+	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/kubernetes-incubator/kube-aws/core/root/defaults"
 	"github.com/kubernetes-incubator/kube-aws/credential"
 	"github.com/kubernetes-incubator/kube-aws/logger"
 	"github.com/kubernetes-incubator/kube-aws/pki"
 	"io/ioutil"
 	"os"
+	"os/user"
 	"path"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 func RenderCredentials(configPath string, renderCredentialsOpts credential.GeneratorOptions) error {
@@ -63,3 +68,169 @@ func LoadCertificates() (map[string]pki.Certificates, error) {
 	}
 	return certs, nil
 }
+
+// IssueUserOptions configures the client certificate and the kubeconfig issued for a user
+type IssueUserOptions struct {
+	Name       string
+	Groups     []string
+	TTL        time.Duration
+	CaCertPath string
+	CaKeyPath  string
+	// Output is the path to the personal kubeconfig
+	Output string
+}
+
+// IssueUserCredentials issues a short-lived client certificate for a user signed by the cluster CA, writes a personal kubeconfig
+// embedding it and records the certificate in the issuance ledger and the issuance log
+func (cl *Cluster) IssueUserCredentials(o IssueUserOptions) (*credential.LedgerEntry, error) {
+	backend, err := cl.context().SecretBackend(cl.Cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := credential.NewUserCertificateIssuerWithBackend(o.CaCertPath, o.CaKeyPath, backend)
+	if err != nil {
+		return nil, err
+	}
+	issuer.Organizations = credential.OrganizationMapping(cl.Cfg.Kubernetes.Authentication.UserCertificates.Organizations)
+	issued, err := issuer.Issue(credential.UserCertificateRequest{Name: o.Name, Groups: o.Groups, TTL: o.TTL})
+	if err != nil {
+		return nil, err
+	}
+
+	issuedBy := currentUserName()
+	var entry credential.LedgerEntry
+	if err := cl.updateLedger(defaults.AssetsDir, func(l *credential.Ledger) error {
+		entry = l.Record(issued.Cert, credential.LedgerSourceIssueUser, issuedBy)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := credential.AppendIssuanceRecord(defaults.AssetsDir, issued.Record(issuedBy)); err != nil {
+		return nil, err
+	}
+
+	// The key is kept only in the personal kubeconfig when no plaintext key should be written to the credentials directory
+	if backend == nil {
+		if _, _, err := issued.WriteToDir(defaults.AssetsDir); err != nil {
+			return nil, err
+		}
+	}
+
+	kubeconfig, err := GenerateKubeconfig(cl.Cfg.Config, KubeconfigOptions{
+		Auth:                  KubeconfigAuthCert,
+		CredentialsDir:        filepath.Dir(o.CaCertPath),
+		EmbedCerts:            true,
+		UserName:              o.Name,
+		ClientCertificateData: issued.CertPEM,
+		ClientKeyData:         issued.KeyPEM,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := kubeconfig.WriteToFile(o.Output); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// RevokeOptions configures the revocation of a certificate and the CRL generated for it
+type RevokeOptions struct {
+	Serial     string
+	Reason     string
+	CaCertPath string
+	CaKeyPath  string
+	// CRLValidity is how long the CRL is valid for
+	CRLValidity time.Duration
+}
+
+// RevokeCertificate marks the certificate with the serial as revoked in the issuance ledger and regenerates the CRL
+// consulted by etcd nodes from all the revoked certificates in the ledger. The revocation of a user certificate is also recorded in the issuance log.
+// The CRL is delivered to etcd nodes on the next `kube-aws apply`
+func (cl *Cluster) RevokeCertificate(o RevokeOptions) (*credential.LedgerEntry, error) {
+	serial, err := credential.NormalizeSerial(o.Serial)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := cl.context().SecretBackend(cl.Cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := credential.NewUserCertificateIssuerWithBackend(o.CaCertPath, o.CaKeyPath, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry credential.LedgerEntry
+	if err := cl.updateLedger(defaults.AssetsDir, func(l *credential.Ledger) error {
+		now := time.Now()
+		e, err := l.Revoke(serial, o.Reason, now)
+		if err != nil {
+			return err
+		}
+		entry = *e
+
+		crl, err := l.CRL(ca.CACert, ca.CAKey, now, o.CRLValidity)
+		if err != nil {
+			return err
+		}
+		if backend != nil {
+			return backend.Put(credential.CRLFile, crl)
+		}
+		path := filepath.Join(defaults.AssetsDir, credential.CRLFile)
+		if err := ioutil.WriteFile(path, crl, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if entry.Source == credential.LedgerSourceIssueUser {
+		if err := credential.AppendIssuanceRecord(defaults.AssetsDir, entry.RevocationRecord(currentUserName())); err != nil {
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+// RecordSignedCertificates records the certificates contained in the assets to the issuance ledger in dir
+func (cl *Cluster) RecordSignedCertificates(dir string, assets *credential.RawAssetsOnDisk) error {
+	if !cl.Cfg.AssetsEncryptionEnabled() {
+		logger.Warnf("Skipped recording certificates to the issuance ledger as it can't be encrypted without KMS\n")
+		return nil
+	}
+	certs, err := assets.SignedCertificates()
+	if err != nil {
+		return err
+	}
+	issuedBy := currentUserName()
+	return cl.updateLedger(dir, func(l *credential.Ledger) error {
+		for _, c := range certs {
+			l.Record(c, credential.LedgerSourceGenerator, issuedBy)
+		}
+		return nil
+	})
+}
+
+func (cl *Cluster) updateLedger(dir string, update func(*credential.Ledger) error) error {
+	if !cl.Cfg.AssetsEncryptionEnabled() {
+		return errors.New("the issuance ledger requires `manageCertificates` to be true and KMS to be available in the region")
+	}
+	kmsConfig := credential.NewKMSConfig(cl.Cfg.KMSKeyARN, nil, cl.session)
+	ledger, err := credential.LoadLedger(dir, kmsConfig.Decryptor())
+	if err != nil {
+		return err
+	}
+	if err := update(ledger); err != nil {
+		return err
+	}
+	return ledger.Save(dir, &credential.EnvelopeEncryptor{KmsKeyARN: cl.Cfg.KMSKeyARN, KmsSvc: kms.New(cl.session)})
+}
+
+func currentUserName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
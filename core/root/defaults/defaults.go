@@ -10,4 +10,5 @@ const (
 	EtcdStackTemplateTmplFile         = "stack-templates/etcd.json.tmpl"
 	NodePoolStackTemplateTmplFile     = "stack-templates/node-pool.json.tmpl"
 	RootStackTemplateTmplFile         = "stack-templates/root.json.tmpl"
+	UpgradeStateFile                  = "upgrade-state.json"
 )
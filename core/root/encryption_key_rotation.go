@@ -0,0 +1,239 @@
+package root
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/core/root/defaults"
+	"github.com/kubernetes-incubator/kube-aws/credential"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+)
+
+const secretListChunkSize = 500
+
+// RotateEncryptionKeyOptions customizes `kube-aws rotate encryption-key`
+type RotateEncryptionKeyOptions struct {
+	// Provider is the type of the new key. Either aescbc or kms. Defaults to kms when the KMS provider plugin is enabled, or aescbc otherwise
+	Provider string
+	// Kubeconfig and Context select the credentials secrets are rewritten with
+	Kubeconfig string
+	Context    string
+}
+
+// secretsClient is the subset of *kubeclient.Client used to rewrite secrets
+type secretsClient interface {
+	kubeclient.Interface
+	Do(method, path string, body interface{}, out interface{}) error
+}
+
+type secretList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []map[string]interface{} `json:"items"`
+}
+
+// RotateEncryptionKey rotates the key secrets are encrypted at rest with by adding a new key as a secondary key, promoting it to the primary key,
+// rewriting all the secrets and finally removing the old keys. Each step changing the encryption config is applied as a rolling update of controller nodes.
+// The progress is recorded in the credentials directory so that an interrupted rotation is resumed by running it again
+func RotateEncryptionKey(configPath string, opts options, awsDebug bool, o RotateEncryptionKeyOptions) error {
+	cl, err := LoadClusterFromFile(configPath, opts, awsDebug)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %v", err)
+	}
+	encryptionAtRest := cl.Cfg.Kubernetes.EncryptionAtRest
+	if !encryptionAtRest.Enabled {
+		return errors.New("`kubernetes.encryptionAtRest.enabled` must be true to rotate the encryption key")
+	}
+	backend, err := cl.context().SecretBackend(cl.Cfg.Config)
+	if err != nil {
+		return err
+	}
+
+	dir := defaults.AssetsDir
+	rotation, err := credential.LoadEncryptionKeyRotation(dir)
+	if err != nil {
+		return err
+	}
+	if rotation == nil {
+		provider := o.Provider
+		if provider == "" {
+			provider = credential.EncryptionProviderAESCBC
+			if encryptionAtRest.KMS.Enabled {
+				provider = credential.EncryptionProviderKMS
+			}
+		}
+		if provider == credential.EncryptionProviderKMS && !encryptionAtRest.KMS.Enabled {
+			return errors.New("`kubernetes.encryptionAtRest.kms.enabled` must be true and applied to the cluster before rotating to a kms key")
+		}
+		if rotation, err = credential.NewEncryptionKeyRotation(provider, time.Now()); err != nil {
+			return err
+		}
+		if provider == credential.EncryptionProviderKMS {
+			rotation.KMSCacheSize = encryptionAtRest.KMS.CacheSizeOrDefault()
+		}
+		if err := rotation.Save(dir); err != nil {
+			return err
+		}
+		logger.Infof("Rotating the encryption key of secrets to the new %s key %s\n", rotation.Provider, rotation.KeyName)
+	} else {
+		if o.Provider != "" && o.Provider != rotation.Provider {
+			return fmt.Errorf("the rotation to the %s key %s started at %s is in progress. Run it again without --provider to resume it", rotation.Provider, rotation.KeyName, rotation.StartedAt.Format(time.RFC3339))
+		}
+		logger.Infof("Resuming the rotation to the %s key %s started at %s\n", rotation.Provider, rotation.KeyName, rotation.StartedAt.Format(time.RFC3339))
+	}
+
+	for step := rotation.NextStep(); step != ""; step = rotation.NextStep() {
+		logger.Infof("Step %d/%d: %s\n", len(rotation.CompletedSteps)+1, len(credential.EncryptionKeyRotationSteps), step)
+
+		if step == credential.RotationStepRewriteSecrets {
+			client, err := kubeclient.NewFromKubeconfig(o.Kubeconfig, o.Context)
+			if err != nil {
+				return err
+			}
+			n, err := rewriteSecrets(client)
+			if err != nil {
+				return fmt.Errorf("failed to rewrite secrets: %v", err)
+			}
+			logger.Infof("Rewrote %d secrets with the key %s\n", n, rotation.KeyName)
+		} else {
+			changed, err := updateEncryptionConfig(dir, backend, func(c *credential.EncryptionConfiguration) (bool, error) {
+				return rotation.ApplyStep(step, c)
+			})
+			if err != nil {
+				return err
+			}
+			if changed {
+				rotation.PendingStep = step
+				if err := rotation.Save(dir); err != nil {
+					return err
+				}
+			}
+			if rotation.PendingStep == step {
+				if err := applyEncryptionConfig(configPath, opts, awsDebug); err != nil {
+					return fmt.Errorf("failed to roll controller nodes for %s: %v", step, err)
+				}
+			} else {
+				logger.Infof("The encryption config is already up to date for %s\n", step)
+			}
+		}
+
+		rotation.Complete(step, time.Now())
+		if err := rotation.Save(dir); err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("Secrets are now encrypted with the key %s\n", rotation.KeyName)
+	return rotation.Finish(dir)
+}
+
+// applyEncryptionConfig rolls controller nodes with the encryption config in the credentials directory or the secret backend.
+// The cluster is reloaded so that the updated encryption config is encrypted and embedded into userdata
+func applyEncryptionConfig(configPath string, opts options, awsDebug bool) error {
+	cl, err := LoadClusterFromFile(configPath, opts, awsDebug)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %v", err)
+	}
+	targets := OperationTargets{cl.Cfg.ControlPlaneStackName()}
+	if _, err := cl.ValidateStack(targets); err != nil {
+		return err
+	}
+	return cl.Apply(targets)
+}
+
+func updateEncryptionConfig(dir string, backend credential.SecretBackend, update func(*credential.EncryptionConfiguration) (bool, error)) (bool, error) {
+	path := filepath.Join(dir, credential.EncryptionConfigFile)
+
+	var data []byte
+	found := false
+	if backend != nil {
+		var err error
+		if data, found, err = backend.Get(credential.EncryptionConfigFile); err != nil {
+			return false, err
+		}
+	}
+	if !found {
+		var err error
+		data, err = ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return false, fmt.Errorf("%s not found. Run `kube-aws apply` to generate it before rotating the encryption key", path)
+		} else if err != nil {
+			return false, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+	}
+
+	c, err := credential.ParseEncryptionConfig(data)
+	if err != nil {
+		return false, err
+	}
+	changed, err := update(c)
+	if err != nil || !changed {
+		return false, err
+	}
+	updated, err := c.Bytes()
+	if err != nil {
+		return false, err
+	}
+
+	if backend != nil {
+		return true, backend.Put(credential.EncryptionConfigFile, updated)
+	}
+	if err := ioutil.WriteFile(path, updated, 0600); err != nil {
+		return false, fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return true, nil
+}
+
+// rewriteSecrets writes every secret back unchanged so that kube-apiserver re-encrypts it with the primary key, and returns the number of rewritten secrets
+func rewriteSecrets(client secretsClient) (int, error) {
+	n := 0
+	cont := ""
+	for {
+		path := fmt.Sprintf("/api/v1/secrets?limit=%d", secretListChunkSize)
+		if cont != "" {
+			path += "&continue=" + url.QueryEscape(cont)
+		}
+		list := secretList{}
+		if err := client.Get(path, &list); err != nil {
+			return n, fmt.Errorf("failed to list secrets: %v", err)
+		}
+		for _, s := range list.Items {
+			if err := rewriteSecret(client, s); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if list.Metadata.Continue == "" {
+			return n, nil
+		}
+		logger.Infof("Rewrote %d secrets so far\n", n)
+		cont = list.Metadata.Continue
+	}
+}
+
+func rewriteSecret(client secretsClient, s map[string]interface{}) error {
+	metadata, _ := s["metadata"].(map[string]interface{})
+	namespace, _ := metadata["namespace"].(string)
+	name, _ := metadata["name"].(string)
+	if namespace == "" || name == "" {
+		return fmt.Errorf("unexpected secret without namespace or name: %v", metadata)
+	}
+
+	s["apiVersion"] = "v1"
+	s["kind"] = "Secret"
+	path := fmt.Sprintf("/api/v1/namespaces/%s/secrets/%s", namespace, name)
+	err := client.Do(http.MethodPut, path, s, nil)
+	// A secret deleted or updated since listed needs no rewrite, as any update is encrypted with the primary key
+	if err != nil && !kubeclient.IsNotFound(err) && !kubeclient.IsConflict(err) {
+		return fmt.Errorf("failed to rewrite secret %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
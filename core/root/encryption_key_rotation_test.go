@@ -0,0 +1,126 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/credential"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSecretsClient struct {
+	pages    []string
+	putErrs  map[string]int
+	rewrites []string
+}
+
+func (c *fakeSecretsClient) Get(path string, out interface{}) error {
+	page := 0
+	if i := strings.Index(path, "&continue="); i >= 0 {
+		fmt.Sscanf(path[i+len("&continue="):], "page%d", &page)
+	}
+	return json.Unmarshal([]byte(c.pages[page]), out)
+}
+
+func (c *fakeSecretsClient) Do(method, path string, body interface{}, out interface{}) error {
+	if code, ok := c.putErrs[path]; ok {
+		return &kubeclient.StatusError{Method: method, Path: path, Code: code}
+	}
+	s := body.(map[string]interface{})
+	if s["kind"] != "Secret" || s["apiVersion"] != "v1" {
+		return fmt.Errorf("unexpected body: %v", s)
+	}
+	c.rewrites = append(c.rewrites, method+" "+path)
+	return nil
+}
+
+func TestRewriteSecrets(t *testing.T) {
+	client := &fakeSecretsClient{
+		pages: []string{
+			`{"metadata":{"continue":"page1"},"items":[{"metadata":{"namespace":"default","name":"a","resourceVersion":"1"},"data":{"k":"dg=="}},{"metadata":{"namespace":"kube-system","name":"b"}}]}`,
+			`{"metadata":{},"items":[{"metadata":{"namespace":"default","name":"deleted"}},{"metadata":{"namespace":"default","name":"updated"}},{"metadata":{"namespace":"default","name":"c"}}]}`,
+		},
+		putErrs: map[string]int{
+			"/api/v1/namespaces/default/secrets/deleted": http.StatusNotFound,
+			"/api/v1/namespaces/default/secrets/updated": http.StatusConflict,
+		},
+	}
+
+	n, err := rewriteSecrets(client)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, []string{
+		"PUT /api/v1/namespaces/default/secrets/a",
+		"PUT /api/v1/namespaces/kube-system/secrets/b",
+		"PUT /api/v1/namespaces/default/secrets/c",
+	}, client.rewrites)
+
+	client.putErrs["/api/v1/namespaces/default/secrets/c"] = http.StatusForbidden
+	_, err = rewriteSecrets(client)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "default/c")
+	}
+}
+
+func TestUpdateEncryptionConfig(t *testing.T) {
+	addKey := func(c *credential.EncryptionConfiguration) (bool, error) {
+		key, err := credential.NewAESEncryptionKey("new")
+		if err != nil {
+			return false, err
+		}
+		return true, c.AddSecondaryKey(credential.EncryptionProvider{AESCBC: &credential.AESEncryptionProvider{Keys: []credential.EncryptionKey{*key}}})
+	}
+
+	t.Run("Disk", func(t *testing.T) {
+		helper.WithTempDir(func(dir string) {
+			_, err := updateEncryptionConfig(dir, nil, addKey)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "kube-aws apply")
+			}
+
+			initial, err := credential.EncryptionConfig()
+			require.NoError(t, err)
+			path := filepath.Join(dir, credential.EncryptionConfigFile)
+			require.NoError(t, ioutil.WriteFile(path, []byte(initial), 0600))
+
+			changed, err := updateEncryptionConfig(dir, nil, addKey)
+			require.NoError(t, err)
+			assert.True(t, changed)
+
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			c, err := credential.ParseEncryptionConfig(data)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"default", "new"}, c.KeyNames())
+		})
+	})
+
+	t.Run("SecretBackend", func(t *testing.T) {
+		helper.WithTempDir(func(dir string) {
+			sm := helper.NewFakeSecretsManager()
+			backend := credential.SecretsManagerBackend{Svc: sm, Prefix: "kube-aws/test/"}
+			initial, err := credential.EncryptionConfig()
+			require.NoError(t, err)
+			require.NoError(t, backend.Put(credential.EncryptionConfigFile, []byte(initial)))
+
+			changed, err := updateEncryptionConfig(dir, backend, addKey)
+			require.NoError(t, err)
+			assert.True(t, changed)
+
+			c, err := credential.ParseEncryptionConfig(sm.Secrets["kube-aws/test/encryption-config.yaml"])
+			require.NoError(t, err)
+			assert.Equal(t, []string{"default", "new"}, c.KeyNames())
+
+			_, err = ioutil.ReadFile(filepath.Join(dir, credential.EncryptionConfigFile))
+			assert.Error(t, err, "expected no plaintext encryption config to be written to the credentials directory")
+		})
+	})
+}
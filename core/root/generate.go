@@ -30,6 +30,9 @@ func RenderStack(configPath string) error {
 		"etcdadm",
 		"kubeconfig.tmpl",
 		"cluster.yaml.tmpl",
+		// Unlike stack templates, the instance type catalog isn't rendered so that it's updated along with kube-aws.
+		// A project adds instance types with its own ec2-instance-types.yaml instead
+		"ec2-instance-types.yaml",
 	}
 
 	if err := builtin.Box().Walk(func(path string, file packr.File) error {
@@ -0,0 +1,292 @@
+package root
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+)
+
+const (
+	instanceRefreshStatusPending    = "Pending"
+	instanceRefreshStatusInProgress = "InProgress"
+	instanceRefreshStatusCancelling = "Cancelling"
+	instanceRefreshStatusSuccessful = "Successful"
+)
+
+// The shapes below are of the EC2 Auto Scaling API for instance refreshes, which the vendored aws-sdk-go predates.
+// They are sent by the query protocol handlers of the autoscaling client like the ones generated in aws-sdk-go
+
+type startInstanceRefreshInput struct {
+	_                    struct{}            `type:"structure"`
+	AutoScalingGroupName *string             `type:"string"`
+	Preferences          *refreshPreferences `type:"structure"`
+}
+
+type refreshPreferences struct {
+	_                     struct{} `type:"structure"`
+	CheckpointDelay       *int64   `type:"integer"`
+	CheckpointPercentages []*int64 `type:"list"`
+	InstanceWarmup        *int64   `type:"integer"`
+	MinHealthyPercentage  *int64   `type:"integer"`
+}
+
+type startInstanceRefreshOutput struct {
+	_                 struct{} `type:"structure"`
+	InstanceRefreshId *string  `type:"string"`
+}
+
+type describeInstanceRefreshesInput struct {
+	_                    struct{}  `type:"structure"`
+	AutoScalingGroupName *string   `type:"string"`
+	InstanceRefreshIds   []*string `type:"list"`
+}
+
+type describeInstanceRefreshesOutput struct {
+	_                 struct{}           `type:"structure"`
+	InstanceRefreshes []*instanceRefresh `type:"list"`
+}
+
+type instanceRefresh struct {
+	_                  struct{} `type:"structure"`
+	InstanceRefreshId  *string  `type:"string"`
+	Status             *string  `type:"string"`
+	StatusReason       *string  `type:"string"`
+	PercentageComplete *int64   `type:"integer"`
+	InstancesToUpdate  *int64   `type:"integer"`
+}
+
+type instanceRefreshAutoScaling interface {
+	DescribeAutoScalingGroups(*autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+	StartInstanceRefresh(*startInstanceRefreshInput) (*startInstanceRefreshOutput, error)
+	DescribeInstanceRefreshes(*describeInstanceRefreshesInput) (*describeInstanceRefreshesOutput, error)
+}
+
+// instanceRefreshClient adds the instance refresh API to the autoscaling client
+type instanceRefreshClient struct {
+	*autoscaling.AutoScaling
+}
+
+func (c instanceRefreshClient) StartInstanceRefresh(input *startInstanceRefreshInput) (*startInstanceRefreshOutput, error) {
+	output := &startInstanceRefreshOutput{}
+	req := c.NewRequest(&request.Operation{Name: "StartInstanceRefresh", HTTPMethod: "POST", HTTPPath: "/"}, input, output)
+	return output, req.Send()
+}
+
+func (c instanceRefreshClient) DescribeInstanceRefreshes(input *describeInstanceRefreshesInput) (*describeInstanceRefreshesOutput, error) {
+	output := &describeInstanceRefreshesOutput{}
+	req := c.NewRequest(&request.Operation{Name: "DescribeInstanceRefreshes", HTTPMethod: "POST", HTTPPath: "/"}, input, output)
+	return output, req.Send()
+}
+
+type instanceRefreshTarget struct {
+	poolName               string
+	nestedStackLogicalName string
+	asgLogicalName         string
+	settings               api.InstanceRefresh
+}
+
+// instanceRefreshes replaces outdated instances of auto scaling groups with instance refreshes and reports their progress until they finish
+type instanceRefreshes struct {
+	rootStackName string
+	targets       []instanceRefreshTarget
+
+	cfn         StackResourceDescriber
+	autoScaling instanceRefreshAutoScaling
+
+	pollInterval time.Duration
+}
+
+// refreshInstances replaces instances of the targeted node pools with `autoScalingGroup.instanceRefresh` enabled, whose launch templates
+// are updated without replacing instances by CloudFormation
+func (cl *Cluster) refreshInstances(targets OperationTargets) error {
+	r := &instanceRefreshes{
+		rootStackName: cl.stackName(),
+		cfn:           cloudformation.New(cl.session),
+		autoScaling:   instanceRefreshClient{autoscaling.New(cl.session)},
+		pollInterval:  15 * time.Second,
+	}
+	for _, np := range cl.nodePoolStacks {
+		pool := np.NodePoolConfig.WorkerNodePool
+		if !targets.IncludeWorker(np.StackName) || !pool.AutoScalingGroup.InstanceRefresh.Enabled {
+			continue
+		}
+		r.targets = append(r.targets, instanceRefreshTarget{
+			poolName:               pool.NodePoolName,
+			nestedStackLogicalName: np.NestedStackName(),
+			asgLogicalName:         pool.LogicalName(),
+			settings:               pool.AutoScalingGroup.InstanceRefresh,
+		})
+	}
+	if len(r.targets) == 0 {
+		return nil
+	}
+	return r.run()
+}
+
+func (r *instanceRefreshes) run() error {
+	// auto scaling group names of node pools keyed by the ids of their instance refreshes
+	refreshes := map[string]string{}
+	pools := map[string]string{}
+	for _, t := range r.targets {
+		asgName, err := r.autoScalingGroupName(t)
+		if err != nil {
+			return err
+		}
+		id, err := r.start(t, asgName)
+		if err != nil {
+			return fmt.Errorf("failed to refresh instances of node pool %s: %v", t.poolName, err)
+		}
+		if id != "" {
+			refreshes[id] = asgName
+			pools[id] = t.poolName
+		}
+	}
+
+	started := time.Now()
+	failures := []string{}
+	reported := map[string]string{}
+	for len(refreshes) > 0 {
+		time.Sleep(r.pollInterval)
+		for id, asgName := range refreshes {
+			out, err := r.autoScaling.DescribeInstanceRefreshes(&describeInstanceRefreshesInput{
+				AutoScalingGroupName: aws.String(asgName),
+				InstanceRefreshIds:   []*string{aws.String(id)},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to describe instance refresh %s of node pool %s: %v", id, pools[id], err)
+			}
+			if len(out.InstanceRefreshes) == 0 {
+				return fmt.Errorf("instance refresh %s of node pool %s not found", id, pools[id])
+			}
+			refresh := out.InstanceRefreshes[0]
+			status := aws.StringValue(refresh.Status)
+
+			progress := fmt.Sprintf("%-12s\t%3d%% complete\t%d instance(s) to update", status, aws.Int64Value(refresh.PercentageComplete), aws.Int64Value(refresh.InstancesToUpdate))
+			if reason := aws.StringValue(refresh.StatusReason); reason != "" {
+				progress += fmt.Sprintf("\t\"%s\"", reason)
+			}
+			if reported[id] != progress {
+				s := int(time.Since(started).Seconds())
+				logger.Infof("+%.2d:%.2d:%.2d\t%-22s\t%s\n", s/3600, (s/60)%60, s%60, pools[id], progress)
+				reported[id] = progress
+			}
+
+			switch status {
+			case instanceRefreshStatusPending, instanceRefreshStatusInProgress, instanceRefreshStatusCancelling:
+				continue
+			case instanceRefreshStatusSuccessful:
+			default:
+				failures = append(failures, fmt.Sprintf("instance refresh %s of node pool %s ended with status %s: %s", id, pools[id], status, aws.StringValue(refresh.StatusReason)))
+			}
+			delete(refreshes, id)
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+func (r *instanceRefreshes) autoScalingGroupName(t instanceRefreshTarget) (string, error) {
+	stackName, err := getNestedStackName(r.cfn, r.rootStackName, t.nestedStackLogicalName)
+	if err != nil {
+		return "", err
+	}
+	out, err := r.cfn.DescribeStackResource(&cloudformation.DescribeStackResourceInput{
+		StackName:         aws.String(stackName),
+		LogicalResourceId: aws.String(t.asgLogicalName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe auto scaling group of node pool %s: %v", t.poolName, err)
+	}
+	return aws.StringValue(out.StackResourceDetail.PhysicalResourceId), nil
+}
+
+// start starts an instance refresh of the auto scaling group when any of its instances is launched from an older version of the launch template.
+// It returns the id of the instance refresh already in progress, if any, so that an interrupted rollout is followed again. An empty id is returned
+// when all the instances are up to date
+func (r *instanceRefreshes) start(t instanceRefreshTarget, asgName string) (string, error) {
+	refreshes, err := r.autoScaling.DescribeInstanceRefreshes(&describeInstanceRefreshesInput{AutoScalingGroupName: aws.String(asgName)})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe instance refreshes of auto scaling group %s: %v", asgName, err)
+	}
+	for _, refresh := range refreshes.InstanceRefreshes {
+		switch aws.StringValue(refresh.Status) {
+		case instanceRefreshStatusPending, instanceRefreshStatusInProgress, instanceRefreshStatusCancelling:
+			logger.Infof("Following instance refresh %s of node pool %s already in progress\n", aws.StringValue(refresh.InstanceRefreshId), t.poolName)
+			return aws.StringValue(refresh.InstanceRefreshId), nil
+		}
+	}
+
+	outdated, err := r.outdatedInstances(asgName)
+	if err != nil {
+		return "", err
+	}
+	if outdated == 0 {
+		logger.Infof("Instances of node pool %s are up to date. Skipping instance refresh\n", t.poolName)
+		return "", nil
+	}
+
+	preferences := &refreshPreferences{}
+	s := t.settings
+	if s.MinHealthyPercentage != nil {
+		preferences.MinHealthyPercentage = aws.Int64(int64(*s.MinHealthyPercentage))
+	}
+	if s.InstanceWarmup != nil {
+		preferences.InstanceWarmup = aws.Int64(int64(*s.InstanceWarmup))
+	}
+	for _, p := range s.CheckpointPercentages {
+		preferences.CheckpointPercentages = append(preferences.CheckpointPercentages, aws.Int64(int64(p)))
+	}
+	if s.CheckpointDelay != nil {
+		preferences.CheckpointDelay = aws.Int64(int64(*s.CheckpointDelay))
+	}
+	out, err := r.autoScaling.StartInstanceRefresh(&startInstanceRefreshInput{
+		AutoScalingGroupName: aws.String(asgName),
+		Preferences:          preferences,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start instance refresh of auto scaling group %s: %v", asgName, err)
+	}
+	id := aws.StringValue(out.InstanceRefreshId)
+	logger.Headingf("Started instance refresh %s of node pool %s to replace %d outdated instance(s)", id, t.poolName, outdated)
+	return id, nil
+}
+
+// outdatedInstances returns the number of instances of the auto scaling group launched from versions of the launch template other than the current one
+func (r *instanceRefreshes) outdatedInstances(asgName string) (int, error) {
+	out, err := r.autoScaling.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(asgName)},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe auto scaling group %s: %v", asgName, err)
+	}
+	if len(out.AutoScalingGroups) == 0 {
+		return 0, fmt.Errorf("auto scaling group %s not found", asgName)
+	}
+	group := out.AutoScalingGroups[0]
+
+	lt := group.LaunchTemplate
+	if group.MixedInstancesPolicy != nil && group.MixedInstancesPolicy.LaunchTemplate != nil {
+		lt = group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	if lt == nil {
+		return 0, fmt.Errorf("auto scaling group %s has no launch template", asgName)
+	}
+
+	outdated := 0
+	for _, i := range group.Instances {
+		if i.LaunchTemplate == nil || aws.StringValue(i.LaunchTemplate.Version) != aws.StringValue(lt.Version) {
+			outdated++
+		}
+	}
+	return outdated, nil
+}
@@ -0,0 +1,177 @@
+package root
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInstanceRefreshCfn struct{}
+
+func (fakeInstanceRefreshCfn) DescribeStackResource(in *cloudformation.DescribeStackResourceInput) (*cloudformation.DescribeStackResourceOutput, error) {
+	ids := map[string]string{
+		"Pool1":      "it-Pool1-ABCDEF",
+		"WorkersASG": "it-Pool1-ABCDEF-WorkersASG-XYZ",
+	}
+	id, ok := ids[aws.StringValue(in.LogicalResourceId)]
+	if !ok {
+		return nil, fmt.Errorf("resource %s not found", aws.StringValue(in.LogicalResourceId))
+	}
+	return &cloudformation.DescribeStackResourceOutput{StackResourceDetail: &cloudformation.StackResourceDetail{PhysicalResourceId: aws.String(id)}}, nil
+}
+
+// fakeInstanceRefreshAutoScaling returns the statuses of the instance refresh in order. The last one is repeated
+type fakeInstanceRefreshAutoScaling struct {
+	instanceVersions []string
+	inProgress       bool
+	statuses         []string
+
+	started *startInstanceRefreshInput
+}
+
+func (a *fakeInstanceRefreshAutoScaling) DescribeAutoScalingGroups(in *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	g := &autoscaling.Group{
+		AutoScalingGroupName: in.AutoScalingGroupNames[0],
+		LaunchTemplate:       &autoscaling.LaunchTemplateSpecification{LaunchTemplateName: aws.String("lt"), Version: aws.String("2")},
+	}
+	for i, v := range a.instanceVersions {
+		g.Instances = append(g.Instances, &autoscaling.Instance{
+			InstanceId:     aws.String(fmt.Sprintf("i-%d", i+1)),
+			LaunchTemplate: &autoscaling.LaunchTemplateSpecification{LaunchTemplateName: aws.String("lt"), Version: aws.String(v)},
+		})
+	}
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, nil
+}
+
+func (a *fakeInstanceRefreshAutoScaling) StartInstanceRefresh(in *startInstanceRefreshInput) (*startInstanceRefreshOutput, error) {
+	a.started = in
+	return &startInstanceRefreshOutput{InstanceRefreshId: aws.String("refresh-2")}, nil
+}
+
+func (a *fakeInstanceRefreshAutoScaling) DescribeInstanceRefreshes(in *describeInstanceRefreshesInput) (*describeInstanceRefreshesOutput, error) {
+	if len(in.InstanceRefreshIds) == 0 {
+		refreshes := []*instanceRefresh{{InstanceRefreshId: aws.String("refresh-0"), Status: aws.String("Successful")}}
+		if a.inProgress {
+			refreshes = append([]*instanceRefresh{{InstanceRefreshId: aws.String("refresh-1"), Status: aws.String("InProgress")}}, refreshes...)
+		}
+		return &describeInstanceRefreshesOutput{InstanceRefreshes: refreshes}, nil
+	}
+	status := a.statuses[0]
+	if len(a.statuses) > 1 {
+		a.statuses = a.statuses[1:]
+	}
+	return &describeInstanceRefreshesOutput{InstanceRefreshes: []*instanceRefresh{{
+		InstanceRefreshId:  in.InstanceRefreshIds[0],
+		Status:             aws.String(status),
+		PercentageComplete: aws.Int64(50),
+		InstancesToUpdate:  aws.Int64(1),
+	}}}, nil
+}
+
+func newTestInstanceRefreshes(as *fakeInstanceRefreshAutoScaling) *instanceRefreshes {
+	minHealthy := 50
+	delay := 600
+	return &instanceRefreshes{
+		rootStackName: "it",
+		targets: []instanceRefreshTarget{{
+			poolName:               "pool1",
+			nestedStackLogicalName: "Pool1",
+			asgLogicalName:         "WorkersASG",
+			settings: api.InstanceRefresh{
+				Enabled:               true,
+				MinHealthyPercentage:  &minHealthy,
+				CheckpointPercentages: []int{20, 100},
+				CheckpointDelay:       &delay,
+			},
+		}},
+		cfn:         fakeInstanceRefreshCfn{},
+		autoScaling: as,
+	}
+}
+
+func TestInstanceRefreshes(t *testing.T) {
+	as := &fakeInstanceRefreshAutoScaling{instanceVersions: []string{"1", "2"}, statuses: []string{"Pending", "InProgress", "Successful"}}
+
+	require.NoError(t, newTestInstanceRefreshes(as).run())
+
+	require.NotNil(t, as.started)
+	assert.Equal(t, "it-Pool1-ABCDEF-WorkersASG-XYZ", aws.StringValue(as.started.AutoScalingGroupName))
+	assert.Equal(t, int64(50), aws.Int64Value(as.started.Preferences.MinHealthyPercentage))
+	assert.Nil(t, as.started.Preferences.InstanceWarmup)
+	assert.Equal(t, []*int64{aws.Int64(20), aws.Int64(100)}, as.started.Preferences.CheckpointPercentages)
+	assert.Equal(t, int64(600), aws.Int64Value(as.started.Preferences.CheckpointDelay))
+}
+
+func TestInstanceRefreshesUpToDate(t *testing.T) {
+	as := &fakeInstanceRefreshAutoScaling{instanceVersions: []string{"2", "2"}, statuses: []string{"Failed"}}
+
+	require.NoError(t, newTestInstanceRefreshes(as).run())
+
+	assert.Nil(t, as.started)
+}
+
+func TestInstanceRefreshesInProgress(t *testing.T) {
+	as := &fakeInstanceRefreshAutoScaling{instanceVersions: []string{"1"}, inProgress: true, statuses: []string{"Successful"}}
+
+	require.NoError(t, newTestInstanceRefreshes(as).run())
+
+	assert.Nil(t, as.started, "expected the instance refresh in progress to be followed instead of starting another one")
+}
+
+func TestInstanceRefreshesFailed(t *testing.T) {
+	as := &fakeInstanceRefreshAutoScaling{instanceVersions: []string{"1"}, statuses: []string{"InProgress", "Cancelled"}}
+
+	err := newTestInstanceRefreshes(as).run()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "instance refresh refresh-2 of node pool pool1 ended with status Cancelled")
+}
+
+func TestInstanceRefreshClient(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		form, _ = url.ParseQuery(string(body))
+		fmt.Fprint(w, `<StartInstanceRefreshResponse xmlns="http://autoscaling.amazonaws.com/doc/2011-01-01/">
+  <StartInstanceRefreshResult>
+    <InstanceRefreshId>08b91cf7-8fa6-48af-b6a6-d227f40f1b9b</InstanceRefreshId>
+  </StartInstanceRefreshResult>
+</StartInstanceRefreshResponse>`)
+	}))
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-west-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+	c := instanceRefreshClient{autoscaling.New(sess)}
+
+	out, err := c.StartInstanceRefresh(&startInstanceRefreshInput{
+		AutoScalingGroupName: aws.String("asg"),
+		Preferences: &refreshPreferences{
+			MinHealthyPercentage:  aws.Int64(90),
+			CheckpointPercentages: []*int64{aws.Int64(20), aws.Int64(100)},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "08b91cf7-8fa6-48af-b6a6-d227f40f1b9b", aws.StringValue(out.InstanceRefreshId))
+	assert.Equal(t, "StartInstanceRefresh", form.Get("Action"))
+	assert.Equal(t, "asg", form.Get("AutoScalingGroupName"))
+	assert.Equal(t, "90", form.Get("Preferences.MinHealthyPercentage"))
+	assert.Equal(t, "20", form.Get("Preferences.CheckpointPercentages.member.1"))
+	assert.Equal(t, "100", form.Get("Preferences.CheckpointPercentages.member.2"))
+}
@@ -0,0 +1,220 @@
+package root
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/kubernetes-incubator/kube-aws/pkg/model"
+)
+
+const (
+	KubeconfigAuthCert = "cert"
+	KubeconfigAuthExec = "exec"
+	KubeconfigAuthOIDC = "oidc"
+
+	KubeconfigExecAWSIAMAuthenticator = "aws-iam-authenticator"
+	KubeconfigExecAWSCLI              = "aws"
+
+	kubeconfigExecAPIVersion = "client.authentication.k8s.io/v1alpha1"
+)
+
+// KubeconfigOptions customizes the kubeconfig generated for a cluster
+type KubeconfigOptions struct {
+	// Auth is the way kubectl authenticates to the cluster. One of cert, exec or oidc
+	Auth string
+	// ExecCommand is the command used to obtain a token when Auth is exec. Either aws-iam-authenticator or aws
+	ExecCommand string
+	// AWSProfile is passed to the exec command via AWS_PROFILE when specified
+	AWSProfile string
+	// OIDCClientSecret is written to the oidc auth-provider entry when specified
+	OIDCClientSecret string
+	// CredentialsDir is the directory containing ca.pem, admin.pem and admin-key.pem
+	CredentialsDir string
+	// EmbedCerts embeds the certificates and the key into the kubeconfig rather than referencing the files
+	EmbedCerts bool
+	// AbsolutePaths makes references to the files absolute so that the kubeconfig is usable from any directory
+	AbsolutePaths bool
+	// UserName overrides the name of the user entry e.g. for personal kubeconfigs
+	UserName string
+	// ClientCertificateData and ClientKeyData are embedded instead of the admin credentials when Auth is cert and they are specified
+	ClientCertificateData []byte
+	ClientKeyData         []byte
+}
+
+// KubeconfigClusterName returns the name of the cluster entry in kubeconfig for the API endpoint
+func KubeconfigClusterName(clusterName, endpointName string) string {
+	return fmt.Sprintf("kube-aws-%s-%s-cluster", clusterName, endpointName)
+}
+
+// KubeconfigContextName returns the name of the context in kubeconfig for the API endpoint
+func KubeconfigContextName(clusterName, endpointName string) string {
+	return fmt.Sprintf("kube-aws-%s-%s-context", clusterName, endpointName)
+}
+
+// KubeconfigUserName returns the name of the user entry in kubeconfig for the authentication method
+func KubeconfigUserName(clusterName, auth string) string {
+	if auth == KubeconfigAuthCert {
+		return fmt.Sprintf("kube-aws-%s-admin", clusterName)
+	}
+	return fmt.Sprintf("kube-aws-%s-%s", clusterName, auth)
+}
+
+// GenerateKubeconfig returns a kubeconfig containing a context for each API endpoint of the cluster.
+// The current context is set to the one for the admin API endpoint.
+func GenerateKubeconfig(c *model.Config, opts KubeconfigOptions) (*kubeclient.Kubeconfig, error) {
+	if opts.Auth == "" {
+		opts.Auth = KubeconfigAuthCert
+	}
+	if opts.CredentialsDir == "" {
+		opts.CredentialsDir = "credentials"
+	}
+
+	user, err := kubeconfigUser(c.Cluster, opts)
+	if err != nil {
+		return nil, err
+	}
+	userName := KubeconfigUserName(c.ClusterName, opts.Auth)
+	if opts.UserName != "" {
+		userName = fmt.Sprintf("kube-aws-%s-%s", c.ClusterName, opts.UserName)
+	}
+
+	cluster := kubeclient.Cluster{}
+	if opts.EmbedCerts {
+		data, err := readCredential(opts.CredentialsDir, "ca.pem")
+		if err != nil {
+			return nil, err
+		}
+		cluster.CertificateAuthorityData = data
+	} else {
+		path, err := credentialPath(opts, "ca.pem")
+		if err != nil {
+			return nil, err
+		}
+		cluster.CertificateAuthority = path
+	}
+
+	names := []string{}
+	for name := range c.APIEndpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	kubeconfig := &kubeclient.Kubeconfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		Users:          []kubeclient.NamedUser{{Name: userName, User: *user}},
+		CurrentContext: KubeconfigContextName(c.ClusterName, c.AdminAPIEndpoint.Name),
+	}
+	for _, name := range names {
+		e := c.APIEndpoints[name]
+		cl := cluster
+		cl.Server = fmt.Sprintf("https://%s", e.DNSName)
+		clusterName := KubeconfigClusterName(c.ClusterName, name)
+		kubeconfig.Clusters = append(kubeconfig.Clusters, kubeclient.NamedCluster{Name: clusterName, Cluster: cl})
+		kubeconfig.Contexts = append(kubeconfig.Contexts, kubeclient.NamedContext{
+			Name: KubeconfigContextName(c.ClusterName, name),
+			Context: kubeclient.Context{
+				Cluster:   clusterName,
+				User:      userName,
+				Namespace: "default",
+			},
+		})
+	}
+
+	return kubeconfig, nil
+}
+
+func kubeconfigUser(c *api.Cluster, opts KubeconfigOptions) (*kubeclient.AuthInfo, error) {
+	switch opts.Auth {
+	case KubeconfigAuthCert:
+		if len(opts.ClientCertificateData) > 0 {
+			return &kubeclient.AuthInfo{
+				ClientCertificateData: base64.StdEncoding.EncodeToString(opts.ClientCertificateData),
+				ClientKeyData:         base64.StdEncoding.EncodeToString(opts.ClientKeyData),
+			}, nil
+		}
+		if opts.EmbedCerts {
+			cert, err := readCredential(opts.CredentialsDir, "admin.pem")
+			if err != nil {
+				return nil, err
+			}
+			key, err := readCredential(opts.CredentialsDir, "admin-key.pem")
+			if err != nil {
+				return nil, err
+			}
+			return &kubeclient.AuthInfo{ClientCertificateData: cert, ClientKeyData: key}, nil
+		}
+		cert, err := credentialPath(opts, "admin.pem")
+		if err != nil {
+			return nil, err
+		}
+		key, err := credentialPath(opts, "admin-key.pem")
+		if err != nil {
+			return nil, err
+		}
+		return &kubeclient.AuthInfo{ClientCertificate: cert, ClientKey: key}, nil
+	case KubeconfigAuthExec:
+		if !c.Kubernetes.Authentication.AWSIAM.Enabled {
+			return nil, fmt.Errorf("exec credentials require `kubernetes.authentication.awsIAM.enabled` to be true")
+		}
+		clusterID := c.Kubernetes.Authentication.AWSIAM.ClusterID
+		if clusterID == "" {
+			clusterID = c.ClusterName
+		}
+		exec := &kubeclient.ExecConfig{APIVersion: kubeconfigExecAPIVersion}
+		switch opts.ExecCommand {
+		case "", KubeconfigExecAWSIAMAuthenticator:
+			exec.Command = KubeconfigExecAWSIAMAuthenticator
+			exec.Args = []string{"token", "-i", clusterID}
+		case KubeconfigExecAWSCLI:
+			exec.Command = KubeconfigExecAWSCLI
+			exec.Args = []string{"--region", c.Region.Name, "eks", "get-token", "--cluster-name", clusterID}
+		default:
+			return nil, fmt.Errorf("unsupported exec command \"%s\". It must be either %s or %s", opts.ExecCommand, KubeconfigExecAWSIAMAuthenticator, KubeconfigExecAWSCLI)
+		}
+		if opts.AWSProfile != "" {
+			exec.Env = []kubeclient.ExecEnvVar{{Name: "AWS_PROFILE", Value: opts.AWSProfile}}
+		}
+		return &kubeclient.AuthInfo{Exec: exec}, nil
+	case KubeconfigAuthOIDC:
+		if !c.Experimental.Oidc.Enabled {
+			return nil, fmt.Errorf("oidc auth-provider requires `experimental.oidc.enabled` to be true")
+		}
+		config := map[string]string{
+			"idp-issuer-url": c.Experimental.Oidc.IssuerUrl,
+			"client-id":      c.Experimental.Oidc.ClientId,
+		}
+		if opts.OIDCClientSecret != "" {
+			config["client-secret"] = opts.OIDCClientSecret
+		}
+		return &kubeclient.AuthInfo{AuthProvider: &kubeclient.AuthProviderConfig{Name: "oidc", Config: config}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth \"%s\". It must be one of %s, %s or %s", opts.Auth, KubeconfigAuthCert, KubeconfigAuthExec, KubeconfigAuthOIDC)
+	}
+}
+
+func credentialPath(opts KubeconfigOptions, name string) (string, error) {
+	path := filepath.Join(opts.CredentialsDir, name)
+	if !opts.AbsolutePaths {
+		return path, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the absolute path of %s: %v", path, err)
+	}
+	return abs, nil
+}
+
+func readCredential(dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
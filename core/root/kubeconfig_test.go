@@ -0,0 +1,130 @@
+package root
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+	"github.com/kubernetes-incubator/kube-aws/pkg/model"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKubeconfigTestConfig() *model.Config {
+	c := api.NewDefaultCluster()
+	c.ClusterName = "test"
+	c.Region = api.RegionForName("us-west-1")
+
+	public := model.APIEndpoint{APIEndpoint: api.APIEndpoint{Name: "public", DNSName: "api.example.com"}}
+	private := model.APIEndpoint{APIEndpoint: api.APIEndpoint{Name: "private", DNSName: "api.internal.example.com"}}
+	return &model.Config{
+		Cluster:          c,
+		AdminAPIEndpoint: public,
+		APIEndpoints:     model.APIEndpoints{"public": public, "private": private},
+	}
+}
+
+func TestGenerateKubeconfig(t *testing.T) {
+	c := newKubeconfigTestConfig()
+
+	kubeconfig, err := GenerateKubeconfig(c, KubeconfigOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "kube-aws-test-public-context", kubeconfig.CurrentContext)
+	require.Len(t, kubeconfig.Clusters, 2)
+	assert.Equal(t, "kube-aws-test-private-cluster", kubeconfig.Clusters[0].Name)
+	assert.Equal(t, "https://api.internal.example.com", kubeconfig.Clusters[0].Cluster.Server)
+	assert.Equal(t, "https://api.example.com", kubeconfig.Clusters[1].Cluster.Server)
+	assert.Equal(t, filepath.Join("credentials", "ca.pem"), kubeconfig.Clusters[1].Cluster.CertificateAuthority)
+	require.Len(t, kubeconfig.Contexts, 2)
+	assert.Equal(t, "kube-aws-test-public-cluster", kubeconfig.Contexts[1].Context.Cluster)
+	assert.Equal(t, "kube-aws-test-admin", kubeconfig.Contexts[1].Context.User)
+	require.Len(t, kubeconfig.Users, 1)
+	assert.Equal(t, filepath.Join("credentials", "admin.pem"), kubeconfig.Users[0].User.ClientCertificate)
+
+	cluster, user, err := kubeconfig.Resolve("kube-aws-test-private-context")
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.internal.example.com", cluster.Server)
+	assert.Equal(t, filepath.Join("credentials", "admin-key.pem"), user.ClientKey)
+}
+
+func TestGenerateKubeconfigEmbedCerts(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		for _, f := range []string{"ca.pem", "admin.pem", "admin-key.pem"} {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, f), []byte(f), 0600))
+		}
+
+		kubeconfig, err := GenerateKubeconfig(newKubeconfigTestConfig(), KubeconfigOptions{CredentialsDir: dir, EmbedCerts: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("ca.pem")), kubeconfig.Clusters[0].Cluster.CertificateAuthorityData)
+		assert.Empty(t, kubeconfig.Clusters[0].Cluster.CertificateAuthority)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("admin-key.pem")), kubeconfig.Users[0].User.ClientKeyData)
+	})
+}
+
+func TestGenerateKubeconfigAuth(t *testing.T) {
+	t.Run("ExecWithAWSIAMAuthenticator", func(t *testing.T) {
+		c := newKubeconfigTestConfig()
+		c.Kubernetes.Authentication.AWSIAM.Enabled = true
+
+		kubeconfig, err := GenerateKubeconfig(c, KubeconfigOptions{Auth: KubeconfigAuthExec, AWSProfile: "admin"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "kube-aws-test-exec", kubeconfig.Users[0].Name)
+		exec := kubeconfig.Users[0].User.Exec
+		require.NotNil(t, exec)
+		assert.Equal(t, "aws-iam-authenticator", exec.Command)
+		assert.Equal(t, []string{"token", "-i", "test"}, exec.Args)
+		assert.Equal(t, "AWS_PROFILE", exec.Env[0].Name)
+		assert.Equal(t, "admin", exec.Env[0].Value)
+	})
+
+	t.Run("ExecWithAWSCLI", func(t *testing.T) {
+		c := newKubeconfigTestConfig()
+		c.Kubernetes.Authentication.AWSIAM.Enabled = true
+		c.Kubernetes.Authentication.AWSIAM.ClusterID = "custom"
+
+		kubeconfig, err := GenerateKubeconfig(c, KubeconfigOptions{Auth: KubeconfigAuthExec, ExecCommand: KubeconfigExecAWSCLI})
+		require.NoError(t, err)
+
+		exec := kubeconfig.Users[0].User.Exec
+		assert.Equal(t, "aws", exec.Command)
+		assert.Equal(t, []string{"--region", "us-west-1", "eks", "get-token", "--cluster-name", "custom"}, exec.Args)
+	})
+
+	t.Run("OIDC", func(t *testing.T) {
+		c := newKubeconfigTestConfig()
+		c.Experimental.Oidc.Enabled = true
+		c.Experimental.Oidc.IssuerUrl = "https://accounts.example.com"
+		c.Experimental.Oidc.ClientId = "kubernetes"
+
+		kubeconfig, err := GenerateKubeconfig(c, KubeconfigOptions{Auth: KubeconfigAuthOIDC})
+		require.NoError(t, err)
+
+		provider := kubeconfig.Users[0].User.AuthProvider
+		require.NotNil(t, provider)
+		assert.Equal(t, "oidc", provider.Name)
+		assert.Equal(t, map[string]string{"idp-issuer-url": "https://accounts.example.com", "client-id": "kubernetes"}, provider.Config)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		testCases := []struct {
+			opts          KubeconfigOptions
+			expectedError string
+		}{
+			{KubeconfigOptions{Auth: KubeconfigAuthExec}, "awsIAM.enabled"},
+			{KubeconfigOptions{Auth: KubeconfigAuthOIDC}, "oidc.enabled"},
+			{KubeconfigOptions{Auth: "token"}, "unsupported auth"},
+		}
+		for _, tc := range testCases {
+			_, err := GenerateKubeconfig(newKubeconfigTestConfig(), tc.opts)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		}
+	})
+}
@@ -97,3 +97,66 @@ func (c Cluster) allNodePoolLogicalNamesinAZ(az string) []string {
 	logger.Debugf("The following nodepools are in AZ %s: %v", az, poolNames)
 	return poolNames
 }
+
+// reportSurgeNodePools reports the targeted node pools with the 'Surge' NodePoolRollingStrategy and returns their names
+func (c Cluster) reportSurgeNodePools(targets OperationTargets) OperationTargets {
+	var surges OperationTargets
+	for _, pool := range c.nodePoolStacks {
+		poolConfig := pool.NodePoolConfig
+		if poolConfig.NodePoolRollingStrategy != "Surge" || !targets.IncludeWorker(pool.StackName) {
+			continue
+		}
+		logger.Infof("Node pool %s is rolled with the Surge strategy. Replacement instances for the whole auto scaling group are brought up "+
+			"before the current ones are removed, and CloudFormation rolls back to the current ones when they fail to signal\n", poolConfig.NodePoolName)
+		surges = append(surges, poolConfig.NodePoolName)
+	}
+	return surges
+}
+
+// nodePoolRolloutBatches groups node pool stack names into batches that can be updated one after another while
+// honoring each pool's NodePoolRollingStrategy, so that tools driving a rollout outside of CloudFormation's DependsOn
+// (e.g. `kube-aws upgrade kubernetes`) see the same ordering as a full `apply`.
+// The 'Canary' pool is rolled alone first, 'Parallel' and 'Surge' pools together next, 'Sequential' pools one at a time
+// in the order they appear in cluster.yaml, and 'AvailabilityZone' pools one availability zone at a time.
+func (c Cluster) nodePoolRolloutBatches() ([][]string, error) {
+	var batches [][]string
+
+	for _, pool := range c.nodePoolStacks {
+		if pool.NodePoolConfig.NodePoolRollingStrategy == "Canary" {
+			batches = append(batches, []string{pool.StackName})
+		}
+	}
+
+	var parallel []string
+	for _, pool := range c.nodePoolStacks {
+		if s := pool.NodePoolConfig.NodePoolRollingStrategy; s == "Parallel" || s == "Surge" {
+			parallel = append(parallel, pool.StackName)
+		}
+	}
+	if len(parallel) > 0 {
+		batches = append(batches, parallel)
+	}
+
+	for _, pool := range c.nodePoolStacks {
+		if pool.NodePoolConfig.NodePoolRollingStrategy == "Sequential" {
+			batches = append(batches, []string{pool.StackName})
+		}
+	}
+
+	order, err := c.azOrder()
+	if err != nil {
+		return nil, fmt.Errorf("can't resolve nodepool availability zone rollout order: %v", err)
+	}
+	for _, az := range order {
+		var batch []string
+		for _, pool := range c.nodePoolStacks {
+			poolConfig := pool.NodePoolConfig
+			if poolConfig.NodePoolRollingStrategy == "AvailabilityZone" && poolConfig.Subnets[0].AvailabilityZone == az {
+				batch = append(batch, pool.StackName)
+			}
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
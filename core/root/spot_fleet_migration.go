@@ -0,0 +1,247 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/nodedrainer"
+	"github.com/kubernetes-incubator/kube-aws/pkg/model"
+)
+
+const spotFleetResourceType = "AWS::EC2::SpotFleet"
+
+type spotFleetMigrationCfn interface {
+	StackResourceDescriber
+	model.StackTemplateGetter
+}
+
+type spotFleetMigrationEC2 interface {
+	DescribeSpotFleetInstances(*ec2.DescribeSpotFleetInstancesInput) (*ec2.DescribeSpotFleetInstancesOutput, error)
+}
+
+type spotFleetMigrationAutoScaling interface {
+	DescribeAutoScalingGroups(*autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+}
+
+type instanceDrainer interface {
+	DrainInstance(instanceID string) error
+}
+
+// spotFleetMigration moves worker nodes of a node pool from its spot fleet to the auto scaling group replacing it
+type spotFleetMigration struct {
+	rootStackName          string
+	nestedStackLogicalName string
+	fleetLogicalName       string
+	asgLogicalName         string
+
+	cfn         spotFleetMigrationCfn
+	ec2         spotFleetMigrationEC2
+	autoScaling spotFleetMigrationAutoScaling
+	kube        nodedrainer.KubeClient
+	drainer     instanceDrainer
+
+	// apply updates the node pool stack with the spot fleet resource carried over from the deployed stack, or without it when fleet is nil
+	apply func(fleet interface{}) error
+
+	readyTimeout time.Duration
+	pollInterval time.Duration
+}
+
+// MigrateSpotFleet moves worker nodes of the node pool from the spot fleet still deployed to the auto scaling group replacing it in cluster.yaml.
+// The auto scaling group is created alongside the spot fleet, which is carried over from the deployed stack as is. Nodes of the fleet are drained
+// only after all the nodes of the group are Ready, and then the fleet is removed.
+// Steps already done according to the deployed stack are skipped, so that an interrupted migration can be resumed by running it again.
+func (cl *Cluster) MigrateSpotFleet(poolName string, kube nodedrainer.KubeClient, readyTimeout, drainTimeout time.Duration) error {
+	if err := cl.ensureNestedStacksLoaded(); err != nil {
+		return err
+	}
+
+	var np *model.Stack
+	for _, s := range cl.nodePoolStacks {
+		if s.NodePoolConfig.NodePoolName == poolName {
+			np = s
+		}
+	}
+	if np == nil {
+		return fmt.Errorf("node pool %s not found in cluster.yaml", poolName)
+	}
+	pool := np.NodePoolConfig.WorkerNodePool
+	if pool.SpotFleet.Enabled() {
+		return fmt.Errorf("node pool %s still has `spotFleet` in cluster.yaml", poolName)
+	}
+	if !pool.MigratedFromSpotFleet {
+		return fmt.Errorf("node pool %s must have `migratedFromSpotFleet: true` so that its auto scaling group can be created alongside the spot fleet", poolName)
+	}
+
+	targets := OperationTargets{np.StackName}
+	m := &spotFleetMigration{
+		rootStackName:          cl.stackName(),
+		nestedStackLogicalName: np.NestedStackName(),
+		fleetLogicalName:       pool.SpotFleetLogicalName(),
+		asgLogicalName:         pool.LogicalName(),
+		cfn:                    cloudformation.New(cl.session),
+		ec2:                    ec2.New(cl.session),
+		autoScaling:            autoscaling.New(cl.session),
+		kube:                   kube,
+		drainer:                nodedrainer.New(nodedrainer.Config{DrainTimeout: drainTimeout}, kube, nil, nil),
+		apply: func(fleet interface{}) error {
+			if err := np.SetExtraCfnResource(pool.SpotFleetLogicalName(), fleet); err != nil {
+				return fmt.Errorf("failed to render node pool %s: %v", poolName, err)
+			}
+			return cl.Apply(targets)
+		},
+		readyTimeout: readyTimeout,
+		pollInterval: 15 * time.Second,
+	}
+	return m.run()
+}
+
+func (m *spotFleetMigration) run() error {
+	stackName, err := getNestedStackName(m.cfn, m.rootStackName, m.nestedStackLogicalName)
+	if err != nil {
+		return err
+	}
+	template, err := getStackTemplate(m.cfn, stackName)
+	if err != nil {
+		return err
+	}
+	resources, err := stackTemplateResources(template)
+	if err != nil {
+		return fmt.Errorf("failed to parse the template of stack %s: %v", stackName, err)
+	}
+
+	fleet, ok := resources[m.fleetLogicalName].(map[string]interface{})
+	if !ok || fleet["Type"] != spotFleetResourceType {
+		logger.Infof("Stack %s has no spot fleet. Nothing to migrate\n", stackName)
+		return nil
+	}
+
+	if _, ok := resources[m.asgLogicalName]; ok {
+		logger.Infof("Auto scaling group %s is already created alongside the spot fleet. Skipping\n", m.asgLogicalName)
+	} else {
+		logger.Headingf("Creating auto scaling group %s alongside spot fleet %s", m.asgLogicalName, m.fleetLogicalName)
+		if err := m.apply(fleet); err != nil {
+			return fmt.Errorf("failed to create the auto scaling group: %v", err)
+		}
+	}
+
+	asgName, err := m.physicalResourceID(stackName, m.asgLogicalName)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Waiting for nodes of auto scaling group %s to be Ready...\n", asgName)
+	if err := m.waitForNodesReady(asgName); err != nil {
+		return err
+	}
+
+	fleetID, err := m.physicalResourceID(stackName, m.fleetLogicalName)
+	if err != nil {
+		return err
+	}
+	instanceIDs, err := m.spotFleetInstances(fleetID)
+	if err != nil {
+		return err
+	}
+	logger.Headingf("Draining %d node(s) of spot fleet %s", len(instanceIDs), fleetID)
+	for _, id := range instanceIDs {
+		if err := m.drainer.DrainInstance(id); err != nil {
+			return fmt.Errorf("%v. The spot fleet is kept until its nodes are drained", err)
+		}
+	}
+
+	logger.Headingf("Removing spot fleet %s", fleetID)
+	if err := m.apply(nil); err != nil {
+		return fmt.Errorf("failed to remove the spot fleet: %v", err)
+	}
+	return nil
+}
+
+func (m *spotFleetMigration) physicalResourceID(stackName, logicalName string) (string, error) {
+	out, err := m.cfn.DescribeStackResource(&cloudformation.DescribeStackResourceInput{
+		StackName:         aws.String(stackName),
+		LogicalResourceId: aws.String(logicalName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe resource %s of stack %s: %v", logicalName, stackName, err)
+	}
+	return aws.StringValue(out.StackResourceDetail.PhysicalResourceId), nil
+}
+
+// waitForNodesReady waits until every instance of the auto scaling group is in service and its node is Ready
+func (m *spotFleetMigration) waitForNodesReady(asgName string) error {
+	deadline := time.Now().Add(m.readyTimeout)
+	for {
+		ready, err := m.nodesReady(asgName)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+		if time.Now().Add(m.pollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for nodes of auto scaling group %s to be Ready. The spot fleet is kept until they are", m.readyTimeout, asgName)
+		}
+		time.Sleep(m.pollInterval)
+	}
+}
+
+func (m *spotFleetMigration) nodesReady(asgName string) (bool, error) {
+	out, err := m.autoScaling.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(asgName)},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to describe auto scaling group %s: %v", asgName, err)
+	}
+	if len(out.AutoScalingGroups) == 0 {
+		return false, fmt.Errorf("auto scaling group %s not found", asgName)
+	}
+
+	instances := out.AutoScalingGroups[0].Instances
+	inService := []string{}
+	for _, i := range instances {
+		if aws.StringValue(i.LifecycleState) == autoscaling.LifecycleStateInService {
+			inService = append(inService, aws.StringValue(i.InstanceId))
+		}
+	}
+	ready, err := nodedrainer.ReadyInstances(m.kube, inService)
+	if err != nil {
+		return false, err
+	}
+	logger.Infof("%d of %d instance(s) of %s are in service and Ready\n", len(ready), len(instances), asgName)
+
+	return len(instances) > 0 && len(ready) == len(instances), nil
+}
+
+func (m *spotFleetMigration) spotFleetInstances(fleetID string) ([]string, error) {
+	ids := []string{}
+	input := &ec2.DescribeSpotFleetInstancesInput{SpotFleetRequestId: aws.String(fleetID)}
+	for {
+		out, err := m.ec2.DescribeSpotFleetInstances(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances of spot fleet %s: %v", fleetID, err)
+		}
+		for _, i := range out.ActiveInstances {
+			ids = append(ids, aws.StringValue(i.InstanceId))
+		}
+		if aws.StringValue(out.NextToken) == "" {
+			return ids, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+func stackTemplateResources(template string) (map[string]interface{}, error) {
+	t := struct {
+		Resources map[string]interface{}
+	}{}
+	if err := json.Unmarshal([]byte(template), &t); err != nil {
+		return nil, err
+	}
+	return t.Resources, nil
+}
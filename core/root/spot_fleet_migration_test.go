@@ -0,0 +1,180 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpotFleet = `{"Type":"AWS::EC2::SpotFleet","Properties":{"SpotFleetRequestConfigData":{"TargetCapacity":2}}}`
+
+type fakeSpotFleetMigrationCfn struct {
+	template string
+}
+
+func (c *fakeSpotFleetMigrationCfn) DescribeStackResource(in *cloudformation.DescribeStackResourceInput) (*cloudformation.DescribeStackResourceOutput, error) {
+	ids := map[string]string{
+		"Pool1":      "it-Pool1-ABCDEF",
+		"Workers":    "sfr-1234",
+		"WorkersASG": "it-Pool1-ABCDEF-WorkersASG-XYZ",
+	}
+	id, ok := ids[aws.StringValue(in.LogicalResourceId)]
+	if !ok {
+		return nil, fmt.Errorf("resource %s not found", aws.StringValue(in.LogicalResourceId))
+	}
+	return &cloudformation.DescribeStackResourceOutput{StackResourceDetail: &cloudformation.StackResourceDetail{PhysicalResourceId: aws.String(id)}}, nil
+}
+
+func (c *fakeSpotFleetMigrationCfn) GetTemplate(in *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error) {
+	return &cloudformation.GetTemplateOutput{TemplateBody: aws.String(c.template)}, nil
+}
+
+type fakeSpotFleetMigrationEC2 struct{}
+
+func (fakeSpotFleetMigrationEC2) DescribeSpotFleetInstances(in *ec2.DescribeSpotFleetInstancesInput) (*ec2.DescribeSpotFleetInstancesOutput, error) {
+	if in.NextToken == nil {
+		return &ec2.DescribeSpotFleetInstancesOutput{
+			ActiveInstances: []*ec2.ActiveInstance{{InstanceId: aws.String("i-fleet-1")}},
+			NextToken:       aws.String("next"),
+		}, nil
+	}
+	return &ec2.DescribeSpotFleetInstancesOutput{ActiveInstances: []*ec2.ActiveInstance{{InstanceId: aws.String("i-fleet-2")}}}, nil
+}
+
+// fakeSpotFleetMigrationAutoScaling returns the lifecycle states of instances in order. The last ones are repeated
+type fakeSpotFleetMigrationAutoScaling struct {
+	states [][]string
+}
+
+func (a *fakeSpotFleetMigrationAutoScaling) DescribeAutoScalingGroups(in *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	g := &autoscaling.Group{}
+	for i, s := range a.states[0] {
+		g.Instances = append(g.Instances, &autoscaling.Instance{InstanceId: aws.String(fmt.Sprintf("i-asg-%d", i+1)), LifecycleState: aws.String(s)})
+	}
+	if len(a.states) > 1 {
+		a.states = a.states[1:]
+	}
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, nil
+}
+
+// fakeNodesClient lists nodes of the instances of the auto scaling group, which become Ready at the second listing
+type fakeNodesClient struct {
+	listed int
+}
+
+func (c *fakeNodesClient) Get(path string, out interface{}) error {
+	c.listed++
+	status := "False"
+	if c.listed > 1 {
+		status = "True"
+	}
+	nodes := `{"items":[`
+	for i := 1; i <= 2; i++ {
+		if i > 1 {
+			nodes += ","
+		}
+		nodes += fmt.Sprintf(`{"metadata":{"name":"ip-10-0-0-%d"},"spec":{"providerID":"aws:///us-west-1a/i-asg-%d"},"status":{"conditions":[{"type":"Ready","status":%q}]}}`, i, i, status)
+	}
+	nodes += "]}"
+	return json.Unmarshal([]byte(nodes), out)
+}
+
+func (c *fakeNodesClient) Do(method, path string, body interface{}, out interface{}) error {
+	return fmt.Errorf("unexpected request: %s %s", method, path)
+}
+
+func (c *fakeNodesClient) Patch(path string, patch interface{}, out interface{}) error {
+	return fmt.Errorf("unexpected patch: %s", path)
+}
+
+type fakeInstanceDrainer struct {
+	drained []string
+	err     error
+}
+
+func (d *fakeInstanceDrainer) DrainInstance(instanceID string) error {
+	d.drained = append(d.drained, instanceID)
+	return d.err
+}
+
+func newTestSpotFleetMigration(template string, applied *[]interface{}) (*spotFleetMigration, *fakeInstanceDrainer) {
+	drainer := &fakeInstanceDrainer{}
+	return &spotFleetMigration{
+		rootStackName:          "it",
+		nestedStackLogicalName: "Pool1",
+		fleetLogicalName:       "Workers",
+		asgLogicalName:         "WorkersASG",
+		cfn:                    &fakeSpotFleetMigrationCfn{template: template},
+		ec2:                    fakeSpotFleetMigrationEC2{},
+		autoScaling:            &fakeSpotFleetMigrationAutoScaling{states: [][]string{{"Pending"}, {"InService", "InService"}}},
+		kube:                   &fakeNodesClient{},
+		drainer:                drainer,
+		apply: func(fleet interface{}) error {
+			*applied = append(*applied, fleet)
+			return nil
+		},
+		readyTimeout: time.Second,
+		pollInterval: time.Millisecond,
+	}, drainer
+}
+
+func TestSpotFleetMigration(t *testing.T) {
+	applied := []interface{}{}
+	m, drainer := newTestSpotFleetMigration(`{"Resources":{"Workers":`+testSpotFleet+`}}`, &applied)
+
+	require.NoError(t, m.run())
+
+	require.Len(t, applied, 2)
+	fleet := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(testSpotFleet), &fleet))
+	assert.Equal(t, fleet, applied[0], "the deployed spot fleet must be kept as is while the auto scaling group is created")
+	assert.Nil(t, applied[1], "the spot fleet must be removed at last")
+	assert.Equal(t, []string{"i-fleet-1", "i-fleet-2"}, drainer.drained)
+}
+
+func TestSpotFleetMigrationResume(t *testing.T) {
+	applied := []interface{}{}
+	m, drainer := newTestSpotFleetMigration(`{"Resources":{"Workers":`+testSpotFleet+`,"WorkersASG":{"Type":"AWS::AutoScaling::AutoScalingGroup"}}}`, &applied)
+	drainer.err = fmt.Errorf("failed to drain node ip-10-0-1-1")
+
+	err := m.run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "The spot fleet is kept until its nodes are drained")
+	assert.Empty(t, applied, "the auto scaling group already exists and the spot fleet must not be removed")
+
+	drainer.err = nil
+	require.NoError(t, m.run())
+	assert.Equal(t, []interface{}{nil}, applied)
+}
+
+func TestSpotFleetMigrationWithoutSpotFleet(t *testing.T) {
+	applied := []interface{}{}
+	m, drainer := newTestSpotFleetMigration(`{"Resources":{"WorkersASG":{"Type":"AWS::AutoScaling::AutoScalingGroup"}}}`, &applied)
+
+	require.NoError(t, m.run())
+	assert.Empty(t, applied)
+	assert.Empty(t, drainer.drained)
+}
+
+func TestSpotFleetMigrationReadyTimeout(t *testing.T) {
+	applied := []interface{}{}
+	m, drainer := newTestSpotFleetMigration(`{"Resources":{"Workers":`+testSpotFleet+`}}`, &applied)
+	// Auto scaling groups without instances never become Ready
+	m.autoScaling = &fakeSpotFleetMigrationAutoScaling{states: [][]string{{}}}
+	m.readyTimeout = 10 * time.Millisecond
+	m.pollInterval = 5 * time.Millisecond
+
+	err := m.run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.Len(t, applied, 1)
+	assert.Empty(t, drainer.drained)
+}
@@ -34,16 +34,42 @@ func getNestedStackName(cfnSvc StackResourceDescriber, stackName string, nestedS
 	return aws.StringValue(output.StackResourceDetail.PhysicalResourceId), nil
 }
 
-func getInstanceScriptUserdata(stackJson string, nestedStackLogicalName string) (string, error) {
+// getUserData returns the userdata in the first launch template or launch configuration found in the stack template.
+// Multiple logical names are accepted so that a stack still having a launch configuration can be compared to one having a launch template
+func getUserData(stackJson string, logicalNames []string) (map[string]interface{}, error) {
 	dest := map[string]interface{}{}
 	err := json.Unmarshal([]byte(stackJson), &dest)
+	if err != nil {
+		return nil, err
+	}
+	res, ok := dest["Resources"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no resources found in the stack template")
+	}
+	for _, name := range logicalNames {
+		r, ok := res[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		props, ok := r["Properties"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if data, ok := props["LaunchTemplateData"].(map[string]interface{}); ok {
+			props = data
+		}
+		if ud, ok := props["UserData"].(map[string]interface{}); ok {
+			return ud, nil
+		}
+	}
+	return nil, fmt.Errorf("no userdata found in any of the resources: %s", strings.Join(logicalNames, ", "))
+}
+
+func getInstanceScriptUserdata(stackJson string, logicalNames []string) (string, error) {
+	ud, err := getUserData(stackJson, logicalNames)
 	if err != nil {
 		return "", err
 	}
-	res := dest["Resources"].(map[string]interface{})
-	lc := res[nestedStackLogicalName].(map[string]interface{})
-	props := lc["Properties"].(map[string]interface{})
-	ud := props["UserData"].(map[string]interface{})
 	fnBase64 := ud["Fn::Base64"].(map[string]interface{})
 	fnJoin := fnBase64["Fn::Join"].([]interface{})
 	joinedItems := fnJoin[1].([]interface{})
@@ -51,16 +77,11 @@ func getInstanceScriptUserdata(stackJson string, nestedStackLogicalName string)
 	return instanceScript, nil
 }
 
-func getInstanceUserdataJson(stackJson string, nestedStackLogicalName string) (string, error) {
-	dest := map[string]interface{}{}
-	err := json.Unmarshal([]byte(stackJson), &dest)
+func getInstanceUserdataJson(stackJson string, logicalNames []string) (string, error) {
+	ud, err := getUserData(stackJson, logicalNames)
 	if err != nil {
 		return "", err
 	}
-	res := dest["Resources"].(map[string]interface{})
-	lc := res[nestedStackLogicalName].(map[string]interface{})
-	props := lc["Properties"].(map[string]interface{})
-	ud := props["UserData"].(map[string]interface{})
 	buf := new(bytes.Buffer)
 	encoder := json.NewEncoder(buf)
 	// Avoid diffs like this:
@@ -0,0 +1,32 @@
+package root
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetInstanceUserdata(t *testing.T) {
+	userdata := `{"Fn::Base64":{"Fn::Join":["",["#!/bin/bash\n","a\n","b\n","instance-script"]]}}`
+
+	launchConfiguration := `{"Resources":{"ControllersLC":{"Type":"AWS::AutoScaling::LaunchConfiguration","Properties":{"UserData":` + userdata + `}}}}`
+	launchTemplate := `{"Resources":{"ControllersLT":{"Type":"AWS::EC2::LaunchTemplate","Properties":{"LaunchTemplateData":{"UserData":` + userdata + `}}}}}`
+
+	names := []string{"ControllersLT", "ControllersLC"}
+
+	for name, stack := range map[string]string{"LaunchConfiguration": launchConfiguration, "LaunchTemplate": launchTemplate} {
+		t.Run(name, func(t *testing.T) {
+			script, err := getInstanceScriptUserdata(stack, names)
+			require.NoError(t, err)
+			assert.Equal(t, "instance-script", script)
+
+			ud, err := getInstanceUserdataJson(stack, names)
+			require.NoError(t, err)
+			assert.JSONEq(t, userdata, ud)
+		})
+	}
+
+	_, err := getInstanceUserdataJson(launchTemplate, []string{"EtcdLT"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,298 @@
+package root
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/kubernetes-incubator/kube-aws/core/root/defaults"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+)
+
+// minimumEtcdVersions lists the oldest etcd release each Kubernetes minor version (and the ones after it) can run against
+var minimumEtcdVersions = []struct {
+	kubernetes string
+	etcd       string
+}{
+	// Kubernetes 1.13 removed the etcd2 storage backend
+	{"1.13", "3.2.0"},
+}
+
+// CheckKubernetesVersionSkew returns an error when upgrading from Kubernetes `from` to `to` breaks the supported version
+// skew policy, or when the etcd version used by the cluster is too old for `to`.
+func CheckKubernetesVersionSkew(from, to, etcdVersion string) error {
+	current, err := semver.NewVersion(from)
+	if err != nil {
+		return fmt.Errorf("current kubernetesVersion \"%s\" is not a valid version: %v", from, err)
+	}
+	target, err := semver.NewVersion(to)
+	if err != nil {
+		return fmt.Errorf("target kubernetes version \"%s\" is not a valid version: %v", to, err)
+	}
+	etcd, err := semver.NewVersion(etcdVersion)
+	if err != nil {
+		return fmt.Errorf("etcd version \"%s\" is not a valid version: %v", etcdVersion, err)
+	}
+
+	if target.Equal(current) {
+		return fmt.Errorf("the cluster is already running kubernetes %s", from)
+	}
+	if target.LessThan(current) {
+		return fmt.Errorf("downgrading kubernetes from %s to %s is not supported", from, to)
+	}
+	if target.Major() != current.Major() {
+		return fmt.Errorf("upgrading kubernetes across major versions(%s to %s) is not supported", from, to)
+	}
+	if target.Minor()-current.Minor() > 1 {
+		return fmt.Errorf("upgrading kubernetes from %s to %s skips minor versions. Upgrade one minor version at a time, e.g. to v%d.%d.x first", from, to, current.Major(), current.Minor()+1)
+	}
+
+	for _, m := range minimumEtcdVersions {
+		c, err := semver.NewConstraint(">= " + m.kubernetes)
+		if err != nil {
+			return fmt.Errorf("[BUG] invalid kubernetes version constraint \"%s\": %v", m.kubernetes, err)
+		}
+		minEtcd, err := semver.NewVersion(m.etcd)
+		if err != nil {
+			return fmt.Errorf("[BUG] invalid etcd version \"%s\": %v", m.etcd, err)
+		}
+		// compare without the pre-release part so that e.g. v1.13.0-rc.1 is treated as 1.13
+		t, _ := semver.NewVersion(fmt.Sprintf("%d.%d.%d", target.Major(), target.Minor(), target.Patch()))
+		if c.Check(t) && etcd.LessThan(minEtcd) {
+			return fmt.Errorf("kubernetes %s requires etcd %s or greater but the cluster is running etcd %s. Upgrade `etcd.version` first", to, m.etcd, etcdVersion)
+		}
+	}
+
+	return nil
+}
+
+// UpgradeState records the progress of a `kube-aws upgrade kubernetes` run so that an interrupted upgrade can be resumed
+type UpgradeState struct {
+	From                 string    `json:"from"`
+	To                   string    `json:"to"`
+	ControlPlaneUpgraded bool      `json:"controlPlaneUpgraded"`
+	NodePoolsUpgraded    []string  `json:"nodePoolsUpgraded,omitempty"`
+	StartedAt            time.Time `json:"startedAt"`
+	UpdatedAt            time.Time `json:"updatedAt"`
+
+	path string
+}
+
+func NewUpgradeState(path, from, to string) *UpgradeState {
+	now := time.Now().UTC()
+	return &UpgradeState{
+		From:      from,
+		To:        to,
+		StartedAt: now,
+		UpdatedAt: now,
+		path:      path,
+	}
+}
+
+// LoadUpgradeState reads the upgrade state at path. It returns nil without an error when there is no upgrade in progress.
+func LoadUpgradeState(path string) (*UpgradeState, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upgrade state %s: %v", path, err)
+	}
+
+	s := &UpgradeState{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse upgrade state %s: %v", path, err)
+	}
+	s.path = path
+
+	return s, nil
+}
+
+func (s *UpgradeState) Save() error {
+	s.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal upgrade state: %v", err)
+	}
+	if err := ioutil.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write upgrade state %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// Remove deletes the state file once the upgrade has completed
+func (s *UpgradeState) Remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove upgrade state %s: %v", s.path, err)
+	}
+	return nil
+}
+
+func (s *UpgradeState) nodePoolUpgraded(stackName string) bool {
+	for _, n := range s.NodePoolsUpgraded {
+		if n == stackName {
+			return true
+		}
+	}
+	return false
+}
+
+var kubernetesVersionKey = regexp.MustCompile(`(?m)^kubernetesVersion:.*$`)
+
+// SetKubernetesVersion rewrites the top-level `kubernetesVersion` of the cluster.yaml at configPath, keeping
+// the rest of the file including comments as is
+func SetKubernetesVersion(configPath, version string) error {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(configPath, setKubernetesVersion(data, version), 0600); err != nil {
+		return fmt.Errorf("failed to update kubernetesVersion in %s: %v", configPath, err)
+	}
+	return nil
+}
+
+func setKubernetesVersion(data []byte, version string) []byte {
+	line := fmt.Sprintf("kubernetesVersion: %s", version)
+
+	if kubernetesVersionKey.Match(data) {
+		return kubernetesVersionKey.ReplaceAll(data, []byte(line))
+	}
+
+	s := string(data)
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return []byte(s + line + "\n")
+}
+
+// UpgradeKubernetes rolls the cluster, which must already be configured with the target kubernetesVersion, to the
+// version recorded in the state.
+// The control plane is updated first. Node pools are updated only after the API server reports healthy, in batches
+// respecting their NodePoolRollingStrategy. The state is saved after each step so that a failed upgrade can be resumed.
+func (cl *Cluster) UpgradeKubernetes(state *UpgradeState, apiHealthTimeout time.Duration) error {
+	if err := cl.ensureNestedStacksLoaded(); err != nil {
+		return err
+	}
+
+	if cl.Cfg.K8sVer != state.To {
+		return fmt.Errorf("kubernetesVersion in cluster.yaml is %s but the upgrade in progress targets %s", cl.Cfg.K8sVer, state.To)
+	}
+
+	if !state.ControlPlaneUpgraded {
+		logger.Headingf("Upgrading the control plane to kubernetes %s", state.To)
+		if err := cl.Apply(OperationTargets{cl.controlPlaneStack.Config.ControlPlaneStackName()}); err != nil {
+			return fmt.Errorf("failed to upgrade control plane: %v", err)
+		}
+		state.ControlPlaneUpgraded = true
+		if err := state.Save(); err != nil {
+			return err
+		}
+	} else {
+		logger.Infof("Control plane is already upgraded to kubernetes %s. Skipping\n", state.To)
+	}
+
+	logger.Infof("Waiting for the API server at %s to become healthy...\n", cl.Cfg.AdminAPIEndpointURL())
+	if err := cl.WaitForAPIServerHealthy(apiHealthTimeout); err != nil {
+		return err
+	}
+
+	batches, err := cl.nodePoolRolloutBatches()
+	if err != nil {
+		return err
+	}
+
+	for _, batch := range batches {
+		var targets OperationTargets
+		for _, stackName := range batch {
+			if state.nodePoolUpgraded(stackName) {
+				logger.Infof("Node pool %s is already upgraded to kubernetes %s. Skipping\n", stackName, state.To)
+				continue
+			}
+			targets = append(targets, stackName)
+		}
+		if len(targets) == 0 {
+			continue
+		}
+
+		logger.Headingf("Upgrading node pool(s) %s to kubernetes %s", targets.String(), state.To)
+		if err := cl.Apply(targets); err != nil {
+			return fmt.Errorf("failed to upgrade node pool(s) %s: %v", targets.String(), err)
+		}
+		state.NodePoolsUpgraded = append(state.NodePoolsUpgraded, targets...)
+		if err := state.Save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WaitForAPIServerHealthy polls the /healthz endpoint of the admin API endpoint until it answers "ok" or the timeout expires.
+// The kube-aws generated CA and admin client certificate are used to authenticate the request.
+func (cl *Cluster) WaitForAPIServerHealthy(timeout time.Duration) error {
+	client, err := apiServerHTTPClient(defaults.AssetsDir)
+	if err != nil {
+		return err
+	}
+	url := cl.Cfg.AdminAPIEndpointURL() + "/healthz"
+	return waitForHealthz(client, url, timeout, 10*time.Second)
+}
+
+func apiServerHTTPClient(assetsDir string) (*http.Client, error) {
+	caCert, err := ioutil.ReadFile(filepath.Join(assetsDir, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse CA certificate in %s", filepath.Join(assetsDir, "ca.pem"))
+	}
+
+	tlsConfig := &tls.Config{RootCAs: pool}
+
+	adminCert := filepath.Join(assetsDir, "admin.pem")
+	adminKey := filepath.Join(assetsDir, "admin-key.pem")
+	if keyPair, err := tls.LoadX509KeyPair(adminCert, adminKey); err == nil {
+		tlsConfig.Certificates = []tls.Certificate{keyPair}
+	} else {
+		logger.Debugf("Checking API server health anonymously as the admin key pair could not be loaded: %v", err)
+	}
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+func waitForHealthz(client *http.Client, url string, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK && strings.TrimSpace(string(body)) == "ok" {
+				return nil
+			}
+			err = fmt.Errorf("unexpected response: %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		logger.Debugf("API server is not healthy yet: %v", err)
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("timed out waiting for the API server at %s to become healthy: %v", url, err)
+		}
+		time.Sleep(interval)
+	}
+}
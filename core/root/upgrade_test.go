@@ -0,0 +1,109 @@
+package root
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckKubernetesVersionSkew(t *testing.T) {
+	testCases := []struct {
+		from, to, etcd string
+		expectedError  string
+	}{
+		{from: "v1.14.3", to: "v1.15.5", etcd: "3.3.17"},
+		{from: "v1.15.1", to: "v1.15.5", etcd: "3.3.17"},
+		{from: "v1.12.10", to: "v1.13.0-rc.1", etcd: "3.2.24"},
+		{from: "v1.15.5", to: "v1.15.5", etcd: "3.3.17", expectedError: "already running"},
+		{from: "v1.15.5", to: "v1.14.0", etcd: "3.3.17", expectedError: "downgrading"},
+		{from: "v1.13.5", to: "v1.15.0", etcd: "3.3.17", expectedError: "skips minor versions"},
+		{from: "v1.15.5", to: "v2.0.0", etcd: "3.3.17", expectedError: "across major versions"},
+		{from: "v1.12.10", to: "v1.13.1", etcd: "2.3.7", expectedError: "requires etcd 3.2.0"},
+		{from: "v1.15.5", to: "latest", etcd: "3.3.17", expectedError: "not a valid version"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s-%s-etcd%s", tc.from, tc.to, tc.etcd), func(t *testing.T) {
+			err := CheckKubernetesVersionSkew(tc.from, tc.to, tc.etcd)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestSetKubernetesVersion(t *testing.T) {
+	t.Run("Replace", func(t *testing.T) {
+		in := "clusterName: test\n# kubernetesVersion: v1.0.0\nkubernetesVersion: v1.14.3\nworker:\n  kubernetesVersion: v1.14.3\n"
+		out := string(setKubernetesVersion([]byte(in), "v1.15.5"))
+		assert.Equal(t, "clusterName: test\n# kubernetesVersion: v1.0.0\nkubernetesVersion: v1.15.5\nworker:\n  kubernetesVersion: v1.14.3\n", out)
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		out := string(setKubernetesVersion([]byte("clusterName: test"), "v1.15.5"))
+		assert.Equal(t, "clusterName: test\nkubernetesVersion: v1.15.5\n", out)
+	})
+}
+
+func TestUpgradeStateRoundTrip(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		path := filepath.Join(dir, "upgrade-state.json")
+
+		s, err := LoadUpgradeState(path)
+		require.NoError(t, err)
+		require.Nil(t, s)
+
+		s = NewUpgradeState(path, "v1.14.3", "v1.15.5")
+		s.ControlPlaneUpgraded = true
+		s.NodePoolsUpgraded = []string{"pool1"}
+		require.NoError(t, s.Save())
+
+		loaded, err := LoadUpgradeState(path)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.14.3", loaded.From)
+		assert.Equal(t, "v1.15.5", loaded.To)
+		assert.True(t, loaded.ControlPlaneUpgraded)
+		assert.True(t, loaded.nodePoolUpgraded("pool1"))
+		assert.False(t, loaded.nodePoolUpgraded("pool2"))
+
+		require.NoError(t, loaded.Remove())
+		s, err = LoadUpgradeState(path)
+		require.NoError(t, err)
+		assert.Nil(t, s)
+	})
+}
+
+func TestWaitForHealthz(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "[-]etcd failed")
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	require.NoError(t, waitForHealthz(server.Client(), server.URL+"/healthz", time.Second, time.Millisecond))
+	assert.Equal(t, 3, calls)
+
+	calls = -1000
+	err := waitForHealthz(server.Client(), server.URL+"/healthz", 10*time.Millisecond, 5*time.Millisecond)
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "timed out"), err.Error())
+	}
+}
@@ -70,6 +70,7 @@ type RawAssetsOnDisk struct {
 	EtcdClientKey             PlaintextFile
 	EtcdTrustedCA             PlaintextFile
 	ServiceAccountKey         PlaintextFile
+	CRL                       PlaintextFile
 
 	// Other assets.
 	AuthTokens        PlaintextFile
@@ -101,6 +102,7 @@ type EncryptedAssetsOnDisk struct {
 	EtcdClientKey             EncryptedFile
 	EtcdTrustedCA             EncryptedFile
 	ServiceAccountKey         EncryptedFile
+	CRL                       EncryptedFile
 
 	// Other encrypted assets.
 	AuthTokens        EncryptedFile
@@ -132,6 +134,8 @@ type CompactAssets struct {
 	EtcdKey                   string
 	EtcdTrustedCA             string
 	ServiceAccountKey         string
+	// CRL is the certificate revocation list generated by `kube-aws credentials revoke`. Empty until any certificate is revoked
+	CRL string
 
 	// Encrypted -> gzip -> base64 encoded assets.
 	AuthTokens        string
@@ -142,7 +146,18 @@ type CompactAssets struct {
 }
 
 func ReadRawAssets(dirname string, manageCertificates bool, caKeyRequiredOnController bool) (*RawAssetsOnDisk, error) {
+	return readRawAssets(dirname, manageCertificates, caKeyRequiredOnController, RawCredentialFileFromPath)
+}
+
+// ReadRawAssetsFromBackend reads the plaintext assets from the secret backend. dirname is used only for naming the assets as if they were files in it
+func ReadRawAssetsFromBackend(dirname string, manageCertificates bool, caKeyRequiredOnController bool, backend SecretBackend) (*RawAssetsOnDisk, error) {
+	store := Store{Backend: backend}
+	return readRawAssets(dirname, manageCertificates, caKeyRequiredOnController, store.RawCredentialFromPath)
+}
+
+func readRawAssets(dirname string, manageCertificates bool, caKeyRequiredOnController bool, read func(string, *string) (*PlaintextFile, error)) (*RawAssetsOnDisk, error) {
 	defaultTokensFile := ""
+	defaultCRL := ""
 	defaultServiceAccountKey := "<<<" + filepath.Join(dirname, "apiserver-key.pem")
 	defaultTLSBootstrapToken, err := RandomTokenString()
 	if err != nil {
@@ -194,6 +209,7 @@ func ReadRawAssets(dirname string, manageCertificates bool, caKeyRequiredOnContr
 			{name: "apiserver-aggregator.pem", data: &r.APIServerAggregatorCert, defaultValue: nil, expiryCheck: true},
 			// allow setting service-account-key from the apiserver-key by default.
 			{name: "service-account-key.pem", data: &r.ServiceAccountKey, defaultValue: &defaultServiceAccountKey},
+			{name: "crl.pem", data: &r.CRL, defaultValue: &defaultCRL, expiryCheck: false},
 		}...)
 
 		if caKeyRequiredOnController {
@@ -203,7 +219,7 @@ func ReadRawAssets(dirname string, manageCertificates bool, caKeyRequiredOnContr
 
 	for _, file := range files {
 		path := filepath.Join(dirname, file.name)
-		data, err := RawCredentialFileFromPath(path, file.defaultValue)
+		data, err := read(path, file.defaultValue)
 		if err != nil {
 			return nil, fmt.Errorf("error reading credential file %s: %v", path, err)
 		}
@@ -227,6 +243,7 @@ func ReadRawAssets(dirname string, manageCertificates bool, caKeyRequiredOnContr
 
 func ReadOrEncryptAssets(dirname string, manageCertificates bool, caKeyRequiredOnController bool, store Store) (*EncryptedAssetsOnDisk, error) {
 	defaultTokensFile := ""
+	defaultCRL := ""
 	defaultServiceAccountKey := "<<<" + filepath.Join(dirname, "apiserver-key.pem")
 	defaultTLSBootstrapToken, err := RandomTokenString()
 	if err != nil {
@@ -275,6 +292,7 @@ func ReadOrEncryptAssets(dirname string, manageCertificates bool, caKeyRequiredO
 			{name: "apiserver-aggregator-key.pem", data: &r.APIServerAggregatorKey, defaultValue: nil, readEncrypted: true, expiryCheck: false},
 			{name: "apiserver-aggregator.pem", data: &r.APIServerAggregatorCert, defaultValue: nil, readEncrypted: false, expiryCheck: true},
 			{name: "service-account-key.pem", data: &r.ServiceAccountKey, defaultValue: &defaultServiceAccountKey, readEncrypted: true, expiryCheck: false},
+			{name: "crl.pem", data: &r.CRL, defaultValue: &defaultCRL, readEncrypted: false, expiryCheck: false},
 		}...)
 
 		if caKeyRequiredOnController {
@@ -295,7 +313,7 @@ func ReadOrEncryptAssets(dirname string, manageCertificates bool, caKeyRequiredO
 				return nil, fmt.Errorf("error persisting %s: %v", path, err)
 			}
 		} else {
-			raw, err := RawCredentialFileFromPath(path, file.defaultValue)
+			raw, err := store.RawCredentialFromPath(path, file.defaultValue)
 			if err != nil {
 				return nil, fmt.Errorf("error reading credential file %s: %v", path, err)
 			}
@@ -436,6 +454,80 @@ func (r *RawAssetsOnMemory) WriteToDir(dirname string, includeCAKey bool) error
 	return nil
 }
 
+// WriteToBackend writes the assets to the secret backend. Empty assets which are symlinked to other files by WriteToDir are written as copies of them
+func (r *RawAssetsOnMemory) WriteToBackend(backend SecretBackend, includeCAKey bool) error {
+	type asset struct {
+		name          string
+		data          []byte
+		overwrite     bool
+		ifEmptyCopyOf []byte
+	}
+	assets := []asset{
+		{"ca.pem", r.CACert, true, nil},
+		{"worker-ca.pem", r.WorkerCACert, true, r.CACert},
+		{"apiserver.pem", r.APIServerCert, true, nil},
+		{"apiserver-key.pem", r.APIServerKey, true, nil},
+		{"kube-controller-manager.pem", r.KubeControllerManagerCert, true, nil},
+		{"kube-controller-manager-key.pem", r.KubeControllerManagerKey, true, nil},
+		{"kube-scheduler.pem", r.KubeSchedulerCert, true, nil},
+		{"kube-scheduler-key.pem", r.KubeSchedulerKey, true, nil},
+		{"worker.pem", r.WorkerCert, true, nil},
+		{"worker-key.pem", r.WorkerKey, true, nil},
+		{"admin.pem", r.AdminCert, true, nil},
+		{"admin-key.pem", r.AdminKey, true, nil},
+		{"etcd.pem", r.EtcdCert, true, nil},
+		{"etcd-key.pem", r.EtcdKey, true, nil},
+		{"etcd-client.pem", r.EtcdClientCert, true, nil},
+		{"etcd-client-key.pem", r.EtcdClientKey, true, nil},
+		{"etcd-trusted-ca.pem", r.EtcdTrustedCA, true, r.CACert},
+		{"apiserver-aggregator-key.pem", r.APIServerAggregatorKey, true, nil},
+		{"apiserver-aggregator.pem", r.APIServerAggregatorCert, true, nil},
+		{"kubelet-tls-bootstrap-token", r.TLSBootstrapToken, true, nil},
+		{"service-account-key.pem", r.ServiceAccountKey, true, r.APIServerKey},
+
+		// Content entirely provided by user, so do not overwrite it if
+		// the secret already exists
+		{"tokens.csv", r.AuthTokens, false, nil},
+		{"encryption-config.yaml", r.EncryptionConfig, false, nil},
+	}
+
+	if includeCAKey {
+		assets = append(assets,
+			asset{"ca-key.pem", r.CAKey, true, nil},
+			asset{"worker-ca-key.pem", r.WorkerCAKey, true, r.CAKey},
+		)
+	}
+
+	for _, asset := range assets {
+		if !asset.overwrite {
+			_, found, err := backend.Get(asset.name)
+			if err != nil {
+				return err
+			}
+			if found {
+				continue
+			}
+		}
+		data := asset.data
+		if len(data) == 0 {
+			if asset.ifEmptyCopyOf != nil {
+				data = asset.ifEmptyCopyOf
+			} else if asset.name == "tokens.csv" {
+				// Empty secrets aren't stored as some backends reject them
+				continue
+			} else {
+				return fmt.Errorf("Not sure what to do for %s", asset.name)
+			}
+		}
+		logger.Infof("Writing %d bytes to %s in %s\n", len(data), asset.name, backend)
+		if err := backend.Put(asset.name, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *EncryptedAssetsOnDisk) WriteToDir(dirname string) error {
 	type asset struct {
 		name string
@@ -464,6 +556,7 @@ func (r *EncryptedAssetsOnDisk) WriteToDir(dirname string) error {
 		{"apiserver-aggregator-key.pem", r.APIServerAggregatorKey},
 		{"apiserver-aggregator.pem", r.APIServerAggregatorCert},
 		{"service-account-key.pem", r.ServiceAccountKey},
+		{"crl.pem", r.CRL},
 		{"tokens.csv", r.AuthTokens},
 		{"kubelet-tls-bootstrap-token", r.TLSBootstrapToken},
 		{"encryption-config.yaml", r.EncryptionConfig},
@@ -519,6 +612,7 @@ func (r *RawAssetsOnDisk) Compact() (*CompactAssets, error) {
 		APIServerAggregatorCert:   compact(r.APIServerAggregatorCert),
 		APIServerAggregatorKey:    compact(r.APIServerAggregatorKey),
 		ServiceAccountKey:         compact(r.ServiceAccountKey),
+		CRL:                       compact(r.CRL),
 
 		AuthTokens:        compact(r.AuthTokens),
 		TLSBootstrapToken: compact(r.TLSBootstrapToken),
@@ -571,6 +665,7 @@ func (r *EncryptedAssetsOnDisk) Compact() (*CompactAssets, error) {
 		APIServerAggregatorCert:   compact(r.APIServerAggregatorCert),
 		APIServerAggregatorKey:    compact(r.APIServerAggregatorKey),
 		ServiceAccountKey:         compact(r.ServiceAccountKey),
+		CRL:                       compact(r.CRL),
 
 		AuthTokens:        compact(r.AuthTokens),
 		TLSBootstrapToken: compact(r.TLSBootstrapToken),
@@ -585,18 +680,37 @@ func (r *EncryptedAssetsOnDisk) Compact() (*CompactAssets, error) {
 type KMSConfig struct {
 	KMSSvc    KMSEncryptionService
 	KMSKeyARN string
+	// SecretBackend is where plaintext credentials are kept instead of the credentials directory. Only encrypted caches are written to the directory when set
+	SecretBackend SecretBackend
 }
 
+// Encryptor returns an envelope encryptor generating a data key on its first encryption when the kms service supports it e.g. the one created from an aws session,
+// or an encryptor calling KMS for each asset otherwise
 func (c KMSConfig) Encryptor() Encryptor {
+	if svc, ok := c.KMSSvc.(KMSDataKeyService); ok {
+		return &EnvelopeEncryptor{
+			KmsKeyARN: c.KMSKeyARN,
+			KmsSvc:    svc,
+		}
+	}
 	return KMSEncryptor{
 		KmsKeyARN: c.KMSKeyARN,
 		KmsSvc:    c.KMSSvc,
 	}
 }
 
+// Decryptor returns a decryptor backed by the kms service, which is available only when the service supports decryption e.g. the one created from an aws session
+func (c KMSConfig) Decryptor() Decryptor {
+	svc, _ := c.KMSSvc.(KMSDecryptionService)
+	return KMSDecryptor{
+		KmsSvc: svc,
+	}
+}
+
 func (c KMSConfig) Store() Store {
 	return Store{
 		Encryptor: c.Encryptor(),
+		Backend:   c.SecretBackend,
 	}
 }
 
@@ -656,27 +770,6 @@ func RandomTokenString() (string, error) {
 	return base64.StdEncoding.EncodeToString(b), nil
 }
 
-func EncryptionConfig() (string, error) {
-	secret, err := RandomTokenString()
-	if err != nil {
-		return "", err
-	}
-	config := `kind: EncryptionConfig
-apiVersion: v1
-resources:
-  - resources:
-    - secrets
-    providers:
-    - aescbc:
-        keys:
-        - name: default
-          secret: %s
-    - identity: {}
-`
-
-	return fmt.Sprintf(config, secret), nil
-}
-
 func (a *CompactAssets) HasAuthTokens() bool {
 	return len(a.AuthTokens) > 0
 }
@@ -684,3 +777,7 @@ func (a *CompactAssets) HasAuthTokens() bool {
 func (a *CompactAssets) HasTLSBootstrapToken() bool {
 	return len(a.TLSBootstrapToken) > 0
 }
+
+func (a *CompactAssets) HasCRL() bool {
+	return len(a.CRL) > 0
+}
@@ -0,0 +1,230 @@
+package credential
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// EncryptionConfigFile is the file under the credentials directory configuring how kube-apiserver encrypts secrets at rest
+	EncryptionConfigFile = "encryption-config.yaml"
+
+	EncryptionProviderAESCBC = "aescbc"
+	EncryptionProviderKMS    = "kms"
+
+	// KMSPluginEndpoint is the socket the KMS provider plugin on controller nodes listens on
+	KMSPluginEndpoint = "unix:///var/run/kmsplugin/socket.sock"
+
+	defaultEncryptionKeyName = "default"
+)
+
+// EncryptionConfiguration is the file passed to kube-apiserver via --experimental-encryption-provider-config.
+// The first provider for a resource encrypts it and all the providers are tried in order to decrypt it
+type EncryptionConfiguration struct {
+	Kind       string                  `yaml:"kind"`
+	APIVersion string                  `yaml:"apiVersion"`
+	Resources  []EncryptedResourceList `yaml:"resources"`
+}
+
+type EncryptedResourceList struct {
+	Resources []string             `yaml:"resources"`
+	Providers []EncryptionProvider `yaml:"providers"`
+}
+
+// EncryptionProvider has exactly one of its fields set
+type EncryptionProvider struct {
+	AESCBC   *AESEncryptionProvider `yaml:"aescbc,omitempty"`
+	KMS      *KMSEncryptionProvider `yaml:"kms,omitempty"`
+	Identity *struct{}              `yaml:"identity,omitempty"`
+}
+
+type AESEncryptionProvider struct {
+	Keys []EncryptionKey `yaml:"keys"`
+}
+
+type EncryptionKey struct {
+	Name   string `yaml:"name"`
+	Secret string `yaml:"secret"`
+}
+
+type KMSEncryptionProvider struct {
+	Name      string `yaml:"name"`
+	Endpoint  string `yaml:"endpoint"`
+	CacheSize int    `yaml:"cachesize,omitempty"`
+}
+
+// EncryptionConfig generates the default encryption config, which encrypts secrets with a random aescbc key
+func EncryptionConfig() (string, error) {
+	key, err := NewAESEncryptionKey(defaultEncryptionKeyName)
+	if err != nil {
+		return "", err
+	}
+	c := EncryptionConfiguration{
+		Kind:       "EncryptionConfig",
+		APIVersion: "v1",
+		Resources: []EncryptedResourceList{
+			{
+				Resources: []string{"secrets"},
+				Providers: []EncryptionProvider{
+					{AESCBC: &AESEncryptionProvider{Keys: []EncryptionKey{*key}}},
+					{Identity: &struct{}{}},
+				},
+			},
+		},
+	}
+	data, err := c.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// NewAESEncryptionKey generates a random 32-byte key for the aescbc provider
+func NewAESEncryptionKey(name string) (*EncryptionKey, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &EncryptionKey{Name: name, Secret: base64.StdEncoding.EncodeToString(b)}, nil
+}
+
+// NewKMSEncryptionProvider returns the provider delegating encryption of data encryption keys to the KMS provider plugin.
+// kube-apiserver caches up to cacheSize decrypted data encryption keys, or its default number of keys when cacheSize is 0
+func NewKMSEncryptionProvider(name string, cacheSize int) EncryptionProvider {
+	return EncryptionProvider{KMS: &KMSEncryptionProvider{Name: name, Endpoint: KMSPluginEndpoint, CacheSize: cacheSize}}
+}
+
+func ParseEncryptionConfig(data []byte) (*EncryptionConfiguration, error) {
+	c := &EncryptionConfiguration{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse encryption config: %v", err)
+	}
+	if c.secrets() == nil {
+		return nil, fmt.Errorf("encryption config has no providers for secrets")
+	}
+	return c, nil
+}
+
+func (c *EncryptionConfiguration) Bytes() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func (c *EncryptionConfiguration) secrets() *EncryptedResourceList {
+	for i, r := range c.Resources {
+		for _, name := range r.Resources {
+			if name == "secrets" {
+				return &c.Resources[i]
+			}
+		}
+	}
+	return nil
+}
+
+// keyNames returns the names of the keys of the provider. A kms provider is a single key named after the provider
+func (p EncryptionProvider) keyNames() []string {
+	names := []string{}
+	if p.AESCBC != nil {
+		for _, k := range p.AESCBC.Keys {
+			names = append(names, k.Name)
+		}
+	}
+	if p.KMS != nil {
+		names = append(names, p.KMS.Name)
+	}
+	return names
+}
+
+// KeyNames returns the names of all the keys secrets can be decrypted with. The first one is the primary key, which encrypts secrets
+func (c *EncryptionConfiguration) KeyNames() []string {
+	names := []string{}
+	for _, p := range c.secrets().Providers {
+		names = append(names, p.keyNames()...)
+	}
+	return names
+}
+
+// PrimaryKeyName returns the name of the key secrets are encrypted with, or an empty string when secrets are stored unencrypted
+func (c *EncryptionConfiguration) PrimaryKeyName() string {
+	names := c.secrets().Providers[0].keyNames()
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+func (c *EncryptionConfiguration) hasKey(name string) bool {
+	for _, n := range c.KeyNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSecondaryKey makes the key of the provider available for decryption right after the primary key, without encrypting anything with it yet.
+// A new aescbc key is added to the primary aescbc provider when there is one. Adding a key which already exists does nothing
+func (c *EncryptionConfiguration) AddSecondaryKey(p EncryptionProvider) error {
+	names := p.keyNames()
+	if len(names) != 1 {
+		return fmt.Errorf("[bug] a key to add must be a single aescbc key or a kms provider but was: %+v", p)
+	}
+	if c.hasKey(names[0]) {
+		return nil
+	}
+	r := c.secrets()
+	if p.AESCBC != nil && r.Providers[0].AESCBC != nil {
+		keys := r.Providers[0].AESCBC.Keys
+		r.Providers[0].AESCBC.Keys = append(keys[:1], append([]EncryptionKey{p.AESCBC.Keys[0]}, keys[1:]...)...)
+		return nil
+	}
+	r.Providers = append(r.Providers[:1], append([]EncryptionProvider{p}, r.Providers[1:]...)...)
+	return nil
+}
+
+// PromoteKey makes the key the primary key so that secrets written from now on are encrypted with it
+func (c *EncryptionConfiguration) PromoteKey(name string) error {
+	r := c.secrets()
+	for i, p := range r.Providers {
+		if p.KMS != nil && p.KMS.Name == name {
+			r.Providers = append([]EncryptionProvider{p}, append(r.Providers[:i:i], r.Providers[i+1:]...)...)
+			return nil
+		}
+		if p.AESCBC == nil {
+			continue
+		}
+		for j, k := range p.AESCBC.Keys {
+			if k.Name == name {
+				p.AESCBC.Keys = append([]EncryptionKey{k}, append(p.AESCBC.Keys[:j:j], p.AESCBC.Keys[j+1:]...)...)
+				r.Providers = append([]EncryptionProvider{p}, append(r.Providers[:i:i], r.Providers[i+1:]...)...)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("encryption key %s not found in the encryption config", name)
+}
+
+// RemoveSecondaryKeys removes all the aescbc keys and kms providers except the primary key and returns the names of the removed keys.
+// The identity provider is kept so that secrets written before encryption at rest was enabled remain readable
+func (c *EncryptionConfiguration) RemoveSecondaryKeys() []string {
+	r := c.secrets()
+	removed := []string{}
+	providers := []EncryptionProvider{r.Providers[0]}
+	if r.Providers[0].AESCBC != nil && len(r.Providers[0].AESCBC.Keys) > 1 {
+		for _, k := range r.Providers[0].AESCBC.Keys[1:] {
+			removed = append(removed, k.Name)
+		}
+		r.Providers[0].AESCBC.Keys = r.Providers[0].AESCBC.Keys[:1]
+	}
+	for _, p := range r.Providers[1:] {
+		if p.Identity != nil {
+			providers = append(providers, p)
+			continue
+		}
+		removed = append(removed, p.keyNames()...)
+	}
+	r.Providers = providers
+	return removed
+}
@@ -0,0 +1,173 @@
+package credential
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+)
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	runSteps := func(t *testing.T, r *EncryptionKeyRotation, c *EncryptionConfiguration) {
+		for step := r.NextStep(); step != ""; step = r.NextStep() {
+			if _, err := r.ApplyStep(step, c); err != nil {
+				t.Fatalf("unexpected error in %s: %v", step, err)
+			}
+			// Every step can be re-run when interrupted
+			if changed, err := r.ApplyStep(step, c); err != nil || changed {
+				t.Errorf("expected %s to be idempotent but changed=%v, err=%v", step, changed, err)
+			}
+			r.Complete(step, time.Now())
+		}
+	}
+
+	t.Run("AESCBC", func(t *testing.T) {
+		data, err := EncryptionConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c, err := ParseEncryptionConfig([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r, err := NewEncryptionKeyRotation(EncryptionProviderAESCBC, time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if r.KeyName != "aescbc-20190102030405" {
+			t.Errorf("unexpected key name: %s", r.KeyName)
+		}
+
+		if _, err := r.ApplyStep(RotationStepAddKey, c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if names := c.KeyNames(); !reflect.DeepEqual(names, []string{"default", r.KeyName}) {
+			t.Errorf("expected the new key to be the secondary key but keys were %v", names)
+		}
+		if _, err := r.ApplyStep(RotationStepPromoteKey, c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if names := c.KeyNames(); !reflect.DeepEqual(names, []string{r.KeyName, "default"}) {
+			t.Errorf("expected the new key to be the primary key but keys were %v", names)
+		}
+		if _, err := r.ApplyStep(RotationStepRemoveOldKeys, c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if names := c.KeyNames(); !reflect.DeepEqual(names, []string{r.KeyName}) {
+			t.Errorf("expected only the new key to remain but keys were %v", names)
+		}
+
+		providers := c.secrets().Providers
+		if len(providers) != 2 || providers[1].Identity == nil {
+			t.Errorf("expected the identity provider to be kept: %+v", providers)
+		}
+		if len(providers[0].AESCBC.Keys[0].Secret) != 44 {
+			t.Errorf("expected a base64-encoded 32-byte secret but was %s", providers[0].AESCBC.Keys[0].Secret)
+		}
+	})
+
+	t.Run("ToKMS", func(t *testing.T) {
+		data, err := EncryptionConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c, err := ParseEncryptionConfig([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r, err := NewEncryptionKeyRotation(EncryptionProviderKMS, time.Now())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.KMSCacheSize = 1000
+		runSteps(t, r, c)
+
+		out, err := c.Bytes()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := `kind: EncryptionConfig
+apiVersion: v1
+resources:
+- resources:
+  - secrets
+  providers:
+  - kms:
+      name: ` + r.KeyName + `
+      endpoint: unix:///var/run/kmsplugin/socket.sock
+      cachesize: 1000
+  - identity: {}
+`
+		if string(out) != expected {
+			t.Errorf("unexpected encryption config:\nexpected:\n%s\nactual:\n%s", expected, string(out))
+		}
+	})
+
+	t.Run("RemoveBeforePromotion", func(t *testing.T) {
+		data, err := EncryptionConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c, err := ParseEncryptionConfig([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r, err := NewEncryptionKeyRotation(EncryptionProviderAESCBC, time.Now())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := r.ApplyStep(RotationStepAddKey, c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := r.ApplyStep(RotationStepRemoveOldKeys, c); err == nil {
+			t.Errorf("expected the primary key not to be removed")
+		}
+	})
+
+	t.Run("UnsupportedProvider", func(t *testing.T) {
+		if _, err := ParseEncryptionConfig([]byte("kind: EncryptionConfig\napiVersion: v1\nresources:\n- resources: [secrets]\n  providers:\n  - secretbox: {}\n")); err == nil {
+			t.Errorf("expected an error for an unsupported provider")
+		}
+		if _, err := NewEncryptionKeyRotation("secretbox", time.Now()); err == nil {
+			t.Errorf("expected an error for an unsupported provider")
+		}
+	})
+
+	t.Run("Progress", func(t *testing.T) {
+		helper.WithTempDir(func(dir string) {
+			r, err := LoadEncryptionKeyRotation(dir)
+			if err != nil || r != nil {
+				t.Fatalf("expected no rotation in progress but was %v, err=%v", r, err)
+			}
+			r, err = NewEncryptionKeyRotation(EncryptionProviderAESCBC, time.Now())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r.Complete(RotationStepAddKey, time.Now())
+			r.PendingStep = RotationStepPromoteKey
+			if err := r.Save(dir); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			loaded, err := LoadEncryptionKeyRotation(dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if loaded.NextStep() != RotationStepPromoteKey || loaded.PendingStep != RotationStepPromoteKey || loaded.KeyName != r.KeyName {
+				t.Errorf("unexpected progress: %+v", loaded)
+			}
+			loaded.Complete(RotationStepPromoteKey, time.Now())
+			if loaded.PendingStep != "" {
+				t.Errorf("expected the pending step to be cleared once completed")
+			}
+
+			if err := loaded.Finish(dir); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r, err := LoadEncryptionKeyRotation(dir); err != nil || r != nil {
+				t.Errorf("expected the progress to be removed but was %v, err=%v", r, err)
+			}
+		})
+	})
+}
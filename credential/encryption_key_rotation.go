@@ -0,0 +1,169 @@
+package credential
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// EncryptionKeyRotationFile is the file under the credentials directory tracking the progress of `kube-aws rotate encryption-key`.
+	// It contains no key material and is removed once the rotation completes
+	EncryptionKeyRotationFile = "encryption-key-rotation.json"
+
+	// RotationStepAddKey adds the new key to every controller as a secondary key, so that any controller can decrypt secrets encrypted with it
+	RotationStepAddKey = "add-key"
+	// RotationStepPromoteKey makes the new key the primary key so that secrets written from then on are encrypted with it
+	RotationStepPromoteKey = "promote-key"
+	// RotationStepRewriteSecrets rewrites every secret so that it is re-encrypted with the new key
+	RotationStepRewriteSecrets = "rewrite-secrets"
+	// RotationStepRemoveOldKeys removes the keys no secret is encrypted with anymore
+	RotationStepRemoveOldKeys = "remove-old-keys"
+)
+
+// EncryptionKeyRotationSteps are the steps of a rotation in the order they must be run
+var EncryptionKeyRotationSteps = []string{
+	RotationStepAddKey,
+	RotationStepPromoteKey,
+	RotationStepRewriteSecrets,
+	RotationStepRemoveOldKeys,
+}
+
+// EncryptionKeyRotation is the progress of a rotation of the key secrets are encrypted at rest with
+type EncryptionKeyRotation struct {
+	// Provider is the type of the new key. Either aescbc or kms
+	Provider string `json:"provider"`
+	// KeyName is the name of the new aescbc key or kms provider in the encryption config
+	KeyName string `json:"keyName"`
+	// KMSCacheSize is the number of data encryption keys kube-apiserver caches for the new kms provider
+	KMSCacheSize   int                         `json:"kmsCacheSize,omitempty"`
+	StartedAt      time.Time                   `json:"startedAt"`
+	CompletedSteps []EncryptionKeyRotationStep `json:"completedSteps"`
+	// PendingStep is the step whose change to the encryption config is not confirmed to be applied to controller nodes yet
+	PendingStep string `json:"pendingStep,omitempty"`
+}
+
+type EncryptionKeyRotationStep struct {
+	Name        string    `json:"name"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// NewEncryptionKeyRotation starts a rotation to a new key of the provider, named after the start time
+func NewEncryptionKeyRotation(provider string, now time.Time) (*EncryptionKeyRotation, error) {
+	if provider != EncryptionProviderAESCBC && provider != EncryptionProviderKMS {
+		return nil, fmt.Errorf("unsupported encryption provider \"%s\". It must be either %s or %s", provider, EncryptionProviderAESCBC, EncryptionProviderKMS)
+	}
+	return &EncryptionKeyRotation{
+		Provider:       provider,
+		KeyName:        fmt.Sprintf("%s-%s", provider, now.UTC().Format("20060102150405")),
+		StartedAt:      now,
+		CompletedSteps: []EncryptionKeyRotationStep{},
+	}, nil
+}
+
+// LoadEncryptionKeyRotation reads the rotation in progress in dir. nil is returned when no rotation is in progress
+func LoadEncryptionKeyRotation(dir string) (*EncryptionKeyRotation, error) {
+	path := filepath.Join(dir, EncryptionKeyRotationFile)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	r := &EncryptionKeyRotation{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return r, nil
+}
+
+// Save writes the progress to dir so that an interrupted rotation can be resumed
+func (r *EncryptionKeyRotation) Save(dir string) error {
+	path := filepath.Join(dir, EncryptionKeyRotationFile)
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize encryption key rotation: %v", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// Finish removes the progress from dir
+func (r *EncryptionKeyRotation) Finish(dir string) error {
+	path := filepath.Join(dir, EncryptionKeyRotationFile)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %v", path, err)
+	}
+	return nil
+}
+
+// NextStep returns the first step not completed yet, or an empty string when the rotation is complete
+func (r *EncryptionKeyRotation) NextStep() string {
+	for _, s := range EncryptionKeyRotationSteps {
+		if !r.Completed(s) {
+			return s
+		}
+	}
+	return ""
+}
+
+func (r *EncryptionKeyRotation) Completed(step string) bool {
+	for _, s := range r.CompletedSteps {
+		if s.Name == step {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *EncryptionKeyRotation) Complete(step string, now time.Time) {
+	if !r.Completed(step) {
+		r.CompletedSteps = append(r.CompletedSteps, EncryptionKeyRotationStep{Name: step, CompletedAt: now})
+	}
+	if r.PendingStep == step {
+		r.PendingStep = ""
+	}
+}
+
+// NewKey returns the key to add to the encryption config in the add-key step
+func (r *EncryptionKeyRotation) NewKey() (EncryptionProvider, error) {
+	if r.Provider == EncryptionProviderKMS {
+		return NewKMSEncryptionProvider(r.KeyName, r.KMSCacheSize), nil
+	}
+	key, err := NewAESEncryptionKey(r.KeyName)
+	if err != nil {
+		return EncryptionProvider{}, err
+	}
+	return EncryptionProvider{AESCBC: &AESEncryptionProvider{Keys: []EncryptionKey{*key}}}, nil
+}
+
+// ApplyStep updates the encryption config for the step. It returns false when the step doesn't change the encryption config
+func (r *EncryptionKeyRotation) ApplyStep(step string, c *EncryptionConfiguration) (bool, error) {
+	switch step {
+	case RotationStepAddKey:
+		if c.hasKey(r.KeyName) {
+			return false, nil
+		}
+		key, err := r.NewKey()
+		if err != nil {
+			return false, err
+		}
+		return true, c.AddSecondaryKey(key)
+	case RotationStepPromoteKey:
+		if c.PrimaryKeyName() == r.KeyName {
+			return false, nil
+		}
+		return true, c.PromoteKey(r.KeyName)
+	case RotationStepRemoveOldKeys:
+		if c.PrimaryKeyName() != r.KeyName {
+			return false, fmt.Errorf("refusing to remove old keys because the primary key is %s rather than the new key %s", c.PrimaryKeyName(), r.KeyName)
+		}
+		return len(c.RemoveSecondaryKeys()) > 0, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,138 @@
+package credential
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// EnvelopeHeaderPrefix starts the header line of an envelope-encrypted asset, which is followed by
+// the base64-encoded wrapped data key and nonce separated by colons.
+// Assets encrypted directly with KMS by older versions of kube-aws never start with it
+const EnvelopeHeaderPrefix = "kube-aws:envelope-v1:"
+
+type dataKey struct {
+	plaintext []byte
+	wrapped   []byte
+}
+
+func (s *EnvelopeEncryptor) ensureDataKey() (*dataKey, error) {
+	if s.dataKey != nil {
+		return s.dataKey, nil
+	}
+	out, err := s.KmsSvc.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(s.KmsKeyARN),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	s.dataKey = &dataKey{plaintext: out.Plaintext, wrapped: out.CiphertextBlob}
+	return s.dataKey, nil
+}
+
+func (s *EnvelopeEncryptor) EncryptedBytes(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return []byte{}, nil
+	}
+
+	key, err := s.ensureDataKey()
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key.plaintext)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(EnvelopeHeaderPrefix)
+	buf.WriteString(base64.StdEncoding.EncodeToString(key.wrapped))
+	buf.WriteString(":")
+	buf.WriteString(base64.StdEncoding.EncodeToString(nonce))
+	buf.WriteString("\n")
+	buf.Write(gcm.Seal(nil, nonce, data, nil))
+	return buf.Bytes(), nil
+}
+
+// IsEnvelopeEncrypted returns true if the data is encrypted by EnvelopeEncryptor rather than directly with KMS
+func IsEnvelopeEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(EnvelopeHeaderPrefix))
+}
+
+// envelope is an envelope-encrypted asset split into its header fields and the sealed data
+type envelope struct {
+	wrappedKey []byte
+	nonce      []byte
+	sealed     []byte
+}
+
+func parseEnvelope(data []byte) (*envelope, error) {
+	if !IsEnvelopeEncrypted(data) {
+		return nil, errors.New("missing envelope header")
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, errors.New("envelope header is not terminated")
+	}
+	fields := bytes.Split(data[len(EnvelopeHeaderPrefix):i], []byte(":"))
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("malformed envelope header: %s", string(data[:i]))
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(string(fields[0]))
+	if err != nil {
+		return nil, fmt.Errorf("malformed data key in envelope header: %v", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(string(fields[1]))
+	if err != nil {
+		return nil, fmt.Errorf("malformed nonce in envelope header: %v", err)
+	}
+	return &envelope{wrappedKey: wrapped, nonce: nonce, sealed: data[i+1:]}, nil
+}
+
+// open decrypts the sealed data with the unwrapped data key, failing unless the authentication tag is valid
+func (e *envelope) open(key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d in envelope header", len(e.nonce))
+	}
+	plaintext, err := gcm.Open(nil, e.nonce, e.sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt envelope: %v", err)
+	}
+	return plaintext, nil
+}
+
+func decryptEnvelope(svc KMSDecryptionService, data []byte) ([]byte, error) {
+	e, err := parseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	out, err := svc.Decrypt(&kms.DecryptInput{CiphertextBlob: e.wrappedKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %v", err)
+	}
+	return e.open(out.Plaintext)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
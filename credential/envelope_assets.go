@@ -0,0 +1,115 @@
+package credential
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// EnvelopeAssetsDecryptor decrypts envelope-encrypted assets on nodes.
+// Each data key is unwrapped with KMS once and kept only in the memory of the process, never written to disk or passed as an argument
+type EnvelopeAssetsDecryptor struct {
+	KmsSvc KMSDecryptionService
+
+	dataKeys map[string][]byte
+}
+
+func NewEnvelopeAssetsDecryptor(svc KMSDecryptionService) *EnvelopeAssetsDecryptor {
+	return &EnvelopeAssetsDecryptor{KmsSvc: svc, dataKeys: map[string][]byte{}}
+}
+
+func (d *EnvelopeAssetsDecryptor) dataKey(wrapped []byte) ([]byte, error) {
+	if key, ok := d.dataKeys[string(wrapped)]; ok {
+		return key, nil
+	}
+	out, err := d.KmsSvc.Decrypt(&kms.DecryptInput{CiphertextBlob: wrapped})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %v", err)
+	}
+	d.dataKeys[string(wrapped)] = out.Plaintext
+	return out.Plaintext, nil
+}
+
+// DecryptFile decrypts the envelope-encrypted file into the file at the path `decrypted`.
+// Nothing is written unless the authentication tag is valid, so that tampered assets never reach the node
+func (d *EnvelopeAssetsDecryptor) DecryptFile(encrypted, decrypted string, perm os.FileMode) error {
+	data, err := ioutil.ReadFile(encrypted)
+	if err != nil {
+		return err
+	}
+	e, err := parseEnvelope(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", encrypted, err)
+	}
+	key, err := d.dataKey(e.wrappedKey)
+	if err != nil {
+		return fmt.Errorf("%s: %v", encrypted, err)
+	}
+	plaintext, err := e.open(key)
+	if err != nil {
+		return fmt.Errorf("%s: %v", encrypted, err)
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(decrypted), filepath.Base(encrypted)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(plaintext); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), decrypted)
+}
+
+// DecryptList decrypts the files listed in the file at listPath and then removes the list.
+// Each line of the list is "<encrypted file> <decrypted file> [<permissions in octal>]". Permissions default to 0600
+func (d *EnvelopeAssetsDecryptor) DecryptList(listPath string) error {
+	f, err := os.Open(listPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return fmt.Errorf("line %d of %s must be \"<encrypted file> <decrypted file> [<permissions>]\"", n, listPath)
+		}
+		perm := os.FileMode(0600)
+		if len(fields) == 3 {
+			p, err := strconv.ParseUint(fields[2], 8, 32)
+			if err != nil {
+				return fmt.Errorf("invalid permissions at line %d of %s: %v", n, listPath, err)
+			}
+			perm = os.FileMode(p)
+		}
+		if err := d.DecryptFile(fields[0], fields[1], perm); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return os.Remove(listPath)
+}
@@ -0,0 +1,179 @@
+package credential
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// countingKMSService counts data keys unwrapped by the decryptor
+type countingKMSService struct {
+	fakeDataKeyKMSService
+	numDecrypted int
+}
+
+func (s *countingKMSService) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	s.numDecrypted++
+	return s.fakeDataKeyKMSService.Decrypt(input)
+}
+
+func TestEnvelopeAssetsDecryptor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-aws-envelope-assets")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	svc := &countingKMSService{}
+	enc := &EnvelopeEncryptor{KmsKeyARN: "keyarn", KmsSvc: svc}
+	write := func(name string, plaintext []byte) string {
+		encrypted, err := enc.EncryptedBytes(plaintext)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		path := filepath.Join(dir, name+".enc")
+		if err := ioutil.WriteFile(path, encrypted, 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return path
+	}
+
+	large := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	caKey := write("ca-key.pem", large)
+	token := write("token", []byte("secret"))
+	list := filepath.Join(dir, ".envelope-assets")
+	content := fmt.Sprintf("%s %s\n%s %s 0644\n", caKey, strings.TrimSuffix(caKey, ".enc"), token, strings.TrimSuffix(token, ".enc"))
+	if err := ioutil.WriteFile(list, []byte(content), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := NewEnvelopeAssetsDecryptor(svc).DecryptList(list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for path, expected := range map[string]struct {
+		content []byte
+		perm    os.FileMode
+	}{
+		filepath.Join(dir, "ca-key.pem"): {large, 0600},
+		filepath.Join(dir, "token"):      {[]byte("secret"), 0644},
+	} {
+		actual, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(actual, expected.content) {
+			t.Errorf("unexpected content of %s", path)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != expected.perm {
+			t.Errorf("expected %s to have permissions %o but was %o", path, expected.perm, info.Mode().Perm())
+		}
+	}
+	if svc.numDecrypted != 1 {
+		t.Errorf("expected the shared data key to be unwrapped once but was %d times", svc.numDecrypted)
+	}
+	if _, err := os.Stat(list); !os.IsNotExist(err) {
+		t.Errorf("expected the list to be removed: %v", err)
+	}
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := write("tampered", []byte("secret"))
+		data, err := ioutil.ReadFile(tampered)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Flipping a bit of the ciphertext rather than the tag would go unnoticed without verifying the tag
+		data[bytes.IndexByte(data, '\n')+1] ^= 1
+		if err := ioutil.WriteFile(tampered, data, 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		decrypted := strings.TrimSuffix(tampered, ".enc")
+		err = NewEnvelopeAssetsDecryptor(svc).DecryptFile(tampered, decrypted, 0600)
+		if err == nil || !strings.Contains(err.Error(), "failed to decrypt envelope") {
+			t.Errorf("expected tampering to be detected but was: %v", err)
+		}
+		if _, err := os.Stat(decrypted); !os.IsNotExist(err) {
+			t.Errorf("expected nothing to be written for the tampered asset: %v", err)
+		}
+	})
+}
+
+// Nodes decrypt envelope-encrypted assets with the IAM credentials of the instance, which can only be obtained with
+// session tokens when the node enforces IMDSv2 with `metadataOptions.httpTokens: required`
+func TestEnvelopeAssetsDecryptorWithIMDSv2Enforced(t *testing.T) {
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			w.Header().Set("X-aws-ec2-metadata-token-ttl-seconds", r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			w.Write([]byte("token"))
+		case r.Header.Get("X-aws-ec2-metadata-token") != "token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("node-role"))
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/node-role":
+			w.Write([]byte(`{"Code":"Success","AccessKeyId":"NODEKEY","SecretAccessKey":"secret","Token":"session","Expiration":"2100-01-01T00:00:00Z"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer imds.Close()
+
+	kmsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=NODEKEY/") {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"__type":"AccessDeniedException","message":"not signed with the instance credentials"}`))
+			return
+		}
+		input := kms.DecryptInput{}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		out, err := fakeKMSService{}.Decrypt(&input)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"InvalidCiphertextException"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer kmsServer.Close()
+
+	dir, err := ioutil.TempDir("", "kube-aws-envelope-assets")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	encrypted, err := (&EnvelopeEncryptor{KmsKeyARN: "keyarn", KmsSvc: &fakeDataKeyKMSService{}}).EncryptedBytes([]byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(dir, "token.enc")
+	if err := ioutil.WriteFile(path, encrypted, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sess := session.Must(session.NewSession(aws.NewConfig().WithRegion("us-west-1")))
+	creds := ec2rolecreds.NewCredentialsWithClient(ec2metadata.New(sess, aws.NewConfig().WithEndpoint(imds.URL+"/latest")))
+	svc := kms.New(sess, aws.NewConfig().WithCredentials(creds).WithEndpoint(kmsServer.URL))
+
+	if err := NewEnvelopeAssetsDecryptor(svc).DecryptFile(path, filepath.Join(dir, "token"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual, _ := ioutil.ReadFile(filepath.Join(dir, "token")); string(actual) != "secret" {
+		t.Errorf("unexpected content of the decrypted asset: %s", actual)
+	}
+}
@@ -0,0 +1,94 @@
+package credential
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// fakeDataKeyKMSService additionally generates data keys wrapped in the way fakeKMSService encrypts
+type fakeDataKeyKMSService struct {
+	fakeKMSService
+	numGenerated int
+}
+
+func (s *fakeDataKeyKMSService) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	s.numGenerated++
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	wrapped, err := s.Encrypt(&kms.EncryptInput{KeyId: input.KeyId, Plaintext: key})
+	if err != nil {
+		return nil, err
+	}
+	return &kms.GenerateDataKeyOutput{KeyId: input.KeyId, Plaintext: key, CiphertextBlob: wrapped.CiphertextBlob}, nil
+}
+
+func TestEnvelopeEncryption(t *testing.T) {
+	svc := &fakeDataKeyKMSService{}
+	kmsConfig := NewKMSConfig("keyarn", svc, nil)
+	enc := kmsConfig.Encryptor()
+	if _, ok := enc.(*EnvelopeEncryptor); !ok {
+		t.Fatalf("expected an envelope encryptor but got %T", enc)
+	}
+	dec := kmsConfig.Decryptor()
+
+	large := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	for _, plaintext := range [][]byte{[]byte("small secret"), large} {
+		encrypted, err := enc.EncryptedBytes(plaintext)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !IsEnvelopeEncrypted(encrypted) {
+			t.Errorf("expected an envelope header: %s", string(encrypted[:40]))
+		}
+		if bytes.Contains(encrypted, plaintext) {
+			t.Errorf("plaintext is included in the encrypted asset")
+		}
+		decrypted, err := dec.DecryptedBytes(encrypted)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("unexpected decrypted bytes of size %d", len(decrypted))
+		}
+	}
+	if svc.numGenerated != 1 {
+		t.Errorf("expected a single data key to be generated per encryptor but was %d", svc.numGenerated)
+	}
+
+	t.Run("Tampered", func(t *testing.T) {
+		encrypted, err := enc.EncryptedBytes([]byte("secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		encrypted[len(encrypted)-1] ^= 1
+		if _, err := dec.DecryptedBytes(encrypted); err == nil || !strings.Contains(err.Error(), "failed to decrypt envelope") {
+			t.Errorf("expected tampering to be detected but was: %v", err)
+		}
+	})
+
+	t.Run("Legacy", func(t *testing.T) {
+		legacy, err := KMSEncryptor{KmsKeyARN: "keyarn", KmsSvc: fakeKMSService{}}.EncryptedBytes([]byte("legacy secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		decrypted, err := dec.DecryptedBytes(legacy)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(decrypted) != "legacy secret" {
+			t.Errorf("unexpected decrypted bytes: %s", string(decrypted))
+		}
+	})
+
+	t.Run("NoDataKeySupport", func(t *testing.T) {
+		if _, ok := NewKMSConfig("keyarn", fakeKMSService{}, nil).Encryptor().(KMSEncryptor); !ok {
+			t.Errorf("expected the direct kms encryptor for a service not generating data keys")
+		}
+	})
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/kubernetes-incubator/kube-aws/logger"
@@ -24,6 +26,8 @@ type Generator struct {
 	APIServerAdditionalIPAddressSans []string
 	EtcdNodeDNSNames                 []string
 	ServiceCIDR                      string
+	// ServiceIPv6CIDR is the IPv6 service CIDR of a dual-stack cluster. Left empty when IPv6 is disabled
+	ServiceIPv6CIDR string
 }
 
 type GeneratorOptions struct {
@@ -46,6 +50,57 @@ type GeneratorOptions struct {
 
 func (c Generator) GenerateAssetsOnDisk(dir string, o GeneratorOptions) (*RawAssetsOnDisk, error) {
 	logger.Info("Generating credentials...")
+	assets, err := c.generateAssets(o, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("--> Writing to the storage")
+	alsoWriteCAKey := o.GenerateCA || c.ManageCertificates
+	if err := assets.WriteToDir(dir, alsoWriteCAKey); err != nil {
+		return nil, fmt.Errorf("Error creating assets: %v", err)
+	}
+
+	{
+		logger.Info("--> Verifying the result")
+		verified, err := ReadRawAssets(dir, c.ManageCertificates, c.ManageCertificates)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed verifying the result: %v", err)
+		}
+
+		return verified, nil
+	}
+}
+
+// GenerateAssetsToBackend generates credentials like GenerateAssetsOnDisk but writes them to the secret backend instead of dir.
+// The existing CA is read from the backend unless its files exist
+func (c Generator) GenerateAssetsToBackend(dir string, o GeneratorOptions, backend SecretBackend) (*RawAssetsOnDisk, error) {
+	logger.Infof("Generating credentials into %s...", backend)
+	assets, err := c.generateAssets(o, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("--> Writing to the secret backend")
+	alsoWriteCAKey := o.GenerateCA || c.ManageCertificates
+	if err := assets.WriteToBackend(backend, alsoWriteCAKey); err != nil {
+		return nil, fmt.Errorf("Error creating assets: %v", err)
+	}
+
+	{
+		logger.Info("--> Verifying the result")
+		verified, err := ReadRawAssetsFromBackend(dir, c.ManageCertificates, c.ManageCertificates, backend)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed verifying the result: %v", err)
+		}
+
+		return verified, nil
+	}
+}
+
+func (c Generator) generateAssets(o GeneratorOptions, backend SecretBackend) (*RawAssetsOnMemory, error) {
 	var caKey *rsa.PrivateKey
 	var caCert *x509.Certificate
 	if o.GenerateCA {
@@ -57,14 +112,14 @@ func (c Generator) GenerateAssetsOnDisk(dir string, o GeneratorOptions) (*RawAss
 		logger.Info("-> Generating new TLS CA\n")
 	} else {
 		logger.Info("-> Parsing existing TLS CA\n")
-		if caKeyBytes, err := ioutil.ReadFile(o.CaKeyPath); err != nil {
+		if caKeyBytes, err := readCAFile(o.CaKeyPath, backend); err != nil {
 			return nil, fmt.Errorf("failed reading ca key file %s : %v", o.CaKeyPath, err)
 		} else {
 			if caKey, err = pki.DecodePrivateKeyPEM(caKeyBytes); err != nil {
 				return nil, fmt.Errorf("failed parsing ca key: %v", err)
 			}
 		}
-		if caCertBytes, err := ioutil.ReadFile(o.CaCertPath); err != nil {
+		if caCertBytes, err := readCAFile(o.CaCertPath, backend); err != nil {
 			return nil, fmt.Errorf("failed reading ca cert file %s : %v", o.CaCertPath, err)
 		} else {
 			if caCert, err = pki.DecodeCertificatePEM(caCertBytes); err != nil {
@@ -81,22 +136,22 @@ func (c Generator) GenerateAssetsOnDisk(dir string, o GeneratorOptions) (*RawAss
 
 	logger.Infof("--> Summarizing the configuration\n    TLS certificates managed by kube-aws=%v, CA key required on controller nodes=%v\n", c.ManageCertificates, true)
 
-	logger.Info("--> Writing to the storage")
-	alsoWriteCAKey := o.GenerateCA || c.ManageCertificates
-	if err := assets.WriteToDir(dir, alsoWriteCAKey); err != nil {
-		return nil, fmt.Errorf("Error creating assets: %v", err)
-	}
-
-	{
-		logger.Info("--> Verifying the result")
-		verified, err := ReadRawAssets(dir, c.ManageCertificates, c.ManageCertificates)
-
-		if err != nil {
-			return nil, fmt.Errorf("failed verifying the result: %v", err)
-		}
+	return assets, nil
+}
 
-		return verified, nil
+// readCAFile reads the file at the path, falling back to the secret of the same name in the backend if any
+func readCAFile(path string, backend SecretBackend) ([]byte, error) {
+	if _, err := os.Stat(path); backend == nil || err == nil {
+		return ioutil.ReadFile(path)
 	}
+	content, found, err := backend.Get(filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("neither %s nor %s in %s exists", path, filepath.Base(path), backend)
+	}
+	return content, nil
 }
 
 func getOrCreatePrivateKey(keyPath string) (*rsa.PrivateKey, error) {
@@ -157,6 +212,15 @@ func (c Generator) GenerateAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Ce
 	// 127.0.0.1 also allows control plane components to reach the apiserver via HTTPS at localhost
 	ipAddresses := []string{kubernetesServiceIPAddr.String(), "127.0.0.1"}
 
+	// Pods reach the apiserver via the IPv6 address of the kubernetes service in a dual-stack cluster
+	if c.ServiceIPv6CIDR != "" {
+		_, serviceIPv6Net, err := net.ParseCIDR(c.ServiceIPv6CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ipv6 serviceCIDR: %v", err)
+		}
+		ipAddresses = append(ipAddresses, netutil.IncrementIP(serviceIPv6Net.IP).String(), "::1")
+	}
+
 	apiServerConfig := pki.ServerCertConfig{
 		CommonName:  "kube-apiserver",
 		DNSNames:    append(dnsNames, c.APIServerExternalDNSNames...),
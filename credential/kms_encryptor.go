@@ -1,6 +1,8 @@
 package credential
 
 import (
+	"errors"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/kms"
 )
@@ -20,3 +22,21 @@ func (s KMSEncryptor) EncryptedBytes(data []byte) ([]byte, error) {
 	}
 	return encryptOutput.CiphertextBlob, nil
 }
+
+func (s KMSDecryptor) DecryptedBytes(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return []byte{}, nil
+	}
+	if s.KmsSvc == nil {
+		return nil, errors.New("the kms service doesn't support decryption")
+	}
+	if IsEnvelopeEncrypted(data) {
+		return decryptEnvelope(s.KmsSvc, data)
+	}
+
+	decryptOutput, err := s.KmsSvc.Decrypt(&kms.DecryptInput{CiphertextBlob: data})
+	if err != nil {
+		return []byte{}, err
+	}
+	return decryptOutput.Plaintext, nil
+}
@@ -0,0 +1,224 @@
+package credential
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/gzipcompressor"
+	"github.com/kubernetes-incubator/kube-aws/pki"
+)
+
+const (
+	// LedgerFile is the file under the credentials directory recording every certificate signed by kube-aws, encrypted with the KMS key
+	LedgerFile = "issuance-ledger.json.enc"
+	// CRLFile is the file under the credentials directory containing the certificate revocation list signed by the cluster CA
+	CRLFile = "crl.pem"
+
+	crlType = "X509 CRL"
+
+	LedgerSourceGenerator = "render credentials"
+	LedgerSourceIssueUser = "credentials issue-user"
+)
+
+// Ledger records the certificates signed by kube-aws so that they can be revoked by serial
+type Ledger struct {
+	Certificates []LedgerEntry `json:"certificates"`
+}
+
+// LedgerEntry is a certificate recorded in the ledger
+type LedgerEntry struct {
+	// Serial is the serial number of the certificate in hex, as shown by `openssl x509 -serial`
+	Serial        string    `json:"serial"`
+	CommonName    string    `json:"commonName"`
+	Organizations []string  `json:"organizations,omitempty"`
+	NotBefore     time.Time `json:"notBefore"`
+	NotAfter      time.Time `json:"notAfter"`
+	IssuedAt      time.Time `json:"issuedAt"`
+	IssuedBy      string    `json:"issuedBy,omitempty"`
+	// Source is the kube-aws command the certificate is signed by
+	Source           string     `json:"source"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	RevocationReason string     `json:"revocationReason,omitempty"`
+}
+
+// Revoked returns true if the certificate is revoked
+func (e LedgerEntry) Revoked() bool {
+	return e.RevokedAt != nil
+}
+
+// FormatSerial formats the serial number of a certificate in the way it is recorded in the ledger
+func FormatSerial(serial *big.Int) string {
+	return fmt.Sprintf("%X", serial)
+}
+
+// NormalizeSerial accepts a serial number in hex, optionally separated by colons as printed by openssl, and returns it in the ledger format
+func NormalizeSerial(serial string) (string, error) {
+	s := strings.Replace(strings.TrimSpace(serial), ":", "", -1)
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok || s == "" {
+		return "", fmt.Errorf("invalid serial \"%s\": it must be a hexadecimal number", serial)
+	}
+	return FormatSerial(n), nil
+}
+
+// LoadLedger decrypts and reads the ledger in dir. An empty ledger is returned when there is no ledger yet
+func LoadLedger(dir string, dec Decryptor) (*Ledger, error) {
+	path := filepath.Join(dir, LedgerFile)
+	encrypted, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Ledger{Certificates: []LedgerEntry{}}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	compressed, err := dec.DecryptedBytes(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %v", path, err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %v", path, err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %v", path, err)
+	}
+
+	l := &Ledger{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return l, nil
+}
+
+// Save encrypts and writes the ledger to dir.
+// The ledger grows with every certificate signed by kube-aws, so that it is envelope-encrypted rather than encrypted directly with KMS,
+// which accepts up to 4KB of plaintext
+func (l *Ledger) Save(dir string, enc *EnvelopeEncryptor) error {
+	path := filepath.Join(dir, LedgerFile)
+	data, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to serialize ledger: %v", err)
+	}
+	compressed, err := gzipcompressor.BytesToGzippedBytes(data)
+	if err != nil {
+		return fmt.Errorf("failed to compress ledger: %v", err)
+	}
+	encrypted, err := enc.EncryptedBytes(compressed)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt ledger: %v", err)
+	}
+	if err := ioutil.WriteFile(path, encrypted, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// Record adds the certificate to the ledger unless it is already recorded
+func (l *Ledger) Record(cert *x509.Certificate, source, issuedBy string) LedgerEntry {
+	serial := FormatSerial(cert.SerialNumber)
+	if e := l.Find(serial); e != nil {
+		return *e
+	}
+	e := LedgerEntry{
+		Serial:        serial,
+		CommonName:    cert.Subject.CommonName,
+		Organizations: cert.Subject.Organization,
+		NotBefore:     cert.NotBefore,
+		NotAfter:      cert.NotAfter,
+		IssuedAt:      time.Now().UTC(),
+		IssuedBy:      issuedBy,
+		Source:        source,
+	}
+	l.Certificates = append(l.Certificates, e)
+	return e
+}
+
+// Find returns the entry for the serial, or nil if the certificate is not recorded
+func (l *Ledger) Find(serial string) *LedgerEntry {
+	for i := range l.Certificates {
+		if l.Certificates[i].Serial == serial {
+			return &l.Certificates[i]
+		}
+	}
+	return nil
+}
+
+// Revoke marks the certificate with the serial as revoked
+func (l *Ledger) Revoke(serial, reason string, at time.Time) (*LedgerEntry, error) {
+	e := l.Find(serial)
+	if e == nil {
+		return nil, fmt.Errorf("no certificate with serial %s found in the ledger", serial)
+	}
+	if e.Revoked() {
+		return nil, fmt.Errorf("certificate with serial %s is already revoked at %s", serial, e.RevokedAt.Format(time.RFC3339))
+	}
+	revokedAt := at.UTC()
+	e.RevokedAt = &revokedAt
+	e.RevocationReason = reason
+	return e, nil
+}
+
+// CRL returns the PEM-encoded certificate revocation list signed by the CA, listing the revoked certificates which are not expired yet
+func (l *Ledger) CRL(caCert *x509.Certificate, caKey *rsa.PrivateKey, now time.Time, validity time.Duration) ([]byte, error) {
+	revoked := []pkix.RevokedCertificate{}
+	for _, e := range l.Certificates {
+		if !e.Revoked() || e.NotAfter.Before(now) {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(e.Serial, 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid serial \"%s\" in the ledger", e.Serial)
+		}
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: *e.RevokedAt})
+	}
+
+	der, err := caCert.CreateCRL(rand.Reader, caKey, revoked, now.UTC(), now.Add(validity).UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create crl: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: crlType, Bytes: der}), nil
+}
+
+// SignedCertificates returns the certificates signed by the cluster CA contained in the assets, excluding the CAs themselves
+func (r *RawAssetsOnDisk) SignedCertificates() ([]*x509.Certificate, error) {
+	files := []PlaintextFile{
+		r.APIServerCert,
+		r.APIServerAggregatorCert,
+		r.KubeControllerManagerCert,
+		r.KubeSchedulerCert,
+		r.WorkerCert,
+		r.AdminCert,
+		r.EtcdCert,
+		r.EtcdClientCert,
+	}
+	certs := []*x509.Certificate{}
+	for _, f := range files {
+		if len(f.Bytes()) == 0 {
+			continue
+		}
+		cs, err := pki.DecodeCertificatesPEM(f.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", f.filePath, err)
+		}
+		for _, c := range cs {
+			if !c.IsCA {
+				certs = append(certs, c)
+			}
+		}
+	}
+	return certs, nil
+}
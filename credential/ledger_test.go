@@ -0,0 +1,189 @@
+package credential
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+)
+
+var fakeCiphertextPrefix = []byte("encrypted:")
+
+// fakeKMSService encrypts by prefixing the plaintext so that the ciphertext can be decrypted back.
+// Like KMS, it rejects plaintexts larger than 4KB
+type fakeKMSService struct{}
+
+func (s fakeKMSService) Encrypt(input *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	if len(input.Plaintext) > 4096 {
+		return nil, fmt.Errorf("plaintext of %d bytes exceeds the 4096 bytes KMS accepts", len(input.Plaintext))
+	}
+	return &kms.EncryptOutput{CiphertextBlob: append(append([]byte{}, fakeCiphertextPrefix...), input.Plaintext...)}, nil
+}
+
+func (s fakeKMSService) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	if !bytes.HasPrefix(input.CiphertextBlob, fakeCiphertextPrefix) {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return &kms.DecryptOutput{Plaintext: bytes.TrimPrefix(input.CiphertextBlob, fakeCiphertextPrefix)}, nil
+}
+
+func TestNormalizeSerial(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+		err      bool
+	}{
+		{"1A2B", "1A2B", false},
+		{"1a:2b", "1A2B", false},
+		{" 001a2b ", "1A2B", false},
+		{"", "", true},
+		{"xyz", "", true},
+	}
+
+	for _, tc := range testCases {
+		actual, err := NormalizeSerial(tc.input)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error for \"%s\" but got %s", tc.input, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for \"%s\": %v", tc.input, err)
+		}
+		if actual != tc.expected {
+			t.Errorf("unexpected serial for \"%s\": expected=%s, actual=%s", tc.input, tc.expected, actual)
+		}
+	}
+}
+
+func TestLedger(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		svc := &fakeDataKeyKMSService{}
+		kmsConfig := NewKMSConfig("keyarn", svc, nil)
+		enc := &EnvelopeEncryptor{KmsKeyARN: "keyarn", KmsSvc: svc}
+		issuer := newTestUserCertificateIssuer(t)
+
+		ledger, err := LoadLedger(dir, kmsConfig.Decryptor())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(ledger.Certificates) != 0 {
+			t.Errorf("expected an empty ledger but got %v", ledger.Certificates)
+		}
+
+		issued := []*IssuedUserCertificate{}
+		for _, name := range []string{"alice", "bob"} {
+			c, err := issuer.Issue(UserCertificateRequest{Name: name, Groups: []string{"devs"}, TTL: time.Hour})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ledger.Record(c.Cert, LedgerSourceIssueUser, "admin")
+			issued = append(issued, c)
+		}
+		// Recording the same certificate twice doesn't duplicate the entry
+		ledger.Record(issued[0].Cert, LedgerSourceIssueUser, "admin")
+
+		if err := ledger.Save(dir, enc); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(dir, LedgerFile))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !IsEnvelopeEncrypted(raw) {
+			t.Errorf("expected the ledger to be envelope-encrypted")
+		}
+
+		loaded, err := LoadLedger(dir, kmsConfig.Decryptor())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(loaded.Certificates) != 2 {
+			t.Fatalf("expected 2 certificates but got %d", len(loaded.Certificates))
+		}
+		bob := loaded.Certificates[1]
+		if bob.Serial != issued[1].Serial() || bob.CommonName != "bob" || bob.IssuedBy != "admin" || bob.Source != LedgerSourceIssueUser {
+			t.Errorf("unexpected entry: %+v", bob)
+		}
+
+		now := time.Now()
+		if _, err := loaded.Revoke(issued[1].Serial(), "leaked", now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := loaded.Revoke(issued[1].Serial(), "leaked", now); err == nil || !strings.Contains(err.Error(), "already revoked") {
+			t.Errorf("expected an error for revoking twice but was: %v", err)
+		}
+		if _, err := loaded.Revoke("ABCDEF", "", now); err == nil || !strings.Contains(err.Error(), "no certificate with serial") {
+			t.Errorf("expected an error for an unknown serial but was: %v", err)
+		}
+
+		crlPEM, err := loaded.CRL(issuer.CACert, issuer.CAKey, now, 24*time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		block, _ := pem.Decode(crlPEM)
+		if block == nil || block.Type != "X509 CRL" {
+			t.Fatalf("invalid crl: %s", string(crlPEM))
+		}
+		crl, err := x509.ParseCRL(block.Bytes)
+		if err != nil {
+			t.Fatalf("failed to parse crl: %v", err)
+		}
+		if err := issuer.CACert.CheckCRLSignature(crl); err != nil {
+			t.Errorf("crl is not signed by the ca: %v", err)
+		}
+		revoked := crl.TBSCertList.RevokedCertificates
+		if len(revoked) != 1 || revoked[0].SerialNumber.Cmp(issued[1].Cert.SerialNumber) != 0 {
+			t.Errorf("unexpected revoked certificates in crl: %+v", revoked)
+		}
+	})
+}
+
+func TestLedgerLargerThanKMSPlaintextLimit(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		svc := &fakeDataKeyKMSService{}
+		kmsConfig := NewKMSConfig("keyarn", svc, nil)
+
+		ledger := &Ledger{Certificates: []LedgerEntry{}}
+		now := time.Now().UTC()
+		for i := 0; i < 500; i++ {
+			ledger.Certificates = append(ledger.Certificates, LedgerEntry{
+				Serial:     fmt.Sprintf("%X", now.UnixNano()*int64(i+1)),
+				CommonName: fmt.Sprintf("user-%d", i),
+				NotBefore:  now,
+				NotAfter:   now.Add(time.Duration(i) * time.Minute),
+				IssuedAt:   now.Add(time.Duration(i) * time.Second),
+				Source:     LedgerSourceIssueUser,
+			})
+		}
+
+		if err := ledger.Save(dir, &EnvelopeEncryptor{KmsKeyARN: "keyarn", KmsSvc: svc}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(dir, LedgerFile))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(raw) <= 4096 {
+			t.Fatalf("expected the encrypted ledger to be larger than 4KB, but was %d bytes", len(raw))
+		}
+
+		loaded, err := LoadLedger(dir, kmsConfig.Decryptor())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(loaded.Certificates) != 500 || loaded.Certificates[499].CommonName != "user-499" {
+			t.Errorf("unexpected certificates loaded: %d", len(loaded.Certificates))
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package credential
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// ssmStandardParameterMaxBytes is the maximum size of a value of a standard SSM parameter. Larger values are stored as advanced parameters
+const ssmStandardParameterMaxBytes = 4096
+
+// SecretBackend stores plaintext credentials outside of the credentials directory, so that no private key needs to be written to the operator's disk
+type SecretBackend interface {
+	// Get returns the secret for the name and true, or false if there is no such secret
+	Get(name string) ([]byte, bool, error)
+	// Put creates or updates the secret for the name
+	Put(name string, value []byte) error
+	// String describes where secrets are stored, used in log messages
+	String() string
+}
+
+type SecretsManagerService interface {
+	GetSecretValue(*secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error)
+	CreateSecret(*secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error)
+	PutSecretValue(*secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error)
+}
+
+type SSMParameterService interface {
+	GetParameter(*ssm.GetParameterInput) (*ssm.GetParameterOutput, error)
+	PutParameter(*ssm.PutParameterInput) (*ssm.PutParameterOutput, error)
+}
+
+// SecretsManagerBackend stores each credential as a binary secret named <prefix><name> in AWS Secrets Manager
+type SecretsManagerBackend struct {
+	Svc    SecretsManagerService
+	Prefix string
+	// KMSKeyID is the KMS key secrets are encrypted with. The account's default key for Secrets Manager is used when empty
+	KMSKeyID string
+}
+
+func (b SecretsManagerBackend) Get(name string) ([]byte, bool, error) {
+	id := b.Prefix + name
+	out, err := b.Svc.GetSecretValue(&secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get secret %s: %v", id, err)
+	}
+	if out.SecretBinary != nil {
+		return out.SecretBinary, true, nil
+	}
+	return []byte(aws.StringValue(out.SecretString)), true, nil
+}
+
+func (b SecretsManagerBackend) Put(name string, value []byte) error {
+	id := b.Prefix + name
+	input := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(id),
+		Description:  aws.String("Managed by kube-aws"),
+		SecretBinary: value,
+	}
+	if b.KMSKeyID != "" {
+		input.KmsKeyId = aws.String(b.KMSKeyID)
+	}
+	_, err := b.Svc.CreateSecret(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceExistsException {
+		_, err = b.Svc.PutSecretValue(&secretsmanager.PutSecretValueInput{SecretId: aws.String(id), SecretBinary: value})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put secret %s: %v", id, err)
+	}
+	return nil
+}
+
+func (b SecretsManagerBackend) String() string {
+	return fmt.Sprintf("AWS Secrets Manager (%s*)", b.Prefix)
+}
+
+// SSMParameterBackend stores each credential as a SecureString parameter named <prefix><name> in SSM Parameter Store
+type SSMParameterBackend struct {
+	Svc    SSMParameterService
+	Prefix string
+	// KMSKeyID is the KMS key parameters are encrypted with. The account's default key for SSM is used when empty
+	KMSKeyID string
+}
+
+func (b SSMParameterBackend) Get(name string) ([]byte, bool, error) {
+	id := b.Prefix + name
+	out, err := b.Svc.GetParameter(&ssm.GetParameterInput{Name: aws.String(id), WithDecryption: aws.Bool(true)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get parameter %s: %v", id, err)
+	}
+	if out.Parameter == nil {
+		return nil, false, nil
+	}
+	return []byte(aws.StringValue(out.Parameter.Value)), true, nil
+}
+
+func (b SSMParameterBackend) Put(name string, value []byte) error {
+	id := b.Prefix + name
+	tier := ssm.ParameterTierStandard
+	if len(value) > ssmStandardParameterMaxBytes {
+		tier = ssm.ParameterTierAdvanced
+	}
+	input := &ssm.PutParameterInput{
+		Name:        aws.String(id),
+		Description: aws.String("Managed by kube-aws"),
+		Value:       aws.String(string(value)),
+		Type:        aws.String(ssm.ParameterTypeSecureString),
+		Tier:        aws.String(tier),
+		Overwrite:   aws.Bool(true),
+	}
+	if b.KMSKeyID != "" {
+		input.KeyId = aws.String(b.KMSKeyID)
+	}
+	if _, err := b.Svc.PutParameter(input); err != nil {
+		return fmt.Errorf("failed to put parameter %s: %v", id, err)
+	}
+	return nil
+}
+
+func (b SSMParameterBackend) String() string {
+	return fmt.Sprintf("SSM Parameter Store (%s*)", b.Prefix)
+}
+
+// VaultBackend stores each credential in the `value` field of the secret at <mountPath>/<prefix><name> in a HashiCorp Vault KV version 2 secrets engine
+type VaultBackend struct {
+	// Address is the URL of the Vault server e.g. https://vault.example.com:8200
+	Address   string
+	Token     string
+	MountPath string
+	Prefix    string
+	// Client defaults to a http client with a timeout
+	Client *http.Client
+}
+
+type vaultKVRequest struct {
+	Data map[string]string `json:"data"`
+}
+
+type vaultKVResponse struct {
+	Data struct {
+		Data map[string]string `json:"data"`
+	} `json:"data"`
+}
+
+type vaultErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+func (b VaultBackend) url(name string) string {
+	return fmt.Sprintf("%s/v1/%s/data/%s%s", strings.TrimSuffix(b.Address, "/"), strings.Trim(b.MountPath, "/"), b.Prefix, name)
+}
+
+func (b VaultBackend) do(method, name string, body []byte) ([]byte, int, error) {
+	req, err := http.NewRequest(method, b.url(name), bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("X-Vault-Token", b.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := b.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, res.StatusCode, err
+	}
+	return data, res.StatusCode, nil
+}
+
+func vaultError(status int, data []byte) error {
+	errRes := vaultErrorResponse{}
+	if json.Unmarshal(data, &errRes) == nil && len(errRes.Errors) > 0 {
+		return fmt.Errorf("vault responded with %d: %s", status, strings.Join(errRes.Errors, ", "))
+	}
+	return fmt.Errorf("vault responded with %d", status)
+}
+
+func (b VaultBackend) Get(name string) ([]byte, bool, error) {
+	data, status, err := b.do("GET", name, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s from vault: %v", b.Prefix+name, err)
+	}
+	if status == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if status >= 300 {
+		return nil, false, fmt.Errorf("failed to read %s from vault: %v", b.Prefix+name, vaultError(status, data))
+	}
+	res := vaultKVResponse{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, false, fmt.Errorf("failed to parse the response for %s from vault: %v", b.Prefix+name, err)
+	}
+	value, ok := res.Data.Data["value"]
+	if !ok {
+		return nil, false, fmt.Errorf("secret %s in vault has no `value` field", b.Prefix+name)
+	}
+	return []byte(value), true, nil
+}
+
+func (b VaultBackend) Put(name string, value []byte) error {
+	body, err := json.Marshal(vaultKVRequest{Data: map[string]string{"value": string(value)}})
+	if err != nil {
+		return err
+	}
+	data, status, err := b.do("POST", name, body)
+	if err == nil && status >= 300 {
+		err = vaultError(status, data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s to vault: %v", b.Prefix+name, err)
+	}
+	return nil
+}
+
+func (b VaultBackend) String() string {
+	return fmt.Sprintf("Vault at %s (%s/%s*)", b.Address, strings.Trim(b.MountPath, "/"), b.Prefix)
+}
@@ -0,0 +1,165 @@
+package credential
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+)
+
+func TestSecretBackends(t *testing.T) {
+	vault := helper.NewFakeVault("root-token")
+	defer vault.Close()
+
+	testCases := []struct {
+		name    string
+		backend SecretBackend
+	}{
+		{"secretsManager", SecretsManagerBackend{Svc: helper.NewFakeSecretsManager(), Prefix: "kube-aws/test/"}},
+		{"ssmParameterStore", SSMParameterBackend{Svc: helper.NewFakeSSMParameterStore(), Prefix: "/kube-aws/test/"}},
+		{"vault", VaultBackend{Address: vault.URL, Token: "root-token", MountPath: "secret", Prefix: "kube-aws/test/"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := tc.backend
+			if _, found, err := b.Get("apiserver-key.pem"); err != nil || found {
+				t.Fatalf("expected no secret but found=%v, err=%v", found, err)
+			}
+			for _, value := range []string{"key1", "key2"} {
+				if err := b.Put("apiserver-key.pem", []byte(value)); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				actual, found, err := b.Get("apiserver-key.pem")
+				if err != nil || !found {
+					t.Fatalf("expected the secret but found=%v, err=%v", found, err)
+				}
+				if string(actual) != value {
+					t.Errorf("unexpected secret: expected=%s, actual=%s", value, string(actual))
+				}
+			}
+		})
+	}
+
+	t.Run("ssmParameterStoreSecureString", func(t *testing.T) {
+		svc := helper.NewFakeSSMParameterStore()
+		b := SSMParameterBackend{Svc: svc, Prefix: "/kube-aws/test/"}
+		if err := b.Put("ca-key.pem", []byte("key")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if typ := svc.Types["/kube-aws/test/ca-key.pem"]; typ != ssm.ParameterTypeSecureString {
+			t.Errorf("expected a SecureString parameter but was %s", typ)
+		}
+	})
+
+	t.Run("vaultPermissionDenied", func(t *testing.T) {
+		b := VaultBackend{Address: vault.URL, Token: "wrong-token", MountPath: "secret", Prefix: "kube-aws/test/"}
+		if _, _, err := b.Get("ca-key.pem"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+			t.Errorf("expected a permission error but was: %v", err)
+		}
+		if err := b.Put("ca-key.pem", []byte("key")); err == nil || !strings.Contains(err.Error(), "permission denied") {
+			t.Errorf("expected a permission error but was: %v", err)
+		}
+	})
+}
+
+func assertNoPlaintextFiles(t *testing.T, dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if ext != "."+CacheFileExtension && ext != "."+FingerprintFileExtension {
+			t.Errorf("unexpected plaintext file in the credentials directory: %s", f.Name())
+		}
+	}
+}
+
+func TestReadOrCreateCompactAssetsWithSecretBackend(t *testing.T) {
+	generator := Generator{
+		TLSCADurationDays:         365,
+		TLSCertDurationDays:       365,
+		ManageCertificates:        true,
+		Region:                    "us-west-1",
+		APIServerExternalDNSNames: []string{"test.example.com"},
+		EtcdNodeDNSNames:          []string{"etcd0.example.com"},
+		ServiceCIDR:               "10.3.0.0/24",
+	}
+
+	t.Run("GeneratedIntoBackend", func(t *testing.T) {
+		helper.WithTempDir(func(dir string) {
+			sm := helper.NewFakeSecretsManager()
+			backend := SecretsManagerBackend{Svc: sm, Prefix: "kube-aws/test/"}
+
+			generated, err := generator.GenerateAssetsToBackend(dir, GeneratorOptions{GenerateCA: true, CommonName: "kube-ca"}, backend)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, found := sm.Secrets["kube-aws/test/ca-key.pem"]; !found {
+				t.Errorf("expected ca-key.pem to be stored in the backend")
+			}
+			if string(sm.Secrets["kube-aws/test/service-account-key.pem"]) != string(sm.Secrets["kube-aws/test/apiserver-key.pem"]) {
+				t.Errorf("expected service-account-key.pem to default to apiserver-key.pem")
+			}
+
+			kmsConfig := KMSConfig{KMSSvc: fakeKMSService{}, KMSKeyARN: "keyarn", SecretBackend: backend}
+			compact, err := ReadOrCreateCompactAssets(dir, true, true, kmsConfig)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if compact.APIServerKey == "" || compact.CACert == "" {
+				t.Errorf("expected compact assets to be read from the backend: %+v", compact)
+			}
+
+			// The encrypted caches are reused as long as the secrets are unchanged
+			before, err := ioutil.ReadFile(filepath.Join(dir, "apiserver-key.pem.enc"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := ReadOrCreateCompactAssets(dir, true, true, kmsConfig); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			after, err := ioutil.ReadFile(filepath.Join(dir, "apiserver-key.pem.enc"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(before) != string(after) {
+				t.Errorf("expected the encrypted cache to be reused")
+			}
+			if string(after) != string(fakeCiphertextPrefix)+generated.APIServerKey.String() {
+				t.Errorf("expected the cache to be encrypted from the secret in the backend")
+			}
+
+			assertNoPlaintextFiles(t, dir)
+		})
+	})
+
+	t.Run("MigratedFromDisk", func(t *testing.T) {
+		helper.WithDummyCredentials(func(dir string) {
+			vault := helper.NewFakeVault("root-token")
+			defer vault.Close()
+			backend := VaultBackend{Address: vault.URL, Token: "root-token", MountPath: "secret", Prefix: "kube-aws/test/"}
+
+			kmsConfig := KMSConfig{KMSSvc: fakeKMSService{}, KMSKeyARN: "keyarn", SecretBackend: backend}
+			if _, err := ReadOrCreateCompactAssets(dir, true, true, kmsConfig); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			local, err := ioutil.ReadFile(filepath.Join(dir, "worker-key.pem"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			migrated, found := vault.Secret("kube-aws/test/worker-key.pem")
+			if !found || migrated["value"] != string(local) {
+				t.Errorf("expected worker-key.pem to be uploaded to vault but was: %v", migrated)
+			}
+			if _, found := vault.Secret("kube-aws/test/kubelet-tls-bootstrap-token"); !found {
+				t.Errorf("expected the generated bootstrap token to be stored in vault")
+			}
+		})
+	})
+}
@@ -2,13 +2,16 @@ package credential
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
 
 	"github.com/kubernetes-incubator/kube-aws/logger"
 )
 
 func (e Store) EncryptedCredentialFromPath(filePath string, defaultValue *string) (*EncryptedFile, error) {
-	raw, errRaw := RawCredentialFileFromPath(filePath, defaultValue)
+	raw, errRaw := e.RawCredentialFromPath(filePath, defaultValue)
 	cache, err := EncryptedCredentialCacheFromPath(filePath, errRaw == nil)
 	if err != nil {
 		if errRaw != nil { // if neither .enc nor raw is there, it is an error
@@ -35,3 +38,59 @@ func (e Store) EncryptedCredentialFromPath(filePath string, defaultValue *string
 
 	return cache, nil
 }
+
+// RawCredentialFromPath reads the plaintext credential for the file path from the secret backend if any, or from the file otherwise.
+// The file name is used as the name of the secret
+func (e Store) RawCredentialFromPath(filePath string, defaultValue *string) (*PlaintextFile, error) {
+	if e.Backend == nil {
+		return RawCredentialFileFromPath(filePath, defaultValue)
+	}
+	return rawCredentialFromBackend(e.Backend, filePath, defaultValue)
+}
+
+func rawCredentialFromBackend(backend SecretBackend, filePath string, defaultValue *string) (*PlaintextFile, error) {
+	name := filepath.Base(filePath)
+	content, found, err := backend.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &PlaintextFile{filePath: filePath, content: content}, nil
+	}
+
+	// Migrates the credential from the local file so that the file can be removed afterwards
+	if _, err := os.Stat(filePath); err == nil {
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := backend.Put(name, content); err != nil {
+			return nil, err
+		}
+		logger.Warnf("uploaded \"%s\" to %s. Remove the local file once you have confirmed that the cluster works with it\n", filePath, backend)
+		return &PlaintextFile{filePath: filePath, content: content}, nil
+	}
+
+	if defaultValue == nil {
+		return nil, fmt.Errorf("%s is not found in %s nor at %s", name, backend, filePath)
+	}
+	// special default value that allows lookup from another secret
+	re := regexp.MustCompile("^<<<([a-z./-]+.pem)$")
+	if re.MatchString(*defaultValue) {
+		altPath := re.FindStringSubmatch(*defaultValue)[1]
+		alt, err := rawCredentialFromBackend(backend, altPath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s and alternate secret %s do not exist: %v", name, filepath.Base(altPath), err)
+		}
+		logger.Infof("creating \"%s\" in %s with contents of \"%s\"\n", name, backend, filepath.Base(altPath))
+		newDefault := alt.String()
+		return rawCredentialFromBackend(backend, filePath, &newDefault)
+	}
+	// Empty secrets aren't stored as some backends reject them
+	if *defaultValue != "" {
+		if err := backend.Put(name, []byte(*defaultValue)); err != nil {
+			return nil, err
+		}
+	}
+	return &PlaintextFile{filePath: filePath, content: []byte(*defaultValue)}, nil
+}
@@ -19,17 +19,44 @@ type EncryptedFile struct {
 
 type Store struct {
 	Encryptor Encryptor
+	// Backend is where plaintext credentials are read from and written to instead of the credentials directory. Local files are used when nil
+	Backend SecretBackend
 }
 
 type KMSEncryptionService interface {
 	Encrypt(*kms.EncryptInput) (*kms.EncryptOutput, error)
 }
 
+type KMSDataKeyService interface {
+	GenerateDataKey(*kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error)
+}
+
+type KMSDecryptionService interface {
+	Decrypt(*kms.DecryptInput) (*kms.DecryptOutput, error)
+}
+
 type Encryptor interface {
 	EncryptedBytes(raw []byte) ([]byte, error)
 }
 
+type Decryptor interface {
+	DecryptedBytes(encrypted []byte) ([]byte, error)
+}
+
 type KMSEncryptor struct {
 	KmsKeyARN string
 	KmsSvc    KMSEncryptionService
 }
+
+// EnvelopeEncryptor encrypts each asset locally with AES-256-GCM under a data key generated by KMS once per encryptor.
+// The data key wrapped by KMS is stored in the header of each encrypted asset
+type EnvelopeEncryptor struct {
+	KmsKeyARN string
+	KmsSvc    KMSDataKeyService
+
+	dataKey *dataKey
+}
+
+type KMSDecryptor struct {
+	KmsSvc KMSDecryptionService
+}
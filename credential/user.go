@@ -0,0 +1,282 @@
+package credential
+
+import (
+	"bufio"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/pki"
+)
+
+const (
+	// UsersDir is the directory under the credentials directory where client certificates issued for users are written
+	UsersDir = "users"
+	// IssuanceLogFile is the file under the credentials directory recording every client certificate issued for users and its revocation.
+	// It contains no secrets, so that it is kept in plaintext for auditing while the issuance ledger is the source of the CRL
+	IssuanceLogFile = "issued-users.log"
+
+	IssuanceEventIssued  = "issued"
+	IssuanceEventRevoked = "revoked"
+)
+
+var userNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._@-]*$`)
+
+// UserCertificateIssuer issues short-lived client certificates for individual users signed by the cluster CA,
+// so that the admin key doesn't need to be shared
+type UserCertificateIssuer struct {
+	CACert *x509.Certificate
+	CAKey  *rsa.PrivateKey
+	// Organizations maps the groups of users to the organizations of their certificates
+	Organizations OrganizationMapping
+}
+
+// OrganizationMapping maps each group a user can be issued a certificate in to the organizations of the certificate,
+// which the Kubernetes API server takes as the groups of the user
+type OrganizationMapping map[string][]string
+
+// UserCertificateRequest describes the user a client certificate is issued for
+type UserCertificateRequest struct {
+	// Name is the username seen by the Kubernetes API server, set to the common name of the certificate
+	Name string
+	// Groups are the groups of the user, whose organizations in the mapping of the issuer are set to the certificate
+	Groups []string
+	// TTL is how long the certificate is valid for
+	TTL time.Duration
+}
+
+// IssuedUserCertificate is a client certificate issued for a user and its private key
+type IssuedUserCertificate struct {
+	Cert    *x509.Certificate
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// IssuanceRecord is an entry in the issuance log
+type IssuanceRecord struct {
+	// Event is either IssuanceEventIssued or IssuanceEventRevoked. Records without it are issuances
+	Event     string    `json:"event,omitempty"`
+	Serial    string    `json:"serial"`
+	Name      string    `json:"name"`
+	Groups    []string  `json:"groups"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	IssuedBy  string    `json:"issuedBy,omitempty"`
+	// RevokedAt, RevokedBy and RevocationReason are set only in revocation records
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	RevokedBy        string     `json:"revokedBy,omitempty"`
+	RevocationReason string     `json:"revocationReason,omitempty"`
+}
+
+// Revoked returns true if the record is a revocation
+func (r IssuanceRecord) Revoked() bool {
+	return r.Event == IssuanceEventRevoked
+}
+
+// NewUserCertificateIssuer reads the cluster CA from the PEM files at the paths
+func NewUserCertificateIssuer(caCertPath, caKeyPath string) (*UserCertificateIssuer, error) {
+	return NewUserCertificateIssuerWithBackend(caCertPath, caKeyPath, nil)
+}
+
+// NewUserCertificateIssuerWithBackend reads the cluster CA from the PEM files at the paths, or from the secrets of the same names in the backend if the files don't exist
+func NewUserCertificateIssuerWithBackend(caCertPath, caKeyPath string, backend SecretBackend) (*UserCertificateIssuer, error) {
+	caCertBytes, err := readCAFile(caCertPath, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading ca cert file %s : %v", caCertPath, err)
+	}
+	caCert, err := pki.DecodeCertificatePEM(caCertBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing ca cert: %v", err)
+	}
+	caKeyBytes, err := readCAFile(caKeyPath, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading ca key file %s : %v", caKeyPath, err)
+	}
+	caKey, err := pki.DecodePrivateKeyPEM(caKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing ca key: %v", err)
+	}
+	return &UserCertificateIssuer{CACert: caCert, CAKey: caKey}, nil
+}
+
+// Resolve returns the organizations the groups are mapped to, without duplicates and in the order of the groups.
+// It returns an error when any of the groups isn't mapped, so that users can't be issued certificates in arbitrary Kubernetes groups
+func (m OrganizationMapping) Resolve(groups []string) ([]string, error) {
+	orgs := []string{}
+	seen := map[string]bool{}
+	for _, g := range groups {
+		mapped, ok := m[g]
+		if !ok {
+			return nil, fmt.Errorf("group \"%s\" isn't mapped to organizations. Add it to `kubernetes.authentication.userCertificates.organizations` in cluster.yaml", g)
+		}
+		for _, o := range mapped {
+			if !seen[o] {
+				seen[o] = true
+				orgs = append(orgs, o)
+			}
+		}
+	}
+	return orgs, nil
+}
+
+func (r UserCertificateRequest) Validate() error {
+	if !userNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("invalid user name \"%s\": it must match %s", r.Name, userNamePattern.String())
+	}
+	if r.TTL <= 0 {
+		return fmt.Errorf("ttl must be positive but was %v", r.TTL)
+	}
+	for _, g := range r.Groups {
+		if g == "" {
+			return fmt.Errorf("group names must not be empty")
+		}
+	}
+	return nil
+}
+
+// Issue generates a private key and a client certificate for the user signed by the cluster CA
+func (i UserCertificateIssuer) Issue(r UserCertificateRequest) (*IssuedUserCertificate, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	if notAfter := time.Now().Add(r.TTL); notAfter.After(i.CACert.NotAfter) {
+		return nil, fmt.Errorf("ttl %v exceeds the expiration of the ca cert at %s", r.TTL, i.CACert.NotAfter.Format(time.RFC3339))
+	}
+
+	orgs, err := i.Organizations.Resolve(r.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := pki.NewPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed generating private key: %v", err)
+	}
+	cfg := pki.ClientCertConfig{
+		CommonName:   r.Name,
+		Organization: orgs,
+		Duration:     r.TTL,
+	}
+	cert, err := pki.NewSignedUserCertificate(cfg, key, i.CACert, i.CAKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed signing client certificate for %s: %v", r.Name, err)
+	}
+
+	return &IssuedUserCertificate{
+		Cert:    cert,
+		CertPEM: pki.EncodeCertificatePEM(cert),
+		KeyPEM:  pki.EncodePrivateKeyPEM(key),
+	}, nil
+}
+
+// Serial returns the serial number of the certificate in hex, as shown by `openssl x509 -serial`
+func (c IssuedUserCertificate) Serial() string {
+	return FormatSerial(c.Cert.SerialNumber)
+}
+
+// WriteToDir writes the certificate and the key to <dir>/users/<name>.pem and <dir>/users/<name>-key.pem
+// and returns their paths
+func (c IssuedUserCertificate) WriteToDir(dir string) (string, string, error) {
+	usersDir := filepath.Join(dir, UsersDir)
+	if err := os.MkdirAll(usersDir, 0700); err != nil {
+		return "", "", fmt.Errorf("failed to create %s: %v", usersDir, err)
+	}
+	name := c.Cert.Subject.CommonName
+	certPath := filepath.Join(usersDir, name+".pem")
+	keyPath := filepath.Join(usersDir, name+"-key.pem")
+	if err := ioutil.WriteFile(certPath, c.CertPEM, 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %v", certPath, err)
+	}
+	if err := ioutil.WriteFile(keyPath, c.KeyPEM, 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %v", keyPath, err)
+	}
+	return certPath, keyPath, nil
+}
+
+// Record returns the entry recorded in the issuance log for the certificate
+func (c IssuedUserCertificate) Record(issuedBy string) IssuanceRecord {
+	return IssuanceRecord{
+		Event:     IssuanceEventIssued,
+		Serial:    c.Serial(),
+		Name:      c.Cert.Subject.CommonName,
+		Groups:    c.Cert.Subject.Organization,
+		NotBefore: c.Cert.NotBefore,
+		NotAfter:  c.Cert.NotAfter,
+		IssuedAt:  time.Now().UTC(),
+		IssuedBy:  issuedBy,
+	}
+}
+
+// RevocationRecord returns the entry recorded in the issuance log for the revocation of the certificate
+func (e LedgerEntry) RevocationRecord(revokedBy string) IssuanceRecord {
+	return IssuanceRecord{
+		Event:            IssuanceEventRevoked,
+		Serial:           e.Serial,
+		Name:             e.CommonName,
+		Groups:           e.Organizations,
+		NotBefore:        e.NotBefore,
+		NotAfter:         e.NotAfter,
+		IssuedAt:         e.IssuedAt,
+		IssuedBy:         e.IssuedBy,
+		RevokedAt:        e.RevokedAt,
+		RevokedBy:        revokedBy,
+		RevocationReason: e.RevocationReason,
+	}
+}
+
+// AppendIssuanceRecord appends the record to the issuance log in dir as a line of JSON
+func AppendIssuanceRecord(dir string, r IssuanceRecord) error {
+	path := filepath.Join(dir, IssuanceLogFile)
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to serialize issuance record: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write to %s: %v", path, err)
+	}
+	return nil
+}
+
+// ReadIssuanceRecords returns all the records in the issuance log in dir, in the order they were recorded
+func ReadIssuanceRecords(dir string) ([]IssuanceRecord, error) {
+	path := filepath.Join(dir, IssuanceLogFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []IssuanceRecord{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	records := []IssuanceRecord{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		r := IssuanceRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of %s: %v", n, path, err)
+		}
+		if r.Event == "" {
+			r.Event = IssuanceEventIssued
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return records, nil
+}
@@ -0,0 +1,178 @@
+package credential
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/pki"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+)
+
+func newTestUserCertificateIssuer(t *testing.T) *UserCertificateIssuer {
+	caKey, caCert, err := pki.NewCA(365, "kube-ca")
+	if err != nil {
+		t.Fatalf("failed to generate ca: %v", err)
+	}
+	orgs := OrganizationMapping{
+		"devs": {"developers"},
+		"ops":  {"developers", "operators"},
+	}
+	return &UserCertificateIssuer{CACert: caCert, CAKey: caKey, Organizations: orgs}
+}
+
+func TestUserCertificateIssuerIssue(t *testing.T) {
+	issuer := newTestUserCertificateIssuer(t)
+
+	issued, err := issuer.Issue(UserCertificateRequest{Name: "alice", Groups: []string{"devs", "ops"}, TTL: 8 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cert := issued.Cert
+	if cert.Subject.CommonName != "alice" {
+		t.Errorf("unexpected common name: %s", cert.Subject.CommonName)
+	}
+	// Groups are mapped to organizations without duplicates, and the organization of the CA isn't added
+	orgs := append([]string{}, cert.Subject.Organization...)
+	sort.Strings(orgs)
+	if strings.Join(orgs, ",") != "developers,operators" {
+		t.Errorf("unexpected organizations: %v", cert.Subject.Organization)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+		t.Errorf("expected a client certificate but got ext key usages %v", cert.ExtKeyUsage)
+	}
+	if d := time.Until(cert.NotAfter); d > 8*time.Hour || d < 7*time.Hour {
+		t.Errorf("unexpected expiration: %v", cert.NotAfter)
+	}
+	if err := cert.CheckSignatureFrom(issuer.CACert); err != nil {
+		t.Errorf("certificate is not signed by the ca: %v", err)
+	}
+	if _, err := pki.DecodePrivateKeyPEM(issued.KeyPEM); err != nil {
+		t.Errorf("invalid key: %v", err)
+	}
+	if issued.Serial() != strings.ToUpper(cert.SerialNumber.Text(16)) {
+		t.Errorf("unexpected serial: %s", issued.Serial())
+	}
+}
+
+func TestUserCertificateIssuerIssueInvalid(t *testing.T) {
+	issuer := newTestUserCertificateIssuer(t)
+
+	testCases := []struct {
+		request UserCertificateRequest
+		err     string
+	}{
+		{UserCertificateRequest{Name: "", TTL: time.Hour}, "invalid user name"},
+		{UserCertificateRequest{Name: "../alice", TTL: time.Hour}, "invalid user name"},
+		{UserCertificateRequest{Name: "alice", TTL: 0}, "ttl must be positive"},
+		{UserCertificateRequest{Name: "alice", Groups: []string{""}, TTL: time.Hour}, "group names must not be empty"},
+		{UserCertificateRequest{Name: "alice", Groups: []string{"system:masters"}, TTL: time.Hour}, "group \"system:masters\" isn't mapped to organizations"},
+		{UserCertificateRequest{Name: "alice", TTL: 2 * 365 * 24 * time.Hour}, "exceeds the expiration of the ca cert"},
+	}
+
+	for _, tc := range testCases {
+		_, err := issuer.Issue(tc.request)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" for %+v but was: %v", tc.err, tc.request, err)
+		}
+	}
+}
+
+func TestIssuanceLog(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		issuer := newTestUserCertificateIssuer(t)
+
+		records, err := ReadIssuanceRecords(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(records) != 0 {
+			t.Errorf("expected no records but got %v", records)
+		}
+
+		ledger := &Ledger{Certificates: []LedgerEntry{}}
+		serials := []string{}
+		for _, name := range []string{"alice", "bob"} {
+			issued, err := issuer.Issue(UserCertificateRequest{Name: name, Groups: []string{"devs"}, TTL: time.Hour})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			certPath, _, err := issued.WriteToDir(dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if certPath != filepath.Join(dir, "users", name+".pem") {
+				t.Errorf("unexpected cert path: %s", certPath)
+			}
+			if err := AppendIssuanceRecord(dir, issued.Record("admin")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ledger.Record(issued.Cert, LedgerSourceIssueUser, "admin")
+			serials = append(serials, issued.Serial())
+		}
+
+		revoked, err := ledger.Revoke(serials[1], "left the team", time.Now())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := AppendIssuanceRecord(dir, revoked.RevocationRecord("security")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		records, err = ReadIssuanceRecords(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("expected 3 records but got %d", len(records))
+		}
+		for i, r := range records[:2] {
+			if r.Serial != serials[i] {
+				t.Errorf("unexpected serial in record %d: expected=%s, actual=%s", i, serials[i], r.Serial)
+			}
+			if r.IssuedBy != "admin" {
+				t.Errorf("unexpected issuer in record %d: %s", i, r.IssuedBy)
+			}
+			if r.Revoked() {
+				t.Errorf("expected record %d to be an issuance: %+v", i, r)
+			}
+		}
+		if records[1].Name != "bob" {
+			t.Errorf("unexpected name in the second record: %s", records[1].Name)
+		}
+		r := records[2]
+		if !r.Revoked() || r.Serial != serials[1] || r.Name != "bob" || r.RevokedBy != "security" || r.RevocationReason != "left the team" || r.RevokedAt == nil {
+			t.Errorf("unexpected revocation record: %+v", r)
+		}
+
+		key, err := ioutil.ReadFile(filepath.Join(dir, "users", "alice-key.pem"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := pki.DecodePrivateKeyPEM(key); err != nil {
+			t.Errorf("invalid key written: %v", err)
+		}
+	})
+}
+
+func TestReadIssuanceRecordsWithoutEvents(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		// Records written before revocations were logged have no event
+		line := `{"serial":"1A2B","name":"alice","groups":["devs"],"notBefore":"2020-01-01T00:00:00Z","notAfter":"2020-01-02T00:00:00Z","issuedAt":"2020-01-01T00:00:00Z"}`
+		if err := ioutil.WriteFile(filepath.Join(dir, IssuanceLogFile), []byte(line+"\n"), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		records, err := ReadIssuanceRecords(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(records) != 1 || records[0].Event != IssuanceEventIssued || records[0].Serial != "1A2B" {
+			t.Errorf("unexpected records: %+v", records)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package ec2catalog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-yaml/yaml"
+	"github.com/kubernetes-incubator/kube-aws/builtin"
+)
+
+const (
+	builtinCatalogFile = "ec2-instance-types.yaml"
+
+	// LocalCatalogFile is the catalog in the directory of cluster.yaml, whose instance types are added to or override the builtin ones
+	LocalCatalogFile = "ec2-instance-types.yaml"
+
+	ArchitectureX86_64 = "x86_64"
+	ArchitectureArm64  = "arm64"
+)
+
+var (
+	defaultCatalog    Catalog
+	defaultCatalogErr error
+	loadDefault       sync.Once
+)
+
+// InstanceType describes the capabilities of an EC2 instance type kube-aws validates settings against
+type InstanceType struct {
+	Name         string `yaml:"name"`
+	Architecture string `yaml:"architecture"`
+	// Nitro is true when EBS volumes are attached to instances as NVMe devices
+	Nitro bool `yaml:"nitro,omitempty"`
+	GPUs  int  `yaml:"gpus,omitempty"`
+	// InstanceStoreDisks is the number of instance store volumes, which appear as NVMe devices when InstanceStoreNvme is true
+	InstanceStoreDisks int  `yaml:"instanceStoreDisks,omitempty"`
+	InstanceStoreNvme  bool `yaml:"instanceStoreNvme,omitempty"`
+}
+
+// Family returns the instance family e.g. "m5d" for "m5d.large"
+func (t *InstanceType) Family() string {
+	return familyOf(t.Name)
+}
+
+func (t *InstanceType) Size() string {
+	if i := strings.Index(t.Name, "."); i >= 0 {
+		return t.Name[i+1:]
+	}
+	return ""
+}
+
+func familyOf(name string) string {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// Catalog is the set of instance types indexed by name
+type Catalog map[string]*InstanceType
+
+// Builtin returns the catalog embedded into kube-aws
+func Builtin() (Catalog, error) {
+	return FromBytes(builtin.Bytes(builtinCatalogFile))
+}
+
+// Default returns the builtin catalog merged with LocalCatalogFile, if it exists in the working directory.
+// It is loaded only once as validations consult it for every node pool
+func Default() (Catalog, error) {
+	loadDefault.Do(func() {
+		defaultCatalog, defaultCatalogErr = loadDefaultCatalog()
+	})
+	return defaultCatalog, defaultCatalogErr
+}
+
+func loadDefaultCatalog() (Catalog, error) {
+	c, err := Builtin()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(LocalCatalogFile)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", LocalCatalogFile, err)
+	}
+	local, err := FromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", LocalCatalogFile, err)
+	}
+	return c.Merge(local), nil
+}
+
+func FromBytes(data []byte) (Catalog, error) {
+	types := []*InstanceType{}
+	if err := yaml.Unmarshal(data, &types); err != nil {
+		return nil, fmt.Errorf("failed to parse instance type catalog: %v", err)
+	}
+	c := Catalog{}
+	for i, t := range types {
+		if t.Name == "" {
+			return nil, fmt.Errorf("`name` of the instance type at index %d must be specified", i)
+		}
+		if t.Architecture != ArchitectureX86_64 && t.Architecture != ArchitectureArm64 {
+			return nil, fmt.Errorf("`architecture` of instance type %s must be either %s or %s but was \"%s\"", t.Name, ArchitectureX86_64, ArchitectureArm64, t.Architecture)
+		}
+		if t.GPUs < 0 || t.InstanceStoreDisks < 0 {
+			return nil, fmt.Errorf("`gpus` and `instanceStoreDisks` of instance type %s must be zero or greater", t.Name)
+		}
+		c[t.Name] = t
+	}
+	return c, nil
+}
+
+// Merge returns a new catalog of the instance types of both catalogs, preferring the ones in `other` for the same names
+func (c Catalog) Merge(other Catalog) Catalog {
+	merged := Catalog{}
+	for name, t := range c {
+		merged[name] = t
+	}
+	for name, t := range other {
+		merged[name] = t
+	}
+	return merged
+}
+
+// Find returns the instance type, or nil when it isn't in the catalog
+func (c Catalog) Find(name string) *InstanceType {
+	return c[name]
+}
+
+// Suggest returns the instance type in the catalog most likely meant by the unknown name e.g. "m5.large" for "m5.larg".
+// It is empty when no instance type is close enough
+func (c Catalog) Suggest(name string) string {
+	best := ""
+	bestDistance := 3
+	for _, candidate := range c.names() {
+		if d := levenshtein(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// InstanceStoreVariant returns the instance type with instance store volumes, which is otherwise the same as the instance type
+// e.g. "m5d.large" for "m5.large". It is nil when there's no such instance type in the catalog
+func (c Catalog) InstanceStoreVariant(t *InstanceType) *InstanceType {
+	v := c.Find(fmt.Sprintf("%sd.%s", t.Family(), t.Size()))
+	if v == nil || v.InstanceStoreDisks == 0 {
+		return nil
+	}
+	return v
+}
+
+// WithGPUs returns the names of instance types of the size with GPUs e.g. "g4dn.xlarge" and "p2.xlarge" for "xlarge"
+func (c Catalog) WithGPUs(size string) []string {
+	names := []string{}
+	for _, name := range c.names() {
+		if t := c[name]; t.GPUs > 0 && t.Size() == size {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (c Catalog) names() []string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,79 @@
+package ec2catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltin(t *testing.T) {
+	c, err := Builtin()
+	require.NoError(t, err)
+
+	m5 := c.Find("m5.large")
+	require.NotNil(t, m5)
+	assert.Equal(t, ArchitectureX86_64, m5.Architecture)
+	assert.True(t, m5.Nitro)
+	assert.Equal(t, 0, m5.InstanceStoreDisks)
+
+	p3 := c.Find("p3.8xlarge")
+	require.NotNil(t, p3)
+	assert.Equal(t, 4, p3.GPUs)
+
+	assert.Equal(t, ArchitectureArm64, c.Find("m6g.large").Architecture)
+	assert.Nil(t, c.Find("m5.huge"))
+}
+
+func TestFromBytes(t *testing.T) {
+	c, err := FromBytes([]byte(`
+- name: x9.large
+  architecture: arm64
+  nitro: true
+  instanceStoreDisks: 2
+  instanceStoreNvme: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, &InstanceType{Name: "x9.large", Architecture: "arm64", Nitro: true, InstanceStoreDisks: 2, InstanceStoreNvme: true}, c.Find("x9.large"))
+
+	_, err = FromBytes([]byte(`
+- name: x9.large
+  architecture: amd64
+`))
+	assert.EqualError(t, err, "`architecture` of instance type x9.large must be either x86_64 or arm64 but was \"amd64\"")
+
+	_, err = FromBytes([]byte(`
+- architecture: x86_64
+`))
+	assert.EqualError(t, err, "`name` of the instance type at index 0 must be specified")
+}
+
+func TestMerge(t *testing.T) {
+	builtin := Catalog{
+		"m5.large":  {Name: "m5.large", Architecture: "x86_64"},
+		"m5d.large": {Name: "m5d.large", Architecture: "x86_64"},
+	}
+	local := Catalog{
+		"m5d.large": {Name: "m5d.large", Architecture: "x86_64", InstanceStoreDisks: 1},
+		"x9.large":  {Name: "x9.large", Architecture: "arm64"},
+	}
+
+	merged := builtin.Merge(local)
+	assert.Len(t, merged, 3)
+	assert.Equal(t, 1, merged.Find("m5d.large").InstanceStoreDisks, "expected the local catalog to override the builtin one")
+	assert.Equal(t, 0, builtin.Find("m5d.large").InstanceStoreDisks, "expected the builtin catalog to be kept as is")
+}
+
+func TestSuggestions(t *testing.T) {
+	c, err := Builtin()
+	require.NoError(t, err)
+
+	assert.Equal(t, "m5.large", c.Suggest("m5.larg"))
+	assert.Equal(t, "c5.xlarge", c.Suggest("c5.xlrage"))
+	assert.Equal(t, "", c.Suggest("z99.enormous"))
+
+	assert.Equal(t, "m5d.large", c.InstanceStoreVariant(c.Find("m5.large")).Name)
+	assert.Nil(t, c.InstanceStoreVariant(c.Find("t3.large")))
+
+	assert.Equal(t, []string{"g3s.xlarge", "g4dn.xlarge", "p2.xlarge"}, c.WithGPUs("xlarge"))
+}
@@ -8,7 +8,7 @@ require (
 	github.com/Masterminds/sprig v2.20.0+incompatible
 	github.com/aryann/difflib v0.0.0-20170710044230-e206f873d14a
 	github.com/aws/amazon-vpc-cni-k8s v1.5.3
-	github.com/aws/aws-sdk-go v1.23.2
+	github.com/aws/aws-sdk-go v1.25.38
 	github.com/coreos/coreos-cloudinit v1.14.0
 	github.com/coreos/yaml v0.0.0-20141224210557-6b16a5714269 // indirect
 	github.com/davecgh/go-spew v1.1.1
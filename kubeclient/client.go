@@ -0,0 +1,266 @@
+package kubeclient
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	inClusterTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// Interface is the minimal set of Kubernetes API operations kube-aws relies on.
+// It is satisfied by *Client and can be faked in tests.
+type Interface interface {
+	// Get reads the resource at the API path e.g. `/apis/apps/v1/deployments` and decodes the JSON response into out
+	Get(path string, out interface{}) error
+}
+
+// Client talks to the Kubernetes API over plain HTTPS
+type Client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// NewFromKubeconfig creates a client for the named context in the kubeconfig at path.
+// An empty context selects the current context.
+func NewFromKubeconfig(path, context string) (*Client, error) {
+	kubeconfig, err := LoadKubeconfig(path)
+	if err != nil {
+		return nil, err
+	}
+	cluster, user, err := kubeconfig.Resolve(context)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig %s: %v", path, err)
+	}
+	return New(cluster, user)
+}
+
+// NewInCluster creates a client authenticated with the service account of the pod it is running in
+func NewInCluster() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set to create an in-cluster client")
+	}
+	token, err := ioutil.ReadFile(inClusterTokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %v", err)
+	}
+	return New(
+		&Cluster{Server: "https://" + net.JoinHostPort(host, port), CertificateAuthority: inClusterCAFile},
+		&AuthInfo{Token: strings.TrimSpace(string(token))},
+	)
+}
+
+func New(cluster *Cluster, user *AuthInfo) (*Client, error) {
+	if cluster.Server == "" {
+		return nil, fmt.Errorf("cluster server must not be empty")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cluster.InsecureSkipTLSVerify}
+
+	ca, err := dataOrFile(cluster.CertificateAuthorityData, cluster.CertificateAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate authority: %v", err)
+	}
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to parse certificate authority")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if user.ClientCertificateData == "" && user.ClientKeyData == "" && user.ClientCertificate != "" && user.ClientKey != "" {
+		// Reload the key pair once rotated e.g. by kubelet so that long-running clients keep authenticating
+		keyPair := &fileKeyPair{certFile: user.ClientCertificate, keyFile: user.ClientKey}
+		if _, err := keyPair.get(); err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return keyPair.get()
+		}
+	} else {
+		cert, err := dataOrFile(user.ClientCertificateData, user.ClientCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		key, err := dataOrFile(user.ClientKeyData, user.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key: %v", err)
+		}
+		if len(cert) > 0 && len(key) > 0 {
+			keyPair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client key pair: %v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{keyPair}
+		}
+	}
+
+	return &Client{
+		server: strings.TrimSuffix(cluster.Server, "/"),
+		token:  user.Token,
+		http: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+func dataOrFile(data, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return ioutil.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// fileKeyPair is a client key pair loaded from files, reloaded whenever either file is modified
+type fileKeyPair struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	keyPair     *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func (p *fileKeyPair) get() (*tls.Certificate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	certInfo, err := os.Stat(p.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+	keyInfo, err := os.Stat(p.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client key: %v", err)
+	}
+	if p.keyPair != nil && certInfo.ModTime().Equal(p.certModTime) && keyInfo.ModTime().Equal(p.keyModTime) {
+		return p.keyPair, nil
+	}
+
+	cert, err := ioutil.ReadFile(p.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+	key, err := ioutil.ReadFile(p.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client key: %v", err)
+	}
+	keyPair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client key pair: %v", err)
+	}
+	p.keyPair, p.certModTime, p.keyModTime = &keyPair, certInfo.ModTime(), keyInfo.ModTime()
+	return p.keyPair, nil
+}
+
+// StatusError is returned when the API server responds with a non-2xx status code
+type StatusError struct {
+	Method string
+	Path   string
+	Code   int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status code %d: %s", e.Method, e.Path, e.Code, e.Body)
+}
+
+// IsNotFound returns true when err is a 404 response from the API server
+func IsNotFound(err error) bool {
+	se, ok := err.(*StatusError)
+	return ok && se.Code == http.StatusNotFound
+}
+
+// IsConflict returns true when err is a 409 response from the API server, e.g. when the resource was modified concurrently
+func IsConflict(err error) bool {
+	se, ok := err.(*StatusError)
+	return ok && se.Code == http.StatusConflict
+}
+
+// IsTooManyRequests returns true when err is a 429 response from the API server, e.g. when an eviction is disallowed by a PodDisruptionBudget
+func IsTooManyRequests(err error) bool {
+	se, ok := err.(*StatusError)
+	return ok && se.Code == http.StatusTooManyRequests
+}
+
+func (c *Client) Get(path string, out interface{}) error {
+	return c.Do(http.MethodGet, path, nil, out)
+}
+
+// Patch applies the JSON merge patch to the resource at the API path and decodes the patched resource into out
+func (c *Client) Patch(path string, patch interface{}, out interface{}) error {
+	return c.do(http.MethodPatch, path, "application/merge-patch+json", patch, out)
+}
+
+// Do sends the JSON encoded body to the API path and decodes the JSON response into out. Either body or out can be nil.
+func (c *Client) Do(method, path string, body interface{}, out interface{}) error {
+	return c.do(method, path, "application/json", body, out)
+}
+
+func (c *Client) do(method, path, contentType string, body interface{}, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %v", err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.server+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response of %s %s: %v", method, path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{Method: method, Path: path, Code: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package kubeclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/pki"
+	"github.com/kubernetes-incubator/kube-aws/test/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKubeconfig(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		path := filepath.Join(dir, "kubeconfig")
+		kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority: credentials/ca.pem
+    server: https://api.example.com
+  name: kube-aws-test-cluster
+contexts:
+- context:
+    cluster: kube-aws-test-cluster
+    namespace: default
+    user: kube-aws-test-admin
+  name: kube-aws-test-context
+users:
+- name: kube-aws-test-admin
+  user:
+    client-certificate: credentials/admin.pem
+    client-key: /abs/admin-key.pem
+current-context: kube-aws-test-context
+`
+		require.NoError(t, ioutil.WriteFile(path, []byte(kubeconfig), 0600))
+
+		c, err := LoadKubeconfig(path)
+		require.NoError(t, err)
+
+		cluster, user, err := c.Resolve("")
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.example.com", cluster.Server)
+		assert.Equal(t, filepath.Join(dir, "credentials/ca.pem"), cluster.CertificateAuthority)
+		assert.Equal(t, filepath.Join(dir, "credentials/admin.pem"), user.ClientCertificate)
+		assert.Equal(t, "/abs/admin-key.pem", user.ClientKey)
+
+		_, _, err = c.Resolve("missing")
+		assert.Error(t, err)
+	})
+}
+
+func TestClientGet(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/apis/apps/v1/deployments":
+			fmt.Fprint(w, `{"items":[{"metadata":{"name":"foo"}}]}`)
+		case "/api/v1/nodes/foo":
+			if r.Method != http.MethodPatch || r.Header.Get("Content-Type") != "application/merge-patch+json" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			fmt.Fprintf(w, `{"metadata":{"name":"foo"},"spec":%s}`, body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind":"Status","code":404}`)
+		}
+	}))
+	defer server.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	client, err := New(
+		&Cluster{Server: server.URL, CertificateAuthorityData: base64.StdEncoding.EncodeToString(ca)},
+		&AuthInfo{Token: "secret"},
+	)
+	require.NoError(t, err)
+
+	out := struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}{}
+	require.NoError(t, client.Get("/apis/apps/v1/deployments", &out))
+	require.Len(t, out.Items, 1)
+	assert.Equal(t, "foo", out.Items[0].Metadata.Name)
+
+	err = client.Get("/apis/extensions/v1beta1/deployments", &out)
+	assert.True(t, IsNotFound(err), "expected not found error but was: %v", err)
+
+	node := struct {
+		Spec struct {
+			Unschedulable bool `json:"unschedulable"`
+		} `json:"spec"`
+	}{}
+	require.NoError(t, client.Patch("/api/v1/nodes/foo", map[string]interface{}{"unschedulable": true}, &node))
+	assert.True(t, node.Spec.Unschedulable)
+}
+
+func TestFileKeyPairReloadsRotatedKeyPair(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		// kubelet writes the rotated certificate and its key into a single file
+		path := filepath.Join(dir, "kubelet-client.pem")
+		write := func(cn string, modTime time.Time) {
+			key, cert, err := pki.NewCA(1, cn)
+			require.NoError(t, err)
+			data := append(pki.EncodeCertificatePEM(cert), pki.EncodePrivateKeyPEM(key)...)
+			require.NoError(t, ioutil.WriteFile(path, data, 0600))
+			require.NoError(t, os.Chtimes(path, modTime, modTime))
+		}
+		commonName := func(keyPair *tls.Certificate) string {
+			cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+			require.NoError(t, err)
+			return cert.Subject.CommonName
+		}
+
+		now := time.Now()
+		write("before-rotation", now.Add(-time.Hour))
+		keyPair := &fileKeyPair{certFile: path, keyFile: path}
+
+		first, err := keyPair.get()
+		require.NoError(t, err)
+		assert.Equal(t, "before-rotation", commonName(first))
+
+		cached, err := keyPair.get()
+		require.NoError(t, err)
+		assert.True(t, first == cached, "the key pair must be reused until the files are modified")
+
+		write("after-rotation", now)
+		rotated, err := keyPair.get()
+		require.NoError(t, err)
+		assert.Equal(t, "after-rotation", commonName(rotated))
+	})
+}
+
+func TestKubeconfigMergeIntoFile(t *testing.T) {
+	helper.WithTempDir(func(dir string) {
+		path := filepath.Join(dir, ".kube", "config")
+
+		existing := `apiVersion: v1
+kind: Config
+preferences:
+  colors: true
+clusters:
+- cluster:
+    server: https://other.example.com
+    proxy-url: http://proxy.example.com
+  name: other
+- cluster:
+    server: https://old.example.com
+  name: kube-aws-test-default-cluster
+contexts:
+- context:
+    cluster: other
+    user: other
+  name: other
+users:
+- name: other
+  user:
+    token: secret
+current-context: other
+`
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, []byte(existing), 0600))
+
+		generated := &Kubeconfig{
+			APIVersion: "v1",
+			Kind:       "Config",
+			Clusters: []NamedCluster{
+				{Name: "kube-aws-test-default-cluster", Cluster: Cluster{Server: "https://new.example.com"}},
+			},
+			Contexts: []NamedContext{
+				{Name: "kube-aws-test-default-context", Context: Context{Cluster: "kube-aws-test-default-cluster", User: "kube-aws-test-exec"}},
+			},
+			Users: []NamedUser{
+				{Name: "kube-aws-test-exec", User: AuthInfo{Exec: &ExecConfig{APIVersion: "client.authentication.k8s.io/v1alpha1", Command: "aws-iam-authenticator", Args: []string{"token", "-i", "test"}}}},
+			},
+			CurrentContext: "kube-aws-test-default-context",
+		}
+		require.NoError(t, generated.MergeIntoFile(path))
+
+		merged, err := readKubeconfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, "kube-aws-test-default-context", merged.CurrentContext)
+		assert.Equal(t, map[interface{}]interface{}{"colors": true}, merged.Extra["preferences"])
+		require.Len(t, merged.Clusters, 2)
+		assert.Equal(t, "http://proxy.example.com", merged.Clusters[0].Cluster.Extra["proxy-url"])
+		assert.Equal(t, "https://new.example.com", merged.Clusters[1].Cluster.Server)
+		assert.Len(t, merged.Contexts, 2)
+		require.Len(t, merged.Users, 2)
+		assert.Equal(t, "aws-iam-authenticator", merged.Users[1].User.Exec.Command)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+}
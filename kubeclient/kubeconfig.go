@@ -0,0 +1,256 @@
+package kubeclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/go-yaml/yaml"
+)
+
+// Kubeconfig is the subset of the kubectl config file format understood by kube-aws
+type Kubeconfig struct {
+	APIVersion     string         `yaml:"apiVersion"`
+	Kind           string         `yaml:"kind"`
+	Clusters       []NamedCluster `yaml:"clusters"`
+	Contexts       []NamedContext `yaml:"contexts"`
+	Users          []NamedUser    `yaml:"users"`
+	CurrentContext string         `yaml:"current-context"`
+	// Extra preserves keys like `preferences` unknown to kube-aws so that merging into an existing kubeconfig doesn't drop them
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+type NamedCluster struct {
+	Name    string  `yaml:"name"`
+	Cluster Cluster `yaml:"cluster"`
+}
+
+type Cluster struct {
+	Server                   string                 `yaml:"server"`
+	CertificateAuthority     string                 `yaml:"certificate-authority,omitempty"`
+	CertificateAuthorityData string                 `yaml:"certificate-authority-data,omitempty"`
+	InsecureSkipTLSVerify    bool                   `yaml:"insecure-skip-tls-verify,omitempty"`
+	Extra                    map[string]interface{} `yaml:",inline"`
+}
+
+type NamedContext struct {
+	Name    string  `yaml:"name"`
+	Context Context `yaml:"context"`
+}
+
+type Context struct {
+	Cluster   string                 `yaml:"cluster"`
+	User      string                 `yaml:"user"`
+	Namespace string                 `yaml:"namespace,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+type NamedUser struct {
+	Name string   `yaml:"name"`
+	User AuthInfo `yaml:"user"`
+}
+
+type AuthInfo struct {
+	ClientCertificate     string `yaml:"client-certificate,omitempty"`
+	ClientCertificateData string `yaml:"client-certificate-data,omitempty"`
+	ClientKey             string `yaml:"client-key,omitempty"`
+	ClientKeyData         string `yaml:"client-key-data,omitempty"`
+	Token                 string `yaml:"token,omitempty"`
+	// Exec runs an external command like `aws-iam-authenticator token` to obtain a bearer token
+	Exec *ExecConfig `yaml:"exec,omitempty"`
+	// AuthProvider obtains a bearer token from an auth provider like `oidc`
+	AuthProvider *AuthProviderConfig    `yaml:"auth-provider,omitempty"`
+	Extra        map[string]interface{} `yaml:",inline"`
+}
+
+type ExecConfig struct {
+	APIVersion string       `yaml:"apiVersion"`
+	Command    string       `yaml:"command"`
+	Args       []string     `yaml:"args,omitempty"`
+	Env        []ExecEnvVar `yaml:"env,omitempty"`
+}
+
+type ExecEnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+type AuthProviderConfig struct {
+	Name   string            `yaml:"name"`
+	Config map[string]string `yaml:"config,omitempty"`
+}
+
+// LoadKubeconfig reads the kubeconfig at path. Relative file references in it are resolved against the directory of path.
+func LoadKubeconfig(path string) (*Kubeconfig, error) {
+	c, err := readKubeconfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	for i := range c.Clusters {
+		resolve(&c.Clusters[i].Cluster.CertificateAuthority)
+	}
+	for i := range c.Users {
+		resolve(&c.Users[i].User.ClientCertificate)
+		resolve(&c.Users[i].User.ClientKey)
+	}
+
+	return c, nil
+}
+
+func readKubeconfig(path string) (*Kubeconfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig %s: %v", path, err)
+	}
+
+	c := &Kubeconfig{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// DefaultKubeconfigPath returns the path kubectl reads its config from when --kubeconfig is omitted
+func DefaultKubeconfigPath() string {
+	if p := os.Getenv("KUBECONFIG"); p != "" {
+		return filepath.SplitList(p)[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".kube", "config")
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// Resolve returns the cluster and the user referenced from the named context, or the current context when name is empty
+func (c *Kubeconfig) Resolve(name string) (*Cluster, *AuthInfo, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return nil, nil, fmt.Errorf("no context specified and current-context is not set")
+	}
+
+	var ctx *Context
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			ctx = &c.Contexts[i].Context
+		}
+	}
+	if ctx == nil {
+		return nil, nil, fmt.Errorf("context \"%s\" not found in kubeconfig", name)
+	}
+
+	var cluster *Cluster
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == ctx.Cluster {
+			cluster = &c.Clusters[i].Cluster
+		}
+	}
+	if cluster == nil {
+		return nil, nil, fmt.Errorf("cluster \"%s\" referenced from context \"%s\" not found in kubeconfig", ctx.Cluster, name)
+	}
+
+	user := &AuthInfo{}
+	for i := range c.Users {
+		if c.Users[i].Name == ctx.User {
+			user = &c.Users[i].User
+		}
+	}
+
+	return cluster, user, nil
+}
+
+// Merge adds the clusters, contexts and users in other to c, replacing the ones with the same names.
+// The current context is switched to the one of other when it is set.
+func (c *Kubeconfig) Merge(other *Kubeconfig) {
+	for _, o := range other.Clusters {
+		replaced := false
+		for i := range c.Clusters {
+			if c.Clusters[i].Name == o.Name {
+				c.Clusters[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Clusters = append(c.Clusters, o)
+		}
+	}
+	for _, o := range other.Contexts {
+		replaced := false
+		for i := range c.Contexts {
+			if c.Contexts[i].Name == o.Name {
+				c.Contexts[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Contexts = append(c.Contexts, o)
+		}
+	}
+	for _, o := range other.Users {
+		replaced := false
+		for i := range c.Users {
+			if c.Users[i].Name == o.Name {
+				c.Users[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Users = append(c.Users, o)
+		}
+	}
+	if other.CurrentContext != "" {
+		c.CurrentContext = other.CurrentContext
+	}
+}
+
+// Bytes returns the kubeconfig serialized in YAML
+func (c *Kubeconfig) Bytes() ([]byte, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize kubeconfig: %v", err)
+	}
+	return data, nil
+}
+
+// MergeIntoFile merges c into the kubeconfig at path and writes the result back.
+// The file and its parent directories are created when missing.
+// Entries and keys in the existing file are kept as-is unless replaced by the ones with the same names in c.
+func (c *Kubeconfig) MergeIntoFile(path string) error {
+	merged := &Kubeconfig{APIVersion: "v1", Kind: "Config"}
+	if _, err := os.Stat(path); err == nil {
+		existing, err := readKubeconfig(path)
+		if err != nil {
+			return err
+		}
+		merged = existing
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat kubeconfig %s: %v", path, err)
+	}
+	merged.Merge(c)
+	return merged.WriteToFile(path)
+}
+
+// WriteToFile writes c to path, overwriting the file if any
+func (c *Kubeconfig) WriteToFile(path string) error {
+	data, err := c.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create the directory for kubeconfig %s: %v", path, err)
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig %s: %v", path, err)
+	}
+	return nil
+}
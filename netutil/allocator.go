@@ -0,0 +1,150 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// Allocator carves non-overlapping IPv4 subnets out of a network
+type Allocator struct {
+	network *net.IPNet
+	used    []*net.IPNet
+}
+
+// NewAllocator returns an allocator with the whole IPv4 network free
+func NewAllocator(network *net.IPNet) (*Allocator, error) {
+	if IsIPv6(network.IP) {
+		return nil, fmt.Errorf("%s is not an IPv4 network", network)
+	}
+	return &Allocator{network: network}, nil
+}
+
+// Reserve marks the subnet as used so that it won't be allocated afterwards
+func (a *Allocator) Reserve(subnet *net.IPNet) error {
+	if IsIPv6(subnet.IP) {
+		return fmt.Errorf("%s is not an IPv4 network", subnet)
+	}
+	if !a.contains(subnet) {
+		return fmt.Errorf("%s is not contained in %s", subnet, a.network)
+	}
+	for _, u := range a.used {
+		if CidrOverlap(u, subnet) {
+			return fmt.Errorf("%s overlaps with %s", subnet, u)
+		}
+	}
+	a.used = append(a.used, subnet)
+	return nil
+}
+
+// Allocate reserves and returns the lowest free subnet with the prefix length
+func (a *Allocator) Allocate(prefixLength int) (*net.IPNet, error) {
+	networkOnes, _ := a.network.Mask.Size()
+	if prefixLength < networkOnes || prefixLength > 32 {
+		return nil, fmt.Errorf("a /%d subnet can't be allocated from %s", prefixLength, a.network)
+	}
+
+	size := blockSize(prefixLength)
+	start, end := a.bounds()
+	for candidate := start; candidate+size <= end; {
+		var overlapping *net.IPNet
+		for _, u := range a.used {
+			uStart, uEnd := rangeOf(u)
+			if uStart < candidate+size && candidate < uEnd {
+				overlapping = u
+				break
+			}
+		}
+		if overlapping == nil {
+			subnet := toIPNet(candidate, prefixLength)
+			a.used = append(a.used, subnet)
+			return subnet, nil
+		}
+		_, uEnd := rangeOf(overlapping)
+		candidate = alignUp(uEnd, size)
+	}
+
+	return nil, fmt.Errorf("no free /%d subnet left in %s", prefixLength, a.network)
+}
+
+// Used returns the reserved and allocated subnets sorted by their addresses
+func (a *Allocator) Used() []*net.IPNet {
+	used := make([]*net.IPNet, len(a.used))
+	copy(used, a.used)
+	sort.Slice(used, func(i, j int) bool {
+		iStart, _ := rangeOf(used[i])
+		jStart, _ := rangeOf(used[j])
+		return iStart < jStart
+	})
+	return used
+}
+
+// Free returns the largest aligned subnets covering the unused address space, sorted by their addresses
+func (a *Allocator) Free() []*net.IPNet {
+	free := []*net.IPNet{}
+	start, end := a.bounds()
+
+	pos := start
+	for _, u := range a.Used() {
+		uStart, uEnd := rangeOf(u)
+		free = appendAlignedBlocks(free, pos, uStart)
+		if uEnd > pos {
+			pos = uEnd
+		}
+	}
+	return appendAlignedBlocks(free, pos, end)
+}
+
+func appendAlignedBlocks(blocks []*net.IPNet, from, to uint64) []*net.IPNet {
+	for from < to {
+		prefixLength := 32
+		for prefixLength > 0 {
+			size := blockSize(prefixLength - 1)
+			if from%size != 0 || from+size > to {
+				break
+			}
+			prefixLength--
+		}
+		blocks = append(blocks, toIPNet(from, prefixLength))
+		from += blockSize(prefixLength)
+	}
+	return blocks
+}
+
+// Size returns the number of addresses in the subnet
+func Size(subnet *net.IPNet) uint64 {
+	start, end := rangeOf(subnet)
+	return end - start
+}
+
+func (a *Allocator) contains(subnet *net.IPNet) bool {
+	start, end := a.bounds()
+	sStart, sEnd := rangeOf(subnet)
+	return start <= sStart && sEnd <= end
+}
+
+func (a *Allocator) bounds() (uint64, uint64) {
+	return rangeOf(a.network)
+}
+
+func rangeOf(n *net.IPNet) (uint64, uint64) {
+	ip := n.IP.Mask(n.Mask).To4()
+	start := uint64(ip[0])<<24 | uint64(ip[1])<<16 | uint64(ip[2])<<8 | uint64(ip[3])
+	ones, _ := n.Mask.Size()
+	return start, start + blockSize(ones)
+}
+
+func blockSize(prefixLength int) uint64 {
+	return uint64(1) << uint(32-prefixLength)
+}
+
+func alignUp(v, size uint64) uint64 {
+	return (v + size - 1) / size * size
+}
+
+func toIPNet(start uint64, prefixLength int) *net.IPNet {
+	return &net.IPNet{
+		IP:   net.IPv4(byte(start>>24), byte(start>>16), byte(start>>8), byte(start)).To4(),
+		Mask: net.CIDRMask(prefixLength, 32),
+	}
+}
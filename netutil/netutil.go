@@ -1,9 +1,16 @@
 package netutil
 
-import "net"
+import (
+	"fmt"
+	"net"
+)
 
 //Does the address space of these networks "a" and "b" overlap?
+//Networks of different address families never overlap.
 func CidrOverlap(a, b *net.IPNet) bool {
+	if IsIPv6(a.IP) != IsIPv6(b.IP) {
+		return false
+	}
 	return a.Contains(b.IP) || b.Contains(a.IP)
 }
 
@@ -21,3 +28,32 @@ func IncrementIP(netIP net.IP) net.IP {
 
 	return ip
 }
+
+//Is the address an IPv6 one? IPv4-mapped IPv6 addresses are treated as IPv4
+func IsIPv6(ip net.IP) bool {
+	return ip.To4() == nil && ip.To16() != nil
+}
+
+//Parse the CIDR notation and ensure it is an IPv4 range
+func ParseIPv4CIDR(cidr string) (*net.IPNet, error) {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if IsIPv6(n.IP) {
+		return nil, fmt.Errorf("%s is not an IPv4 CIDR", cidr)
+	}
+	return n, nil
+}
+
+//Parse the CIDR notation and ensure it is an IPv6 range
+func ParseIPv6CIDR(cidr string) (*net.IPNet, error) {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if !IsIPv6(n.IP) {
+		return nil, fmt.Errorf("%s is not an IPv6 CIDR", cidr)
+	}
+	return n, nil
+}
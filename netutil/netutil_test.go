@@ -0,0 +1,125 @@
+package netutil
+
+import (
+	"net"
+	"testing"
+)
+
+func mustParseCIDR(t *testing.T, cidr string) *net.IPNet {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", cidr, err)
+	}
+	return n
+}
+
+func TestCidrOverlap(t *testing.T) {
+	testCases := []struct {
+		a, b    string
+		overlap bool
+	}{
+		{"10.0.0.0/16", "10.0.1.0/24", true},
+		{"10.0.0.0/16", "10.1.0.0/16", false},
+		{"fd00::/56", "fd00:0:0:1::/64", true},
+		{"fd00::/56", "fd00:0:0:100::/64", false},
+		{"2001:db8::/32", "2001:db8::/48", true},
+		// networks of different families never overlap, even when their raw bytes would
+		{"0.0.0.0/0", "::/0", false},
+	}
+
+	for _, tc := range testCases {
+		a, b := mustParseCIDR(t, tc.a), mustParseCIDR(t, tc.b)
+		if CidrOverlap(a, b) != tc.overlap || CidrOverlap(b, a) != tc.overlap {
+			t.Errorf("expected overlap of %s and %s to be %v", tc.a, tc.b, tc.overlap)
+		}
+	}
+}
+
+func TestIncrementIP(t *testing.T) {
+	testCases := []struct {
+		ip, expected string
+	}{
+		{"10.3.0.0", "10.3.0.1"},
+		{"10.3.0.255", "10.3.1.0"},
+		{"fd00::", "fd00::1"},
+		{"fd00::ffff", "fd00::1:0"},
+	}
+
+	for _, tc := range testCases {
+		actual := IncrementIP(net.ParseIP(tc.ip))
+		if !actual.Equal(net.ParseIP(tc.expected)) {
+			t.Errorf("expected next IP of %s to be %s but was %s", tc.ip, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseCIDRFamily(t *testing.T) {
+	if _, err := ParseIPv6CIDR("fd00::/108"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := ParseIPv6CIDR("10.3.0.0/24"); err == nil {
+		t.Errorf("expected an error for an IPv4 CIDR")
+	}
+	if _, err := ParseIPv4CIDR("10.3.0.0/24"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := ParseIPv4CIDR("fd00::/108"); err == nil {
+		t.Errorf("expected an error for an IPv6 CIDR")
+	}
+}
+
+func TestAllocator(t *testing.T) {
+	a, err := NewAllocator(mustParseCIDR(t, "10.0.0.0/16"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := a.Reserve(mustParseCIDR(t, "10.0.1.0/24")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := a.Reserve(mustParseCIDR(t, "10.0.1.128/25")); err == nil {
+		t.Errorf("expected an error for an overlapping subnet")
+	}
+	if err := a.Reserve(mustParseCIDR(t, "10.1.0.0/24")); err == nil {
+		t.Errorf("expected an error for a subnet outside of the network")
+	}
+
+	allocations := []struct {
+		prefixLength int
+		expected     string
+	}{
+		{24, "10.0.0.0/24"},
+		{20, "10.0.16.0/20"},
+		{24, "10.0.2.0/24"},
+		{23, "10.0.4.0/23"},
+		{17, "10.0.128.0/17"},
+	}
+	for _, tc := range allocations {
+		subnet, err := a.Allocate(tc.prefixLength)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if subnet.String() != tc.expected {
+			t.Errorf("expected /%d to be allocated at %s but was %s", tc.prefixLength, tc.expected, subnet)
+		}
+	}
+
+	if _, err := a.Allocate(17); err == nil {
+		t.Errorf("expected an error when the network is exhausted")
+	}
+	if _, err := a.Allocate(15); err == nil {
+		t.Errorf("expected an error for a subnet larger than the network")
+	}
+
+	expectedFree := []string{"10.0.3.0/24", "10.0.6.0/23", "10.0.8.0/21", "10.0.32.0/19", "10.0.64.0/18"}
+	free := a.Free()
+	if len(free) != len(expectedFree) {
+		t.Fatalf("unexpected free subnets: %v", free)
+	}
+	for i, f := range free {
+		if f.String() != expectedFree[i] {
+			t.Errorf("expected free subnet #%d to be %s but was %s", i, expectedFree[i], f)
+		}
+	}
+}
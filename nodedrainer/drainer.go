@@ -0,0 +1,301 @@
+package nodedrainer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+)
+
+const (
+	// DefaultPollInterval is the interval of retrying evictions disallowed by PodDisruptionBudgets and checking if evicted pods are gone
+	DefaultPollInterval = 5 * time.Second
+
+	// receiveWaitTimeSeconds enables long polling of the queue
+	receiveWaitTimeSeconds = 20
+	maxMessagesPerReceive  = 10
+)
+
+type sqsService interface {
+	ReceiveMessage(*sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(*sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error)
+}
+
+type autoScalingService interface {
+	CompleteLifecycleAction(*autoscaling.CompleteLifecycleActionInput) (*autoscaling.CompleteLifecycleActionOutput, error)
+	DescribeAutoScalingGroups(*autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+}
+
+type Config struct {
+	// QueueURL is the SQS queue auto scaling lifecycle actions and spot instance interruption warnings are sent to
+	QueueURL string
+	// ClusterName is the name of the cluster, whose auto scaling groups are tagged with `kubernetes.io/cluster/<ClusterName>`
+	ClusterName string
+	// DrainTimeout is the maximum time to wait for a node to be drained. The lifecycle action is completed once it elapses
+	// even if some pods are still running, so that the instance isn't kept until the heartbeat timeout of the lifecycle hook
+	DrainTimeout time.Duration
+	PollInterval time.Duration
+}
+
+// Drainer cordons and drains nodes whose instances are going to be terminated by auto scaling groups or spot interruptions.
+// Pods are evicted via the eviction API so that PodDisruptionBudgets are respected
+type Drainer struct {
+	Config
+
+	kube        KubeClient
+	sqs         sqsService
+	autoScaling autoScalingService
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func New(config Config, kube KubeClient, sqsSvc sqsService, asSvc autoScalingService) *Drainer {
+	if config.PollInterval == 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	return &Drainer{
+		Config:      config,
+		kube:        kube,
+		sqs:         sqsSvc,
+		autoScaling: asSvc,
+		now:         time.Now,
+		sleep:       time.Sleep,
+	}
+}
+
+// Run receives and handles messages from the queue until stop is closed.
+// Messages received at once are handled concurrently so that nodes terminated in the same batch of a rolling update are drained in parallel
+func (d *Drainer) Run(stop <-chan struct{}) error {
+	// Keep messages invisible to other receives while they're being handled
+	visibilityTimeout := int64((d.DrainTimeout + time.Minute) / time.Second)
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		out, err := d.sqs.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(d.QueueURL),
+			MaxNumberOfMessages: aws.Int64(maxMessagesPerReceive),
+			WaitTimeSeconds:     aws.Int64(receiveWaitTimeSeconds),
+			VisibilityTimeout:   aws.Int64(visibilityTimeout),
+		})
+		if err != nil {
+			logger.Errorf("failed to receive messages from %s: %v", d.QueueURL, err)
+			d.sleep(d.PollInterval)
+			continue
+		}
+
+		var wg sync.WaitGroup
+		for _, m := range out.Messages {
+			wg.Add(1)
+			go func(m *sqs.Message) {
+				defer wg.Done()
+				d.handleMessage(m)
+			}(m)
+		}
+		wg.Wait()
+	}
+}
+
+// handleMessage deletes the message once it is handled. Otherwise it is received again after the visibility timeout
+func (d *Drainer) handleMessage(m *sqs.Message) {
+	e, err := ParseEvent(aws.StringValue(m.Body))
+	if err != nil {
+		logger.Errorf("ignoring malformed message %s: %v", aws.StringValue(m.MessageId), err)
+	} else if err := d.Handle(e); err != nil {
+		logger.Errorf("failed to handle %s: %v", e, err)
+		return
+	}
+
+	if _, err := d.sqs.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(d.QueueURL),
+		ReceiptHandle: m.ReceiptHandle,
+	}); err != nil {
+		logger.Errorf("failed to delete message %s: %v", aws.StringValue(m.MessageId), err)
+	}
+}
+
+// Handle cordons and drains the node of the instance being terminated, and then completes the lifecycle action if any.
+// An error is returned only when the event should be handled again
+func (d *Drainer) Handle(e Event) error {
+	if e.Kind == EventIgnored {
+		return nil
+	}
+
+	if e.Kind == EventLifecycleTermination {
+		// The lifecycle rule matches auto scaling groups by the name prefix `<clusterName>-`, which is shared by those of
+		// other clusters e.g. `<clusterName>-foo`. Their lifecycle actions are left to their own drainers
+		owned, err := d.ownsAutoScalingGroup(e.AutoScalingGroupName)
+		if err != nil {
+			return err
+		}
+		if !owned {
+			logger.Infof("ignoring %s as the auto scaling group doesn't belong to cluster %s", e, d.ClusterName)
+			return nil
+		}
+	}
+
+	n, err := findNode(d.kube, e.InstanceID)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		// An instance of this cluster terminated before it registered itself as a node, e.g. because it failed to boot, still has
+		// its lifecycle action completed so that the termination isn't held until the heartbeat timeout of the lifecycle hook.
+		// Spot interruptions are sent for every instance in the region
+		logger.Infof("skipping draining on %s as it isn't a node of this cluster", e)
+	} else {
+		name := n.Metadata.Name
+
+		logger.Infof("draining node %s on %s", name, e)
+		if err := cordon(d.kube, name); err != nil {
+			return err
+		}
+		if err := d.Drain(name); err != nil {
+			logger.Errorf("giving up draining node %s: %v", name, err)
+		} else {
+			logger.Infof("drained node %s", name)
+		}
+	}
+
+	if e.Kind != EventLifecycleTermination {
+		return nil
+	}
+	_, err = d.autoScaling.CompleteLifecycleAction(&autoscaling.CompleteLifecycleActionInput{
+		AutoScalingGroupName:  aws.String(e.AutoScalingGroupName),
+		LifecycleHookName:     aws.String(e.LifecycleHookName),
+		LifecycleActionToken:  aws.String(e.LifecycleActionToken),
+		InstanceId:            aws.String(e.InstanceID),
+		LifecycleActionResult: aws.String("CONTINUE"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete lifecycle action of %s: %v", e.InstanceID, err)
+	}
+	return nil
+}
+
+// ownsAutoScalingGroup returns true when the auto scaling group is tagged as the one of this cluster.
+// A group which no longer exists isn't owned as there is no lifecycle action left to complete
+func (d *Drainer) ownsAutoScalingGroup(name string) (bool, error) {
+	out, err := d.autoScaling.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: aws.StringSlice([]string{name}),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to describe auto scaling group %s: %v", name, err)
+	}
+	key := "kubernetes.io/cluster/" + d.ClusterName
+	for _, g := range out.AutoScalingGroups {
+		for _, t := range g.Tags {
+			if aws.StringValue(t.Key) == key {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// DrainInstance cordons and drains the node of the EC2 instance. It does nothing when the instance isn't a node of the cluster
+func (d *Drainer) DrainInstance(instanceID string) error {
+	n, err := findNode(d.kube, instanceID)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		logger.Infof("skipping %s as it isn't a node of this cluster", instanceID)
+		return nil
+	}
+	name := n.Metadata.Name
+
+	logger.Infof("draining node %s on %s", name, instanceID)
+	if err := cordon(d.kube, name); err != nil {
+		return err
+	}
+	if err := d.Drain(name); err != nil {
+		return fmt.Errorf("failed to drain node %s: %v", name, err)
+	}
+	logger.Infof("drained node %s", name)
+	return nil
+}
+
+// Drain evicts all the pods on the node except mirror pods and pods managed by daemonsets, and waits for them to be gone.
+// Evictions disallowed by PodDisruptionBudgets are retried until DrainTimeout elapses
+func (d *Drainer) Drain(nodeName string) error {
+	deadline := d.now().Add(d.DrainTimeout)
+
+	pods, err := listPodsOn(d.kube, nodeName)
+	if err != nil {
+		return err
+	}
+	pending := []pod{}
+	for _, p := range pods {
+		if p.evictable() {
+			pending = append(pending, p)
+		}
+	}
+
+	evicted := []pod{}
+	for {
+		blocked := []pod{}
+		for _, p := range pending {
+			err := evict(d.kube, p)
+			switch {
+			case err == nil, kubeclient.IsNotFound(err):
+				evicted = append(evicted, p)
+			case kubeclient.IsTooManyRequests(err):
+				blocked = append(blocked, p)
+			default:
+				return fmt.Errorf("failed to evict pod %s: %v", p, err)
+			}
+		}
+		if len(blocked) == 0 {
+			break
+		}
+		if !d.now().Before(deadline) {
+			return fmt.Errorf("timed out after %s while evictions of %s are disallowed by PodDisruptionBudgets", d.DrainTimeout, podNames(blocked))
+		}
+		logger.Infof("evictions of %s are disallowed by PodDisruptionBudgets. retrying in %s", podNames(blocked), d.PollInterval)
+		d.sleep(d.PollInterval)
+		pending = blocked
+	}
+
+	for len(evicted) > 0 {
+		remaining := []pod{}
+		for _, p := range evicted {
+			gone, err := deleted(d.kube, p)
+			if err != nil {
+				return fmt.Errorf("failed to get pod %s: %v", p, err)
+			}
+			if !gone {
+				remaining = append(remaining, p)
+			}
+		}
+		if len(remaining) == 0 {
+			break
+		}
+		if !d.now().Before(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s to terminate", d.DrainTimeout, podNames(remaining))
+		}
+		d.sleep(d.PollInterval)
+		evicted = remaining
+	}
+
+	return nil
+}
+
+func podNames(pods []pod) string {
+	names := make([]string, len(pods))
+	for i, p := range pods {
+		names[i] = p.String()
+	}
+	return strings.Join(names, ", ")
+}
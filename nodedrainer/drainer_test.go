@@ -0,0 +1,372 @@
+package nodedrainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKubeClient is an in-memory Kubernetes API serving nodes, pods, evictions and events.
+// Evicted pods are deleted immediately, unless their evictions are disallowed by a PodDisruptionBudget
+type fakeKubeClient struct {
+	mu    sync.Mutex
+	nodes map[string]*node
+	pods  map[string]*pod
+	// pdbRejections is the number of times evictions of the pod are rejected as if they violated a PodDisruptionBudget
+	pdbRejections map[string]int
+	evicted       []string
+	events        []event
+}
+
+func newFakeKubeClient() *fakeKubeClient {
+	return &fakeKubeClient{nodes: map[string]*node{}, pods: map[string]*pod{}, pdbRejections: map[string]int{}}
+}
+
+func (c *fakeKubeClient) addNode(name, instanceID string) {
+	n := &node{Metadata: objectMeta{Name: name}}
+	n.Spec.ProviderID = "aws:///us-west-1a/" + instanceID
+	c.nodes[name] = n
+}
+
+func (c *fakeKubeClient) addPod(namespace, name, nodeName string, annotations map[string]string, owners ...ownerReference) {
+	p := &pod{Metadata: objectMeta{Namespace: namespace, Name: name, UID: namespace + "-" + name, Annotations: annotations, OwnerReferences: owners}}
+	p.Status.Phase = "Running"
+	c.pods[nodeName+"/"+p.String()] = p
+}
+
+func (c *fakeKubeClient) Get(path string, out interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case path == "/api/v1/nodes":
+		list := nodeList{}
+		for _, n := range c.nodes {
+			list.Items = append(list.Items, *n)
+		}
+		return roundTrip(list, out)
+	case strings.HasPrefix(path, "/api/v1/nodes/"):
+		if n, ok := c.nodes[strings.TrimPrefix(path, "/api/v1/nodes/")]; ok {
+			return roundTrip(n, out)
+		}
+	case strings.HasPrefix(path, "/api/v1/pods?fieldSelector=spec.nodeName%3D"):
+		nodeName := strings.TrimPrefix(path, "/api/v1/pods?fieldSelector=spec.nodeName%3D")
+		list := podList{}
+		for key, p := range c.pods {
+			if strings.HasPrefix(key, nodeName+"/") {
+				list.Items = append(list.Items, *p)
+			}
+		}
+		return roundTrip(list, out)
+	}
+	for key, p := range c.pods {
+		if podPath(*p) == path && strings.HasSuffix(key, "/"+p.String()) {
+			return roundTrip(p, out)
+		}
+	}
+	return &kubeclient.StatusError{Method: http.MethodGet, Path: path, Code: http.StatusNotFound}
+}
+
+func (c *fakeKubeClient) Do(method, path string, body interface{}, out interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if method == http.MethodPost && path == "/api/v1/namespaces/default/events" {
+		e := event{}
+		if err := roundTrip(body, &e); err != nil {
+			return err
+		}
+		c.events = append(c.events, e)
+		return nil
+	}
+
+	for key, p := range c.pods {
+		if method != http.MethodPost || podPath(*p)+"/eviction" != path {
+			continue
+		}
+		if c.pdbRejections[p.String()] > 0 {
+			c.pdbRejections[p.String()]--
+			return &kubeclient.StatusError{Method: method, Path: path, Code: http.StatusTooManyRequests}
+		}
+		delete(c.pods, key)
+		c.evicted = append(c.evicted, p.String())
+		return nil
+	}
+	return &kubeclient.StatusError{Method: method, Path: path, Code: http.StatusNotFound}
+}
+
+func (c *fakeKubeClient) Patch(path string, patch interface{}, out interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := strings.TrimPrefix(path, "/api/v1/nodes/")
+	n, ok := c.nodes[name]
+	if !ok {
+		return &kubeclient.StatusError{Method: http.MethodPatch, Path: path, Code: http.StatusNotFound}
+	}
+	if err := roundTrip(patch, n); err != nil {
+		return err
+	}
+	if out != nil {
+		return roundTrip(n, out)
+	}
+	return nil
+}
+
+func roundTrip(in interface{}, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+type fakeAutoScaling struct {
+	mu        sync.Mutex
+	completed []*autoscaling.CompleteLifecycleActionInput
+	// clusters are the names of the clusters owning auto scaling groups other than those of the test cluster
+	clusters map[string]string
+}
+
+func (a *fakeAutoScaling) DescribeAutoScalingGroups(in *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := &autoscaling.DescribeAutoScalingGroupsOutput{}
+	for _, name := range aws.StringValueSlice(in.AutoScalingGroupNames) {
+		cluster, ok := a.clusters[name]
+		if !ok {
+			cluster = "test"
+		}
+		out.AutoScalingGroups = append(out.AutoScalingGroups, &autoscaling.Group{
+			AutoScalingGroupName: aws.String(name),
+			Tags:                 []*autoscaling.TagDescription{{Key: aws.String("kubernetes.io/cluster/" + cluster), Value: aws.String("owned")}},
+		})
+	}
+	return out, nil
+}
+
+func (a *fakeAutoScaling) CompleteLifecycleAction(in *autoscaling.CompleteLifecycleActionInput) (*autoscaling.CompleteLifecycleActionOutput, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.completed = append(a.completed, in)
+	return &autoscaling.CompleteLifecycleActionOutput{}, nil
+}
+
+type fakeSQS struct {
+	mu       sync.Mutex
+	messages []*sqs.Message
+	deleted  []string
+}
+
+func (q *fakeSQS) ReceiveMessage(in *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := &sqs.ReceiveMessageOutput{Messages: q.messages}
+	q.messages = nil
+	return out, nil
+}
+
+func (q *fakeSQS) DeleteMessage(in *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deleted = append(q.deleted, aws.StringValue(in.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// newTestDrainer returns a drainer whose clock advances only while it sleeps
+func newTestDrainer(kube KubeClient, q sqsService, as autoScalingService) *Drainer {
+	d := New(Config{QueueURL: "https://sqs.us-west-1.amazonaws.com/123456789012/test", ClusterName: "test", DrainTimeout: time.Minute}, kube, q, as)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	d.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	d.sleep = func(duration time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(duration)
+	}
+	return d
+}
+
+func TestDrainerHandleLifecycleTermination(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addNode("ip-10-0-0-2", "i-2")
+	isController := true
+	kube.addPod("default", "web-1", "ip-10-0-0-1", nil)
+	kube.addPod("default", "web-2", "ip-10-0-0-2", nil)
+	kube.addPod("kube-system", "fluentd-abcde", "ip-10-0-0-1", nil, ownerReference{Kind: "DaemonSet", Name: "fluentd", Controller: &isController})
+	kube.addPod("kube-system", "kube-proxy-ip-10-0-0-1", "ip-10-0-0-1", map[string]string{mirrorPodAnnotation: "abc"})
+	as := &fakeAutoScaling{}
+
+	d := newTestDrainer(kube, &fakeSQS{}, as)
+	err := d.Handle(Event{Kind: EventLifecycleTermination, InstanceID: "i-1", AutoScalingGroupName: "pool1", LifecycleHookName: "pool1NodeDrainerLH", LifecycleActionToken: "token"})
+	require.NoError(t, err)
+
+	assert.True(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+	assert.False(t, kube.nodes["ip-10-0-0-2"].Spec.Unschedulable)
+	assert.Equal(t, []string{"default/web-1"}, kube.evicted)
+
+	require.Len(t, as.completed, 1)
+	assert.Equal(t, "pool1", aws.StringValue(as.completed[0].AutoScalingGroupName))
+	assert.Equal(t, "pool1NodeDrainerLH", aws.StringValue(as.completed[0].LifecycleHookName))
+	assert.Equal(t, "i-1", aws.StringValue(as.completed[0].InstanceId))
+	assert.Equal(t, "CONTINUE", aws.StringValue(as.completed[0].LifecycleActionResult))
+}
+
+func TestDrainerRespectsPodDisruptionBudgets(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addPod("default", "db-0", "ip-10-0-0-1", nil)
+	kube.addPod("default", "web-1", "ip-10-0-0-1", nil)
+	kube.pdbRejections["default/db-0"] = 3
+
+	d := newTestDrainer(kube, &fakeSQS{}, &fakeAutoScaling{})
+	start := d.now()
+	require.NoError(t, d.Drain("ip-10-0-0-1"))
+
+	assert.ElementsMatch(t, []string{"default/web-1", "default/db-0"}, kube.evicted)
+	assert.Equal(t, "default/db-0", kube.evicted[1], "pods blocked by PodDisruptionBudgets must be retried after the others are evicted")
+	assert.Equal(t, 3*DefaultPollInterval, d.now().Sub(start))
+}
+
+func TestDrainerTimeout(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addPod("default", "db-0", "ip-10-0-0-1", nil)
+	kube.pdbRejections["default/db-0"] = 1000
+	as := &fakeAutoScaling{}
+
+	d := newTestDrainer(kube, &fakeSQS{}, as)
+	err := d.Drain("ip-10-0-0-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "evictions of default/db-0 are disallowed by PodDisruptionBudgets")
+
+	// The lifecycle action is completed after DrainTimeout even though the node couldn't be drained
+	require.NoError(t, d.Handle(Event{Kind: EventLifecycleTermination, InstanceID: "i-1", AutoScalingGroupName: "pool1", LifecycleHookName: "lh"}))
+	assert.Len(t, as.completed, 1)
+	assert.Empty(t, kube.evicted)
+}
+
+func TestDrainerHandleSpotInterruptionAndUnknownInstances(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addPod("default", "web-1", "ip-10-0-0-1", nil)
+	as := &fakeAutoScaling{}
+
+	d := newTestDrainer(kube, &fakeSQS{}, as)
+	require.NoError(t, d.Handle(Event{Kind: EventSpotInterruption, InstanceID: "i-1"}))
+	assert.True(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+	assert.Equal(t, []string{"default/web-1"}, kube.evicted)
+
+	// Interruptions of instances of other clusters are left to their own drainers
+	require.NoError(t, d.Handle(Event{Kind: EventSpotInterruption, InstanceID: "i-other"}))
+	assert.Equal(t, []string{"default/web-1"}, kube.evicted)
+
+	// The lifecycle action of an instance terminated before it registered itself as a node is completed without draining
+	require.NoError(t, d.Handle(Event{Kind: EventLifecycleTermination, InstanceID: "i-unregistered", AutoScalingGroupName: "pool1", LifecycleHookName: "lh"}))
+	require.Len(t, as.completed, 1)
+	assert.Equal(t, "i-unregistered", aws.StringValue(as.completed[0].InstanceId))
+	assert.Equal(t, "CONTINUE", aws.StringValue(as.completed[0].LifecycleActionResult))
+}
+
+func TestDrainerIgnoresLifecycleActionsOfOtherClusters(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addPod("default", "web-1", "ip-10-0-0-1", nil)
+	// The lifecycle rule of the cluster `test` matches the auto scaling groups of the cluster `test-bar` by the name prefix `test-`
+	as := &fakeAutoScaling{clusters: map[string]string{"test-bar-pool1": "test-bar"}}
+
+	d := newTestDrainer(kube, &fakeSQS{}, as)
+	require.NoError(t, d.Handle(Event{Kind: EventLifecycleTermination, InstanceID: "i-1", AutoScalingGroupName: "test-bar-pool1", LifecycleHookName: "lh"}))
+	require.NoError(t, d.Handle(Event{Kind: EventLifecycleTermination, InstanceID: "i-other", AutoScalingGroupName: "test-bar-pool1", LifecycleHookName: "lh"}))
+	assert.False(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+	assert.Empty(t, kube.evicted)
+	assert.Empty(t, as.completed)
+}
+
+func TestDrainerDrainInstance(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addPod("default", "web-1", "ip-10-0-0-1", nil)
+	kube.addPod("default", "db-0", "ip-10-0-0-1", nil)
+	kube.pdbRejections["default/db-0"] = 1000
+
+	d := newTestDrainer(kube, nil, nil)
+	err := d.DrainInstance("i-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to drain node ip-10-0-0-1")
+	assert.True(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+	assert.Equal(t, []string{"default/web-1"}, kube.evicted)
+
+	require.NoError(t, d.DrainInstance("i-other"))
+}
+
+func TestReadyInstances(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addNode("ip-10-0-0-2", "i-2")
+	kube.addNode("ip-10-0-0-3", "i-3")
+	kube.nodes["ip-10-0-0-1"].Status.Conditions = []nodeCondition{{Type: "Ready", Status: "True"}}
+	kube.nodes["ip-10-0-0-2"].Status.Conditions = []nodeCondition{{Type: "Ready", Status: "False"}}
+	kube.nodes["ip-10-0-0-3"].Status.Conditions = []nodeCondition{{Type: "Ready", Status: "True"}}
+
+	ready, err := ReadyInstances(kube, []string{"i-1", "i-2", "i-4"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-1"}, ready)
+}
+
+func TestDrainerRun(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addPod("default", "web-1", "ip-10-0-0-1", nil)
+	as := &fakeAutoScaling{}
+	q := &fakeSQS{messages: []*sqs.Message{
+		{MessageId: aws.String("1"), ReceiptHandle: aws.String("r1"), Body: aws.String(`{"detail-type":"EC2 Instance-terminate Lifecycle Action","source":"aws.autoscaling","detail":{"LifecycleActionToken":"token","AutoScalingGroupName":"pool1","LifecycleHookName":"lh","EC2InstanceId":"i-1","LifecycleTransition":"autoscaling:EC2_INSTANCE_TERMINATING"}}`)},
+		{MessageId: aws.String("2"), ReceiptHandle: aws.String("r2"), Body: aws.String(`not json`)},
+	}}
+
+	d := newTestDrainer(kube, q, as)
+	stop := make(chan struct{})
+	d.sleep = func(time.Duration) {}
+	received := 0
+	d.sqs = receiveHook{q, func() {
+		received++
+		if received == 2 {
+			close(stop)
+		}
+	}}
+	require.NoError(t, d.Run(stop))
+
+	assert.ElementsMatch(t, []string{"r1", "r2"}, q.deleted)
+	assert.Len(t, as.completed, 1)
+	assert.Equal(t, []string{"default/web-1"}, kube.evicted)
+}
+
+// receiveHook calls the hook after every receive
+type receiveHook struct {
+	*fakeSQS
+	hook func()
+}
+
+func (r receiveHook) ReceiveMessage(in *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	defer r.hook()
+	if aws.Int64Value(in.VisibilityTimeout) != 120 {
+		return nil, fmt.Errorf("unexpected visibility timeout: %d", aws.Int64Value(in.VisibilityTimeout))
+	}
+	return r.fakeSQS.ReceiveMessage(in)
+}
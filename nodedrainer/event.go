@@ -0,0 +1,109 @@
+package nodedrainer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	lifecycleTransitionTerminating = "autoscaling:EC2_INSTANCE_TERMINATING"
+	testNotification               = "autoscaling:TEST_NOTIFICATION"
+
+	detailTypeLifecycleAction  = "EC2 Instance-terminate Lifecycle Action"
+	detailTypeSpotInterruption = "EC2 Spot Instance Interruption Warning"
+)
+
+type EventKind string
+
+const (
+	// EventLifecycleTermination is sent when an auto scaling group is about to terminate an instance and waits for
+	// the lifecycle action to be completed
+	EventLifecycleTermination EventKind = "LifecycleTermination"
+	// EventSpotInterruption is sent two minutes before a spot instance is interrupted
+	EventSpotInterruption EventKind = "SpotInterruption"
+	// EventIgnored is any other message, like the test notification sent when a lifecycle hook is created
+	EventIgnored EventKind = "Ignored"
+)
+
+// Event is a notice that the instance is going to be terminated
+type Event struct {
+	Kind       EventKind
+	InstanceID string
+
+	// The following are set only for EventLifecycleTermination
+	AutoScalingGroupName string
+	LifecycleHookName    string
+	LifecycleActionToken string
+}
+
+func (e Event) String() string {
+	if e.Kind == EventLifecycleTermination {
+		return fmt.Sprintf("%s of %s in %s", e.Kind, e.InstanceID, e.AutoScalingGroupName)
+	}
+	return fmt.Sprintf("%s of %s", e.Kind, e.InstanceID)
+}
+
+type lifecycleAction struct {
+	Event                string `json:"Event"`
+	LifecycleTransition  string `json:"LifecycleTransition"`
+	EC2InstanceID        string `json:"EC2InstanceId"`
+	AutoScalingGroupName string `json:"AutoScalingGroupName"`
+	LifecycleHookName    string `json:"LifecycleHookName"`
+	LifecycleActionToken string `json:"LifecycleActionToken"`
+}
+
+type cloudWatchEvent struct {
+	DetailType string          `json:"detail-type"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+type spotInterruption struct {
+	InstanceID string `json:"instance-id"`
+}
+
+// ParseEvent parses the body of an SQS message, which is either an EventBridge(CloudWatch Events) event or
+// a lifecycle notification sent directly from an auto scaling group
+func ParseEvent(body string) (Event, error) {
+	e := cloudWatchEvent{}
+	if err := json.Unmarshal([]byte(body), &e); err != nil {
+		return Event{}, fmt.Errorf("failed to parse message: %v", err)
+	}
+
+	switch e.DetailType {
+	case "":
+		return parseLifecycleAction([]byte(body))
+	case detailTypeLifecycleAction:
+		return parseLifecycleAction(e.Detail)
+	case detailTypeSpotInterruption:
+		s := spotInterruption{}
+		if err := json.Unmarshal(e.Detail, &s); err != nil {
+			return Event{}, fmt.Errorf("failed to parse spot instance interruption warning: %v", err)
+		}
+		if s.InstanceID == "" {
+			return Event{}, fmt.Errorf("missing instance-id in spot instance interruption warning: %s", body)
+		}
+		return Event{Kind: EventSpotInterruption, InstanceID: s.InstanceID}, nil
+	default:
+		return Event{Kind: EventIgnored}, nil
+	}
+}
+
+func parseLifecycleAction(data []byte) (Event, error) {
+	a := lifecycleAction{}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return Event{}, fmt.Errorf("failed to parse lifecycle action: %v", err)
+	}
+	if a.Event == testNotification || a.LifecycleTransition != lifecycleTransitionTerminating {
+		return Event{Kind: EventIgnored}, nil
+	}
+	if a.EC2InstanceID == "" || a.AutoScalingGroupName == "" || a.LifecycleHookName == "" {
+		return Event{}, fmt.Errorf("missing EC2InstanceId, AutoScalingGroupName or LifecycleHookName in lifecycle action: %s", string(data))
+	}
+	return Event{
+		Kind:                 EventLifecycleTermination,
+		InstanceID:           a.EC2InstanceID,
+		AutoScalingGroupName: a.AutoScalingGroupName,
+		LifecycleHookName:    a.LifecycleHookName,
+		LifecycleActionToken: a.LifecycleActionToken,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package nodedrainer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEvent(t *testing.T) {
+	lifecycle := Event{
+		Kind:                 EventLifecycleTermination,
+		InstanceID:           "i-0123456789abcdef0",
+		AutoScalingGroupName: "mycluster-pool1-Workers-1ABC",
+		LifecycleHookName:    "mycluster-pool1-WorkersNodeDrainerLH-1DEF",
+		LifecycleActionToken: "c613620e-07e2-4ed2-a9e2-ef8258911ade",
+	}
+
+	testCases := []struct {
+		context  string
+		body     string
+		expected Event
+	}{
+		{
+			context:  "LifecycleNotification",
+			body:     `{"Origin":"AutoScalingGroup","LifecycleHookName":"mycluster-pool1-WorkersNodeDrainerLH-1DEF","Destination":"EC2","AccountId":"123456789012","RequestId":"x","LifecycleActionToken":"c613620e-07e2-4ed2-a9e2-ef8258911ade","AutoScalingGroupName":"mycluster-pool1-Workers-1ABC","Service":"AWS Auto Scaling","Time":"2020-01-01T00:00:00.000Z","EC2InstanceId":"i-0123456789abcdef0","LifecycleTransition":"autoscaling:EC2_INSTANCE_TERMINATING"}`,
+			expected: lifecycle,
+		},
+		{
+			context:  "EventBridgeLifecycleAction",
+			body:     `{"version":"0","id":"x","detail-type":"EC2 Instance-terminate Lifecycle Action","source":"aws.autoscaling","account":"123456789012","time":"2020-01-01T00:00:00Z","region":"us-west-1","resources":[],"detail":{"LifecycleActionToken":"c613620e-07e2-4ed2-a9e2-ef8258911ade","AutoScalingGroupName":"mycluster-pool1-Workers-1ABC","LifecycleHookName":"mycluster-pool1-WorkersNodeDrainerLH-1DEF","EC2InstanceId":"i-0123456789abcdef0","LifecycleTransition":"autoscaling:EC2_INSTANCE_TERMINATING"}}`,
+			expected: lifecycle,
+		},
+		{
+			context:  "EventBridgeSpotInterruption",
+			body:     `{"version":"0","id":"x","detail-type":"EC2 Spot Instance Interruption Warning","source":"aws.ec2","account":"123456789012","region":"us-west-1","resources":[],"detail":{"instance-id":"i-0123456789abcdef0","instance-action":"terminate"}}`,
+			expected: Event{Kind: EventSpotInterruption, InstanceID: "i-0123456789abcdef0"},
+		},
+		{
+			context:  "TestNotification",
+			body:     `{"AccountId":"123456789012","RequestId":"x","AutoScalingGroupARN":"arn","AutoScalingGroupName":"mycluster-pool1-Workers-1ABC","Service":"AWS Auto Scaling","Event":"autoscaling:TEST_NOTIFICATION","Time":"2020-01-01T00:00:00.000Z"}`,
+			expected: Event{Kind: EventIgnored},
+		},
+		{
+			context:  "LaunchingLifecycleAction",
+			body:     `{"LifecycleHookName":"launch","AutoScalingGroupName":"asg","EC2InstanceId":"i-1","LifecycleTransition":"autoscaling:EC2_INSTANCE_LAUNCHING"}`,
+			expected: Event{Kind: EventIgnored},
+		},
+		{
+			context:  "OtherEvent",
+			body:     `{"detail-type":"EC2 Instance State-change Notification","detail":{"instance-id":"i-1","state":"running"}}`,
+			expected: Event{Kind: EventIgnored},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.context, func(t *testing.T) {
+			e, err := ParseEvent(tc.body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, e)
+		})
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"detail-type":"EC2 Spot Instance Interruption Warning","detail":{}}`,
+		`{"LifecycleTransition":"autoscaling:EC2_INSTANCE_TERMINATING","EC2InstanceId":"i-1"}`,
+	} {
+		_, err := ParseEvent(body)
+		assert.Error(t, err, body)
+	}
+}
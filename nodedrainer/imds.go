@@ -0,0 +1,126 @@
+package nodedrainer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIMDSEndpoint is the base URL of the instance metadata service
+	DefaultIMDSEndpoint = "http://169.254.169.254/latest"
+
+	imdsTokenTTL = 6 * time.Hour
+	// imdsTokenRetryInterval is how long IMDSv1 is used after a session token couldn't be obtained
+	imdsTokenRetryInterval = 10 * time.Minute
+)
+
+type metadataService interface {
+	// GetMetadata returns the instance metadata at the path under `meta-data/`, or false when it doesn't exist
+	GetMetadata(path string) (string, bool, error)
+}
+
+// IMDSClient retrieves instance metadata with session tokens so that it works regardless of whether IMDSv2 is enforced.
+// Responses to session token requests don't reach pods outside the host network when the hop limit of the instance is 1,
+// so that it falls back to IMDSv1 for a while when a session token can't be obtained
+type IMDSClient struct {
+	Endpoint string
+
+	client *http.Client
+	now    func() time.Time
+
+	mu         sync.Mutex
+	token      string
+	expiresAt  time.Time
+	v1RetryAt  time.Time
+	v1TokenErr error
+}
+
+func NewIMDSClient() *IMDSClient {
+	return &IMDSClient{
+		Endpoint: DefaultIMDSEndpoint,
+		client:   &http.Client{Timeout: 2 * time.Second},
+		now:      time.Now,
+	}
+}
+
+func (c *IMDSClient) GetMetadata(path string) (string, bool, error) {
+	token, tokenErr := c.sessionToken()
+
+	req, err := http.NewRequest(http.MethodGet, c.Endpoint+"/meta-data/"+path, nil)
+	if err != nil {
+		return "", false, err
+	}
+	if tokenErr == nil {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get instance metadata %s: %v", path, err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read instance metadata %s: %v", path, err)
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return string(body), true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	case http.StatusUnauthorized:
+		if tokenErr != nil {
+			// IMDSv2 is enforced, which requires the hop limit to be 2 or greater for pods outside the host network
+			return "", false, fmt.Errorf("failed to get instance metadata %s with IMDSv1: %s. IMDSv2 is enforced but a session token couldn't be obtained, "+
+				"which requires `httpPutResponseHopLimit` of 2 or greater: %v", path, res.Status, tokenErr)
+		}
+		// The token has been invalidated before its expiration e.g. by a restart of IMDS
+		c.mu.Lock()
+		c.token = ""
+		c.mu.Unlock()
+	}
+	return "", false, fmt.Errorf("failed to get instance metadata %s: %s", path, res.Status)
+}
+
+// sessionToken returns the cached session token, which is renewed a minute before it expires
+func (c *IMDSClient) sessionToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.now().Add(time.Minute).Before(c.expiresAt) {
+		return c.token, nil
+	}
+	if c.now().Before(c.v1RetryAt) {
+		return "", c.v1TokenErr
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.Endpoint+"/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", strconv.Itoa(int(imdsTokenTTL/time.Second)))
+	res, err := c.client.Do(req)
+	if err != nil {
+		// The response doesn't reach this pod e.g. due to the hop limit. Don't wait for the timeout on every request
+		c.v1RetryAt = c.now().Add(imdsTokenRetryInterval)
+		c.v1TokenErr = fmt.Errorf("failed to obtain instance metadata session token: %v", err)
+		return "", c.v1TokenErr
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read instance metadata session token: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to obtain instance metadata session token: %s", res.Status)
+	}
+
+	c.token = string(body)
+	c.expiresAt = c.now().Add(imdsTokenTTL)
+	return c.token, nil
+}
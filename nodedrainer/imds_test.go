@@ -0,0 +1,83 @@
+package nodedrainer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIMDSClient(t *testing.T) {
+	tokenRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			assert.Equal(t, "21600", r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			tokenRequests++
+			w.Write([]byte("token"))
+		case r.Header.Get("X-aws-ec2-metadata-token") != "token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/latest/meta-data/spot/instance-action":
+			w.Write([]byte(`{"action": "terminate", "time": "2020-01-01T00:02:00Z"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewIMDSClient()
+	c.Endpoint = server.URL + "/latest"
+
+	action, found, err := c.GetMetadata(spotInstanceActionPath)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, `{"action": "terminate", "time": "2020-01-01T00:02:00Z"}`, action)
+
+	_, found, err = c.GetMetadata(rebalanceRecommendationPath)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	assert.Equal(t, 1, tokenRequests, "the session token must be reused until it expires")
+}
+
+func TestIMDSClientFallsBackToIMDSv1(t *testing.T) {
+	for _, enforced := range []bool{false, true} {
+		tokenRequests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPut:
+				// Drop the connection as the response to a pod beyond the hop limit never arrives
+				tokenRequests++
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				conn.Close()
+			case enforced:
+				w.WriteHeader(http.StatusUnauthorized)
+			case r.URL.Path == "/latest/meta-data/spot/instance-action":
+				w.Write([]byte(`{"action": "stop", "time": "2020-01-01T00:02:00Z"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		c := NewIMDSClient()
+		c.Endpoint = server.URL + "/latest"
+
+		action, found, err := c.GetMetadata(spotInstanceActionPath)
+		if enforced {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "httpPutResponseHopLimit")
+		} else {
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, `{"action": "stop", "time": "2020-01-01T00:02:00Z"}`, action)
+		}
+
+		_, _, _ = c.GetMetadata(rebalanceRecommendationPath)
+		assert.Equal(t, 1, tokenRequests, "a session token must not be requested again until the retry interval passes")
+
+		server.Close()
+	}
+}
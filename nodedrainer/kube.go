@@ -0,0 +1,234 @@
+package nodedrainer
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/kubeclient"
+)
+
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// KubeClient is the set of Kubernetes API operations the drainer relies on.
+// It is satisfied by *kubeclient.Client and can be faked in tests
+type KubeClient interface {
+	kubeclient.Interface
+	Do(method, path string, body interface{}, out interface{}) error
+	Patch(path string, patch interface{}, out interface{}) error
+}
+
+type objectMeta struct {
+	Name            string            `json:"name,omitempty"`
+	GenerateName    string            `json:"generateName,omitempty"`
+	Namespace       string            `json:"namespace,omitempty"`
+	UID             string            `json:"uid,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+	OwnerReferences []ownerReference  `json:"ownerReferences,omitempty"`
+}
+
+type ownerReference struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Controller *bool  `json:"controller,omitempty"`
+}
+
+type node struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		ProviderID    string `json:"providerID"`
+		Unschedulable bool   `json:"unschedulable"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []nodeCondition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+type nodeCondition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+func (n node) ready() bool {
+	for _, c := range n.Status.Conditions {
+		if c.Type == "Ready" {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+// instanceID returns the ID of the EC2 instance from the provider ID, e.g. aws:///us-west-2a/i-0123456789abcdef0
+func (n node) instanceID() string {
+	return n.Spec.ProviderID[strings.LastIndex(n.Spec.ProviderID, "/")+1:]
+}
+
+type nodeList struct {
+	Items []node `json:"items"`
+}
+
+type pod struct {
+	Metadata objectMeta `json:"metadata"`
+	Status   struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+func (p pod) String() string {
+	return p.Metadata.Namespace + "/" + p.Metadata.Name
+}
+
+// evictable returns false for pods which are recreated on the node right after eviction, or can't be evicted at all
+func (p pod) evictable() bool {
+	if _, ok := p.Metadata.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	for _, o := range p.Metadata.OwnerReferences {
+		if o.Kind == "DaemonSet" && o.Controller != nil && *o.Controller {
+			return false
+		}
+	}
+	return p.Status.Phase != "Succeeded" && p.Status.Phase != "Failed"
+}
+
+type podList struct {
+	Items []pod `json:"items"`
+}
+
+type eviction struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+}
+
+// findNode returns the node whose provider ID points to the EC2 instance, or nil when the instance isn't a node of the cluster
+func findNode(client KubeClient, instanceID string) (*node, error) {
+	nodes := nodeList{}
+	if err := client.Get("/api/v1/nodes", &nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	for i, n := range nodes.Items {
+		if n.instanceID() == instanceID {
+			return &nodes.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ReadyInstances returns the IDs of the EC2 instances among instanceIDs whose nodes are registered and Ready
+func ReadyInstances(client KubeClient, instanceIDs []string) ([]string, error) {
+	nodes := nodeList{}
+	if err := client.Get("/api/v1/nodes", &nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	ready := map[string]bool{}
+	for _, n := range nodes.Items {
+		if n.ready() {
+			ready[n.instanceID()] = true
+		}
+	}
+	ids := []string{}
+	for _, id := range instanceIDs {
+		if ready[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func cordon(client KubeClient, name string) error {
+	patch := map[string]interface{}{"spec": map[string]interface{}{"unschedulable": true}}
+	if err := client.Patch("/api/v1/nodes/"+name, patch, nil); err != nil {
+		return fmt.Errorf("failed to cordon node %s: %v", name, err)
+	}
+	return nil
+}
+
+func listPodsOn(client KubeClient, nodeName string) ([]pod, error) {
+	pods := podList{}
+	if err := client.Get("/api/v1/pods?fieldSelector="+url.QueryEscape("spec.nodeName="+nodeName), &pods); err != nil {
+		return nil, fmt.Errorf("failed to list pods on node %s: %v", nodeName, err)
+	}
+	return pods.Items, nil
+}
+
+// evict requests the eviction of the pod, which is rejected with 429 Too Many Requests while it would violate a PodDisruptionBudget
+func evict(client KubeClient, p pod) error {
+	body := eviction{
+		APIVersion: "policy/v1beta1",
+		Kind:       "Eviction",
+		Metadata:   objectMeta{Name: p.Metadata.Name, Namespace: p.Metadata.Namespace},
+	}
+	return client.Do(http.MethodPost, podPath(p)+"/eviction", body, nil)
+}
+
+// deleted returns true when the pod is gone, or replaced by another pod with the same name
+func deleted(client KubeClient, p pod) (bool, error) {
+	current := pod{}
+	if err := client.Get(podPath(p), &current); err != nil {
+		if kubeclient.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return current.Metadata.UID != p.Metadata.UID, nil
+}
+
+func podPath(p pod) string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/pods/%s", p.Metadata.Namespace, p.Metadata.Name)
+}
+
+type objectReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	UID  string `json:"uid,omitempty"`
+}
+
+type event struct {
+	APIVersion     string          `json:"apiVersion"`
+	Kind           string          `json:"kind"`
+	Metadata       objectMeta      `json:"metadata"`
+	InvolvedObject objectReference `json:"involvedObject"`
+	Reason         string          `json:"reason"`
+	Message        string          `json:"message"`
+	Type           string          `json:"type"`
+	Source         struct {
+		Component string `json:"component"`
+		Host      string `json:"host"`
+	} `json:"source"`
+	FirstTimestamp string `json:"firstTimestamp"`
+	LastTimestamp  string `json:"lastTimestamp"`
+	Count          int    `json:"count"`
+}
+
+func getNode(client KubeClient, name string) (*node, error) {
+	n := node{}
+	if err := client.Get("/api/v1/nodes/"+name, &n); err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %v", name, err)
+	}
+	return &n, nil
+}
+
+// recordEvent creates an event about the node, which is shown by `kubectl describe node`
+func recordEvent(client KubeClient, component string, n *node, eventType, reason, message string, now time.Time) error {
+	e := event{
+		APIVersion:     "v1",
+		Kind:           "Event",
+		Metadata:       objectMeta{GenerateName: n.Metadata.Name + ".", Namespace: "default"},
+		InvolvedObject: objectReference{Kind: "Node", Name: n.Metadata.Name, UID: n.Metadata.UID},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		FirstTimestamp: now.UTC().Format(time.RFC3339),
+		LastTimestamp:  now.UTC().Format(time.RFC3339),
+		Count:          1,
+	}
+	e.Source.Component = component
+	e.Source.Host = n.Metadata.Name
+	if err := client.Do(http.MethodPost, "/api/v1/namespaces/default/events", e, nil); err != nil {
+		return fmt.Errorf("failed to record event %s of node %s: %v", reason, n.Metadata.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package nodedrainer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Metrics counts what the spot interruption handler observed and did, and serves the counts in the Prometheus text format
+type Metrics struct {
+	NodeName string
+
+	mu                       sync.Mutex
+	interruptionNotices      int
+	rebalanceRecommendations int
+	drainsSucceeded          int
+	drainsFailed             int
+}
+
+func (m *Metrics) incInterruptionNotices() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interruptionNotices++
+}
+
+func (m *Metrics) incRebalanceRecommendations() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rebalanceRecommendations++
+}
+
+func (m *Metrics) incDrains(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.drainsFailed++
+	} else {
+		m.drainsSucceeded++
+	}
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.write(w)
+}
+
+// write writes the metrics in the Prometheus text exposition format
+func (m *Metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counter := func(name, help string, samples ...string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, s := range samples {
+			fmt.Fprintf(w, "%s%s\n", name, s)
+		}
+	}
+	counter("kube_aws_spot_interruption_notices_total", "Number of spot instance interruption notices received",
+		fmt.Sprintf(`{node=%q} %d`, m.NodeName, m.interruptionNotices))
+	counter("kube_aws_spot_rebalance_recommendations_total", "Number of EC2 instance rebalance recommendations received",
+		fmt.Sprintf(`{node=%q} %d`, m.NodeName, m.rebalanceRecommendations))
+	counter("kube_aws_spot_node_drains_total", "Number of drains of the node triggered by interruption notices or rebalance recommendations",
+		fmt.Sprintf(`{node=%q,result="succeeded"} %d`, m.NodeName, m.drainsSucceeded),
+		fmt.Sprintf(`{node=%q,result="failed"} %d`, m.NodeName, m.drainsFailed))
+}
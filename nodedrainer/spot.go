@@ -0,0 +1,167 @@
+package nodedrainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kubernetes-incubator/kube-aws/logger"
+)
+
+const (
+	// DefaultSpotDrainTimeout leaves a few seconds of the two-minute interruption notice before the instance is interrupted
+	DefaultSpotDrainTimeout = 110 * time.Second
+	// DefaultSpotPollInterval is the interval of polling the instance metadata, which is updated every few seconds
+	DefaultSpotPollInterval = 5 * time.Second
+
+	spotInstanceActionPath      = "spot/instance-action"
+	rebalanceRecommendationPath = "events/recommendations/rebalance"
+
+	spotInterruptionHandlerComponent = "kube-spot-interruption-handler"
+)
+
+type SpotHandlerConfig struct {
+	// NodeName is the name of the node the handler runs on
+	NodeName     string
+	DrainTimeout time.Duration
+	PollInterval time.Duration
+	// DrainOnRebalanceRecommendation drains the node on a rebalance recommendation instead of waiting for the interruption notice
+	DrainOnRebalanceRecommendation bool
+}
+
+// SpotHandler polls the instance metadata of the node it runs on for spot instance interruption notices and EC2 instance
+// rebalance recommendations. They are recorded as events of the node and counted in Metrics
+type SpotHandler struct {
+	SpotHandlerConfig
+	Metrics *Metrics
+
+	kube     KubeClient
+	metadata metadataService
+	drainer  *Drainer
+
+	now   func() time.Time
+	sleep func(time.Duration)
+
+	node                 *node
+	interrupted          bool
+	rebalanceRecommended bool
+	drained              bool
+}
+
+type spotInstanceAction struct {
+	Action string `json:"action"`
+	Time   string `json:"time"`
+}
+
+func NewSpotHandler(config SpotHandlerConfig, kube KubeClient, metadata metadataService) *SpotHandler {
+	if config.DrainTimeout == 0 {
+		config.DrainTimeout = DefaultSpotDrainTimeout
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = DefaultSpotPollInterval
+	}
+	return &SpotHandler{
+		SpotHandlerConfig: config,
+		Metrics:           &Metrics{NodeName: config.NodeName},
+		kube:              kube,
+		metadata:          metadata,
+		// Evicted pods have only two minutes to terminate, so check them more often than the node drainer does
+		drainer: New(Config{DrainTimeout: config.DrainTimeout, PollInterval: time.Second}, kube, nil, nil),
+		now:     time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+// Run polls the instance metadata until stop is closed
+func (h *SpotHandler) Run(stop <-chan struct{}) error {
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		if err := h.Poll(); err != nil {
+			logger.Errorf("%v", err)
+		}
+		h.sleep(h.PollInterval)
+	}
+}
+
+// Poll checks the instance metadata once. Interruption notices and rebalance recommendations are handled only the first time they are seen
+func (h *SpotHandler) Poll() error {
+	if h.node == nil {
+		n, err := getNode(h.kube, h.NodeName)
+		if err != nil {
+			return err
+		}
+		h.node = n
+	}
+
+	if !h.interrupted {
+		body, found, err := h.metadata.GetMetadata(spotInstanceActionPath)
+		if err != nil {
+			return err
+		}
+		if found {
+			h.interrupted = true
+			h.Metrics.incInterruptionNotices()
+
+			action := spotInstanceAction{}
+			if err := json.Unmarshal([]byte(body), &action); err != nil {
+				logger.Warnf("failed to parse spot instance interruption notice %q: %v", body, err)
+			}
+			h.record("Warning", "SpotInterruption", fmt.Sprintf("Spot instance interruption notice received: the instance is going to %s at %s", action.Action, action.Time))
+			return h.drain()
+		}
+	}
+
+	if !h.rebalanceRecommended {
+		_, found, err := h.metadata.GetMetadata(rebalanceRecommendationPath)
+		if err != nil {
+			return err
+		}
+		if found {
+			h.rebalanceRecommended = true
+			h.Metrics.incRebalanceRecommendations()
+
+			h.record("Warning", "RebalanceRecommendation", "EC2 instance rebalance recommendation received: the instance is at an elevated risk of interruption")
+			if h.DrainOnRebalanceRecommendation {
+				return h.drain()
+			}
+		}
+	}
+
+	return nil
+}
+
+// drain cordons and drains the node unless it has already been drained
+func (h *SpotHandler) drain() error {
+	if h.drained {
+		return nil
+	}
+	name := h.node.Metadata.Name
+
+	logger.Infof("draining node %s", name)
+	if err := cordon(h.kube, name); err != nil {
+		return err
+	}
+	h.drained = true
+
+	err := h.drainer.Drain(name)
+	h.Metrics.incDrains(err)
+	if err != nil {
+		h.record("Warning", "DrainFailed", fmt.Sprintf("Failed to drain node: %v", err))
+		return fmt.Errorf("giving up draining node %s: %v", name, err)
+	}
+	h.record("Normal", "Drained", "Drained node before the instance is interrupted")
+	logger.Infof("drained node %s", name)
+	return nil
+}
+
+// record creates an event of the node. Failures are only logged as events are informational
+func (h *SpotHandler) record(eventType, reason, message string) {
+	if err := recordEvent(h.kube, spotInterruptionHandlerComponent, h.node, eventType, reason, message, h.now()); err != nil {
+		logger.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package nodedrainer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMetadata map[string]string
+
+func (m fakeMetadata) GetMetadata(path string) (string, bool, error) {
+	v, ok := m[path]
+	return v, ok, nil
+}
+
+func newTestSpotHandler(kube KubeClient, metadata metadataService, drainOnRebalance bool) *SpotHandler {
+	h := NewSpotHandler(SpotHandlerConfig{NodeName: "ip-10-0-0-1", DrainOnRebalanceRecommendation: drainOnRebalance}, kube, metadata)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+	h.drainer.now = h.now
+	h.drainer.sleep = func(d time.Duration) { now = now.Add(d) }
+	return h
+}
+
+func eventReasons(events []event) []string {
+	reasons := []string{}
+	for _, e := range events {
+		reasons = append(reasons, e.Reason)
+	}
+	return reasons
+}
+
+func TestSpotHandlerInterruption(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.nodes["ip-10-0-0-1"].Metadata.UID = "uid-1"
+	kube.addPod("default", "web-1", "ip-10-0-0-1", nil)
+	metadata := fakeMetadata{}
+
+	h := newTestSpotHandler(kube, metadata, false)
+	require.NoError(t, h.Poll())
+	assert.False(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+	assert.Empty(t, kube.events)
+
+	metadata[spotInstanceActionPath] = `{"action": "terminate", "time": "2020-01-01T00:02:00Z"}`
+	require.NoError(t, h.Poll())
+	assert.True(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+	assert.Equal(t, []string{"default/web-1"}, kube.evicted)
+	assert.Equal(t, []string{"SpotInterruption", "Drained"}, eventReasons(kube.events))
+
+	e := kube.events[0]
+	assert.Equal(t, "Warning", e.Type)
+	assert.Equal(t, "Spot instance interruption notice received: the instance is going to terminate at 2020-01-01T00:02:00Z", e.Message)
+	assert.Equal(t, objectReference{Kind: "Node", Name: "ip-10-0-0-1", UID: "uid-1"}, e.InvolvedObject)
+	assert.Equal(t, "ip-10-0-0-1.", e.Metadata.GenerateName)
+	assert.Equal(t, spotInterruptionHandlerComponent, e.Source.Component)
+
+	// The notice stays in the instance metadata until the instance is interrupted
+	require.NoError(t, h.Poll())
+	assert.Len(t, kube.events, 2)
+
+	buf := &bytes.Buffer{}
+	h.Metrics.write(buf)
+	assert.Contains(t, buf.String(), `kube_aws_spot_interruption_notices_total{node="ip-10-0-0-1"} 1`)
+	assert.Contains(t, buf.String(), `kube_aws_spot_node_drains_total{node="ip-10-0-0-1",result="succeeded"} 1`)
+	assert.Contains(t, buf.String(), `kube_aws_spot_node_drains_total{node="ip-10-0-0-1",result="failed"} 0`)
+}
+
+func TestSpotHandlerRebalanceRecommendation(t *testing.T) {
+	for _, drain := range []bool{false, true} {
+		kube := newFakeKubeClient()
+		kube.addNode("ip-10-0-0-1", "i-1")
+		kube.addPod("default", "web-1", "ip-10-0-0-1", nil)
+		metadata := fakeMetadata{rebalanceRecommendationPath: `{"noticeTime": "2020-01-01T00:00:00Z"}`}
+
+		h := newTestSpotHandler(kube, metadata, drain)
+		require.NoError(t, h.Poll())
+		require.NoError(t, h.Poll())
+
+		buf := &bytes.Buffer{}
+		h.Metrics.write(buf)
+		assert.Contains(t, buf.String(), `kube_aws_spot_rebalance_recommendations_total{node="ip-10-0-0-1"} 1`)
+
+		if drain {
+			assert.True(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+			assert.Equal(t, []string{"RebalanceRecommendation", "Drained"}, eventReasons(kube.events))
+		} else {
+			assert.False(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+			assert.Empty(t, kube.evicted)
+			assert.Equal(t, []string{"RebalanceRecommendation"}, eventReasons(kube.events))
+		}
+
+		// The interruption notice following a recommendation doesn't drain the node twice
+		metadata[spotInstanceActionPath] = `{"action": "terminate", "time": "2020-01-01T00:02:00Z"}`
+		require.NoError(t, h.Poll())
+		assert.True(t, kube.nodes["ip-10-0-0-1"].Spec.Unschedulable)
+		assert.Equal(t, []string{"default/web-1"}, kube.evicted)
+	}
+}
+
+func TestSpotHandlerDrainFailure(t *testing.T) {
+	kube := newFakeKubeClient()
+	kube.addNode("ip-10-0-0-1", "i-1")
+	kube.addPod("default", "db-0", "ip-10-0-0-1", nil)
+	kube.pdbRejections["default/db-0"] = 1000
+	metadata := fakeMetadata{spotInstanceActionPath: `{"action": "stop", "time": "2020-01-01T00:02:00Z"}`}
+
+	h := newTestSpotHandler(kube, metadata, false)
+	err := h.Poll()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disallowed by PodDisruptionBudgets")
+	assert.Equal(t, []string{"SpotInterruption", "DrainFailed"}, eventReasons(kube.events))
+
+	buf := &bytes.Buffer{}
+	h.Metrics.write(buf)
+	assert.Contains(t, buf.String(), `kube_aws_spot_node_drains_total{node="ip-10-0-0-1",result="failed"} 1`)
+}
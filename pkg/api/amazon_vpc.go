@@ -33,7 +33,7 @@ declare -A instance_ip_available
 
 	script = script + `
 
-instance_type=$(curl http://169.254.169.254/latest/meta-data/instance-type)
+instance_type=$(/opt/bin/imds meta-data/instance-type)
 
 enis=${instance_eni_available["$instance_type"]}
 
@@ -6,7 +6,7 @@ import (
 )
 
 // APIEndpoint is a Kubernetes API endpoint to which various clients connect.
-// Each endpoint can be served by an existing ELB or a kube-aws managed ELB.
+// Each endpoint can be served by an existing ELB, a kube-aws managed ELB or a DNS round-robin of controller nodes.
 type APIEndpoint struct {
 	// Name is the unique name of this API endpoint used by kube-aws for identifying this API endpoint
 	Name string `yaml:"name,omitempty"`
@@ -16,13 +16,21 @@ type APIEndpoint struct {
 	DNSName string `yaml:"dnsName,omitempty"`
 	// LoadBalancer is a set of an ELB and relevant settings and resources to serve a Kubernetes API hosted by controller nodes
 	LoadBalancer APIEndpointLB `yaml:"loadBalancer,omitempty"`
-	//DNSRoundRobin APIDNSRoundRobin `yaml:"dnsRoundRobin,omitempty"`
-	UnknownKeys `yaml:",inline"`
+	// DNSRoundRobin is the settings of the DNS round-robin of controller nodes serving this endpoint without a load balancer
+	DNSRoundRobin APIDNSRoundRobin `yaml:"dnsRoundRobin,omitempty"`
+	UnknownKeys   `yaml:",inline"`
 }
 
 // Validate returns an error when there's any user error in the `apiEndpoint` settings
 func (e APIEndpoint) Validate() error {
-	if err := e.LoadBalancer.Validate(); err != nil {
+	if e.DNSRoundRobinEnabled() {
+		if e.LoadBalancer.ManageELB() || e.LoadBalancer.Identifier.HasIdentifier() {
+			return errors.New("loadBalancer must be omitted when dnsRoundRobin is specified")
+		}
+		if err := e.DNSRoundRobin.Validate(); err != nil {
+			return fmt.Errorf("invalid dnsRoundRobin: %v", err)
+		}
+	} else if err := e.LoadBalancer.Validate(); err != nil {
 		return fmt.Errorf("invalid loadBalancer: %v", err)
 	}
 	if e.DNSName == "" {
@@ -30,3 +38,8 @@ func (e APIEndpoint) Validate() error {
 	}
 	return nil
 }
+
+// DNSRoundRobinEnabled returns true when this endpoint is served by a DNS round-robin of controller nodes instead of a load balancer
+func (e APIEndpoint) DNSRoundRobinEnabled() bool {
+	return e.DNSRoundRobin.HostedZone.HasIdentifier() || e.DNSRoundRobin.RecordSetTTLSpecified != nil || e.DNSRoundRobin.PrivateSpecified != nil
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultDNSRoundRobinRecordSetTTL is the default value for the dnsRoundRobin.recordSetTTL key.
+// It is shorter than the one for load balancers so that clients stop resolving the DNS name to a deregistered controller node soon
+const DefaultDNSRoundRobinRecordSetTTL = 60
+
+// APIDNSRoundRobin is the settings of an API endpoint served without a load balancer, by a DNS name resolved to all the healthy controller nodes
+type APIDNSRoundRobin struct {
+	// APIAccessAllowedSourceCIDRs is network ranges of sources you'd like Kubernetes API accesses to be allowed from, in CIDR notation
+	APIAccessAllowedSourceCIDRs CIDRRanges `yaml:"apiAccessAllowedSourceCIDRs,omitempty"`
+	// PrivateSpecified determines the resulting DNS round robin uses private IPs of the nodes for an endpoint
+	PrivateSpecified *bool `yaml:"private,omitempty"`
+	// HostedZone is where the resulting A records are created for an endpoint
+	// Beware that kube-aws will never create a hosted zone used for a DNS round-robin because
+	// Doing so would result in CloudFormation to be unable to remove the hosted zone when the stack is deleted
+	HostedZone HostedZone `yaml:"hostedZone,omitempty"`
+	// RecordSetTTLSpecified is the TTL for the A records of controller nodes. Defaults to 60 if nil
+	RecordSetTTLSpecified *int `yaml:"recordSetTTL,omitempty"`
+	// HealthCheck configures how each controller node checks the health of its kube-apiserver before registering its IP,
+	// and deregistering it once it became unhealthy
+	HealthCheck APIEndpointLBHealthCheck `yaml:"healthCheck,omitempty"`
+}
+
+// UnmarshalYAML unmarshals YAML data to an APIDNSRoundRobin object with defaults
+func (r *APIDNSRoundRobin) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type t APIDNSRoundRobin
+	work := t(APIDNSRoundRobin{
+		APIAccessAllowedSourceCIDRs: DefaultCIDRRanges(),
+	})
+	if err := unmarshal(&work); err != nil {
+		return fmt.Errorf("failed to parse API endpoint DNS round-robin config: %v", err)
+	}
+	*r = APIDNSRoundRobin(work)
+	return nil
+}
+
+// Enabled returns true when controller nodes should register themselves to the DNS name of the endpoint
+func (r APIDNSRoundRobin) Enabled() bool {
+	return r.HostedZone.HasIdentifier()
+}
+
+// Private returns true when controller nodes register their private IPs rather than public IPs
+func (r APIDNSRoundRobin) Private() bool {
+	return r.PrivateSpecified != nil && *r.PrivateSpecified
+}
+
+// RecordSetTTL is the TTL for the A records of controller nodes. Defaults to 60 if `recordSetTTL` is omitted
+func (r APIDNSRoundRobin) RecordSetTTL() int {
+	if r.RecordSetTTLSpecified != nil {
+		return *r.RecordSetTTLSpecified
+	}
+	return DefaultDNSRoundRobinRecordSetTTL
+}
+
+// HostedZoneID returns the ID of the hosted zone without the `/hostedzone/` prefix Route 53 APIs may return
+func (r APIDNSRoundRobin) HostedZoneID() string {
+	return strings.TrimPrefix(strings.TrimPrefix(r.HostedZone.ID, "/"), "hostedzone/")
+}
+
+// Validate returns an error when there's any user error in the settings of the `dnsRoundRobin` field
+func (r APIDNSRoundRobin) Validate() error {
+	if !r.Enabled() {
+		return errors.New("hostedZone.id is required for a DNS round-robin")
+	}
+	if r.RecordSetTTL() < 1 {
+		return errors.New("recordSetTTL must be at least 1 second")
+	}
+	if err := r.HealthCheck.validate(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -3,11 +3,28 @@ package api
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // DefaultRecordSetTTL is the default value for the loadBalancer.recordSetTTL key
 const DefaultRecordSetTTL = 300
 
+const (
+	// DefaultHealthCheckHealthyThreshold is the default value for the loadBalancer.healthCheck.healthyThreshold key
+	DefaultHealthCheckHealthyThreshold = 3
+	// DefaultHealthCheckUnhealthyThreshold is the default value for the loadBalancer.healthCheck.unhealthyThreshold key
+	DefaultHealthCheckUnhealthyThreshold = 3
+	// DefaultHealthCheckIntervalSeconds is the default value for the loadBalancer.healthCheck.intervalSeconds key
+	DefaultHealthCheckIntervalSeconds = 10
+	// DefaultHealthCheckTimeoutSeconds is the default value for the loadBalancer.healthCheck.timeoutSeconds key
+	DefaultHealthCheckTimeoutSeconds = 8
+)
+
+// APIServerHealthzProxyPort is the port on controller nodes serving the `/healthz` endpoint of the local kube-apiserver
+// without authentication, so that network load balancers are able to check the health of kube-apiservers.
+// kube-apiserver itself can't serve it as anonymous requests are disabled
+const APIServerHealthzProxyPort = 10280
+
 // APIEndpointLB is a set of an ELB and relevant settings and resources to serve a Kubernetes API hosted by controller nodes
 type APIEndpointLB struct {
 	// APIAccessAllowedSourceCIDRs is network ranges of sources you'd like Kubernetes API accesses to be allowed from, in CIDR notation
@@ -33,6 +50,98 @@ type APIEndpointLB struct {
 	SecurityGroupIds []string `yaml:"securityGroupIds"`
 	// Load balancer type. It is 'classic' by default, but can be changed to 'network'
 	Type *string `yaml:"type,omitempty"`
+	// CrossZoneSpecified determines if the load balancer distributes requests across controller nodes in all the AZs. Defaults to true if nil
+	CrossZoneSpecified *bool `yaml:"crossZone,omitempty"`
+	// AccessLogs configures the load balancer to store its access logs in S3
+	AccessLogs APIEndpointLBAccessLogs `yaml:"accessLogs,omitempty"`
+	// TLS configures a network load balancer to terminate TLS with an ACM certificate
+	TLS APIEndpointLBTLS `yaml:"tls,omitempty"`
+	// HealthCheck configures how the load balancer checks the health of controller nodes
+	HealthCheck APIEndpointLBHealthCheck `yaml:"healthCheck,omitempty"`
+	// SecurityGroupsEnabled creates a network load balancer with security groups, which crossZone, accessLogs, tls, healthCheck and migrateFrom require.
+	// A security group can't be added to a network load balancer created without one e.g. by an older kube-aws,
+	// so that enabling this for an existing endpoint replaces its network load balancer. Classic ELBs always have security groups
+	SecurityGroupsEnabled bool `yaml:"securityGroupsEnabled,omitempty"`
+	// MigrateFrom is the type of the load balancer which served this endpoint before its type was changed to 'network'.
+	// The previous load balancer is kept registered with controller nodes until this is removed, so that
+	// clients which still resolve the DNS name to the previous load balancer aren't disconnected
+	MigrateFrom string `yaml:"migrateFrom,omitempty"`
+}
+
+// APIEndpointLBAccessLogs is the settings of the access logs of an API endpoint load balancer
+type APIEndpointLBAccessLogs struct {
+	Enabled  bool   `yaml:"enabled,omitempty"`
+	S3Bucket string `yaml:"s3Bucket,omitempty"`
+	S3Prefix string `yaml:"s3Prefix,omitempty"`
+	// EmitInterval is the interval in minutes for publishing access logs. Either 5 or 60, and only for classic ELBs
+	EmitInterval int `yaml:"emitInterval,omitempty"`
+}
+
+// APIEndpointLBTLS is the settings of the TLS listener of an API endpoint network load balancer
+type APIEndpointLBTLS struct {
+	CertificateArn string `yaml:"certificateArn,omitempty"`
+	SSLPolicy      string `yaml:"sslPolicy,omitempty"`
+}
+
+// Enabled returns true when the load balancer terminates TLS
+func (t APIEndpointLBTLS) Enabled() bool {
+	return t.CertificateArn != ""
+}
+
+// APIEndpointLBHealthCheck is the settings of the health check of controller nodes behind an API endpoint load balancer
+type APIEndpointLBHealthCheck struct {
+	HealthyThresholdSpecified   int `yaml:"healthyThreshold,omitempty"`
+	UnhealthyThresholdSpecified int `yaml:"unhealthyThreshold,omitempty"`
+	IntervalSecondsSpecified    int `yaml:"intervalSeconds,omitempty"`
+	TimeoutSecondsSpecified     int `yaml:"timeoutSeconds,omitempty"`
+}
+
+// HealthyThreshold is the number of consecutive successful health checks required to consider a controller node healthy
+func (h APIEndpointLBHealthCheck) HealthyThreshold() int {
+	if h.HealthyThresholdSpecified > 0 {
+		return h.HealthyThresholdSpecified
+	}
+	return DefaultHealthCheckHealthyThreshold
+}
+
+// UnhealthyThreshold is the number of consecutive failed health checks required to consider a controller node unhealthy
+func (h APIEndpointLBHealthCheck) UnhealthyThreshold() int {
+	if h.UnhealthyThresholdSpecified > 0 {
+		return h.UnhealthyThresholdSpecified
+	}
+	return DefaultHealthCheckUnhealthyThreshold
+}
+
+// IntervalSeconds is the interval between health checks of a controller node
+func (h APIEndpointLBHealthCheck) IntervalSeconds() int {
+	if h.IntervalSecondsSpecified > 0 {
+		return h.IntervalSecondsSpecified
+	}
+	return DefaultHealthCheckIntervalSeconds
+}
+
+// TimeoutSeconds is the time without a response after which a health check fails
+func (h APIEndpointLBHealthCheck) TimeoutSeconds() int {
+	if h.TimeoutSecondsSpecified > 0 {
+		return h.TimeoutSecondsSpecified
+	}
+	return DefaultHealthCheckTimeoutSeconds
+}
+
+func (h APIEndpointLBHealthCheck) validate() error {
+	if h.HealthyThreshold() < 2 || h.HealthyThreshold() > 10 {
+		return fmt.Errorf("healthCheck.healthyThreshold must be between 2 and 10, but was %d", h.HealthyThreshold())
+	}
+	if h.UnhealthyThreshold() < 2 || h.UnhealthyThreshold() > 10 {
+		return fmt.Errorf("healthCheck.unhealthyThreshold must be between 2 and 10, but was %d", h.UnhealthyThreshold())
+	}
+	if h.IntervalSeconds() < 5 || h.IntervalSeconds() > 300 {
+		return fmt.Errorf("healthCheck.intervalSeconds must be between 5 and 300, but was %d", h.IntervalSeconds())
+	}
+	if h.TimeoutSeconds() < 2 || h.TimeoutSeconds() >= h.IntervalSeconds() {
+		return fmt.Errorf("healthCheck.timeoutSeconds must be at least 2 and less than healthCheck.intervalSeconds(=%d), but was %d", h.IntervalSeconds(), h.TimeoutSeconds())
+	}
+	return nil
 }
 
 // UnmarshalYAML unmarshals YAML data to an APIEndpointLB object with defaults
@@ -73,14 +182,36 @@ func (e APIEndpointLB) NetworkLoadBalancer() bool {
 	return e.Type != nil && *e.Type == "network"
 }
 
+// LegacyNetworkLoadBalancer returns true if the load balancer is a network load balancer without security groups,
+// which is kept as older versions of kube-aws created it so that it isn't replaced
+func (e APIEndpointLB) LegacyNetworkLoadBalancer() bool {
+	return e.NetworkLoadBalancer() && !e.SecurityGroupsEnabled
+}
+
+// CrossZone returns true if the load balancer distributes requests across controller nodes in all the AZs
+func (e APIEndpointLB) CrossZone() bool {
+	return e.CrossZoneSpecified == nil || *e.CrossZoneSpecified
+}
+
+// TerminatesTLS returns true if the load balancer terminates TLS instead of passing it through to kube-apiservers.
+// Clients can't authenticate with their client certificates via such a load balancer
+func (e APIEndpointLB) TerminatesTLS() bool {
+	return e.NetworkLoadBalancer() && e.TLS.Enabled()
+}
+
 // ManageELBRecordSet returns true if kube-aws should create a record set for the ELB
 func (e APIEndpointLB) ManageELBRecordSet() bool {
-	return e.HostedZone.HasIdentifier()
+	return e.hostedZoneSpecified()
+}
+
+// hostedZoneSpecified returns true if the hosted zone is either specified by the user or defaulted to `privateHostedZone`
+func (e APIEndpointLB) hostedZoneSpecified() bool {
+	return e.HostedZone.HasIdentifier() || e.HostedZone.IDFromFn != ""
 }
 
 // ManageSecurityGroup returns true if kube-aws should create a security group for this ELB
 func (e APIEndpointLB) ManageSecurityGroup() bool {
-	return !e.NetworkLoadBalancer() && len(e.APIAccessAllowedSourceCIDRs) > 0
+	return !e.LegacyNetworkLoadBalancer() && len(e.APIAccessAllowedSourceCIDRs) > 0
 }
 
 // Validate returns an error when there's any user error in the settings of the `loadBalancer` field
@@ -109,7 +240,7 @@ func (e APIEndpointLB) Validate() error {
 		return nil
 	}
 
-	if e.HostedZone.HasIdentifier() {
+	if e.hostedZoneSpecified() {
 		if e.RecordSetManaged != nil && !*e.RecordSetManaged {
 			return errors.New("hostedZone.id must be omitted when you want kube-aws not to touch Route53")
 		}
@@ -137,10 +268,76 @@ func (e APIEndpointLB) Validate() error {
 		return errors.New("load balancer type must be either 'classic' or 'network'")
 	}
 
-	if e.NetworkLoadBalancer() {
+	if e.SecurityGroupsEnabled && !e.NetworkLoadBalancer() {
+		return errors.New("securityGroupsEnabled can be specified only for a network load balancer, as a classic ELB always has security groups")
+	}
+
+	if e.LegacyNetworkLoadBalancer() {
+		specified := []string{}
 		if len(e.SecurityGroupIds) > 0 {
-			return errors.New("cannot specify security group IDs for a network load balancer")
+			specified = append(specified, "securityGroupIds")
+		}
+		if e.CrossZoneSpecified != nil {
+			specified = append(specified, "crossZone")
+		}
+		if e.AccessLogs != (APIEndpointLBAccessLogs{}) {
+			specified = append(specified, "accessLogs")
+		}
+		if e.TLS != (APIEndpointLBTLS{}) {
+			specified = append(specified, "tls")
 		}
+		if e.HealthCheck != (APIEndpointLBHealthCheck{}) {
+			specified = append(specified, "healthCheck")
+		}
+		if e.MigrateFrom != "" {
+			specified = append(specified, "migrateFrom")
+		}
+		if len(specified) > 0 {
+			return fmt.Errorf("%s can be specified for a network load balancer only when securityGroupsEnabled is true. "+
+				"Enabling it for an existing endpoint replaces its network load balancer, which `migrateFrom: network` does without disconnecting clients", strings.Join(specified, ", "))
+		}
+	}
+
+	if e.MigrateFrom != "" {
+		if !e.NetworkLoadBalancer() {
+			return errors.New("migrateFrom can be specified only for a network load balancer")
+		}
+		if e.MigrateFrom != "classic" && e.MigrateFrom != "network" {
+			return fmt.Errorf("migrateFrom must be either 'classic' or 'network', but was '%s'", e.MigrateFrom)
+		}
+	}
+
+	if e.TLS.SSLPolicy != "" && !e.TLS.Enabled() {
+		return errors.New("tls.certificateArn must be specified when tls.sslPolicy is specified")
+	}
+
+	if e.TLS.Enabled() {
+		if !e.NetworkLoadBalancer() {
+			return errors.New("tls can be specified only for a network load balancer")
+		}
+		if !strings.HasPrefix(e.TLS.CertificateArn, "arn:") {
+			return fmt.Errorf("tls.certificateArn must be the ARN of an ACM certificate, but was '%s'", e.TLS.CertificateArn)
+		}
+	}
+
+	if e.AccessLogs.Enabled {
+		if e.AccessLogs.S3Bucket == "" {
+			return errors.New("accessLogs.s3Bucket must be specified when access logs are enabled")
+		}
+		if e.NetworkLoadBalancer() {
+			if !e.TLS.Enabled() {
+				return errors.New("a network load balancer writes access logs only for TLS listeners. Specify tls.certificateArn to enable access logs")
+			}
+			if e.AccessLogs.EmitInterval != 0 {
+				return errors.New("accessLogs.emitInterval can't be specified for a network load balancer")
+			}
+		} else if e.AccessLogs.EmitInterval != 0 && e.AccessLogs.EmitInterval != 5 && e.AccessLogs.EmitInterval != 60 {
+			return fmt.Errorf("accessLogs.emitInterval must be either 5 or 60, but was %d", e.AccessLogs.EmitInterval)
+		}
+	}
+
+	if err := e.HealthCheck.validate(); err != nil {
+		return err
 	}
 
 	return nil
@@ -150,7 +347,7 @@ func (e APIEndpointLB) managedELBImplied() bool {
 	return len(e.SubnetReferences) > 0 ||
 		e.explicitlyPrivate() ||
 		e.explicitlyPublic() ||
-		e.HostedZone.HasIdentifier() ||
+		e.hostedZoneSpecified() ||
 		len(e.SecurityGroupIds) > 0 ||
 		e.RecordSetManaged != nil
 }
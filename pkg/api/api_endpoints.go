@@ -58,11 +58,27 @@ func (e APIEndpoints) HasNetworkLoadBalancers() bool {
 	return false
 }
 
-//type APIDNSRoundRobin struct {
-//	// PrivateSpecified determines the resulting DNS round robin uses private IPs of the nodes for an endpoint
-//	PrivateSpecified bool
-//	// HostedZone is where the resulting A records are created for an endpoint
-//      // Beware that kube-aws will never create a hosted zone used for a DNS round-robin because
-//      // Doing so would result in CloudFormation to be unable to remove the hosted zone when the stack is deleted
-//	HostedZone HostedZone
-//}
+// HasDNSRoundRobins returns true if there's any API endpoint served by a DNS round-robin of controller nodes
+func (e APIEndpoints) HasDNSRoundRobins() bool {
+	for _, apiEndpoint := range e {
+		if apiEndpoint.DNSRoundRobinEnabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasHealthzProxy returns true if there's any API endpoint network load balancer checking the health of kube-apiservers via the /healthz proxy on controller nodes
+func (e APIEndpoints) HasHealthzProxy() bool {
+	for _, apiEndpoint := range e {
+		if apiEndpoint.LoadBalancer.NetworkLoadBalancer() && !apiEndpoint.LoadBalancer.LegacyNetworkLoadBalancer() {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthzProxyPort is the port network load balancers check the health of kube-apiservers on controller nodes at
+func (e APIEndpoints) HealthzProxyPort() int {
+	return APIServerHealthzProxyPort
+}
@@ -6,11 +6,16 @@ import (
 
 // Configuration specific to auto scaling groups
 type AutoScalingGroup struct {
-	MinSize                            *int           `yaml:"minSize,omitempty"`
-	MaxSize                            int            `yaml:"maxSize,omitempty"`
-	RollingUpdateMinInstancesInService *int           `yaml:"rollingUpdateMinInstancesInService,omitempty"`
-	MixedInstances                     MixedInstances `yaml:"mixedInstances,omitempty"`
-	UnknownKeys                        `yaml:",inline"`
+	MinSize                            *int            `yaml:"minSize,omitempty"`
+	MaxSize                            int             `yaml:"maxSize,omitempty"`
+	RollingUpdateMinInstancesInService *int            `yaml:"rollingUpdateMinInstancesInService,omitempty"`
+	MixedInstances                     MixedInstances  `yaml:"mixedInstances,omitempty"`
+	WarmPool                           WarmPool        `yaml:"warmPool,omitempty"`
+	InstanceRefresh                    InstanceRefresh `yaml:"instanceRefresh,omitempty"`
+	// ScheduledActions and TargetTrackingPolicies resize the group within minSize and maxSize, or change them on schedule
+	ScheduledActions       []ScheduledAction      `yaml:"scheduledActions,omitempty"`
+	TargetTrackingPolicies []TargetTrackingPolicy `yaml:"targetTrackingPolicies,omitempty"`
+	UnknownKeys            `yaml:",inline"`
 }
 
 func (asg AutoScalingGroup) Validate() error {
@@ -27,7 +32,19 @@ func (asg AutoScalingGroup) Validate() error {
 	if asg.RollingUpdateMinInstancesInService != nil && *asg.RollingUpdateMinInstancesInService < 0 {
 		return fmt.Errorf("`autoScalingGroup.rollingUpdateMinInstancesInService` must be greater than or equal to 0 but was %d", *asg.RollingUpdateMinInstancesInService)
 	}
+	if err := asg.WarmPool.Validate(); err != nil {
+		return err
+	}
+	if err := asg.InstanceRefresh.Validate(); err != nil {
+		return err
+	}
+	if err := asg.validateScalingSettings(); err != nil {
+		return err
+	}
 	if asg.MixedInstances.Enabled {
+		if asg.WarmPool.Enabled {
+			return fmt.Errorf("`autoScalingGroup.warmPool` can't be enabled along with `autoScalingGroup.mixedInstances` because warm pools don't support mixed instances policies")
+		}
 		return asg.MixedInstances.Validate()
 	}
 	return nil
@@ -60,11 +60,26 @@ func TestValidateAsgMixedInstances(t *testing.T) {
 	// Expect error if string fields set to incorrect values
 	a.MixedInstances.OnDemandAllocationStrategy = "invalid-value"
 	err = a.Validate()
-	require.EqualError(t, err, "`mixedInstances.onDemandAllocationStrategy` must be equal to 'prioritized' if specified")
+	require.EqualError(t, err, "`mixedInstances.onDemandAllocationStrategy` must be one of 'prioritized', 'lowest-price' if specified")
 	a.MixedInstances.OnDemandAllocationStrategy = "prioritized"
 	a.MixedInstances.SpotAllocationStrategy = "invalid-value"
 	err = a.Validate()
-	require.EqualError(t, err, "`mixedInstances.spotAllocationStrategy` must be equal to 'lowest-price' if specified")
+	require.EqualError(t, err, "`mixedInstances.spotAllocationStrategy` must be one of 'lowest-price', 'capacity-optimized', 'capacity-optimized-prioritized', 'price-capacity-optimized' if specified")
+
+	// Expect no error for current allocation strategies
+	for _, s := range []string{"capacity-optimized", "capacity-optimized-prioritized", "price-capacity-optimized"} {
+		a.MixedInstances.SpotAllocationStrategy = s
+		require.NoError(t, a.Validate())
+	}
+	a.MixedInstances.OnDemandAllocationStrategy = "lowest-price"
+	require.NoError(t, a.Validate())
+	a.MixedInstances.OnDemandAllocationStrategy = "prioritized"
+
+	// Expect error if spot instance pools are specified for strategies other than lowest-price
+	a.MixedInstances.SpotInstancePools = 2
+	err = a.Validate()
+	require.EqualError(t, err, "`mixedInstances.spotInstancePools` can be specified only when `mixedInstances.spotAllocationStrategy` is 'lowest-price'")
+	a.MixedInstances.SpotInstancePools = 0
 
 	// Expect no error if string fields set to correct values
 	a.MixedInstances.SpotAllocationStrategy = "lowest-price"
@@ -111,3 +126,92 @@ func TestValidateAsgMixedInstances(t *testing.T) {
 	err = a.Validate()
 	require.NoError(t, err)
 }
+
+func TestValidateAsgMixedInstancesOverrides(t *testing.T) {
+	mi := MixedInstances{
+		Enabled: true,
+		Overrides: []InstanceTypeOverride{
+			{InstanceType: "c5.large", WeightedCapacity: 1},
+			{InstanceType: "c5.xlarge", WeightedCapacity: 2},
+		},
+	}
+	require.NoError(t, mi.Validate())
+	require.True(t, mi.HasWeightedCapacity())
+	require.Equal(t, mi.Overrides, mi.InstanceTypeOverrides())
+
+	mi.InstanceTypes = []string{"m5.large"}
+	require.EqualError(t, mi.Validate(), "`mixedInstances.instanceTypes` and `mixedInstances.overrides` can't be specified at the same time")
+
+	mi.InstanceTypes = nil
+	mi.Overrides[1].WeightedCapacity = 1000
+	require.EqualError(t, mi.Validate(), "`mixedInstances.overrides[1].weightedCapacity` (1000) must be in range 1-999 if specified")
+
+	mi.Overrides[1] = InstanceTypeOverride{WeightedCapacity: 2}
+	require.EqualError(t, mi.Validate(), "`mixedInstances.overrides[1].instanceType` must be specified")
+
+	mi = MixedInstances{Enabled: true, InstanceTypes: []string{"t3.medium", "t2.medium"}}
+	require.False(t, mi.HasWeightedCapacity())
+	require.Equal(t, []InstanceTypeOverride{{InstanceType: "t3.medium"}, {InstanceType: "t2.medium"}}, mi.InstanceTypeOverrides())
+}
+
+func TestValidateAsgWarmPool(t *testing.T) {
+	minSize := 2
+	capacity := 1
+	a := AutoScalingGroup{
+		WarmPool: WarmPool{
+			MinSize: &minSize,
+		},
+	}
+
+	// Expect no error if the warm pool is not enabled
+	require.NoError(t, a.Validate())
+
+	// Expect error if minSize exceeds maxGroupPreparedCapacity
+	a.WarmPool.Enabled = true
+	a.WarmPool.MaxGroupPreparedCapacity = &capacity
+	err := a.Validate()
+	require.EqualError(t, err, "`autoScalingGroup.warmPool.minSize` (2) must be less than or equal to `autoScalingGroup.warmPool.maxGroupPreparedCapacity` (1)")
+	capacity = 3
+	require.NoError(t, a.Validate())
+
+	// Expect error if poolState is invalid
+	a.WarmPool.PoolState = "Hibernated"
+	err = a.Validate()
+	require.EqualError(t, err, "`autoScalingGroup.warmPool.poolState` must be one of 'Stopped', 'Running' if specified")
+	a.WarmPool.PoolState = "Running"
+	require.NoError(t, a.Validate())
+
+	// Expect error if mixed instances are enabled as well
+	a.MixedInstances.Enabled = true
+	err = a.Validate()
+	require.EqualError(t, err, "`autoScalingGroup.warmPool` can't be enabled along with `autoScalingGroup.mixedInstances` because warm pools don't support mixed instances policies")
+}
+
+func TestValidateAsgInstanceRefresh(t *testing.T) {
+	minHealthy := 101
+	delay := 3600
+	a := AutoScalingGroup{
+		InstanceRefresh: InstanceRefresh{
+			Enabled:              true,
+			MinHealthyPercentage: &minHealthy,
+		},
+	}
+
+	// Expect error if minHealthyPercentage is out of range
+	err := a.Validate()
+	require.EqualError(t, err, "`autoScalingGroup.instanceRefresh.minHealthyPercentage` must be in range 0-100 but was 101")
+	minHealthy = 90
+	require.NoError(t, a.Validate())
+
+	// Expect error if checkpointDelay is specified without checkpoints
+	a.InstanceRefresh.CheckpointDelay = &delay
+	err = a.Validate()
+	require.EqualError(t, err, "`autoScalingGroup.instanceRefresh.checkpointDelay` can be specified only with `autoScalingGroup.instanceRefresh.checkpointPercentages`")
+
+	// Expect error if checkpoints are not in ascending order
+	a.InstanceRefresh.CheckpointPercentages = []int{50, 20}
+	err = a.Validate()
+	require.EqualError(t, err, "`autoScalingGroup.instanceRefresh.checkpointPercentages` must be in ascending order within range 1-100 but was [50 20]")
+	a.InstanceRefresh.CheckpointPercentages = []int{20, 50, 100}
+	require.NoError(t, a.Validate())
+}
@@ -1,7 +1,8 @@
 package api
 
 type KubernetesAuthentication struct {
-	AWSIAM AWSIAM `yaml:"awsIAM"`
+	AWSIAM           AWSIAM           `yaml:"awsIAM"`
+	UserCertificates UserCertificates `yaml:"userCertificates,omitempty"`
 }
 
 type AWSIAM struct {
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	defaultCanaryTimeout  = 600
+	defaultCanaryInterval = 10
+)
+
+// Canary configures the node pool with the "Canary" NodePoolRollingStrategy, which kube-aws rolls before all the other node pools.
+// The other node pools are rolled only after all the health checks pass. Otherwise the canary node pool is rolled back and the rollout is aborted
+type Canary struct {
+	HealthChecks []CanaryHealthCheck `yaml:"healthChecks,omitempty"`
+	// Timeout is the number of seconds to wait for all the health checks to pass. Defaults to 600
+	Timeout int `yaml:"timeout,omitempty"`
+	// Interval is the number of seconds between attempts of the health checks. Defaults to 10
+	Interval int `yaml:"interval,omitempty"`
+	// DisableRollback keeps the canary node pool as is when the health checks fail, e.g. for investigation
+	DisableRollback bool `yaml:"disableRollback,omitempty"`
+	UnknownKeys     `yaml:",inline"`
+}
+
+// CanaryHealthCheck is run by kube-aws on the machine running `kube-aws apply`, in the directory containing cluster.yaml
+type CanaryHealthCheck struct {
+	Name string `yaml:"name,omitempty"`
+	// Command is run with `sh -c` and passes when it exits with 0, e.g. `kubectl --kubeconfig=kubeconfig get --raw /healthz`
+	Command string `yaml:"command,omitempty"`
+	// HTTPGet passes when the URL responds with the expected status
+	HTTPGet     *HTTPGetHealthCheck `yaml:"httpGet,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+type HTTPGetHealthCheck struct {
+	URL string `yaml:"url,omitempty"`
+	// ExpectedStatus defaults to 200
+	ExpectedStatus int `yaml:"expectedStatus,omitempty"`
+}
+
+func (c Canary) TimeoutOrDefault() int {
+	if c.Timeout == 0 {
+		return defaultCanaryTimeout
+	}
+	return c.Timeout
+}
+
+func (c Canary) IntervalOrDefault() int {
+	if c.Interval == 0 {
+		return defaultCanaryInterval
+	}
+	return c.Interval
+}
+
+func (h HTTPGetHealthCheck) ExpectedStatusOrDefault() int {
+	if h.ExpectedStatus == 0 {
+		return 200
+	}
+	return h.ExpectedStatus
+}
+
+func (c Canary) Validate(path string) error {
+	if len(c.HealthChecks) == 0 {
+		return fmt.Errorf("`%s.canary.healthChecks` must contain at least one health check for the Canary nodePoolRollingStrategy", path)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("`%s.canary.timeout` must be zero or greater but was %d", path, c.Timeout)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("`%s.canary.interval` must be zero or greater but was %d", path, c.Interval)
+	}
+	names := map[string]bool{}
+	for i, h := range c.HealthChecks {
+		if h.Name == "" {
+			return fmt.Errorf("`%s.canary.healthChecks[%d].name` must not be empty", path, i)
+		}
+		if names[h.Name] {
+			return fmt.Errorf("`%s.canary.healthChecks` contains the health check \"%s\" more than once", path, h.Name)
+		}
+		names[h.Name] = true
+
+		if (h.Command == "") == (h.HTTPGet == nil) {
+			return fmt.Errorf("`%s.canary.healthChecks[name=%s]` must have either `command` or `httpGet`", path, h.Name)
+		}
+		if h.HTTPGet != nil {
+			u, err := url.Parse(h.HTTPGet.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("`%s.canary.healthChecks[name=%s].httpGet.url` must be an http or https URL but was \"%s\"", path, h.Name, h.HTTPGet.URL)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestCanaryValidate(t *testing.T) {
+	testCases := []struct {
+		canary  Canary
+		isValid bool
+	}{
+		{
+			canary: Canary{HealthChecks: []CanaryHealthCheck{
+				{Name: "nodes-ready", Command: "kubectl --kubeconfig=kubeconfig wait --for=condition=Ready nodes --all"},
+				{Name: "ingress", HTTPGet: &HTTPGetHealthCheck{URL: "https://example.com/healthz"}},
+			}},
+			isValid: true,
+		},
+		// Invalid, at least one health check is required
+		{
+			canary:  Canary{},
+			isValid: false,
+		},
+		// Invalid, a health check has either command or httpGet
+		{
+			canary:  Canary{HealthChecks: []CanaryHealthCheck{{Name: "both", Command: "true", HTTPGet: &HTTPGetHealthCheck{URL: "http://example.com"}}}},
+			isValid: false,
+		},
+		{
+			canary:  Canary{HealthChecks: []CanaryHealthCheck{{Name: "none"}}},
+			isValid: false,
+		},
+		// Invalid, health checks are named uniquely
+		{
+			canary:  Canary{HealthChecks: []CanaryHealthCheck{{Name: "check", Command: "true"}, {Name: "check", Command: "true"}}},
+			isValid: false,
+		},
+		// Invalid, only http and https URLs are supported
+		{
+			canary:  Canary{HealthChecks: []CanaryHealthCheck{{Name: "tcp", HTTPGet: &HTTPGetHealthCheck{URL: "tcp://example.com:443"}}}},
+			isValid: false,
+		},
+		{
+			canary:  Canary{Timeout: -1, HealthChecks: []CanaryHealthCheck{{Name: "check", Command: "true"}}},
+			isValid: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.canary.Validate("worker.nodePools[name=pool1]")
+		if testCase.isValid && err != nil {
+			t.Errorf("Expected %+v to be valid, but it was not: %v", testCase.canary, err)
+		}
+		if !testCase.isValid && err == nil {
+			t.Errorf("Expected %+v to be invalid, but it was not", testCase.canary)
+		}
+	}
+}
@@ -32,6 +32,10 @@ const (
 	CSIDefaultLivenessProbeImageTag   = "v1.1.0"
 	CSIDefaultNodeDriverRegistrarTag  = "v1.2.0"
 	CSIDefaultAmazonEBSDriverImageTag = "v0.4.0"
+
+	// The default AWS CLI image follows the master branch rather than a release
+	defaultAWSCliImageRepo = "quay.io/coreos/awscli"
+	defaultAWSCliImageTag  = "master"
 )
 
 func NewDefaultCluster() *Cluster {
@@ -264,7 +268,7 @@ func NewDefaultCluster() *Cluster {
 			},
 			CloudFormationStreaming:            true,
 			HyperkubeImage:                     Image{Repo: "k8s.gcr.io/hyperkube-amd64", Tag: KUBERNETES_VERSION, RktPullDocker: true},
-			AWSCliImage:                        Image{Repo: "quay.io/coreos/awscli", Tag: "master", RktPullDocker: false},
+			AWSCliImage:                        Image{Repo: defaultAWSCliImageRepo, Tag: defaultAWSCliImageTag, RktPullDocker: false},
 			ClusterProportionalAutoscalerImage: Image{Repo: "k8s.gcr.io/cluster-proportional-autoscaler-amd64", Tag: "1.5.0", RktPullDocker: false},
 			CoreDnsImage:                       Image{Repo: "coredns/coredns", Tag: "1.5.0", RktPullDocker: false},
 			KubeDnsImage:                       Image{Repo: "k8s.gcr.io/k8s-dns-kube-dns-amd64", Tag: "1.15.2", RktPullDocker: false},
@@ -340,6 +344,8 @@ func (c *Cluster) Load() error {
 
 	c.ConsumeDeprecatedKeys()
 
+	c.usePrivateHostedZone()
+
 	if err := c.validate(cpStackName); err != nil {
 		return fmt.Errorf("invalid cluster: %v", err)
 	}
@@ -376,6 +382,10 @@ func (c *Cluster) SetDefaults() error {
 		if s.Name == "" {
 			c.Subnets[i].Name = fmt.Sprintf("Subnet%d", i)
 		}
+		if c.IPv6.Enabled && s.IPv6CIDRIndex == nil {
+			index := i
+			c.Subnets[i].IPv6CIDRIndex = &index
+		}
 	}
 
 	for i, s := range c.Controller.Subnets {
@@ -460,6 +470,7 @@ type KubeClusterSettings struct {
 	DNSServiceIP string `yaml:"dnsServiceIP,omitempty"`
 	PodCIDR      string `yaml:"podCIDR,omitempty"`
 	ServiceCIDR  string `yaml:"serviceCIDR,omitempty"`
+	IPv6         IPv6   `yaml:"ipv6,omitempty"`
 }
 
 // Part of configuration which can't be provided via user input but is computed from user input
@@ -479,19 +490,21 @@ type ComputedDeploymentSettings struct {
 // Though it is highly configurable, it's basically users' responsibility to provide `correct` values if they're going beyond the defaults.
 type DeploymentSettings struct {
 	ComputedDeploymentSettings
-	CloudFormation                        CloudFormation  `yaml:"cloudformation,omitempty"`
-	ClusterName                           string          `yaml:"clusterName,omitempty"`
-	S3URI                                 string          `yaml:"s3URI,omitempty"`
-	DisableContainerLinuxAutomaticUpdates bool            `yaml:"disableContainerLinuxAutomaticUpdates,omitempty"`
-	KeyName                               string          `yaml:"keyName,omitempty"`
-	Region                                Region          `yaml:",inline"`
-	AvailabilityZone                      string          `yaml:"availabilityZone,omitempty"`
-	ReleaseChannel                        string          `yaml:"releaseChannel,omitempty"`
-	AmiId                                 string          `yaml:"amiId,omitempty"`
-	DeprecatedVPCID                       string          `yaml:"vpcId,omitempty"`
-	VPC                                   VPC             `yaml:"vpc,omitempty"`
-	DeprecatedInternetGatewayID           string          `yaml:"internetGatewayId,omitempty"`
-	InternetGateway                       InternetGateway `yaml:"internetGateway,omitempty"`
+	CloudFormation                        CloudFormation    `yaml:"cloudformation,omitempty"`
+	ClusterName                           string            `yaml:"clusterName,omitempty"`
+	S3URI                                 string            `yaml:"s3URI,omitempty"`
+	DisableContainerLinuxAutomaticUpdates bool              `yaml:"disableContainerLinuxAutomaticUpdates,omitempty"`
+	KeyName                               string            `yaml:"keyName,omitempty"`
+	Region                                Region            `yaml:",inline"`
+	AvailabilityZone                      string            `yaml:"availabilityZone,omitempty"`
+	ReleaseChannel                        string            `yaml:"releaseChannel,omitempty"`
+	AmiId                                 string            `yaml:"amiId,omitempty"`
+	DeprecatedVPCID                       string            `yaml:"vpcId,omitempty"`
+	VPC                                   VPC               `yaml:"vpc,omitempty"`
+	DeprecatedInternetGatewayID           string            `yaml:"internetGatewayId,omitempty"`
+	InternetGateway                       InternetGateway   `yaml:"internetGateway,omitempty"`
+	PrivateLinks                          PrivateLinks      `yaml:"privateLinks,omitempty"`
+	PrivateHostedZone                     PrivateHostedZone `yaml:"privateHostedZone,omitempty"`
 	// Required for validations like e.g. if instance cidr is contained in vpc cidr
 	VPCCIDR                   string `yaml:"vpcCIDR,omitempty"`
 	InstanceCIDR              string `yaml:"instanceCIDR,omitempty"`
@@ -579,6 +592,8 @@ type Cluster struct {
 	CustomSettings              map[string]interface{}  `yaml:"customSettings,omitempty"`
 	KubeResourcesAutosave       `yaml:"kubeResourcesAutosave,omitempty"`
 	OpenICMP                    bool `yaml:"openICMP,omitempty"`
+	// SecretBackend configures where plaintext credentials are kept instead of the credentials directory
+	SecretBackend SecretBackend `yaml:"secretBackend,omitempty"`
 }
 
 type WaitSignal struct {
@@ -672,17 +687,22 @@ func (c Cluster) ExternalDNSNames() []string {
 	return names
 }
 
-// APIAccessAllowedSourceCIDRsForControllerSG returns all the CIDRs of Kubernetes API endpoints that controller nodes must allow access from
+// APIAccessAllowedSourceCIDRsForControllerSG returns all the CIDRs of Kubernetes API endpoints that controller nodes must allow access from,
+// which are those of network load balancers preserving client IPs and DNS round-robins exposing controller nodes directly
 func (c Cluster) APIAccessAllowedSourceCIDRsForControllerSG() []string {
 	cidrs := []string{}
 	seen := map[string]bool{}
 
 	for _, e := range c.APIEndpointConfigs {
-		if !e.LoadBalancer.NetworkLoadBalancer() {
+		var ranges CIDRRanges
+		if e.DNSRoundRobinEnabled() {
+			ranges = e.DNSRoundRobin.APIAccessAllowedSourceCIDRs
+		} else if e.LoadBalancer.NetworkLoadBalancer() {
+			ranges = e.LoadBalancer.APIAccessAllowedSourceCIDRs
+		} else {
 			continue
 		}
 
-		ranges := e.LoadBalancer.APIAccessAllowedSourceCIDRs
 		if len(ranges) > 0 {
 			for _, r := range ranges {
 				val := r.String()
@@ -725,12 +745,12 @@ func (c Cluster) validate(cpStackName string) error {
 		vpcNet = deploymentValidationResult.vpcNet
 	}
 
-	_, podNet, err := net.ParseCIDR(c.PodCIDR)
+	podNet, err := netutil.ParseIPv4CIDR(c.PodCIDR)
 	if err != nil {
 		return fmt.Errorf("invalid podCIDR: %v", err)
 	}
 
-	_, serviceNet, err := net.ParseCIDR(c.ServiceCIDR)
+	serviceNet, err := netutil.ParseIPv4CIDR(c.ServiceCIDR)
 	if err != nil {
 		return fmt.Errorf("invalid serviceCIDR: %v", err)
 	}
@@ -757,6 +777,30 @@ func (c Cluster) validate(cpStackName string) error {
 		return fmt.Errorf("dnsServiceIp conflicts with kubernetesServiceIp (%s)", dnsServiceIPAddr)
 	}
 
+	if err := c.validateIPv6(); err != nil {
+		return err
+	}
+
+	if err := c.validatePrivateLinks(); err != nil {
+		return err
+	}
+
+	if err := c.PrivateHostedZone.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.validateSecretBackend(); err != nil {
+		return err
+	}
+
+	if err := c.validateEncryptionAtRest(); err != nil {
+		return err
+	}
+
+	if err := c.validateSecurityGroupRules(vpcNet); err != nil {
+		return err
+	}
+
 	if err := c.Controller.Validate(); err != nil {
 		return err
 	}
@@ -769,6 +813,14 @@ func (c Cluster) validate(cpStackName string) error {
 		return err
 	}
 
+	if err := c.Controller.MetadataOptions.ValidateAWSCliImage("controller", c.AWSCliImage); err != nil {
+		return err
+	}
+
+	if err := c.Etcd.MetadataOptions.ValidateAWSCliImage("etcd", c.AWSCliImage); err != nil {
+		return err
+	}
+
 	if c.WorkerTenancy != "default" && c.WorkerSpotPrice != "" {
 		return fmt.Errorf("selected worker tenancy (%s) is incompatible with spot instances", c.WorkerTenancy)
 	}
@@ -782,6 +834,10 @@ func (c Cluster) validate(cpStackName string) error {
 		return fmt.Errorf("awsNodeLabels can't be enabled for controllers because the total number of characters in clusterName(=\"%s\") exceeds the limit of %d", c.ClusterName, limit)
 	}
 
+	if c.Experimental.NodeDrainer.Enabled && len(c.NodeDrainerQueueName()) > 80 {
+		return fmt.Errorf("nodeDrainer can't be enabled because the name of its SQS queue \"%s\" derived from clusterName exceeds the limit of 80 characters", c.NodeDrainerQueueName())
+	}
+
 	if c.Controller.InstanceType == "t2.micro" || c.Etcd.InstanceType == "t2.micro" || c.Controller.InstanceType == "t2.nano" || c.Etcd.InstanceType == "t2.nano" {
 		logger.Warn(`instance types "t2.nano" and "t2.micro" are not recommended. See https://github.com/kubernetes-incubator/kube-aws/issues/258 for more information`)
 	}
@@ -801,6 +857,25 @@ func (c Cluster) validate(cpStackName string) error {
 		if err := w.Validate(c.Experimental); err != nil {
 			return err
 		}
+
+		awsCliImage := w.AWSCliImage
+		awsCliImage.MergeIfEmpty(c.AWSCliImage)
+		if err := w.MetadataOptions.ValidateAWSCliImage(fmt.Sprintf("worker.nodePools[name=%s]", w.NodePoolName), awsCliImage); err != nil {
+			return err
+		}
+	}
+
+	if c.Worker.NodePoolRollingStrategy == "Canary" {
+		return errors.New("`worker.nodePoolRollingStrategy` can't be Canary. Set `nodePoolRollingStrategy: Canary` to the node pool rolled first instead")
+	}
+	canaries := []string{}
+	for _, w := range c.Worker.NodePools {
+		if w.NodePoolRollingStrategy == "Canary" {
+			canaries = append(canaries, w.NodePoolName)
+		}
+	}
+	if len(canaries) > 1 {
+		return fmt.Errorf("only one node pool can use the Canary nodePoolRollingStrategy but %s do", strings.Join(canaries, ", "))
 	}
 
 	for i, e := range c.APIEndpointConfigs {
@@ -816,6 +891,10 @@ func (c Cluster) validate(cpStackName string) error {
 		return fmt.Errorf("networkingdaemonsets - you can only enable typha when deploying type 'canal'")
 	}
 
+	if err := c.Kubernetes.Authentication.UserCertificates.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -845,6 +924,10 @@ func (c DefaultWorkerSettings) Validate() error {
 		if c.WorkerRootVolumeIOPS < 100 || c.WorkerRootVolumeIOPS > 20000 {
 			return fmt.Errorf("invalid workerRootVolumeIOPS: %d", c.WorkerRootVolumeIOPS)
 		}
+	} else if c.WorkerRootVolumeType == "gp3" {
+		if c.WorkerRootVolumeIOPS != 0 && (c.WorkerRootVolumeIOPS < 3000 || c.WorkerRootVolumeIOPS > 16000) {
+			return fmt.Errorf("invalid workerRootVolumeIOPS for volume type 'gp3': %d", c.WorkerRootVolumeIOPS)
+		}
 	} else {
 		if c.WorkerRootVolumeIOPS != 0 {
 			return fmt.Errorf("invalid workerRootVolumeIOPS for volume type '%s': %d", c.WorkerRootVolumeType, c.WorkerRootVolumeIOPS)
@@ -912,6 +995,9 @@ func (c *Cluster) ControllerFeatureGates() FeatureGates {
 		gates["CSIMigration"] = "true"
 		gates["CSIMigrationAWS"] = "true"
 	}
+	if c.IPv6.Enabled {
+		gates["IPv6DualStack"] = "true"
+	}
 	return gates
 }
 
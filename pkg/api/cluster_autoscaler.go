@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	clusterAutoscalerNodeTemplateTagPrefix = "k8s.io/cluster-autoscaler/node-template/"
+	nvidiaGPUResourceName                  = "nvidia.com/gpu"
+)
+
+// invalidLabelValueChars matches characters replaced by `toLabel` when the node pool name is passed to kubelet as a label value
+var invalidLabelValueChars = regexp.MustCompile("[^a-z0-9A-Z_.-]")
+
+// ClusterAutoscalerOptions is the per-node-pool settings for cluster-autoscaler, which are rendered into the values
+// of the cluster-autoscaler plugin
+type ClusterAutoscalerOptions struct {
+	// Priority is the priority of the node pool for the priority expander. Node pools with higher priorities are scaled up first.
+	// The priority expander is used only when any node pool has a priority
+	Priority *int `yaml:"priority,omitempty"`
+	// ScaleDownDisabled annotates nodes in the node pool so that cluster-autoscaler never removes them
+	ScaleDownDisabled bool `yaml:"scaleDownDisabled,omitempty"`
+	UnknownKeys       `yaml:",inline"`
+}
+
+func (a ClusterAutoscalerOptions) Validate(path string) error {
+	if a.Priority != nil && *a.Priority < 0 {
+		return fmt.Errorf("`%s.clusterAutoscaler.priority` must be zero or greater but was %d", path, *a.Priority)
+	}
+	return nil
+}
+
+// ClusterAutoscalerNodeTemplateTags returns tags of the auto scaling group describing the labels, taints and resources of nodes in the node pool.
+// cluster-autoscaler reads them to simulate a node of the node pool when it has no node, so that the node pool can be scaled from zero
+func (c WorkerNodePool) ClusterAutoscalerNodeTemplateTags() map[string]string {
+	tags := map[string]string{}
+
+	labels := map[string]string{
+		"node.kubernetes.io/role":          invalidLabelValueChars.ReplaceAllString(c.NodePoolName, "_"),
+		"beta.kubernetes.io/instance-type": c.InstanceType,
+	}
+	if c.Gpu.Nvidia.IsEnabledOn(c.InstanceType) {
+		labels["kube-aws.coreos.com/gpu"] = "nvidia"
+		labels["kube-aws.coreos.com/nvidia-gpu-version"] = c.Gpu.Nvidia.Version
+	}
+	for k, v := range c.NodeLabels {
+		labels[k] = v
+	}
+	for k, v := range labels {
+		tags[clusterAutoscalerNodeTemplateTagPrefix+"label/"+k] = v
+	}
+
+	for _, t := range c.Taints {
+		tags[clusterAutoscalerNodeTemplateTagPrefix+"taint/"+t.Key] = fmt.Sprintf("%s:%s", t.Value, t.Effect)
+	}
+
+	if c.Gpu.Nvidia.IsEnabledOn(c.InstanceType) || c.Experimental.GpuSupport.Enabled && isGpuEnabledInstanceType(c.InstanceType) {
+		// cluster-autoscaler can't know the number of GPUs before a node of the instance type is running
+		if t := lookupInstanceType(c.InstanceType); t != nil && t.GPUs > 0 {
+			tags[clusterAutoscalerNodeTemplateTagPrefix+"resources/"+nvidiaGPUResourceName] = strconv.Itoa(t.GPUs)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClusterAutoscalerOptionsValidate(t *testing.T) {
+	zero := 0
+	negative := -1
+	testCases := []struct {
+		options ClusterAutoscalerOptions
+		isValid bool
+	}{
+		{
+			options: ClusterAutoscalerOptions{},
+			isValid: true,
+		},
+		{
+			options: ClusterAutoscalerOptions{Priority: &zero, ScaleDownDisabled: true},
+			isValid: true,
+		},
+		// Invalid, priorities are non-negative
+		{
+			options: ClusterAutoscalerOptions{Priority: &negative},
+			isValid: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.options.Validate("worker.nodePools[name=pool1]")
+		if testCase.isValid && err != nil {
+			t.Errorf("Expected %+v to be valid, but it was not: %v", testCase.options, err)
+		}
+		if !testCase.isValid && err == nil {
+			t.Errorf("Expected %+v to be invalid, but it was not", testCase.options)
+		}
+	}
+}
+
+func TestClusterAutoscalerNodeTemplateTags(t *testing.T) {
+	pool := NewDefaultNodePoolConfig()
+	pool.NodePoolName = "gpu pool"
+	pool.InstanceType = "p3.8xlarge"
+	pool.Gpu.Nvidia = NvidiaSetting{Enabled: true, Version: "384.66"}
+	pool.NodeLabels = NodeLabels{"role": "ml"}
+	pool.Taints = Taints{{Key: "dedicated", Value: "ml", Effect: "NoSchedule"}}
+
+	expected := map[string]string{
+		"k8s.io/cluster-autoscaler/node-template/label/node.kubernetes.io/role":                "gpu_pool",
+		"k8s.io/cluster-autoscaler/node-template/label/beta.kubernetes.io/instance-type":       "p3.8xlarge",
+		"k8s.io/cluster-autoscaler/node-template/label/kube-aws.coreos.com/gpu":                "nvidia",
+		"k8s.io/cluster-autoscaler/node-template/label/kube-aws.coreos.com/nvidia-gpu-version": "384.66",
+		"k8s.io/cluster-autoscaler/node-template/label/role":                                   "ml",
+		"k8s.io/cluster-autoscaler/node-template/taint/dedicated":                              "ml:NoSchedule",
+		"k8s.io/cluster-autoscaler/node-template/resources/nvidia.com/gpu":                     "4",
+	}
+	if actual := pool.ClusterAutoscalerNodeTemplateTags(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected node template tags %+v, but was %+v", expected, actual)
+	}
+
+	plain := NewDefaultNodePoolConfig()
+	plain.NodePoolName = "pool1"
+	expected = map[string]string{
+		"k8s.io/cluster-autoscaler/node-template/label/node.kubernetes.io/role":          "pool1",
+		"k8s.io/cluster-autoscaler/node-template/label/beta.kubernetes.io/instance-type": "t2.medium",
+	}
+	if actual := plain.ClusterAutoscalerNodeTemplateTags(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected node template tags %+v, but was %+v", expected, actual)
+	}
+}
@@ -12,6 +12,7 @@ type Controller struct {
 	LoadBalancer       ControllerElb       `yaml:"loadBalancer,omitempty"`
 	IAMConfig          IAMConfig           `yaml:"iam,omitempty"`
 	SecurityGroupIds   []string            `yaml:"securityGroupIds"`
+	SecurityGroupRules SecurityGroupRules  `yaml:"securityGroupRules,omitempty"`
 	VolumeMounts       []NodeVolumeMount   `yaml:"volumeMounts,omitempty"`
 	Subnets            Subnets             `yaml:"subnets,omitempty"`
 	CustomFiles        []CustomFile        `yaml:"customFiles,omitempty"`
@@ -43,10 +44,16 @@ func (c Controller) LogicalName() string {
 	return "Controllers"
 }
 
+// LaunchConfigurationLogicalName returns the logical name of the launch configuration controller nodes were launched from before launch templates
 func (c Controller) LaunchConfigurationLogicalName() string {
 	return c.LogicalName() + "LC"
 }
 
+// LaunchTemplateLogicalName returns the logical name of the launch template controller nodes are launched from
+func (c Controller) LaunchTemplateLogicalName() string {
+	return c.LogicalName() + "LT"
+}
+
 func (c Controller) SecurityGroupRefs() []string {
 	refs := []string{}
 
@@ -69,6 +76,10 @@ func (c Controller) Validate() error {
 		if rootVolume.IOPS < 100 || rootVolume.IOPS > 20000 {
 			return fmt.Errorf("invalid controller.rootVolume.iops: %d", rootVolume.IOPS)
 		}
+	} else if rootVolume.Type == "gp3" {
+		if rootVolume.IOPS != 0 && (rootVolume.IOPS < 3000 || rootVolume.IOPS > 16000) {
+			return fmt.Errorf("invalid controller.rootVolume.iops for type \"gp3\": %d", rootVolume.IOPS)
+		}
 	} else {
 		if rootVolume.IOPS != 0 {
 			return fmt.Errorf("invalid controller.rootVolume.iops for type \"%s\": %d", rootVolume.Type, rootVolume.IOPS)
@@ -92,9 +103,38 @@ func (c Controller) Validate() error {
 		return err
 	}
 
+	if mi := c.AutoScalingGroup.MixedInstances; mi.Enabled {
+		if mi.OnDemandBaseCapacity != 0 || mi.OnDemandPercentageAboveBaseCapacity != 0 || mi.SpotAllocationStrategy != "" || mi.SpotInstancePools != 0 || mi.SpotMaxPrice != "" || len(mi.Overrides) != 0 {
+			return errors.New("`controller.autoScalingGroup.mixedInstances` accepts only `instanceTypes` and `onDemandAllocationStrategy` because controller nodes are always on-demand instances")
+		}
+		if len(mi.InstanceTypes) == 0 {
+			return errors.New("`controller.autoScalingGroup.mixedInstances.instanceTypes` must be specified when `controller.autoScalingGroup.mixedInstances.enabled` is true")
+		}
+	}
+
+	if c.AutoScalingGroup.WarmPool.Enabled || c.AutoScalingGroup.InstanceRefresh.Enabled {
+		return errors.New("`controller.autoScalingGroup.warmPool` and `controller.autoScalingGroup.instanceRefresh` are supported only by worker node pools")
+	}
+
+	if c.AutoScalingGroup.HasScalingSettings() {
+		return errors.New("`controller.autoScalingGroup.scheduledActions` and `controller.autoScalingGroup.targetTrackingPolicies` are supported only by worker node pools")
+	}
+
+	if err := c.MetadataOptions.Validate("controller"); err != nil {
+		return err
+	}
+
 	if err := c.IAMConfig.Validate(); err != nil {
 		return err
 	}
+	if err := (instanceTypeSettings{
+		path:           "controller",
+		instanceType:   c.InstanceType,
+		volumeMounts:   c.VolumeMounts,
+		mixedInstances: c.AutoScalingGroup.MixedInstances,
+	}).validate(); err != nil {
+		return err
+	}
 	if err := ValidateVolumeMounts(c.VolumeMounts); err != nil {
 		return err
 	}
@@ -59,10 +59,14 @@ func (c DeploymentSettings) WithDefaultsFrom(main DeploymentSettings) Deployment
 	// * Region
 	// * ContainerRuntime
 	// * KMSKeyARN
+	// * PrivateLinks
+	// * PrivateHostedZone
 	// * ElasticFileSystemID
 	c.Region = main.Region
 	c.ContainerRuntime = main.ContainerRuntime
 	c.KMSKeyARN = main.KMSKeyARN
+	c.PrivateLinks = main.PrivateLinks
+	c.PrivateHostedZone = main.PrivateHostedZone
 
 	// TODO Allow providing one or more elasticFileSystemId's to be mounted both per-node-pool/cluster-wide
 	// TODO Allow providing elasticFileSystemId to a node pool in managed subnets.
@@ -122,7 +126,7 @@ func (c DeploymentSettings) Validate() (*DeploymentValidationResult, error) {
 		return nil, errors.New("kmsKeyArn must reference the same region as the one being deployed to")
 	}
 
-	_, vpcNet, err := net.ParseCIDR(c.VPCCIDR)
+	vpcNet, err := netutil.ParseIPv4CIDR(c.VPCCIDR)
 	if err != nil {
 		return nil, fmt.Errorf("invalid vpcCIDR: %v", err)
 	}
@@ -131,7 +135,7 @@ func (c DeploymentSettings) Validate() (*DeploymentValidationResult, error) {
 		if c.AvailabilityZone == "" {
 			return nil, fmt.Errorf("availabilityZone must be set")
 		}
-		_, instanceCIDR, err := net.ParseCIDR(c.InstanceCIDR)
+		instanceCIDR, err := netutil.ParseIPv4CIDR(c.InstanceCIDR)
 		if err != nil {
 			return nil, fmt.Errorf("invalid instanceCIDR: %v", err)
 		}
@@ -170,7 +174,7 @@ func (c DeploymentSettings) Validate() (*DeploymentValidationResult, error) {
 			if subnet.AvailabilityZone == "" {
 				return nil, fmt.Errorf("availabilityZone must be set for subnet #%d", i)
 			}
-			_, instanceCIDR, err := net.ParseCIDR(subnet.InstanceCIDR)
+			instanceCIDR, err := netutil.ParseIPv4CIDR(subnet.InstanceCIDR)
 			if err != nil {
 				return nil, fmt.Errorf("invalid instanceCIDR for subnet #%d: %v", i, err)
 			}
@@ -280,6 +284,9 @@ func (c DeploymentSettings) FindNATGatewayForPrivateSubnet(s Subnet) (*NATGatewa
 
 func (c DeploymentSettings) NATGateways() []NATGateway {
 	ngws := []NATGateway{}
+	if c.PrivateLinks.InternetEgressDisabled() {
+		return ngws
+	}
 	for _, privateSubnet := range c.PrivateSubnets() {
 		var publicSubnet Subnet
 		ngwConfig := privateSubnet.NATGateway
@@ -9,11 +9,17 @@ type EC2Instance struct {
 	RootVolume    `yaml:"rootVolume,omitempty"`
 	Tenancy       string            `yaml:"tenancy,omitempty"`
 	InstanceTags  map[string]string `yaml:"instanceTags,omitempty"`
+	// MetadataOptions hardens the instance metadata service of the instances e.g. by enforcing IMDSv2
+	MetadataOptions MetadataOptions `yaml:"metadataOptions,omitempty"`
 }
 
+// nvmeEC2InstanceFamily is consulted only for instance types missing in the EC2 instance type catalog
 var nvmeEC2InstanceFamily = []string{"c5", "m5"}
 
 func isNvmeEC2InstanceType(instanceType string) bool {
+	if t := lookupInstanceType(instanceType); t != nil {
+		return t.Nitro
+	}
 	for _, family := range nvmeEC2InstanceFamily {
 		if strings.HasPrefix(instanceType, family) {
 			return true
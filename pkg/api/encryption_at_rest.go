@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	defaultEncryptionAtRestKMSCacheSize = 1000
+)
+
+// EncryptionAtRestKMS configures aws-encryption-provider, the KMS provider plugin for kube-apiserver.
+// See https://github.com/kubernetes-sigs/aws-encryption-provider
+type EncryptionAtRestKMS struct {
+	Enabled bool `yaml:"enabled"`
+	// KeyARN is the KMS key data encryption keys are encrypted with. Defaults to `kmsKeyArn`
+	KeyARN string `yaml:"keyArn,omitempty"`
+	// Image is the image of aws-encryption-provider to run on controller nodes
+	Image Image `yaml:"image,omitempty"`
+	// CacheSize is the number of decrypted data encryption keys kube-apiserver keeps in memory. Defaults to 1000
+	CacheSize int `yaml:"cacheSize,omitempty"`
+}
+
+func (k EncryptionAtRestKMS) CacheSizeOrDefault() int {
+	if k.CacheSize > 0 {
+		return k.CacheSize
+	}
+	return defaultEncryptionAtRestKMSCacheSize
+}
+
+// EncryptionAtRestKMSKeyARN returns the KMS key the KMS provider plugin encrypts data encryption keys with
+func (c Cluster) EncryptionAtRestKMSKeyARN() string {
+	if c.Kubernetes.EncryptionAtRest.KMS.KeyARN != "" {
+		return c.Kubernetes.EncryptionAtRest.KMS.KeyARN
+	}
+	return c.KMSKeyARN
+}
+
+func (c Cluster) validateEncryptionAtRest() error {
+	kms := c.Kubernetes.EncryptionAtRest.KMS
+	if !kms.Enabled {
+		return nil
+	}
+	if !c.Kubernetes.EncryptionAtRest.Enabled {
+		return errors.New("`kubernetes.encryptionAtRest.kms` requires `kubernetes.encryptionAtRest.enabled` to be true")
+	}
+	if c.EncryptionAtRestKMSKeyARN() == "" {
+		return errors.New("either `kubernetes.encryptionAtRest.kms.keyArn` or `kmsKeyArn` must be specified to enable `kubernetes.encryptionAtRest.kms`")
+	}
+	if kms.Image.Repo == "" || kms.Image.Tag == "" {
+		return errors.New("`kubernetes.encryptionAtRest.kms.image.repo` and `kubernetes.encryptionAtRest.kms.image.tag` must be specified. Build aws-encryption-provider and push it to a registry reachable from controller nodes")
+	}
+	if kms.CacheSize < 0 {
+		return fmt.Errorf("`kubernetes.encryptionAtRest.kms.cacheSize` must not be negative but was %d", kms.CacheSize)
+	}
+	return nil
+}
@@ -20,12 +20,13 @@ type Etcd struct {
 	DisasterRecovery   EtcdDisasterRecovery `yaml:"disasterRecovery,omitempty"`
 	VolumeMounts       []NodeVolumeMount    `yaml:"volumeMounts,omitempty"`
 	EC2Instance        `yaml:",inline"`
-	UserSuppliedArgs   UserSuppliedArgs `yaml:"userSuppliedArgs,omitempty"`
-	IAMConfig          IAMConfig        `yaml:"iam,omitempty"`
-	Nodes              []EtcdNode       `yaml:"nodes,omitempty"`
-	SecurityGroupIds   []string         `yaml:"securityGroupIds"`
-	Snapshot           EtcdSnapshot     `yaml:"snapshot,omitempty"`
-	Subnets            Subnets          `yaml:"subnets,omitempty"`
+	UserSuppliedArgs   UserSuppliedArgs   `yaml:"userSuppliedArgs,omitempty"`
+	IAMConfig          IAMConfig          `yaml:"iam,omitempty"`
+	Nodes              []EtcdNode         `yaml:"nodes,omitempty"`
+	SecurityGroupIds   []string           `yaml:"securityGroupIds"`
+	SecurityGroupRules SecurityGroupRules `yaml:"securityGroupRules,omitempty"`
+	Snapshot           EtcdSnapshot       `yaml:"snapshot,omitempty"`
+	Subnets            Subnets            `yaml:"subnets,omitempty"`
 	StackExists        bool
 	UnknownKeys        `yaml:",inline"`
 }
@@ -158,6 +159,10 @@ func ValidateQuotaBackendBytes(bytes int) error {
 }
 
 func (e Etcd) Validate() error {
+	if err := (instanceTypeSettings{path: "etcd", instanceType: e.InstanceType, volumeMounts: e.VolumeMounts}).validate(); err != nil {
+		return err
+	}
+
 	if err := ValidateVolumeMounts(e.VolumeMounts); err != nil {
 		return err
 	}
@@ -166,6 +171,10 @@ func (e Etcd) Validate() error {
 		return err
 	}
 
+	if err := e.MetadataOptions.Validate("etcd"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -35,9 +35,11 @@ func (c EtcdCluster) GetMemberIdentityProvider() string {
 	panic(fmt.Errorf("Unsupported memberIdentityProvider: %s", p))
 }
 
+// Notes:
+// * A hosted zone referenced via `idFromFn`, including the cluster's `privateHostedZone`, isn't managed by the etcd stack
 func (e EtcdCluster) hostedZoneManaged() bool {
 	return e.GetMemberIdentityProvider() == MemberIdentityProviderENI &&
-		!e.HostedZone.HasIdentifier() && !e.EC2InternalDomainUsed()
+		!e.HostedZone.HasIdentifier() && e.HostedZone.IDFromFn == "" && !e.EC2InternalDomainUsed()
 }
 
 // Notes:
@@ -3,10 +3,12 @@ package api
 import (
 	"errors"
 	"fmt"
+	"github.com/kubernetes-incubator/kube-aws/ec2catalog"
 	"github.com/kubernetes-incubator/kube-aws/logger"
 	"strings"
 )
 
+// GPUEnabledInstanceFamily is consulted only for instance types missing in the EC2 instance type catalog
 var GPUEnabledInstanceFamily = []string{"p2", "p3", "g2", "g3"}
 
 type Gpu struct {
@@ -19,6 +21,9 @@ type NvidiaSetting struct {
 }
 
 func isGpuEnabledInstanceType(instanceType string) bool {
+	if t := lookupInstanceType(instanceType); t != nil {
+		return t.GPUs > 0
+	}
 	for _, family := range GPUEnabledInstanceFamily {
 		if strings.HasPrefix(instanceType, family) {
 			return true
@@ -43,7 +48,15 @@ func (c NvidiaSetting) IsEnabledOn(instanceType string) bool {
 
 func (c Gpu) Validate(instanceType string, experimentalGpuSupportEnabled bool) error {
 	if c.Nvidia.Enabled && !isGpuEnabledInstanceType(instanceType) {
-		return errors.New(fmt.Sprintf("instance type %v doesn't support GPU. You can enable Nvidia driver intallation support only when use %v instance family.", instanceType, GPUEnabledInstanceFamily))
+		msg := fmt.Sprintf("instance type %v doesn't support GPU. You can enable Nvidia driver intallation support only on instance types with GPUs.", instanceType)
+		if t := lookupInstanceType(instanceType); t != nil {
+			if catalog, err := ec2catalog.Default(); err == nil {
+				if suggestions := catalog.WithGPUs(t.Size()); len(suggestions) > 0 {
+					msg += fmt.Sprintf(" Instance types of the same size with GPUs are %s", strings.Join(suggestions, ", "))
+				}
+			}
+		}
+		return errors.New(msg)
 	}
 	if !c.Nvidia.Enabled && !experimentalGpuSupportEnabled && isGpuEnabledInstanceType(instanceType) {
 		logger.Warnf("Nvidia GPU driver intallation is disabled although instance type %v does support GPU.  You have to install Nvidia GPU driver by yourself to schedule gpu resource.\n", instanceType)
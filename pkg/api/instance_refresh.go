@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+)
+
+// InstanceRefresh rolls out changes to the launch template of the auto scaling group with EC2 Instance Refresh, which kube-aws starts
+// and reports the progress of after updating the stack, instead of the rolling update of CloudFormation
+type InstanceRefresh struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// MinHealthyPercentage is the percentage of the desired capacity which must stay in service and healthy during the refresh. Defaults to 90
+	MinHealthyPercentage *int `yaml:"minHealthyPercentage,omitempty"`
+	// InstanceWarmup is the number of seconds until a new instance is counted as healthy. Defaults to the health check grace period of the group
+	InstanceWarmup *int `yaml:"instanceWarmup,omitempty"`
+	// CheckpointPercentages are the percentages of replaced instances at which the refresh pauses for CheckpointDelay seconds
+	CheckpointPercentages []int `yaml:"checkpointPercentages,omitempty"`
+	// CheckpointDelay is the number of seconds the refresh pauses at each checkpoint. Defaults to 3600
+	CheckpointDelay *int `yaml:"checkpointDelay,omitempty"`
+	UnknownKeys     `yaml:",inline"`
+}
+
+func (r InstanceRefresh) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if p := r.MinHealthyPercentage; p != nil && (*p < 0 || *p > 100) {
+		return fmt.Errorf("`autoScalingGroup.instanceRefresh.minHealthyPercentage` must be in range 0-100 but was %d", *p)
+	}
+	if r.InstanceWarmup != nil && *r.InstanceWarmup < 0 {
+		return fmt.Errorf("`autoScalingGroup.instanceRefresh.instanceWarmup` must be zero or greater if specified")
+	}
+	last := 0
+	for _, p := range r.CheckpointPercentages {
+		if p <= last || p > 100 {
+			return fmt.Errorf("`autoScalingGroup.instanceRefresh.checkpointPercentages` must be in ascending order within range 1-100 but was %v", r.CheckpointPercentages)
+		}
+		last = p
+	}
+	if r.CheckpointDelay != nil {
+		if len(r.CheckpointPercentages) == 0 {
+			return fmt.Errorf("`autoScalingGroup.instanceRefresh.checkpointDelay` can be specified only with `autoScalingGroup.instanceRefresh.checkpointPercentages`")
+		}
+		if *r.CheckpointDelay < 0 || *r.CheckpointDelay > 172800 {
+			return fmt.Errorf("`autoScalingGroup.instanceRefresh.checkpointDelay` must be in range 0-172800 but was %d", *r.CheckpointDelay)
+		}
+	}
+	return nil
+}
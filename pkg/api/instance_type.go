@@ -0,0 +1,181 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/ec2catalog"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+)
+
+// nvmeDevicePattern matches names of NVMe devices, which EBS volumes and instance store volumes of Nitro instances appear as
+var nvmeDevicePattern = regexp.MustCompile(`^(/dev/)?nvme([0-9]+)n1$`)
+
+// lookupInstanceType returns the instance type from the EC2 instance type catalog, or nil when it isn't in the catalog
+// or the catalog can't be loaded, in which case callers fall back to their own assumptions
+func lookupInstanceType(instanceType string) *ec2catalog.InstanceType {
+	catalog, err := ec2catalog.Default()
+	if err != nil {
+		return nil
+	}
+	return catalog.Find(instanceType)
+}
+
+// instanceTypeSettings is the settings of nodes, which depend on the capabilities of their instance type
+type instanceTypeSettings struct {
+	// path is the key of the nodes in cluster.yaml e.g. "controller" and "worker.nodePools[name=pool1]"
+	path                  string
+	instanceType          string
+	volumeMounts          []NodeVolumeMount
+	raid0Mounts           []Raid0Mount
+	ephemeralImageStorage EphemeralImageStorage
+	mixedInstances        MixedInstances
+}
+
+// validate rejects settings the instance type doesn't support according to the EC2 instance type catalog,
+// which would otherwise fail only once CloudFormation or the instances try to apply them
+func (s instanceTypeSettings) validate() error {
+	catalog, err := ec2catalog.Default()
+	if err != nil {
+		return err
+	}
+
+	t := catalog.Find(s.instanceType)
+	if t == nil {
+		warnUnknownInstanceType(catalog, fmt.Sprintf("%s.instanceType", s.path), s.instanceType)
+	}
+
+	if err := validateNvmeDeviceNames(s.path, s.instanceType, t, s.volumeMounts, s.raid0Mounts); err != nil {
+		return err
+	}
+
+	if eis := s.ephemeralImageStorage; eis.Enabled && t != nil {
+		if err := validateEphemeralImageStorage(catalog, s.path, t, eis); err != nil {
+			return err
+		}
+	}
+
+	if mi := s.mixedInstances; mi.Enabled {
+		if err := validateInstanceTypeArchitectures(catalog, s.path, s.instanceType, mi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c WorkerNodePool) validateInstanceTypeCapabilities() error {
+	return instanceTypeSettings{
+		path:                  fmt.Sprintf("worker.nodePools[name=%s]", c.NodePoolName),
+		instanceType:          c.InstanceType,
+		volumeMounts:          c.VolumeMounts,
+		raid0Mounts:           c.Raid0Mounts,
+		ephemeralImageStorage: c.Experimental.EphemeralImageStorage,
+		mixedInstances:        c.AutoScalingGroup.MixedInstances,
+	}.validate()
+}
+
+func warnUnknownInstanceType(catalog ec2catalog.Catalog, key, instanceType string) {
+	if s := catalog.Suggest(instanceType); s != "" {
+		logger.Warnf("`%s` \"%s\" is unknown to kube-aws. Did you mean \"%s\"? Add it to %s if it's a new instance type\n", key, instanceType, s, ec2catalog.LocalCatalogFile)
+		return
+	}
+	logger.Warnf("`%s` \"%s\" is unknown to kube-aws and its capabilities aren't validated. Add it to %s to validate them\n", key, instanceType, ec2catalog.LocalCatalogFile)
+}
+
+// validateNvmeDeviceNames rejects NVMe device names for EBS volumes, whose names are assigned by the order the volumes are attached.
+// kube-aws links the stable device names in the block device mappings to the NVMe devices on Nitro instances instead
+func validateNvmeDeviceNames(path, instanceType string, t *ec2catalog.InstanceType, volumes []NodeVolumeMount, raid0s []Raid0Mount) error {
+	check := func(key, device string) error {
+		m := nvmeDevicePattern.FindStringSubmatch(device)
+		if m == nil {
+			return nil
+		}
+		suggestion := suggestedEBSDeviceName(m[2])
+		if t != nil && !t.Nitro {
+			return fmt.Errorf("`%s` \"%s\" is an NVMe device but instance type %s attaches EBS volumes as Xen devices. Specify \"%s\" instead", key, device, instanceType, suggestion)
+		}
+		return fmt.Errorf("`%s` \"%s\" can't be specified as the names of NVMe devices depend on the order EBS volumes are attached. "+
+			"Specify \"%s\" instead, which kube-aws links to the NVMe device of the volume on Nitro instances", key, device, suggestion)
+	}
+
+	for i, v := range volumes {
+		if err := check(fmt.Sprintf("%s.volumeMounts[%d].device", path, i), v.Device); err != nil {
+			return err
+		}
+	}
+	for i, r := range raid0s {
+		for j, d := range r.Devices {
+			if err := check(fmt.Sprintf("%s.raid0Mounts[%d].devices[%d]", path, i, j), d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// suggestedEBSDeviceName returns the device name for the nth NVMe device, counting from /dev/xvdf as nvme0n1 is the root volume
+func suggestedEBSDeviceName(nvmeIndex string) string {
+	n, _ := strconv.Atoi(nvmeIndex)
+	if n < 1 || n > 'z'-'f'+1 {
+		n = 1
+	}
+	return fmt.Sprintf("/dev/xvd%c", 'f'+n-1)
+}
+
+func validateEphemeralImageStorage(catalog ec2catalog.Catalog, path string, t *ec2catalog.InstanceType, eis EphemeralImageStorage) error {
+	key := fmt.Sprintf("%s.ephemeralImageStorage", path)
+
+	if t.InstanceStoreDisks == 0 {
+		if v := catalog.InstanceStoreVariant(t); v != nil {
+			return fmt.Errorf("`%s` requires an instance store volume but instance type %s has none. Use e.g. %s instead", key, t.Name, v.Name)
+		}
+		return fmt.Errorf("`%s` requires an instance store volume but instance type %s has none", key, t.Name)
+	}
+
+	isNvme := nvmeDevicePattern.MatchString(eis.Disk)
+	if t.InstanceStoreNvme && !isNvme {
+		// the root volume is always nvme0n1 and the instance store volumes come next as they're attached on boot
+		return fmt.Errorf("`%s.disk` \"%s\" doesn't exist because instance store volumes of instance type %s are NVMe devices. Specify \"nvme1n1\" instead", key, eis.Disk, t.Name)
+	}
+	if !t.InstanceStoreNvme && isNvme {
+		return fmt.Errorf("`%s.disk` \"%s\" doesn't exist because instance store volumes of instance type %s aren't NVMe devices. Specify \"xvdb\" instead", key, eis.Disk, t.Name)
+	}
+	return nil
+}
+
+// validateInstanceTypeArchitectures rejects mixed instances of different architectures, as instances of an auto scaling group boot the same AMI
+func validateInstanceTypeArchitectures(catalog ec2catalog.Catalog, path, instanceType string, mi MixedInstances) error {
+	instanceTypesByArch := map[string][]string{}
+	add := func(name string) {
+		if t := catalog.Find(name); t != nil {
+			for _, known := range instanceTypesByArch[t.Architecture] {
+				if known == name {
+					return
+				}
+			}
+			instanceTypesByArch[t.Architecture] = append(instanceTypesByArch[t.Architecture], name)
+		}
+	}
+
+	add(instanceType)
+	for i, o := range mi.InstanceTypeOverrides() {
+		if catalog.Find(o.InstanceType) == nil {
+			warnUnknownInstanceType(catalog, fmt.Sprintf("%s.autoScalingGroup.mixedInstances.instanceTypes[%d]", path, i), o.InstanceType)
+		}
+		add(o.InstanceType)
+	}
+	if len(instanceTypesByArch) < 2 {
+		return nil
+	}
+
+	archs := []string{}
+	for arch, types := range instanceTypesByArch {
+		archs = append(archs, fmt.Sprintf("%s (%s)", arch, strings.Join(types, ", ")))
+	}
+	sort.Strings(archs)
+	return fmt.Errorf("`%s.autoScalingGroup.mixedInstances` mixes instance types of architectures %s but all the instances boot the same AMI. "+
+		"Remove the instance types of either architecture", path, strings.Join(archs, " and "))
+}
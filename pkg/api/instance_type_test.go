@@ -0,0 +1,165 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateInstanceTypeCapabilities(t *testing.T) {
+	testCases := []struct {
+		context       string
+		pool          WorkerNodePool
+		expectedError string
+	}{
+		{
+			context: "RaidOnNitroInstances",
+			pool: WorkerNodePool{
+				EC2Instance: EC2Instance{InstanceType: "r5.large"},
+				Raid0Mounts: []Raid0Mount{{Devices: []string{"/dev/xvdf", "/dev/xvdg"}}},
+			},
+		},
+		{
+			context: "NvmeDeviceNamesOfRaid",
+			pool: WorkerNodePool{
+				EC2Instance: EC2Instance{InstanceType: "r5.large"},
+				Raid0Mounts: []Raid0Mount{{Devices: []string{"/dev/xvdf", "/dev/nvme2n1"}}},
+			},
+			expectedError: "`worker.nodePools[name=pool1].raid0Mounts[0].devices[1]` \"/dev/nvme2n1\" can't be specified as the names of NVMe devices depend on the order EBS volumes are attached. " +
+				"Specify \"/dev/xvdg\" instead",
+		},
+		{
+			context: "NvmeDeviceNameOfVolumeOnXenInstances",
+			pool: WorkerNodePool{
+				EC2Instance:  EC2Instance{InstanceType: "m4.large"},
+				VolumeMounts: []NodeVolumeMount{{Device: "/dev/nvme1n1"}},
+			},
+			expectedError: "`worker.nodePools[name=pool1].volumeMounts[0].device` \"/dev/nvme1n1\" is an NVMe device but instance type m4.large attaches EBS volumes as Xen devices. Specify \"/dev/xvdf\" instead",
+		},
+		{
+			context: "EphemeralImageStorageWithoutInstanceStore",
+			pool: WorkerNodePool{
+				EC2Instance:  EC2Instance{InstanceType: "m5.xlarge"},
+				Experimental: Experimental{EphemeralImageStorage: EphemeralImageStorage{Enabled: true, Disk: "xvdb"}},
+			},
+			expectedError: "`worker.nodePools[name=pool1].ephemeralImageStorage` requires an instance store volume but instance type m5.xlarge has none. Use e.g. m5d.xlarge instead",
+		},
+		{
+			context: "EphemeralImageStorageOnXenDeviceOfNvmeInstanceStore",
+			pool: WorkerNodePool{
+				EC2Instance:  EC2Instance{InstanceType: "m5d.xlarge"},
+				Experimental: Experimental{EphemeralImageStorage: EphemeralImageStorage{Enabled: true, Disk: "xvdb"}},
+			},
+			expectedError: "`worker.nodePools[name=pool1].ephemeralImageStorage.disk` \"xvdb\" doesn't exist because instance store volumes of instance type m5d.xlarge are NVMe devices. Specify \"nvme1n1\" instead",
+		},
+		{
+			context: "EphemeralImageStorageOnNvmeInstanceStore",
+			pool: WorkerNodePool{
+				EC2Instance:  EC2Instance{InstanceType: "m5d.xlarge"},
+				Experimental: Experimental{EphemeralImageStorage: EphemeralImageStorage{Enabled: true, Disk: "nvme1n1"}},
+			},
+		},
+		{
+			context: "EphemeralImageStorageOnUnknownInstanceType",
+			pool: WorkerNodePool{
+				EC2Instance:  EC2Instance{InstanceType: "x9.large"},
+				Experimental: Experimental{EphemeralImageStorage: EphemeralImageStorage{Enabled: true, Disk: "xvdb"}},
+			},
+		},
+		{
+			context: "MixedInstancesOfDifferentArchitectures",
+			pool: WorkerNodePool{
+				EC2Instance: EC2Instance{InstanceType: "m5.large"},
+				AutoScalingGroup: AutoScalingGroup{
+					MixedInstances: MixedInstances{Enabled: true, InstanceTypes: []string{"m5.large", "m6g.large", "c6g.large", "x9.large"}},
+				},
+			},
+			expectedError: "`worker.nodePools[name=pool1].autoScalingGroup.mixedInstances` mixes instance types of architectures arm64 (m6g.large, c6g.large) and x86_64 (m5.large)",
+		},
+		{
+			context: "MixedInstancesOfSameArchitecture",
+			pool: WorkerNodePool{
+				EC2Instance: EC2Instance{InstanceType: "m6g.large"},
+				AutoScalingGroup: AutoScalingGroup{
+					MixedInstances: MixedInstances{Enabled: true, Overrides: []InstanceTypeOverride{{InstanceType: "m6g.large"}, {InstanceType: "c6g.xlarge", WeightedCapacity: 2}}},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.context, func(t *testing.T) {
+			pool := testCase.pool
+			pool.NodePoolName = "pool1"
+			err := pool.validateInstanceTypeCapabilities()
+			if testCase.expectedError == "" {
+				if err != nil {
+					t.Errorf("expected no error, but was: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Errorf("expected error containing %s, but was: %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestInstanceTypeCapabilitiesFromCatalog(t *testing.T) {
+	if !(EC2Instance{InstanceType: "r5.large"}).HasNvmeDevices() {
+		t.Error("expected r5.large to have NVMe devices as it's a Nitro instance type")
+	}
+	if (EC2Instance{InstanceType: "m4.large"}).HasNvmeDevices() {
+		t.Error("expected m4.large not to have NVMe devices")
+	}
+	// instance types missing in the catalog are assumed from their families
+	if !(EC2Instance{InstanceType: "c5.unknown"}).HasNvmeDevices() {
+		t.Error("expected c5.unknown to have NVMe devices as a c5 instance type")
+	}
+
+	if !isGpuEnabledInstanceType("g4dn.xlarge") {
+		t.Error("expected g4dn.xlarge to be GPU-enabled")
+	}
+	if isGpuEnabledInstanceType("m5.large") {
+		t.Error("expected m5.large not to be GPU-enabled")
+	}
+
+	err := Gpu{Nvidia: NvidiaSetting{Enabled: true, Version: "384.66"}}.Validate("m5.xlarge", false)
+	if err == nil || !strings.Contains(err.Error(), "Instance types of the same size with GPUs are g3s.xlarge, g4dn.xlarge, p2.xlarge") {
+		t.Errorf("expected GPU-enabled instance types to be suggested, but was: %v", err)
+	}
+}
+
+func TestValidateInstanceTypeCapabilitiesOfControllerAndEtcd(t *testing.T) {
+	controller := NewDefaultController()
+	controller.InstanceType = "m5.large"
+	controller.AutoScalingGroup.MixedInstances = MixedInstances{Enabled: true, InstanceTypes: []string{"m5.large", "m6g.large"}}
+	err := controller.Validate()
+	if err == nil || !strings.Contains(err.Error(), "`controller.autoScalingGroup.mixedInstances` mixes instance types of architectures") {
+		t.Errorf("expected controller instance types of different architectures to be invalid, but was: %v", err)
+	}
+
+	etcd := NewDefaultEtcd()
+	etcd.InstanceType = "m5.large"
+	etcd.VolumeMounts = []NodeVolumeMount{{Device: "/dev/nvme1n1"}}
+	err = etcd.Validate()
+	if err == nil || !strings.Contains(err.Error(), "`etcd.volumeMounts[0].device` \"/dev/nvme1n1\" can't be specified") {
+		t.Errorf("expected the NVMe device name of the etcd volume to be invalid, but was: %v", err)
+	}
+}
+
+func TestWorkerRootVolumeIOPSInheritedForSameVolumeType(t *testing.T) {
+	main := DefaultWorkerSettings{WorkerRootVolumeType: "io1", WorkerRootVolumeIOPS: 300}
+
+	io1 := WorkerNodePool{}.WithDefaultsFrom(main)
+	if io1.RootVolume.Type != "io1" || io1.RootVolume.IOPS != 300 {
+		t.Errorf("expected the io1 root volume to inherit the IOPS, but was: %+v", io1.RootVolume)
+	}
+
+	gp3 := WorkerNodePool{EC2Instance: EC2Instance{RootVolume: RootVolume{Type: "gp3"}}}.WithDefaultsFrom(main)
+	if gp3.RootVolume.IOPS != 0 {
+		t.Errorf("expected the gp3 root volume not to inherit the IOPS of io1, but was: %+v", gp3.RootVolume)
+	}
+	if err := gp3.RootVolume.Validate(); err != nil {
+		t.Errorf("expected the gp3 root volume to be valid, but was: %v", err)
+	}
+}
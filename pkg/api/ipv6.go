@@ -0,0 +1,144 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Masterminds/semver"
+	"github.com/kubernetes-incubator/kube-aws/logger"
+	"github.com/kubernetes-incubator/kube-aws/netutil"
+)
+
+const (
+	// maxIPv6SubnetsPerVPC is the number of /64 subnets kube-aws is able to carve out of the /56 IPv6 CIDR block of a VPC
+	maxIPv6SubnetsPerVPC = 256
+	// minIPv6ServiceCIDRPrefixLength is the largest IPv6 service CIDR kube-apiserver accepts
+	minIPv6ServiceCIDRPrefixLength = 108
+)
+
+// IPv6 configures dual-stack networking for the VPC, subnets, pods and services
+type IPv6 struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// VPCCIDR is the IPv6 CIDR block already associated with the existing VPC specified via `vpc.id`.
+	// An Amazon-provided IPv6 CIDR block is associated to the VPC when kube-aws manages it.
+	VPCCIDR string `yaml:"vpcCIDR,omitempty"`
+	// PodCIDR is the IPv6 range pod IPs are allocated from, in addition to the IPv4 `podCIDR`
+	PodCIDR string `yaml:"podCIDR,omitempty"`
+	// ServiceCIDR is the IPv6 range service cluster IPs are allocated from, in addition to the IPv4 `serviceCIDR`
+	ServiceCIDR string `yaml:"serviceCIDR,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+// KubernetesServiceIP returns the IPv6 address of the `kubernetes` service which must be included in the apiserver certificate
+func (c IPv6) KubernetesServiceIP() (net.IP, error) {
+	_, serviceNet, err := net.ParseCIDR(c.ServiceCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ipv6.serviceCIDR: %v", err)
+	}
+	return netutil.IncrementIP(serviceNet.IP), nil
+}
+
+// PodCIDRs returns the comma-separated pod CIDRs for kube-controller-manager and kube-proxy, which includes the IPv6 one when dual-stack is enabled
+func (c KubeClusterSettings) PodCIDRs() string {
+	if c.IPv6.Enabled {
+		return c.PodCIDR + "," + c.IPv6.PodCIDR
+	}
+	return c.PodCIDR
+}
+
+// ServiceCIDRs returns the comma-separated service CIDRs for kube-apiserver and kube-controller-manager, which includes the IPv6 one when dual-stack is enabled
+func (c KubeClusterSettings) ServiceCIDRs() string {
+	if c.IPv6.Enabled {
+		return c.ServiceCIDR + "," + c.IPv6.ServiceCIDR
+	}
+	return c.ServiceCIDR
+}
+
+func (c Cluster) validateIPv6() error {
+	ipv6 := c.IPv6
+
+	if !ipv6.Enabled {
+		if ipv6.VPCCIDR != "" || ipv6.PodCIDR != "" || ipv6.ServiceCIDR != "" {
+			return errors.New("`ipv6.vpcCIDR`, `ipv6.podCIDR` and `ipv6.serviceCIDR` can't be specified unless `ipv6.enabled` is true")
+		}
+		for i, s := range c.Subnets {
+			if s.IPv6CIDRIndex != nil {
+				return fmt.Errorf("`subnets[%d].ipv6CIDRIndex` can't be specified unless `ipv6.enabled` is true", i)
+			}
+		}
+		return nil
+	}
+
+	constraint, err := semver.NewConstraint(">= 1.16")
+	if err != nil {
+		return fmt.Errorf("[BUG] ipv6 min version could not be parsed")
+	}
+	version, _ := semver.NewVersion(c.K8sVer) // already validated in DeploymentSettings.Validate()
+	if !constraint.Check(version) {
+		return fmt.Errorf("dual-stack networking requires kubernetesVersion 1.16 or greater but was %s", c.K8sVer)
+	}
+
+	var ipv6VPCNet *net.IPNet
+	if c.VPC.HasIdentifier() {
+		if ipv6.VPCCIDR == "" {
+			return errors.New("`ipv6.vpcCIDR` must be set to the IPv6 CIDR block associated with the existing VPC when `ipv6.enabled` is true")
+		}
+		if ipv6VPCNet, err = netutil.ParseIPv6CIDR(ipv6.VPCCIDR); err != nil {
+			return fmt.Errorf("invalid ipv6.vpcCIDR: %v", err)
+		}
+		if ones, _ := ipv6VPCNet.Mask.Size(); ones > 56 {
+			return fmt.Errorf("ipv6.vpcCIDR (%s) must be a /56 or larger IPv6 CIDR block", ipv6.VPCCIDR)
+		}
+	} else if ipv6.VPCCIDR != "" {
+		return errors.New("`ipv6.vpcCIDR` can be specified only for an existing VPC. An Amazon-provided IPv6 CIDR block is associated with the VPC managed by kube-aws")
+	}
+
+	if ipv6.PodCIDR == "" || ipv6.ServiceCIDR == "" {
+		return errors.New("both `ipv6.podCIDR` and `ipv6.serviceCIDR` must be set when `ipv6.enabled` is true")
+	}
+	podNet, err := netutil.ParseIPv6CIDR(ipv6.PodCIDR)
+	if err != nil {
+		return fmt.Errorf("invalid ipv6.podCIDR: %v", err)
+	}
+	serviceNet, err := netutil.ParseIPv6CIDR(ipv6.ServiceCIDR)
+	if err != nil {
+		return fmt.Errorf("invalid ipv6.serviceCIDR: %v", err)
+	}
+	if ones, _ := serviceNet.Mask.Size(); ones < minIPv6ServiceCIDRPrefixLength {
+		return fmt.Errorf("ipv6.serviceCIDR (%s) is too large. Its prefix length must be %d or greater", ipv6.ServiceCIDR, minIPv6ServiceCIDRPrefixLength)
+	}
+	if netutil.CidrOverlap(serviceNet, podNet) {
+		return fmt.Errorf("ipv6.serviceCIDR (%s) overlaps with ipv6.podCIDR (%s)", ipv6.ServiceCIDR, ipv6.PodCIDR)
+	}
+	if ipv6VPCNet != nil {
+		if netutil.CidrOverlap(serviceNet, ipv6VPCNet) {
+			return fmt.Errorf("ipv6.vpcCIDR (%s) overlaps with ipv6.serviceCIDR (%s)", ipv6.VPCCIDR, ipv6.ServiceCIDR)
+		}
+		if netutil.CidrOverlap(podNet, ipv6VPCNet) {
+			return fmt.Errorf("ipv6.vpcCIDR (%s) overlaps with ipv6.podCIDR (%s)", ipv6.VPCCIDR, ipv6.PodCIDR)
+		}
+	}
+
+	seen := map[int]int{}
+	for i, s := range c.Subnets {
+		if !s.ManageSubnet() {
+			continue
+		}
+		index := i
+		if s.IPv6CIDRIndex != nil {
+			index = *s.IPv6CIDRIndex
+		}
+		if index < 0 || index >= maxIPv6SubnetsPerVPC {
+			return fmt.Errorf("ipv6CIDRIndex of subnet #%d must be between 0 and %d but was %d", i, maxIPv6SubnetsPerVPC-1, index)
+		}
+		if j, ok := seen[index]; ok {
+			return fmt.Errorf("subnet #%d and subnet #%d are assigned the same ipv6CIDRIndex %d", j, i, index)
+		}
+		seen[index] = i
+	}
+
+	logger.Warnf("ipv6 is enabled but the %s pod network doesn't assign IPv6 addresses to pods by itself. Make sure to deploy a CNI plugin supporting dual-stack", c.Kubernetes.Networking.SelfHosting.Type)
+
+	return nil
+}
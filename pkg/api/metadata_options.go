@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+)
+
+const (
+	HttpTokensOptional = "optional"
+	HttpTokensRequired = "required"
+
+	HttpEndpointEnabled  = "enabled"
+	HttpEndpointDisabled = "disabled"
+)
+
+// MetadataOptions configures the instance metadata service(IMDS) of EC2 instances launched from a launch template.
+// Omitted fields are left to the EC2 defaults, which are `httpTokens: optional`, `httpPutResponseHopLimit: 1` and `httpEndpoint: enabled`
+type MetadataOptions struct {
+	// HttpTokens is either `optional` or `required`. `required` enforces IMDSv2, that is, every request to IMDS must be accompanied by a session token
+	HttpTokens string `yaml:"httpTokens,omitempty"`
+	// HttpPutResponseHopLimit is the hop limit of responses to session token requests, between 1 and 64.
+	// `1` prevents pods not in the host network from obtaining session tokens
+	HttpPutResponseHopLimit int `yaml:"httpPutResponseHopLimit,omitempty"`
+	// HttpEndpoint is either `enabled` or `disabled`
+	HttpEndpoint string `yaml:"httpEndpoint,omitempty"`
+	UnknownKeys  `yaml:",inline"`
+}
+
+// HasOptions returns true when any of the metadata options is specified so that it should be rendered into a launch template
+func (o MetadataOptions) HasOptions() bool {
+	return o.HttpTokens != "" || o.HttpPutResponseHopLimit != 0 || o.HttpEndpoint != ""
+}
+
+// TokensRequired returns true when the instance metadata can only be retrieved with session tokens
+func (o MetadataOptions) TokensRequired() bool {
+	return o.HttpTokens == HttpTokensRequired
+}
+
+// LaunchTemplateMetadataOptions returns the value of the `MetadataOptions` property of `AWS::EC2::LaunchTemplate`'s `LaunchTemplateData`
+func (o MetadataOptions) LaunchTemplateMetadataOptions() map[string]interface{} {
+	opts := map[string]interface{}{}
+	if o.HttpTokens != "" {
+		opts["HttpTokens"] = o.HttpTokens
+	}
+	if o.HttpPutResponseHopLimit != 0 {
+		opts["HttpPutResponseHopLimit"] = o.HttpPutResponseHopLimit
+	}
+	if o.HttpEndpoint != "" {
+		opts["HttpEndpoint"] = o.HttpEndpoint
+	}
+	return opts
+}
+
+// ValidateAWSCliImage rejects `httpTokens: required` unless the AWS CLI image nodes run is pinned.
+// Scripts run by kube-aws e.g. decrypt-assets call the AWS APIs with the image, whose AWS CLI obtains the IAM credentials
+// of the node from the instance metadata, with session tokens only since 1.16.287. The default image follows the master branch
+// of quay.io/coreos/awscli, which can't be relied on to be that recent
+func (o MetadataOptions) ValidateAWSCliImage(path string, image Image) error {
+	if o.TokensRequired() && image.Repo == defaultAWSCliImageRepo && image.Tag == defaultAWSCliImageTag {
+		return fmt.Errorf("`%s.metadataOptions.httpTokens` can't be \"%s\" with the default `awsCliImage` %s, which isn't pinned to a version of AWS CLI. "+
+			"Set `awsCliImage` to an image of AWS CLI 1.16.287 or greater, which obtains the IAM credentials of nodes with session tokens", path, HttpTokensRequired, image.RepoWithTag())
+	}
+	return nil
+}
+
+func (o MetadataOptions) Validate(path string) error {
+	switch o.HttpTokens {
+	case "", HttpTokensOptional, HttpTokensRequired:
+	default:
+		return fmt.Errorf("`%s.metadataOptions.httpTokens` must be either \"%s\" or \"%s\", but was \"%s\"", path, HttpTokensOptional, HttpTokensRequired, o.HttpTokens)
+	}
+
+	if o.HttpPutResponseHopLimit != 0 && (o.HttpPutResponseHopLimit < 1 || o.HttpPutResponseHopLimit > 64) {
+		return fmt.Errorf("`%s.metadataOptions.httpPutResponseHopLimit` must be between 1 and 64, but was %d", path, o.HttpPutResponseHopLimit)
+	}
+
+	switch o.HttpEndpoint {
+	case "", HttpEndpointEnabled:
+	case HttpEndpointDisabled:
+		// Nodes rely on IMDS for their IAM credentials, which are required to fetch userdata from S3, to call the AWS APIs from kubelet and so on
+		return fmt.Errorf("`%s.metadataOptions.httpEndpoint` can't be \"%s\" because kube-aws nodes obtain their IAM credentials from the instance metadata", path, HttpEndpointDisabled)
+	default:
+		return fmt.Errorf("`%s.metadataOptions.httpEndpoint` must be either \"%s\" or \"%s\", but was \"%s\"", path, HttpEndpointEnabled, HttpEndpointDisabled, o.HttpEndpoint)
+	}
+
+	return nil
+}
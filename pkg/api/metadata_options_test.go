@@ -0,0 +1,65 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetadataOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		opts MetadataOptions
+		err  string
+	}{
+		{MetadataOptions{}, ""},
+		{MetadataOptions{HttpTokens: "required", HttpPutResponseHopLimit: 1, HttpEndpoint: "enabled"}, ""},
+		{MetadataOptions{HttpTokens: "optional", HttpPutResponseHopLimit: 64}, ""},
+		{MetadataOptions{HttpTokens: "v2"}, "`controller.metadataOptions.httpTokens` must be either \"optional\" or \"required\""},
+		{MetadataOptions{HttpPutResponseHopLimit: 65}, "must be between 1 and 64"},
+		{MetadataOptions{HttpPutResponseHopLimit: -1}, "must be between 1 and 64"},
+		{MetadataOptions{HttpEndpoint: "disabled"}, "nodes obtain their IAM credentials from the instance metadata"},
+		{MetadataOptions{HttpEndpoint: "off"}, "must be either \"enabled\" or \"disabled\""},
+	}
+
+	for _, tc := range testCases {
+		err := tc.opts.Validate("controller")
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %+v: %v", tc.opts, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" for %+v but was: %v", tc.err, tc.opts, err)
+		}
+	}
+}
+
+func TestMetadataOptionsValidateAWSCliImage(t *testing.T) {
+	defaultImage := Image{Repo: defaultAWSCliImageRepo, Tag: defaultAWSCliImageTag}
+	pinnedImage := Image{Repo: defaultAWSCliImageRepo, Tag: "1.18.69"}
+
+	if err := (MetadataOptions{HttpTokens: "required"}).ValidateAWSCliImage("etcd", defaultImage); err == nil || !strings.Contains(err.Error(), "`etcd.metadataOptions.httpTokens` can't be \"required\" with the default `awsCliImage`") {
+		t.Errorf("expected enforcing IMDSv2 with the default awscli image to be rejected but was: %v", err)
+	}
+	for _, opts := range []MetadataOptions{{}, {HttpTokens: "optional"}} {
+		if err := opts.ValidateAWSCliImage("etcd", defaultImage); err != nil {
+			t.Errorf("unexpected error for %+v: %v", opts, err)
+		}
+	}
+	if err := (MetadataOptions{HttpTokens: "required"}).ValidateAWSCliImage("etcd", pinnedImage); err != nil {
+		t.Errorf("unexpected error for the pinned awscli image: %v", err)
+	}
+}
+
+func TestMetadataOptionsLaunchTemplateMetadataOptions(t *testing.T) {
+	if (MetadataOptions{}).HasOptions() {
+		t.Error("empty metadata options must not be rendered")
+	}
+
+	opts := MetadataOptions{HttpTokens: "required", HttpPutResponseHopLimit: 2}
+	expected := map[string]interface{}{"HttpTokens": "required", "HttpPutResponseHopLimit": 2}
+	if actual := opts.LaunchTemplateMetadataOptions(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected metadata options: expected=%v, actual=%v", expected, actual)
+	}
+}
@@ -1,6 +1,15 @@
 package api
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// See https://docs.aws.amazon.com/autoscaling/ec2/userguide/ec2-auto-scaling-mixed-instances-groups.html#allocation-strategies
+	onDemandAllocationStrategies = []string{"prioritized", "lowest-price"}
+	spotAllocationStrategies     = []string{"lowest-price", "capacity-optimized", "capacity-optimized-prioritized", "price-capacity-optimized"}
+)
 
 type MixedInstances struct {
 	Enabled                             bool     `yaml:"enabled,omitempty"`
@@ -11,12 +20,20 @@ type MixedInstances struct {
 	SpotInstancePools                   int      `yaml:"spotInstancePools,omitempty"`
 	SpotMaxPrice                        string   `yaml:"spotMaxPrice,omitempty"`
 	InstanceTypes                       []string `yaml:"instanceTypes,omitempty"`
+	// Overrides are instance types with the number of capacity units each instance provides.
+	// The size and the desired capacity of the auto scaling group are counted in units instead of instances when specified
+	Overrides []InstanceTypeOverride `yaml:"overrides,omitempty"`
+}
+
+type InstanceTypeOverride struct {
+	InstanceType     string `yaml:"instanceType,omitempty"`
+	WeightedCapacity int    `yaml:"weightedCapacity,omitempty"`
 }
 
 func (mi MixedInstances) Validate() error {
 	// See https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-properties-autoscaling-autoscalinggroup-instancesdistribution.html for valid values
-	if mi.OnDemandAllocationStrategy != "" && mi.OnDemandAllocationStrategy != "prioritized" {
-		return fmt.Errorf("`mixedInstances.onDemandAllocationStrategy` must be equal to 'prioritized' if specified")
+	if mi.OnDemandAllocationStrategy != "" && !containsString(onDemandAllocationStrategies, mi.OnDemandAllocationStrategy) {
+		return fmt.Errorf("`mixedInstances.onDemandAllocationStrategy` must be one of %s if specified", quoteAll(onDemandAllocationStrategies))
 	}
 	if mi.OnDemandBaseCapacity < 0 {
 		return fmt.Errorf("`mixedInstances.onDemandBaseCapacity` (%d) must be zero or greater if specified", mi.OnDemandBaseCapacity)
@@ -24,15 +41,74 @@ func (mi MixedInstances) Validate() error {
 	if mi.OnDemandPercentageAboveBaseCapacity < 0 || mi.OnDemandPercentageAboveBaseCapacity > 100 {
 		return fmt.Errorf("`mixedInstances.onDemandPercentageAboveBaseCapacity` (%d) must be in range 0-100", mi.OnDemandPercentageAboveBaseCapacity)
 	}
-	if mi.SpotAllocationStrategy != "" && mi.SpotAllocationStrategy != "lowest-price" {
-		return fmt.Errorf("`mixedInstances.spotAllocationStrategy` must be equal to 'lowest-price' if specified")
+	if mi.SpotAllocationStrategy != "" && !containsString(spotAllocationStrategies, mi.SpotAllocationStrategy) {
+		return fmt.Errorf("`mixedInstances.spotAllocationStrategy` must be one of %s if specified", quoteAll(spotAllocationStrategies))
 	}
 	if mi.SpotInstancePools < 0 || mi.SpotInstancePools > 20 {
 		return fmt.Errorf("`mixedInstances.spotInstancePools` (%d) must be in range 0-20", mi.SpotInstancePools)
 	}
+	if mi.SpotInstancePools != 0 && !mi.UsesSpotInstancePools() {
+		return fmt.Errorf("`mixedInstances.spotInstancePools` can be specified only when `mixedInstances.spotAllocationStrategy` is 'lowest-price'")
+	}
 	if len(mi.SpotMaxPrice) > 255 {
 		return fmt.Errorf("`mixedInstances.spotMaxPrice` can have a maximum length of 255")
 	}
+	if len(mi.InstanceTypes) > 0 && len(mi.Overrides) > 0 {
+		return fmt.Errorf("`mixedInstances.instanceTypes` and `mixedInstances.overrides` can't be specified at the same time")
+	}
+	for i, o := range mi.Overrides {
+		if o.InstanceType == "" {
+			return fmt.Errorf("`mixedInstances.overrides[%d].instanceType` must be specified", i)
+		}
+		if o.WeightedCapacity < 0 || o.WeightedCapacity > 999 {
+			return fmt.Errorf("`mixedInstances.overrides[%d].weightedCapacity` (%d) must be in range 1-999 if specified", i, o.WeightedCapacity)
+		}
+	}
 
 	return nil
 }
+
+// UsesSpotInstancePools returns true when spot instances are diversified across the `spotInstancePools` cheapest instance types.
+// Other allocation strategies don't accept the number of pools
+func (mi MixedInstances) UsesSpotInstancePools() bool {
+	return mi.SpotAllocationStrategy == "" || mi.SpotAllocationStrategy == "lowest-price"
+}
+
+// InstanceTypeOverrides returns the instance types the auto scaling group launches, either from `instanceTypes` or `overrides`
+func (mi MixedInstances) InstanceTypeOverrides() []InstanceTypeOverride {
+	if len(mi.Overrides) > 0 {
+		return mi.Overrides
+	}
+	overrides := make([]InstanceTypeOverride, len(mi.InstanceTypes))
+	for i, t := range mi.InstanceTypes {
+		overrides[i] = InstanceTypeOverride{InstanceType: t}
+	}
+	return overrides
+}
+
+// HasWeightedCapacity returns true when any instance counts as more than one unit of capacity
+func (mi MixedInstances) HasWeightedCapacity() bool {
+	for _, o := range mi.Overrides {
+		if o.WeightedCapacity > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + v + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -2,13 +2,20 @@ package api
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
+// DefaultNodeDrainerImageRepo is the repository of the kube-aws image the node drainer runs by default
+const DefaultNodeDrainerImageRepo = "quay.io/kube-aws/kube-aws"
+
 type NodeDrainer struct {
 	Enabled      bool    `yaml:"enabled"`
 	DrainTimeout int     `yaml:"drainTimeout"`
 	IAMRole      IAMRole `yaml:"iamRole,omitempty"`
+	// Image is the kube-aws image to run `kube-aws node-drainer`, `kube-aws spot-interruption-handler` and `kube-aws decrypt-envelope-assets` with.
+	// Defaults to DefaultNodeDrainerImageRepo tagged with the version of kube-aws rendering the cluster
+	Image Image `yaml:"image,omitempty"`
 }
 
 func (nd *NodeDrainer) DrainTimeoutInSeconds() int {
@@ -26,3 +33,8 @@ func (nd *NodeDrainer) Validate() error {
 
 	return nil
 }
+
+// NodeDrainerQueueName returns the name of the SQS queue the node drainer rules send lifecycle actions and spot interruption warnings to
+func (c *Cluster) NodeDrainerQueueName() string {
+	return strings.Replace(c.ClusterName, ":", "-", -1) + "-node-drainer"
+}
@@ -92,3 +92,11 @@ func TestValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestNodeDrainerQueueName(t *testing.T) {
+	c := Cluster{}
+	c.ClusterName = "test:cluster"
+	if actual := c.NodeDrainerQueueName(); actual != "test-cluster-node-drainer" {
+		t.Errorf("Expected node drainer queue name to be test-cluster-node-drainer, but was %s", actual)
+	}
+}
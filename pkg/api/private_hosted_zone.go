@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultControllerRecordName is the default value for the privateHostedZone.controllerRecordName key
+const DefaultControllerRecordName = "controllers"
+
+// PrivateHostedZone configures a Route 53 private hosted zone created in the network stack and owned by the cluster.
+// etcd nodes, API endpoints and controller nodes get their DNS records in it when they don't specify other hosted zones
+type PrivateHostedZone struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Name is the domain name of the hosted zone e.g. `mycluster.internal`
+	Name string `yaml:"name,omitempty"`
+	// AdditionalVPCs are VPCs other than the cluster's one the hosted zone is associated with, such as a shared services VPC peered with the cluster's one
+	AdditionalVPCs []PrivateHostedZoneVPC `yaml:"additionalVPCs,omitempty"`
+	// ControllerRecordName is the name relative to the hosted zone every controller node registers the private IP of itself to. Defaults to `controllers`
+	ControllerRecordName string `yaml:"controllerRecordName,omitempty"`
+	UnknownKeys          `yaml:",inline"`
+}
+
+// PrivateHostedZoneVPC is a VPC a private hosted zone is associated with
+type PrivateHostedZoneVPC struct {
+	ID string `yaml:"id,omitempty"`
+	// Region is the region the VPC is in. Defaults to the region of the cluster
+	Region      string `yaml:"region,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+// VPCRegion returns the region the VPC is in
+func (v PrivateHostedZoneVPC) VPCRegion(r Region) string {
+	if v.Region == "" {
+		return r.Name
+	}
+	return v.Region
+}
+
+// DomainName returns the name of the hosted zone without the trailing dot
+func (z PrivateHostedZone) DomainName() string {
+	return strings.TrimSuffix(z.Name, ".")
+}
+
+// Contains returns true if the DNS name is the apex of or a subdomain of the hosted zone
+func (z PrivateHostedZone) Contains(dnsName string) bool {
+	if !z.Enabled || z.DomainName() == "" {
+		return false
+	}
+	n := strings.TrimSuffix(dnsName, ".")
+	return n == z.DomainName() || strings.HasSuffix(n, "."+z.DomainName())
+}
+
+// ControllerRecordSetName returns the DNS name resolved to the private IPs of all the healthy controller nodes
+func (z PrivateHostedZone) ControllerRecordSetName() string {
+	name := z.ControllerRecordName
+	if name == "" {
+		name = DefaultControllerRecordName
+	}
+	return fmt.Sprintf("%s.%s", name, z.DomainName())
+}
+
+// ControllerHealthCheck returns the health check controller nodes run before registering themselves to the hosted zone
+func (z PrivateHostedZone) ControllerHealthCheck() APIEndpointLBHealthCheck {
+	return APIEndpointLBHealthCheck{}
+}
+
+// ControllerRecordSetTTL returns the TTL of A records of controller nodes
+func (z PrivateHostedZone) ControllerRecordSetTTL() int {
+	return DefaultDNSRoundRobinRecordSetTTL
+}
+
+// Ref returns a CloudFormation ref to the ID of the hosted zone, which can be used from the stacks other than the network stack
+func (z PrivateHostedZone) Ref() string {
+	return `{"Fn::ImportValue" : {"Fn::Sub" : "${NetworkStackName}-PrivateHostedZone"}}`
+}
+
+func (z PrivateHostedZone) Validate() error {
+	if !z.Enabled {
+		if z.Name != "" || len(z.AdditionalVPCs) > 0 || z.ControllerRecordName != "" {
+			return errors.New("`privateHostedZone.name`, `privateHostedZone.additionalVPCs` and `privateHostedZone.controllerRecordName` can't be specified unless `privateHostedZone.enabled` is true")
+		}
+		return nil
+	}
+
+	if z.DomainName() == "" {
+		return errors.New("`privateHostedZone.name` must be specified when `privateHostedZone.enabled` is true")
+	}
+	if strings.Contains(z.ControllerRecordName, ".") {
+		return fmt.Errorf("`privateHostedZone.controllerRecordName` must be a single label relative to the hosted zone, but was \"%s\"", z.ControllerRecordName)
+	}
+
+	seen := map[string]bool{}
+	for i, v := range z.AdditionalVPCs {
+		if !strings.HasPrefix(v.ID, "vpc-") {
+			return fmt.Errorf("`privateHostedZone.additionalVPCs[%d].id` must be a VPC ID starting with \"vpc-\", but was \"%s\"", i, v.ID)
+		}
+		if seen[v.ID] {
+			return fmt.Errorf("VPC \"%s\" is duplicated in `privateHostedZone.additionalVPCs`", v.ID)
+		}
+		seen[v.ID] = true
+	}
+	return nil
+}
+
+// usePrivateHostedZone makes etcd nodes and API endpoint load balancers without their own hosted zones create records in the private hosted zone.
+// This must be done before validation because a managed record set of an API endpoint load balancer requires a hosted zone
+func (c *Cluster) usePrivateHostedZone() {
+	z := c.PrivateHostedZone
+	if !z.Enabled || z.DomainName() == "" {
+		return
+	}
+
+	e := &c.Etcd.Cluster
+	if e.NodeShouldHaveSecondaryENI() && !e.HostedZone.HasIdentifier() && e.HostedZone.IDFromFn == "" && (e.ManageRecordSets == nil || *e.ManageRecordSets) {
+		if e.InternalDomainName == "" {
+			e.InternalDomainName = z.DomainName()
+		}
+		if z.Contains(e.InternalDomainName) {
+			e.HostedZone.IDFromFn = z.Ref()
+		}
+	}
+
+	for i, ep := range c.APIEndpointConfigs {
+		lb := ep.LoadBalancer
+		if lb.Identifier.HasIdentifier() || (lb.Managed != nil && !*lb.Managed) || lb.RecordSetManaged != nil || lb.hostedZoneSpecified() {
+			continue
+		}
+		if z.Contains(ep.DNSName) {
+			c.APIEndpointConfigs[i].LoadBalancer.HostedZone.IDFromFn = z.Ref()
+		}
+	}
+}
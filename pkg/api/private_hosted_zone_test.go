@@ -0,0 +1,69 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrivateHostedZoneValidate(t *testing.T) {
+	testCases := []struct {
+		zone PrivateHostedZone
+		err  string
+	}{
+		{PrivateHostedZone{}, ""},
+		{PrivateHostedZone{Enabled: true, Name: "mycluster.internal"}, ""},
+		{PrivateHostedZone{Enabled: true, Name: "mycluster.internal.", ControllerRecordName: "cp", AdditionalVPCs: []PrivateHostedZoneVPC{{ID: "vpc-1"}, {ID: "vpc-2", Region: "us-east-1"}}}, ""},
+		{PrivateHostedZone{Name: "mycluster.internal"}, "can't be specified unless `privateHostedZone.enabled` is true"},
+		{PrivateHostedZone{Enabled: true}, "`privateHostedZone.name` must be specified"},
+		{PrivateHostedZone{Enabled: true, Name: "mycluster.internal", ControllerRecordName: "cp.k8s"}, "must be a single label"},
+		{PrivateHostedZone{Enabled: true, Name: "mycluster.internal", AdditionalVPCs: []PrivateHostedZoneVPC{{ID: "subnet-1"}}}, "must be a VPC ID"},
+		{PrivateHostedZone{Enabled: true, Name: "mycluster.internal", AdditionalVPCs: []PrivateHostedZoneVPC{{ID: "vpc-1"}, {ID: "vpc-1"}}}, "VPC \"vpc-1\" is duplicated"},
+	}
+
+	for _, tc := range testCases {
+		err := tc.zone.Validate()
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %+v: %v", tc.zone, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" for %+v but was: %v", tc.err, tc.zone, err)
+		}
+	}
+}
+
+func TestPrivateHostedZoneNames(t *testing.T) {
+	z := PrivateHostedZone{Enabled: true, Name: "mycluster.internal."}
+
+	for name, expected := range map[string]bool{
+		"mycluster.internal":           true,
+		"api.mycluster.internal.":      true,
+		"etcd0.k8s.mycluster.internal": true,
+		"othercluster.internal":        false,
+		"api.xmycluster.internal":      false,
+	} {
+		if actual := z.Contains(name); actual != expected {
+			t.Errorf("unexpected result of Contains(%s): expected=%v, actual=%v", name, expected, actual)
+		}
+	}
+
+	if actual := z.ControllerRecordSetName(); actual != "controllers.mycluster.internal" {
+		t.Errorf("unexpected controller record set name: %s", actual)
+	}
+
+	if (PrivateHostedZone{Name: "mycluster.internal"}).Contains("api.mycluster.internal") {
+		t.Error("a disabled private hosted zone must not contain any name")
+	}
+}
+
+func TestPrivateHostedZoneVPCRegion(t *testing.T) {
+	r := RegionForName("us-west-1")
+	if actual := (PrivateHostedZoneVPC{ID: "vpc-1"}).VPCRegion(r); actual != "us-west-1" {
+		t.Errorf("expected the region to default to the cluster's one but was %s", actual)
+	}
+	if actual := (PrivateHostedZoneVPC{ID: "vpc-1", Region: "eu-west-1"}).VPCRegion(r); actual != "eu-west-1" {
+		t.Errorf("unexpected region: %s", actual)
+	}
+}
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/logger"
+)
+
+const (
+	VPCEndpointTypeGateway   = "Gateway"
+	VPCEndpointTypeInterface = "Interface"
+)
+
+// privateLinkServices maps each service accepted in `privateLinks.services` to the AWS services its VPC endpoints are created for
+var privateLinkServices = map[string][]string{
+	"s3":                   {"s3"},
+	"ecr":                  {"ecr.api", "ecr.dkr"},
+	"ec2":                  {"ec2"},
+	"sts":                  {"sts"},
+	"cloudformation":       {"cloudformation"},
+	"kms":                  {"kms"},
+	"logs":                 {"logs"},
+	"ssm":                  {"ssm", "ssmmessages", "ec2messages"},
+	"elasticloadbalancing": {"elasticloadbalancing"},
+	"autoscaling":          {"autoscaling"},
+}
+
+// defaultPrivateLinkServices are the services nodes talk to while bootstrapping and running, in the order of the resulting endpoints
+var defaultPrivateLinkServices = []string{
+	"s3",
+	"ecr",
+	"ec2",
+	"sts",
+	"cloudformation",
+	"kms",
+	"logs",
+	"ssm",
+	"elasticloadbalancing",
+	"autoscaling",
+}
+
+// PrivateLinks configures VPC endpoints so that nodes are able to reach AWS services without going through NAT gateways or the internet gateway
+type PrivateLinks struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Services are the AWS services VPC endpoints are created for. Defaults to all the supported services
+	Services []string `yaml:"services,omitempty"`
+	// DisableInternetEgress prevents kube-aws from creating NAT gateways, the internet gateway and routes to them
+	// so that the cluster is fully private
+	DisableInternetEgress bool `yaml:"disableInternetEgress,omitempty"`
+	UnknownKeys           `yaml:",inline"`
+}
+
+// VPCEndpoint is a VPC endpoint created in the network stack for one of the services enabled via `privateLinks`
+type VPCEndpoint struct {
+	// Service is the name of the AWS service without the `com.amazonaws.<region>.` prefix e.g. `ecr.dkr`
+	Service string
+	// Type is either Gateway or Interface
+	Type string
+}
+
+// LogicalName returns the logical name of the cfn resource for the VPC endpoint
+func (e VPCEndpoint) LogicalName() string {
+	name := "VPCEndpoint"
+	for _, part := range strings.Split(e.Service, ".") {
+		name += strings.Title(part)
+	}
+	return name
+}
+
+// Gateway returns true if the VPC endpoint is a gateway endpoint associated to route tables rather than an interface endpoint with ENIs in subnets
+func (e VPCEndpoint) Gateway() bool {
+	return e.Type == VPCEndpointTypeGateway
+}
+
+// ServiceName returns the full name of the AWS service the VPC endpoint connects to
+func (e VPCEndpoint) ServiceName(r Region) string {
+	// Interface endpoints in China regions are named in the reverse domain name of amazonaws.com.cn
+	if r.IsChina() && !e.Gateway() {
+		return fmt.Sprintf("cn.com.amazonaws.%s.%s", r.Name, e.Service)
+	}
+	return fmt.Sprintf("com.amazonaws.%s.%s", r.Name, e.Service)
+}
+
+// EnabledServices returns the services VPC endpoints are created for
+func (c PrivateLinks) EnabledServices() []string {
+	if len(c.Services) == 0 {
+		return defaultPrivateLinkServices
+	}
+	return c.Services
+}
+
+// HasService returns true if VPC endpoints are created for the service
+func (c PrivateLinks) HasService(name string) bool {
+	if !c.Enabled {
+		return false
+	}
+	for _, s := range c.EnabledServices() {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Endpoints returns the VPC endpoints to be created in the network stack
+func (c PrivateLinks) Endpoints() []VPCEndpoint {
+	endpoints := []VPCEndpoint{}
+	if !c.Enabled {
+		return endpoints
+	}
+	for _, s := range c.EnabledServices() {
+		for _, svc := range privateLinkServices[s] {
+			t := VPCEndpointTypeInterface
+			if svc == "s3" {
+				t = VPCEndpointTypeGateway
+			}
+			endpoints = append(endpoints, VPCEndpoint{Service: svc, Type: t})
+		}
+	}
+	return endpoints
+}
+
+// InterfaceEndpoints returns the VPC endpoints which need ENIs in subnets and the security group
+func (c PrivateLinks) InterfaceEndpoints() []VPCEndpoint {
+	endpoints := []VPCEndpoint{}
+	for _, e := range c.Endpoints() {
+		if !e.Gateway() {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
+
+// InternetEgressDisabled returns true if the cluster is fully private and reaches AWS services only via VPC endpoints
+func (c PrivateLinks) InternetEgressDisabled() bool {
+	return c.Enabled && c.DisableInternetEgress
+}
+
+func (c PrivateLinks) Validate() error {
+	if !c.Enabled {
+		if len(c.Services) > 0 || c.DisableInternetEgress {
+			return errors.New("`privateLinks.services` and `privateLinks.disableInternetEgress` can't be specified unless `privateLinks.enabled` is true")
+		}
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, s := range c.Services {
+		if _, ok := privateLinkServices[s]; !ok {
+			return fmt.Errorf("unsupported service \"%s\" in `privateLinks.services`. It must be one of: %s", s, strings.Join(defaultPrivateLinkServices, ", "))
+		}
+		if seen[s] {
+			return fmt.Errorf("service \"%s\" is duplicated in `privateLinks.services`", s)
+		}
+		seen[s] = true
+	}
+	return nil
+}
+
+func (c Cluster) validatePrivateLinks() error {
+	p := c.PrivateLinks
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	if !p.Enabled {
+		return nil
+	}
+
+	if !p.DisableInternetEgress {
+		if !p.HasService("s3") {
+			logger.Warn("VPC endpoint for s3 is not enabled in `privateLinks.services`. Nodes without internet egress can't fetch their userdata from s3")
+		}
+		return nil
+	}
+
+	if c.IPv6.Enabled {
+		return errors.New("`ipv6.enabled` can't be true when `privateLinks.disableInternetEgress` is true because private subnets route IPv6 traffic via an egress-only internet gateway")
+	}
+	if len(c.Subnets) == 0 {
+		return errors.New("`subnets` must be specified and all of them must be private when `privateLinks.disableInternetEgress` is true")
+	}
+	for i, s := range c.Subnets {
+		if !s.Private {
+			return fmt.Errorf("subnets[%d] must be private when `privateLinks.disableInternetEgress` is true", i)
+		}
+		if s.NATGateway.HasIdentifier() {
+			return fmt.Errorf("`subnets[%d].natGateway` can't be specified when `privateLinks.disableInternetEgress` is true", i)
+		}
+	}
+	if c.ExternalDNSName != "" && !c.Controller.LoadBalancer.Private {
+		return errors.New("`controller.loadBalancer.private` must be true when `privateLinks.disableInternetEgress` is true")
+	}
+	for i, e := range c.APIEndpointConfigs {
+		if e.LoadBalancer.ManageELB() && !e.LoadBalancer.Private() {
+			return fmt.Errorf("`apiEndpoints[%d].loadBalancer.private` must be true when `privateLinks.disableInternetEgress` is true", i)
+		}
+	}
+
+	if unreachable := c.unreachableEndpoints(); len(unreachable) > 0 {
+		return fmt.Errorf("`privateLinks.services` must include %s when `privateLinks.disableInternetEgress` is true: %s",
+			strings.Join(unreachable.services(), ", "), strings.Join(unreachable.reasons(), ", "))
+	}
+
+	// Whatever else is fetched from the internet might still be reachable e.g. via a proxy or a peered VPC
+	for _, w := range c.egressRequirements() {
+		logger.Warnf("%s, which requires internet egress. It won't work while `privateLinks.disableInternetEgress` is true\n", w)
+	}
+
+	return nil
+}
+
+// unreachableEndpoint is an AWS API nodes call, which has no VPC endpoint enabled via `privateLinks.services`
+type unreachableEndpoint struct {
+	service string
+	reason  string
+}
+
+type unreachableEndpoints []unreachableEndpoint
+
+func (es unreachableEndpoints) services() []string {
+	services := []string{}
+	for _, e := range es {
+		services = append(services, e.service)
+	}
+	return services
+}
+
+func (es unreachableEndpoints) reasons() []string {
+	reasons := []string{}
+	for _, e := range es {
+		reasons = append(reasons, e.reason)
+	}
+	return reasons
+}
+
+// unreachableEndpoints returns the AWS APIs nodes can't call without internet egress because no VPC endpoints are created for them
+func (c Cluster) unreachableEndpoints() unreachableEndpoints {
+	p := c.PrivateLinks
+	es := unreachableEndpoints{}
+
+	if !p.HasService("s3") {
+		es = append(es, unreachableEndpoint{"s3", "nodes fetch their userdata from s3"})
+	}
+	for _, svc := range []string{"ec2", "sts", "cloudformation", "autoscaling"} {
+		if !p.HasService(svc) {
+			es = append(es, unreachableEndpoint{svc, fmt.Sprintf("nodes call the %s API while bootstrapping", svc)})
+		}
+	}
+	if c.KMSKeyARN != "" && !p.HasService("kms") {
+		es = append(es, unreachableEndpoint{"kms", "`kmsKeyArn` is specified"})
+	}
+	if c.CloudWatchLogging.Enabled && !p.HasService("logs") {
+		es = append(es, unreachableEndpoint{"logs", "`cloudWatchLogging.enabled` is true"})
+	}
+	if c.AmazonSsmAgent.Enabled && !p.HasService("ssm") {
+		es = append(es, unreachableEndpoint{"ssm", "`amazonSsmAgent.enabled` is true"})
+	}
+	return es
+}
+
+// egressRequirements returns the settings which still require internet egress
+func (c Cluster) egressRequirements() []string {
+	reqs := []string{}
+
+	if !c.DisableContainerLinuxAutomaticUpdates {
+		reqs = append(reqs, "automatic updates are enabled via `disableContainerLinuxAutomaticUpdates: false` and update-engine polls the public flatcar update endpoint")
+	}
+	if c.AmazonSsmAgent.Enabled && c.AmazonSsmAgent.DownloadUrl != "" {
+		reqs = append(reqs, fmt.Sprintf("the amazon ssm agent is downloaded from %s", c.AmazonSsmAgent.DownloadUrl))
+	}
+
+	for _, i := range c.publicImages() {
+		reqs = append(reqs, fmt.Sprintf("`%s` is pulled from the public image registry %s", i.key, i.image.Repo))
+	}
+
+	return reqs
+}
+
+type namedImage struct {
+	key   string
+	image Image
+}
+
+// publicImages returns the images which are not pulled from the ECR of the region the cluster is deployed to
+func (c Cluster) publicImages() []namedImage {
+	images := []namedImage{
+		{"hyperkubeImage", c.HyperkubeImage},
+		{"awsCliImage", c.AWSCliImage},
+		{"clusterProportionalAutoscalerImage", c.ClusterProportionalAutoscalerImage},
+		{"coreDnsImage", c.CoreDnsImage},
+		{"kubeDnsImage", c.KubeDnsImage},
+		{"kubeDnsMasqImage", c.KubeDnsMasqImage},
+		{"kubeReschedulerImage", c.KubeReschedulerImage},
+		{"dnsMasqMetricsImage", c.DnsMasqMetricsImage},
+		{"execHealthzImage", c.ExecHealthzImage},
+		{"helmImage", c.HelmImage},
+		{"tillerImage", c.TillerImage},
+		{"metricsServerImage", c.MetricsServerImage},
+		{"addonResizerImage", c.AddonResizerImage},
+		{"pauseImage", c.PauseImage},
+		{"journaldCloudWatchLogsImage", c.JournaldCloudWatchLogsImage},
+	}
+
+	ecr := fmt.Sprintf(".dkr.ecr.%s.%s/", c.Region.Name, c.Region.PublicDomainName())
+	result := []namedImage{}
+	for _, i := range images {
+		if i.image.Repo == "" {
+			continue
+		}
+		if c.PrivateLinks.HasService("ecr") && strings.Contains(i.image.Repo, ecr) {
+			continue
+		}
+		result = append(result, i)
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrivateLinksEndpoints(t *testing.T) {
+	p := PrivateLinks{Enabled: true, Services: []string{"s3", "ecr", "ssm"}}
+
+	logicalNames := []string{}
+	for _, e := range p.Endpoints() {
+		logicalNames = append(logicalNames, e.LogicalName())
+	}
+	expected := []string{
+		"VPCEndpointS3",
+		"VPCEndpointEcrApi",
+		"VPCEndpointEcrDkr",
+		"VPCEndpointSsm",
+		"VPCEndpointSsmmessages",
+		"VPCEndpointEc2messages",
+	}
+	if !reflect.DeepEqual(logicalNames, expected) {
+		t.Errorf("unexpected endpoints: expected=%v, actual=%v", expected, logicalNames)
+	}
+
+	if n := len(p.InterfaceEndpoints()); n != 5 {
+		t.Errorf("expected 5 interface endpoints but was %d", n)
+	}
+
+	if n := len(PrivateLinks{Enabled: true}.Endpoints()); n != 13 {
+		t.Errorf("expected 13 endpoints for the default services but was %d", n)
+	}
+
+	if n := len(PrivateLinks{Services: []string{"s3"}}.Endpoints()); n != 0 {
+		t.Errorf("expected no endpoint while private links are disabled but was %d", n)
+	}
+}
+
+func TestVPCEndpointServiceName(t *testing.T) {
+	testCases := []struct {
+		endpoint VPCEndpoint
+		region   string
+		expected string
+	}{
+		{VPCEndpoint{Service: "s3", Type: VPCEndpointTypeGateway}, "us-west-1", "com.amazonaws.us-west-1.s3"},
+		{VPCEndpoint{Service: "ecr.dkr", Type: VPCEndpointTypeInterface}, "us-west-1", "com.amazonaws.us-west-1.ecr.dkr"},
+		{VPCEndpoint{Service: "s3", Type: VPCEndpointTypeGateway}, "cn-north-1", "com.amazonaws.cn-north-1.s3"},
+		{VPCEndpoint{Service: "ecr.dkr", Type: VPCEndpointTypeInterface}, "cn-north-1", "cn.com.amazonaws.cn-north-1.ecr.dkr"},
+	}
+
+	for _, tc := range testCases {
+		actual := tc.endpoint.ServiceName(RegionForName(tc.region))
+		if actual != tc.expected {
+			t.Errorf("unexpected service name for %s in %s: expected=%s, actual=%s", tc.endpoint.Service, tc.region, tc.expected, actual)
+		}
+	}
+}
+
+func TestPrivateLinksValidate(t *testing.T) {
+	testCases := []struct {
+		privateLinks PrivateLinks
+		err          string
+	}{
+		{PrivateLinks{}, ""},
+		{PrivateLinks{Enabled: true}, ""},
+		{PrivateLinks{Enabled: true, Services: []string{"s3", "sts"}, DisableInternetEgress: true}, ""},
+		{PrivateLinks{Services: []string{"s3"}}, "can't be specified unless `privateLinks.enabled` is true"},
+		{PrivateLinks{DisableInternetEgress: true}, "can't be specified unless `privateLinks.enabled` is true"},
+		{PrivateLinks{Enabled: true, Services: []string{"sqs"}}, "unsupported service \"sqs\""},
+		{PrivateLinks{Enabled: true, Services: []string{"s3", "s3"}}, "service \"s3\" is duplicated"},
+	}
+
+	for _, tc := range testCases {
+		err := tc.privateLinks.Validate()
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %+v: %v", tc.privateLinks, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" for %+v but was: %v", tc.err, tc.privateLinks, err)
+		}
+	}
+}
+
+func TestNATGatewaysWithInternetEgressDisabled(t *testing.T) {
+	c := DeploymentSettings{
+		Subnets: Subnets{
+			NewPrivateSubnet("us-west-1a", "10.0.1.0/24"),
+		},
+		PrivateLinks: PrivateLinks{Enabled: true, DisableInternetEgress: true},
+	}
+	c.Subnets[0].Name = "Private1"
+
+	if n := len(c.NATGateways()); n != 0 {
+		t.Errorf("expected no NAT gateway but was %d", n)
+	}
+}
@@ -33,13 +33,18 @@ func (v RootVolume) Validate() error {
 		if v.IOPS < 100 || v.IOPS > 20000 {
 			return fmt.Errorf(`invalid rootVolumeIOPS %d in %+v: rootVolumeIOPS must be between 100 and 20000`, v.IOPS, v)
 		}
+	} else if v.Type == "gp3" {
+		// gp3 volumes provide 3000 IOPS when IOPS aren't provisioned
+		if v.IOPS != 0 && (v.IOPS < 3000 || v.IOPS > 16000) {
+			return fmt.Errorf(`invalid rootVolumeIOPS %d in %+v: rootVolumeIOPS must be between 3000 and 16000 when rootVolumeType is "gp3"`, v.IOPS, v)
+		}
 	} else {
 		if v.IOPS != 0 {
 			return fmt.Errorf(`invalid rootVolumeIOPS %d for volume type "%s" in %+v": rootVolumeIOPS must be 0 when rootVolumeType is "standard" or "gp2"`, v.IOPS, v.Type, v)
 		}
 
 		if v.Type != "standard" && v.Type != "gp2" {
-			return fmt.Errorf(`invalid rootVolumeType "%s" in %+v: rootVolumeType must be one of "standard", "gp2", "io1"`, v.Type, v)
+			return fmt.Errorf(`invalid rootVolumeType "%s" in %+v: rootVolumeType must be one of "standard", "gp2", "gp3", "io1"`, v.Type, v)
 		}
 	}
 	return nil
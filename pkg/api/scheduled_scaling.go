@@ -0,0 +1,147 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	targetTrackingMetrics = []string{"ASGAverageCPUUtilization", "ASGAverageNetworkIn", "ASGAverageNetworkOut"}
+
+	scalingNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+	cronFieldPattern   = regexp.MustCompile(`^[0-9A-Za-z*/,?#-]+$`)
+	timeZonePattern    = regexp.MustCompile(`^(UTC|[A-Za-z_]+(/[A-Za-z0-9_+-]+){1,2})$`)
+)
+
+// ScheduledAction changes the size of the auto scaling group on a recurring schedule, e.g. ahead of predictable daily load
+type ScheduledAction struct {
+	Name string `yaml:"name,omitempty"`
+	// Recurrence is the schedule in the cron format of five fields, e.g. "0 8 * * 1-5"
+	Recurrence string `yaml:"recurrence,omitempty"`
+	// TimeZone is the IANA time zone the recurrence is evaluated in, e.g. "Europe/London". Defaults to UTC
+	TimeZone        string `yaml:"timeZone,omitempty"`
+	MinSize         *int   `yaml:"minSize,omitempty"`
+	MaxSize         *int   `yaml:"maxSize,omitempty"`
+	DesiredCapacity *int   `yaml:"desiredCapacity,omitempty"`
+	UnknownKeys     `yaml:",inline"`
+}
+
+// TargetTrackingPolicy scales the auto scaling group out and in to keep the metric close to the target value
+type TargetTrackingPolicy struct {
+	Name string `yaml:"name,omitempty"`
+	// PredefinedMetric is one of "ASGAverageCPUUtilization", "ASGAverageNetworkIn" and "ASGAverageNetworkOut"
+	PredefinedMetric string  `yaml:"predefinedMetric,omitempty"`
+	TargetValue      float64 `yaml:"targetValue,omitempty"`
+	// DisableScaleIn makes the policy only scale out, e.g. to leave scaling in to scheduled actions
+	DisableScaleIn bool `yaml:"disableScaleIn,omitempty"`
+	// EstimatedInstanceWarmup is the number of seconds until a new instance contributes to the metric. Defaults to the health check grace period
+	EstimatedInstanceWarmup *int `yaml:"estimatedInstanceWarmup,omitempty"`
+	UnknownKeys             `yaml:",inline"`
+}
+
+// LogicalName returns the name in CamelCase for suffixing the logical name of the CloudFormation resource
+func (a ScheduledAction) LogicalName() string {
+	return scalingLogicalName(a.Name)
+}
+
+func (p TargetTrackingPolicy) LogicalName() string {
+	return scalingLogicalName(p.Name)
+}
+
+func scalingLogicalName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' })
+	for i, w := range words {
+		words[i] = strings.Title(w)
+	}
+	return strings.Join(words, "")
+}
+
+func (a ScheduledAction) Validate() error {
+	if !scalingNamePattern.MatchString(a.Name) {
+		return fmt.Errorf("`autoScalingGroup.scheduledActions[].name` must consist of alphanumeric characters, '-' and '_' but was \"%s\"", a.Name)
+	}
+	path := fmt.Sprintf("autoScalingGroup.scheduledActions[name=%s]", a.Name)
+
+	fields := strings.Fields(a.Recurrence)
+	if len(fields) != 5 {
+		return fmt.Errorf("`%s.recurrence` must be a cron expression of five fields e.g. \"0 8 * * 1-5\" but was \"%s\"", path, a.Recurrence)
+	}
+	for _, f := range fields {
+		if !cronFieldPattern.MatchString(f) {
+			return fmt.Errorf("`%s.recurrence` must be a cron expression of five fields e.g. \"0 8 * * 1-5\" but was \"%s\"", path, a.Recurrence)
+		}
+	}
+	if a.TimeZone != "" && !timeZonePattern.MatchString(a.TimeZone) {
+		return fmt.Errorf("`%s.timeZone` must be an IANA time zone e.g. \"Europe/London\" but was \"%s\"", path, a.TimeZone)
+	}
+
+	if a.MinSize == nil && a.MaxSize == nil && a.DesiredCapacity == nil {
+		return fmt.Errorf("`%s` must specify at least one of `minSize`, `maxSize` and `desiredCapacity`", path)
+	}
+	for key, v := range map[string]*int{"minSize": a.MinSize, "maxSize": a.MaxSize, "desiredCapacity": a.DesiredCapacity} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("`%s.%s` must be zero or greater but was %d", path, key, *v)
+		}
+	}
+	if a.MinSize != nil && a.MaxSize != nil && *a.MinSize > *a.MaxSize {
+		return fmt.Errorf("`%s.minSize` (%d) must be less than or equal to `maxSize` (%d)", path, *a.MinSize, *a.MaxSize)
+	}
+	if d := a.DesiredCapacity; d != nil {
+		if a.MinSize != nil && *d < *a.MinSize || a.MaxSize != nil && *d > *a.MaxSize {
+			return fmt.Errorf("`%s.desiredCapacity` (%d) must be within `minSize` and `maxSize`", path, *d)
+		}
+	}
+	return nil
+}
+
+func (p TargetTrackingPolicy) Validate() error {
+	if !scalingNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("`autoScalingGroup.targetTrackingPolicies[].name` must consist of alphanumeric characters, '-' and '_' but was \"%s\"", p.Name)
+	}
+	path := fmt.Sprintf("autoScalingGroup.targetTrackingPolicies[name=%s]", p.Name)
+
+	if !containsString(targetTrackingMetrics, p.PredefinedMetric) {
+		return fmt.Errorf("`%s.predefinedMetric` must be one of %s but was \"%s\"", path, quoteAll(targetTrackingMetrics), p.PredefinedMetric)
+	}
+	if p.TargetValue <= 0 {
+		return fmt.Errorf("`%s.targetValue` must be greater than zero", path)
+	}
+	if p.PredefinedMetric == "ASGAverageCPUUtilization" && p.TargetValue > 100 {
+		return fmt.Errorf("`%s.targetValue` must be a percentage of 100 or less for ASGAverageCPUUtilization but was %v", path, p.TargetValue)
+	}
+	if p.EstimatedInstanceWarmup != nil && *p.EstimatedInstanceWarmup < 0 {
+		return fmt.Errorf("`%s.estimatedInstanceWarmup` must be zero or greater if specified", path)
+	}
+	return nil
+}
+
+// HasScalingSettings returns true when the auto scaling group is resized by scheduled actions or target tracking policies of its own
+func (asg AutoScalingGroup) HasScalingSettings() bool {
+	return len(asg.ScheduledActions) > 0 || len(asg.TargetTrackingPolicies) > 0
+}
+
+func (asg AutoScalingGroup) validateScalingSettings() error {
+	logicalNames := map[string]string{}
+	for _, a := range asg.ScheduledActions {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+		if other, ok := logicalNames[a.LogicalName()]; ok {
+			return fmt.Errorf("`autoScalingGroup.scheduledActions` contains \"%s\" and \"%s\", whose names must differ in more than '-' and '_'", other, a.Name)
+		}
+		logicalNames[a.LogicalName()] = a.Name
+	}
+
+	logicalNames = map[string]string{}
+	for _, p := range asg.TargetTrackingPolicies {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if other, ok := logicalNames[p.LogicalName()]; ok {
+			return fmt.Errorf("`autoScalingGroup.targetTrackingPolicies` contains \"%s\" and \"%s\", whose names must differ in more than '-' and '_'", other, p.Name)
+		}
+		logicalNames[p.LogicalName()] = p.Name
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestScheduledActionValidate(t *testing.T) {
+	one, three, five := 1, 3, 5
+	negative := -1
+
+	testCases := []struct {
+		action  ScheduledAction
+		isValid bool
+	}{
+		{
+			action:  ScheduledAction{Name: "business-hours", Recurrence: "0 8 * * 1-5", TimeZone: "Europe/London", MinSize: &three, MaxSize: &five, DesiredCapacity: &three},
+			isValid: true,
+		},
+		{
+			action:  ScheduledAction{Name: "nights", Recurrence: "0 20 * * *", TimeZone: "UTC", MinSize: &one},
+			isValid: true,
+		},
+		// Invalid, the name is used in the logical name of the resource
+		{
+			action:  ScheduledAction{Name: "business hours", Recurrence: "0 8 * * 1-5", MinSize: &three},
+			isValid: false,
+		},
+		// Invalid, the recurrence has five fields
+		{
+			action:  ScheduledAction{Name: "nights", Recurrence: "0 0 20 * * *", MinSize: &one},
+			isValid: false,
+		},
+		{
+			action:  ScheduledAction{Name: "nights", Recurrence: "@daily", MinSize: &one},
+			isValid: false,
+		},
+		{
+			action:  ScheduledAction{Name: "nights", Recurrence: "0 20 * * *", TimeZone: "+09:00", MinSize: &one},
+			isValid: false,
+		},
+		// Invalid, at least one size is required
+		{
+			action:  ScheduledAction{Name: "nights", Recurrence: "0 20 * * *"},
+			isValid: false,
+		},
+		{
+			action:  ScheduledAction{Name: "nights", Recurrence: "0 20 * * *", MinSize: &negative},
+			isValid: false,
+		},
+		{
+			action:  ScheduledAction{Name: "nights", Recurrence: "0 20 * * *", MinSize: &five, MaxSize: &three},
+			isValid: false,
+		},
+		{
+			action:  ScheduledAction{Name: "nights", Recurrence: "0 20 * * *", MinSize: &three, DesiredCapacity: &one},
+			isValid: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.action.Validate()
+		if testCase.isValid && err != nil {
+			t.Errorf("Expected %+v to be valid, but it was not: %v", testCase.action, err)
+		}
+		if !testCase.isValid && err == nil {
+			t.Errorf("Expected %+v to be invalid, but it was not", testCase.action)
+		}
+	}
+}
+
+func TestTargetTrackingPolicyValidate(t *testing.T) {
+	warmup := 300
+	negative := -1
+
+	testCases := []struct {
+		policy  TargetTrackingPolicy
+		isValid bool
+	}{
+		{
+			policy:  TargetTrackingPolicy{Name: "cpu", PredefinedMetric: "ASGAverageCPUUtilization", TargetValue: 60, EstimatedInstanceWarmup: &warmup},
+			isValid: true,
+		},
+		{
+			policy:  TargetTrackingPolicy{Name: "network-in", PredefinedMetric: "ASGAverageNetworkIn", TargetValue: 50000000, DisableScaleIn: true},
+			isValid: true,
+		},
+		// Invalid, only the metrics of auto scaling groups are supported
+		{
+			policy:  TargetTrackingPolicy{Name: "requests", PredefinedMetric: "ALBRequestCountPerTarget", TargetValue: 1000},
+			isValid: false,
+		},
+		{
+			policy:  TargetTrackingPolicy{Name: "cpu", PredefinedMetric: "ASGAverageCPUUtilization"},
+			isValid: false,
+		},
+		{
+			policy:  TargetTrackingPolicy{Name: "cpu", PredefinedMetric: "ASGAverageCPUUtilization", TargetValue: 120},
+			isValid: false,
+		},
+		{
+			policy:  TargetTrackingPolicy{Name: "cpu", PredefinedMetric: "ASGAverageCPUUtilization", TargetValue: 60, EstimatedInstanceWarmup: &negative},
+			isValid: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.policy.Validate()
+		if testCase.isValid && err != nil {
+			t.Errorf("Expected %+v to be valid, but it was not: %v", testCase.policy, err)
+		}
+		if !testCase.isValid && err == nil {
+			t.Errorf("Expected %+v to be invalid, but it was not", testCase.policy)
+		}
+	}
+}
+
+func TestScalingSettingsLogicalNames(t *testing.T) {
+	one := 1
+	if name := (ScheduledAction{Name: "business-hours_eu"}).LogicalName(); name != "BusinessHoursEu" {
+		t.Errorf("expected logical name BusinessHoursEu, but was %s", name)
+	}
+
+	asg := AutoScalingGroup{ScheduledActions: []ScheduledAction{
+		{Name: "business-hours", Recurrence: "0 8 * * 1-5", MinSize: &one},
+		{Name: "business_hours", Recurrence: "0 9 * * 1-5", MinSize: &one},
+	}}
+	if err := asg.validateScalingSettings(); err == nil {
+		t.Error("expected scheduled actions with the same logical name to be invalid, but they were not")
+	}
+}
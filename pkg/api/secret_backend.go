@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	SecretBackendTypeSecretsManager    = "secretsManager"
+	SecretBackendTypeSSMParameterStore = "ssmParameterStore"
+	SecretBackendTypeVault             = "vault"
+
+	defaultVaultMountPath = "secret"
+)
+
+// SecretBackend configures where kube-aws keeps the plaintext credentials, which are otherwise written to the credentials directory.
+// Only the KMS-encrypted caches of them are written to the directory when a backend is configured
+type SecretBackend struct {
+	// Type is one of `secretsManager`, `ssmParameterStore` and `vault`. Credentials are kept in the credentials directory when omitted
+	Type string `yaml:"type,omitempty"`
+	// Prefix is prepended to the name of each credential e.g. `apiserver-key.pem`. Defaults to `kube-aws/<clusterName>/`, with a leading slash for `ssmParameterStore`
+	Prefix      string             `yaml:"prefix,omitempty"`
+	Vault       VaultSecretBackend `yaml:"vault,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+// VaultSecretBackend configures the HashiCorp Vault KV version 2 secrets engine credentials are kept in.
+// The token is read from the VAULT_TOKEN environment variable so that it never appears in cluster.yaml
+type VaultSecretBackend struct {
+	// Address defaults to the VAULT_ADDR environment variable
+	Address string `yaml:"address,omitempty"`
+	// MountPath is the path the KV secrets engine is mounted at. Defaults to `secret`
+	MountPath   string `yaml:"mountPath,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+func (b SecretBackend) Enabled() bool {
+	return b.Type != ""
+}
+
+// SecretPrefix returns the prefix of the names of the credentials of the cluster
+func (b SecretBackend) SecretPrefix(clusterName string) string {
+	if b.Prefix != "" {
+		return b.Prefix
+	}
+	prefix := fmt.Sprintf("kube-aws/%s/", clusterName)
+	if b.Type == SecretBackendTypeSSMParameterStore {
+		return "/" + prefix
+	}
+	return prefix
+}
+
+func (b VaultSecretBackend) MountPathOrDefault() string {
+	if b.MountPath != "" {
+		return b.MountPath
+	}
+	return defaultVaultMountPath
+}
+
+func (b SecretBackend) Validate() error {
+	switch b.Type {
+	case "":
+		if b.Prefix != "" || b.Vault.Address != "" || b.Vault.MountPath != "" {
+			return errors.New("`secretBackend.type` must be specified to configure `secretBackend`")
+		}
+		return nil
+	case SecretBackendTypeSecretsManager, SecretBackendTypeSSMParameterStore:
+		if b.Vault.Address != "" || b.Vault.MountPath != "" {
+			return fmt.Errorf("`secretBackend.vault` can't be specified when `secretBackend.type` is %s", b.Type)
+		}
+	case SecretBackendTypeVault:
+	default:
+		return fmt.Errorf("unsupported `secretBackend.type` \"%s\". It must be one of: %s", b.Type, strings.Join([]string{SecretBackendTypeSecretsManager, SecretBackendTypeSSMParameterStore, SecretBackendTypeVault}, ", "))
+	}
+
+	if strings.Contains(b.Prefix, "..") {
+		return fmt.Errorf("`secretBackend.prefix` must not contain \"..\" but was \"%s\"", b.Prefix)
+	}
+	if b.Type == SecretBackendTypeSSMParameterStore && b.Prefix != "" && !strings.HasPrefix(b.Prefix, "/") {
+		return fmt.Errorf("`secretBackend.prefix` must start with \"/\" for %s but was \"%s\"", SecretBackendTypeSSMParameterStore, b.Prefix)
+	}
+	return nil
+}
+
+func (c Cluster) validateSecretBackend() error {
+	if err := c.SecretBackend.Validate(); err != nil {
+		return err
+	}
+	if c.SecretBackend.Enabled() && !c.AssetsEncryptionEnabled() {
+		return errors.New("`secretBackend` requires `manageCertificates` to be true and KMS to be available in the region, because nodes receive credentials encrypted with the KMS key")
+	}
+	return nil
+}
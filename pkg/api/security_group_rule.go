@@ -0,0 +1,337 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/netutil"
+)
+
+const (
+	SecurityGroupRuleProtocolTCP    = "tcp"
+	SecurityGroupRuleProtocolUDP    = "udp"
+	SecurityGroupRuleProtocolICMP   = "icmp"
+	SecurityGroupRuleProtocolICMPv6 = "icmpv6"
+	SecurityGroupRuleProtocolAll    = "all"
+
+	ManagedSecurityGroupController = "controller"
+	ManagedSecurityGroupEtcd       = "etcd"
+	ManagedSecurityGroupWorker     = "worker"
+)
+
+// managedSecurityGroup is a security group created in the network stack which can be referenced from security group rules by its name
+type managedSecurityGroup struct {
+	// logicalName is the logical name of the security group in the network stack
+	logicalName string
+	// exportName is the suffix of the name of the network stack output the security group is imported from other stacks with
+	exportName string
+}
+
+var managedSecurityGroups = map[string]managedSecurityGroup{
+	ManagedSecurityGroupController: {"SecurityGroupController", "ControllerSecurityGroup"},
+	ManagedSecurityGroupEtcd:       {"SecurityGroupEtcd", "EtcdSecurityGroup"},
+	ManagedSecurityGroupWorker:     {"SecurityGroupWorker", "WorkerSecurityGroup"},
+}
+
+// SecurityGroupRules are additional rules of the security group kube-aws manages for controller, etcd or worker nodes, or a node pool
+type SecurityGroupRules struct {
+	// Ingress rules allow inbound traffic in addition to the traffic kube-aws allows by default
+	Ingress []SecurityGroupRule `yaml:"ingress,omitempty"`
+	// Egress rules replace the default rules allowing all outbound traffic when specified
+	Egress      []SecurityGroupRule `yaml:"egress,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+// SecurityGroupRule allows traffic from or to exactly one of an IPv4 CIDR, an IPv6 CIDR or a security group
+type SecurityGroupRule struct {
+	Description string `yaml:"description,omitempty"`
+	// Protocol is one of tcp, udp, icmp, icmpv6 or all
+	Protocol string `yaml:"protocol"`
+	// FromPort and ToPort are the range of tcp or udp ports. ToPort defaults to FromPort
+	FromPort int    `yaml:"fromPort,omitempty"`
+	ToPort   int    `yaml:"toPort,omitempty"`
+	CIDR     string `yaml:"cidr,omitempty"`
+	CIDRIPv6 string `yaml:"cidrIPv6,omitempty"`
+	// SecurityGroup is either the name of a security group managed by kube-aws(controller, etcd or worker) or the ID of an existing security group
+	SecurityGroup string `yaml:"securityGroup,omitempty"`
+	UnknownKeys   `yaml:",inline"`
+}
+
+// SecurityGroupRuleResource is a security group rule rendered as an AWS::EC2::SecurityGroupIngress or AWS::EC2::SecurityGroupEgress resource.
+// Rules are rendered as separate resources rather than inline rules so that security groups can reference each other without circular dependencies
+type SecurityGroupRuleResource struct {
+	LogicalName string
+	Type        string
+	// Properties is the JSON-encoded properties of the resource
+	Properties string
+}
+
+func (r SecurityGroupRules) HasRules() bool {
+	return len(r.Ingress) > 0 || len(r.Egress) > 0
+}
+
+// RestrictsEgress returns true when the default rules allowing all outbound traffic are replaced with the egress rules
+func (r SecurityGroupRules) RestrictsEgress() bool {
+	return len(r.Egress) > 0
+}
+
+func (r SecurityGroupRule) ports() (int, int) {
+	switch r.Protocol {
+	case SecurityGroupRuleProtocolTCP, SecurityGroupRuleProtocolUDP:
+		if r.ToPort == 0 {
+			return r.FromPort, r.FromPort
+		}
+		return r.FromPort, r.ToPort
+	}
+	return -1, -1
+}
+
+func (r SecurityGroupRule) ipProtocol() string {
+	switch r.Protocol {
+	case SecurityGroupRuleProtocolICMPv6:
+		return "58"
+	case SecurityGroupRuleProtocolAll:
+		return "-1"
+	}
+	return r.Protocol
+}
+
+// securityGroupRef returns the CloudFormation expression referencing the security group the rule allows traffic from or to
+func (r SecurityGroupRule) securityGroupRef(inNetworkStack bool) string {
+	g, ok := managedSecurityGroups[r.SecurityGroup]
+	if !ok {
+		return fmt.Sprintf(`"%s"`, r.SecurityGroup)
+	}
+	if inNetworkStack {
+		return fmt.Sprintf(`{"Ref":"%s"}`, g.logicalName)
+	}
+	return fmt.Sprintf(`{"Fn::ImportValue":{"Fn::Sub":"${NetworkStackName}-%s"}}`, g.exportName)
+}
+
+func (r SecurityGroupRule) resource(groupLogicalName, direction string, inNetworkStack bool) (SecurityGroupRuleResource, error) {
+	from, to := r.ports()
+	props := map[string]interface{}{
+		"GroupId":    json.RawMessage(fmt.Sprintf(`{"Ref":"%s"}`, groupLogicalName)),
+		"IpProtocol": r.ipProtocol(),
+		"FromPort":   from,
+		"ToPort":     to,
+	}
+	peer := "Source"
+	if direction == "Egress" {
+		peer = "Destination"
+	}
+	switch {
+	case r.CIDR != "":
+		props["CidrIp"] = r.CIDR
+	case r.CIDRIPv6 != "":
+		props["CidrIpv6"] = r.CIDRIPv6
+	default:
+		props[peer+"SecurityGroupId"] = json.RawMessage(r.securityGroupRef(inNetworkStack))
+	}
+	key, err := json.Marshal(props)
+	if err != nil {
+		return SecurityGroupRuleResource{}, fmt.Errorf("failed to render security group rule %+v: %v", r, err)
+	}
+	// Named after the content rather than the index so that reordering rules doesn't replace them,
+	// which would fail as AWS rejects a rule duplicating another one not deleted yet.
+	// The description is excluded as it can be updated in place and doesn't make rules distinct in AWS
+	h := fnv.New32a()
+	h.Write(key)
+
+	if r.Description != "" {
+		props["Description"] = r.Description
+	}
+	data, err := json.Marshal(props)
+	if err != nil {
+		return SecurityGroupRuleResource{}, fmt.Errorf("failed to render security group rule %+v: %v", r, err)
+	}
+	return SecurityGroupRuleResource{
+		LogicalName: fmt.Sprintf("%s%s%08x", groupLogicalName, direction, h.Sum32()),
+		Type:        "AWS::EC2::SecurityGroup" + direction,
+		Properties:  string(data),
+	}, nil
+}
+
+// Resources returns the ingress and egress rules of the security group named groupLogicalName as CloudFormation resources.
+// inNetworkStack must be true when they are rendered into the network stack, so that the security groups kube-aws manages are referenced directly rather than imported
+func (r SecurityGroupRules) Resources(groupLogicalName string, inNetworkStack bool) ([]SecurityGroupRuleResource, error) {
+	resources := []SecurityGroupRuleResource{}
+	for _, d := range []struct {
+		direction string
+		rules     []SecurityGroupRule
+	}{{"Ingress", r.Ingress}, {"Egress", r.Egress}} {
+		for _, rule := range d.rules {
+			res, err := rule.resource(groupLogicalName, d.direction, inNetworkStack)
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, res)
+		}
+	}
+	return resources, nil
+}
+
+// Validate returns an error when any of the rules is malformed. keyPath is the path to the rules in cluster.yaml, like `controller.securityGroupRules`
+func (r SecurityGroupRules) Validate(keyPath string) error {
+	if err := r.FailWhenUnknownKeysFound(keyPath); err != nil {
+		return err
+	}
+	for _, d := range []struct {
+		name      string
+		direction string
+		rules     []SecurityGroupRule
+	}{{"ingress", "Ingress", r.Ingress}, {"egress", "Egress", r.Egress}} {
+		seen := map[string]int{}
+		for i, rule := range d.rules {
+			path := fmt.Sprintf("%s.%s[%d]", keyPath, d.name, i)
+			if err := rule.FailWhenUnknownKeysFound(path); err != nil {
+				return err
+			}
+			if err := rule.validate(); err != nil {
+				return fmt.Errorf("invalid %s: %v", path, err)
+			}
+			res, err := rule.resource("", d.direction, false)
+			if err != nil {
+				return err
+			}
+			if j, ok := seen[res.LogicalName]; ok {
+				return fmt.Errorf("%s duplicates %s.%s[%d]", path, keyPath, d.name, j)
+			}
+			seen[res.LogicalName] = i
+		}
+	}
+	return nil
+}
+
+func (r SecurityGroupRule) validate() error {
+	switch r.Protocol {
+	case SecurityGroupRuleProtocolTCP, SecurityGroupRuleProtocolUDP:
+		from, to := r.ports()
+		if from < 0 || to > 65535 || from > to {
+			return fmt.Errorf("invalid port range %d-%d. fromPort and toPort must be between 0 and 65535, and fromPort must not be greater than toPort", from, to)
+		}
+	case SecurityGroupRuleProtocolICMP, SecurityGroupRuleProtocolICMPv6, SecurityGroupRuleProtocolAll:
+		if r.FromPort != 0 || r.ToPort != 0 {
+			return fmt.Errorf("fromPort and toPort can't be specified for the protocol %s", r.Protocol)
+		}
+	default:
+		return fmt.Errorf("unsupported protocol \"%s\". It must be one of tcp, udp, icmp, icmpv6 or all", r.Protocol)
+	}
+
+	targets := 0
+	for _, t := range []string{r.CIDR, r.CIDRIPv6, r.SecurityGroup} {
+		if t != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("exactly one of cidr, cidrIPv6 or securityGroup must be specified")
+	}
+	if r.CIDR != "" {
+		if _, err := netutil.ParseIPv4CIDR(r.CIDR); err != nil {
+			return fmt.Errorf("invalid cidr: %v", err)
+		}
+	}
+	if r.CIDRIPv6 != "" {
+		if _, ipnet, err := net.ParseCIDR(r.CIDRIPv6); err != nil || !netutil.IsIPv6(ipnet.IP) {
+			return fmt.Errorf("invalid cidrIPv6 \"%s\"", r.CIDRIPv6)
+		}
+	}
+	if r.SecurityGroup != "" {
+		if _, ok := managedSecurityGroups[r.SecurityGroup]; !ok && !strings.HasPrefix(r.SecurityGroup, "sg-") {
+			return fmt.Errorf("unknown securityGroup \"%s\". It must be one of %s, %s or %s, or the ID of an existing security group", r.SecurityGroup, ManagedSecurityGroupController, ManagedSecurityGroupEtcd, ManagedSecurityGroupWorker)
+		}
+	}
+	return nil
+}
+
+// allows returns true when the rule allows tcp traffic on the port from or to the managed security group, or any address in vpcNet
+func (r SecurityGroupRule) allows(port int, group string, vpcNet *net.IPNet) bool {
+	if r.Protocol != SecurityGroupRuleProtocolTCP && r.Protocol != SecurityGroupRuleProtocolAll {
+		return false
+	}
+	if r.Protocol == SecurityGroupRuleProtocolTCP {
+		if from, to := r.ports(); port < from || port > to {
+			return false
+		}
+	}
+	if r.SecurityGroup != "" {
+		return r.SecurityGroup == group
+	}
+	if r.CIDR == "" {
+		return false
+	}
+	_, ipnet, err := net.ParseCIDR(r.CIDR)
+	if err != nil {
+		return false
+	}
+	ruleOnes, _ := ipnet.Mask.Size()
+	vpcOnes, _ := vpcNet.Mask.Size()
+	return ipnet.Contains(vpcNet.IP) && ruleOnes <= vpcOnes
+}
+
+// requiredEgress is outbound traffic which must be allowed for the cluster to function
+type requiredEgress struct {
+	port        int
+	group       string
+	description string
+}
+
+var requiredEgresses = map[string][]requiredEgress{
+	ManagedSecurityGroupController: {
+		{2379, ManagedSecurityGroupEtcd, "kube-apiserver to etcd"},
+		{443, ManagedSecurityGroupController, "kubelet to kube-apiserver"},
+		{10250, ManagedSecurityGroupController, "kube-apiserver to kubelet on controller nodes"},
+		{10250, ManagedSecurityGroupWorker, "kube-apiserver to kubelet on worker nodes"},
+	},
+	ManagedSecurityGroupEtcd: {
+		{2379, ManagedSecurityGroupEtcd, "etcd health checks between etcd members"},
+		{2380, ManagedSecurityGroupEtcd, "etcd peer traffic"},
+	},
+	ManagedSecurityGroupWorker: {
+		{443, ManagedSecurityGroupController, "kubelet to kube-apiserver"},
+	},
+}
+
+// validateRequiredEgress returns an error when the egress rules of the role block traffic kube-aws relies on
+func (r SecurityGroupRules) validateRequiredEgress(role string, vpcNet *net.IPNet) error {
+	if !r.RestrictsEgress() {
+		return nil
+	}
+	for _, e := range requiredEgresses[role] {
+		allowed := false
+		for _, rule := range r.Egress {
+			if rule.allows(e.port, e.group, vpcNet) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("`%s.securityGroupRules.egress` must allow tcp port %d to the %s security group or the VPC CIDR, which is required for %s", role, e.port, e.group, e.description)
+		}
+	}
+	return nil
+}
+
+func (c Cluster) validateSecurityGroupRules(vpcNet *net.IPNet) error {
+	for _, role := range []struct {
+		name  string
+		rules SecurityGroupRules
+	}{
+		{ManagedSecurityGroupController, c.Controller.SecurityGroupRules},
+		{ManagedSecurityGroupEtcd, c.Etcd.SecurityGroupRules},
+		{ManagedSecurityGroupWorker, c.Worker.SecurityGroupRules},
+	} {
+		if err := role.rules.Validate(role.name + ".securityGroupRules"); err != nil {
+			return err
+		}
+		if err := role.rules.validateRequiredEgress(role.name, vpcNet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestValidateRequiredEgress(t *testing.T) {
+	rules := SecurityGroupRules{
+		Egress: []SecurityGroupRule{
+			{Protocol: SecurityGroupRuleProtocolTCP, FromPort: 443, CIDR: "172.16.0.0/16"},
+		},
+	}
+
+	_, vpcNet, _ := net.ParseCIDR("10.0.0.0/16")
+	err := rules.validateRequiredEgress(ManagedSecurityGroupWorker, vpcNet)
+	if err == nil || !strings.Contains(err.Error(), "must allow tcp port 443 to the controller security group or the VPC CIDR") {
+		t.Errorf("expected egress outside of the VPC CIDR to be rejected but was: %v", err)
+	}
+
+	rules.Egress[0].CIDR = "10.0.0.0/8"
+	if err := rules.validateRequiredEgress(ManagedSecurityGroupWorker, vpcNet); err != nil {
+		t.Errorf("expected egress covering the VPC CIDR to be accepted but was: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+)
+
+// SpotFleetReplacementAllocationStrategy launches spot instances from the pools with the most spare capacity,
+// which are the least likely to be interrupted
+const SpotFleetReplacementAllocationStrategy = "capacity-optimized"
+
+// SpotFleetReplacement is the part of a node pool config replacing its `spotFleet` with an auto scaling group of mixed instances.
+// It is marshaled into the snippet `kube-aws migrate spotfleet` asks users to put into cluster.yaml
+type SpotFleetReplacement struct {
+	Name                  string     `yaml:"name"`
+	MigratedFromSpotFleet bool       `yaml:"migratedFromSpotFleet"`
+	Count                 int        `yaml:"count"`
+	InstanceType          string     `yaml:"instanceType"`
+	RootVolume            RootVolume `yaml:"rootVolume"`
+	AutoScalingGroup      struct {
+		MixedInstances MixedInstances `yaml:"mixedInstances"`
+	} `yaml:"autoScalingGroup"`
+
+	// Notes explain where the auto scaling group behaves differently from the spot fleet
+	Notes []string `yaml:"-"`
+}
+
+// SpotFleetReplacement translates the spot fleet of the node pool into an auto scaling group of spot instances.
+// Weighted capacities of launch specifications are kept so that `count` remains the number of units in the fleet
+func (c WorkerNodePool) SpotFleetReplacement() (*SpotFleetReplacement, error) {
+	f := c.SpotFleet
+	if !f.Enabled() {
+		return nil, fmt.Errorf("node pool %s doesn't have a spot fleet", c.NodePoolName)
+	}
+	if len(f.LaunchSpecifications) == 0 {
+		return nil, fmt.Errorf("spot fleet of node pool %s has no launch specifications", c.NodePoolName)
+	}
+
+	r := &SpotFleetReplacement{
+		Name:                  c.NodePoolName,
+		MigratedFromSpotFleet: true,
+		Count:                 f.TargetCapacity,
+		InstanceType:          f.LaunchSpecifications[0].InstanceType,
+		RootVolume:            RootVolume{Type: f.RootVolumeType},
+	}
+
+	mi := MixedInstances{
+		Enabled:                             true,
+		OnDemandBaseCapacity:                0,
+		OnDemandPercentageAboveBaseCapacity: 0,
+		SpotAllocationStrategy:              SpotFleetReplacementAllocationStrategy,
+	}
+	sizes := map[int]bool{}
+	for _, spec := range f.LaunchSpecifications {
+		mi.Overrides = append(mi.Overrides, InstanceTypeOverride{InstanceType: spec.InstanceType, WeightedCapacity: spec.WeightedCapacity})
+
+		// Launch templates have a single root volume for every instance type, which is large enough for the largest one
+		sizes[spec.RootVolume.Size] = true
+		if spec.RootVolume.Size > r.RootVolume.Size {
+			r.RootVolume.Size = spec.RootVolume.Size
+		}
+		if spec.RootVolume.IOPS > r.RootVolume.IOPS {
+			r.RootVolume.IOPS = spec.RootVolume.IOPS
+		}
+		if spec.RootVolume.Type != "" && spec.RootVolume.Type != f.RootVolumeType {
+			r.Notes = append(r.Notes, fmt.Sprintf("the root volume type %s of %s is replaced with %s, which is used for every instance type", spec.RootVolume.Type, spec.InstanceType, f.RootVolumeType))
+		}
+	}
+	r.AutoScalingGroup.MixedInstances = mi
+
+	if len(sizes) > 1 {
+		r.Notes = append(r.Notes, fmt.Sprintf("root volumes of every instance type are %d GiB, the largest size in the launch specifications", r.RootVolume.Size))
+	}
+	if mi.HasWeightedCapacity() {
+		r.Notes = append(r.Notes, fmt.Sprintf("`count` is %d units of capacity rather than instances, and `waitSignal` is disabled because instances don't signal in units", r.Count))
+	}
+	r.Notes = append(r.Notes, "`migratedFromSpotFleet: true` must stay in cluster.yaml permanently, even after the migration has completed. Removing it renames and therefore replaces the auto scaling group")
+	r.Notes = append(r.Notes, fmt.Sprintf("`spotMaxPrice` is omitted so that spot prices are capped at the on-demand price instead of the fleet's %s per unit hour", f.SpotPrice))
+
+	return r, nil
+}
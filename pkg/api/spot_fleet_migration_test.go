@@ -0,0 +1,108 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-yaml/yaml"
+)
+
+func TestSpotFleetReplacement(t *testing.T) {
+	pool := WorkerNodePool{}
+	err := yaml.Unmarshal([]byte(`
+name: spotty
+spotFleet:
+  targetCapacity: 10
+  spotPrice: "0.05"
+  unitRootVolumeSize: 40
+  launchSpecifications:
+  - weightedCapacity: 1
+    instanceType: c5.large
+  - weightedCapacity: 2
+    instanceType: c5.xlarge
+`), &pool)
+	if err != nil {
+		t.Fatalf("failed to parse node pool: %v", err)
+	}
+
+	r, err := pool.SpotFleetReplacement()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r.Name != "spotty" || !r.MigratedFromSpotFleet || r.Count != 10 || r.InstanceType != "c5.large" {
+		t.Errorf("unexpected node pool settings: %+v", r)
+	}
+	if expected := (RootVolume{Type: "gp2", Size: 80}); !reflect.DeepEqual(r.RootVolume, expected) {
+		t.Errorf("expected root volume %+v, but was %+v", expected, r.RootVolume)
+	}
+
+	mi := r.AutoScalingGroup.MixedInstances
+	expected := MixedInstances{
+		Enabled:                true,
+		SpotAllocationStrategy: "capacity-optimized",
+		Overrides: []InstanceTypeOverride{
+			{InstanceType: "c5.large", WeightedCapacity: 1},
+			{InstanceType: "c5.xlarge", WeightedCapacity: 2},
+		},
+	}
+	if !reflect.DeepEqual(mi, expected) {
+		t.Errorf("expected mixed instances %+v, but was %+v", expected, mi)
+	}
+	if err := mi.Validate(); err != nil {
+		t.Errorf("expected the translated mixed instances to be valid: %v", err)
+	}
+
+	notes := strings.Join(r.Notes, "\n")
+	for _, expected := range []string{"80 GiB", "10 units of capacity", "0.05 per unit hour", "must stay in cluster.yaml permanently"} {
+		if !strings.Contains(notes, expected) {
+			t.Errorf("expected notes to contain %q: %s", expected, notes)
+		}
+	}
+
+	// The snippet parses back into a node pool replacing the spot fleet
+	out, err := yaml.Marshal(r)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	migrated := WorkerNodePool{}
+	if err := yaml.Unmarshal(out, &migrated); err != nil {
+		t.Fatalf("failed to parse the snippet %s: %v", out, err)
+	}
+	if migrated.SpotFleet.Enabled() || !migrated.AutoScalingGroup.MixedInstances.Enabled {
+		t.Errorf("expected the snippet to replace the spot fleet with mixed instances: %s", out)
+	}
+	if migrated.LogicalName() != "WorkersASG" {
+		t.Errorf("expected the auto scaling group not to reuse the logical name of the spot fleet, but was %s", migrated.LogicalName())
+	}
+	if err := migrated.validate(false); err != nil {
+		t.Errorf("expected the snippet to be valid: %v", err)
+	}
+}
+
+func TestSpotFleetReplacementWithoutSpotFleet(t *testing.T) {
+	if _, err := (WorkerNodePool{NodePoolName: "pool1"}).SpotFleetReplacement(); err == nil {
+		t.Error("expected an error for a node pool without spot fleet")
+	}
+}
+
+func TestMigratedFromSpotFleetWithSpotFleet(t *testing.T) {
+	pool := WorkerNodePool{}
+	err := yaml.Unmarshal([]byte(`
+name: pool1
+migratedFromSpotFleet: true
+spotFleet:
+  targetCapacity: 3
+`), &pool)
+	if err != nil {
+		t.Fatalf("failed to parse node pool: %v", err)
+	}
+
+	if pool.LogicalName() != "Workers" {
+		t.Errorf("expected the spot fleet to keep its logical name, but was %s", pool.LogicalName())
+	}
+	if err := pool.validate(false); err == nil || !strings.Contains(err.Error(), "migratedFromSpotFleet") {
+		t.Errorf("expected an error for migratedFromSpotFleet along with spotFleet, but was %v", err)
+	}
+}
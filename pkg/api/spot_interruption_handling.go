@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+)
+
+const (
+	defaultSpotInterruptionHandlerMetricsPort = 9253
+)
+
+// SpotInterruptionHandling deploys a handler to every node in the pool, which polls the instance metadata for
+// spot instance interruption notices and EC2 instance rebalance recommendations.
+// The node is cordoned and drained on an interruption notice. Both are recorded as Kubernetes events and Prometheus metrics.
+// The handler authenticates with the kubelet credentials of the node so that it can act on no other nodes and pods
+type SpotInterruptionHandling struct {
+	Enabled                 bool                    `yaml:"enabled"`
+	RebalanceRecommendation RebalanceRecommendation `yaml:"rebalanceRecommendation,omitempty"`
+	// MetricsPort is the port of the handler pod it serves Prometheus metrics on. Defaults to 9253
+	MetricsPort int `yaml:"metricsPort,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+// RebalanceRecommendation configures what to do when EC2 recommends the instance to be rebalanced because it is at an elevated risk of interruption
+type RebalanceRecommendation struct {
+	// Drain cordons and drains the node on a rebalance recommendation instead of waiting for the interruption notice
+	Drain bool `yaml:"drain,omitempty"`
+	// LaunchReplacement enables capacity rebalancing of the auto scaling group or the spot fleet, which launches a replacement
+	// instance early and then terminates the one at risk
+	LaunchReplacement bool `yaml:"launchReplacement,omitempty"`
+	UnknownKeys       `yaml:",inline"`
+}
+
+func (h SpotInterruptionHandling) MetricsPortOrDefault() int {
+	if h.MetricsPort > 0 {
+		return h.MetricsPort
+	}
+	return defaultSpotInterruptionHandlerMetricsPort
+}
+
+func (h SpotInterruptionHandling) Validate(path string) error {
+	if !h.Enabled {
+		if h.RebalanceRecommendation.Drain || h.RebalanceRecommendation.LaunchReplacement {
+			return fmt.Errorf("`%s.spotInterruptionHandling.rebalanceRecommendation` requires `%s.spotInterruptionHandling.enabled` to be true", path, path)
+		}
+		return nil
+	}
+	if h.MetricsPort < 0 || h.MetricsPort > 65535 {
+		return fmt.Errorf("`%s.spotInterruptionHandling.metricsPort` must be between 1 and 65535 but was %d", path, h.MetricsPort)
+	}
+	return nil
+}
+
+// RunsSpotInstances returns true when some or all of the instances in the node pool are spot instances
+func (c WorkerNodePool) RunsSpotInstances() bool {
+	if c.SpotFleet.Enabled() || c.SpotPrice != "" {
+		return true
+	}
+	mi := c.AutoScalingGroup.MixedInstances
+	return mi.Enabled && mi.OnDemandPercentageAboveBaseCapacity < 100
+}
+
+// SpotInterruptionHandlingEnabled returns true when any node pool needs the spot interruption handler deployed
+func (c *Cluster) SpotInterruptionHandlingEnabled() bool {
+	for _, np := range c.Worker.NodePools {
+		if np.SpotInterruptionHandling.Enabled {
+			return true
+		}
+	}
+	return false
+}
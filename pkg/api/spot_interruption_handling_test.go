@@ -0,0 +1,68 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestSpotInterruptionHandlingValidate(t *testing.T) {
+	testCases := []struct {
+		handling SpotInterruptionHandling
+		isValid  bool
+	}{
+		{
+			handling: SpotInterruptionHandling{},
+			isValid:  true,
+		},
+		{
+			handling: SpotInterruptionHandling{Enabled: true, RebalanceRecommendation: RebalanceRecommendation{Drain: true, LaunchReplacement: true}, MetricsPort: 9999},
+			isValid:  true,
+		},
+		// Invalid, rebalance recommendations are handled only when enabled
+		{
+			handling: SpotInterruptionHandling{RebalanceRecommendation: RebalanceRecommendation{LaunchReplacement: true}},
+			isValid:  false,
+		},
+		// Invalid, metricsPort is out of range
+		{
+			handling: SpotInterruptionHandling{Enabled: true, MetricsPort: 65536},
+			isValid:  false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.handling.Validate("worker.nodePools[name=pool1]")
+		if testCase.isValid && err != nil {
+			t.Errorf("Expected %+v to be valid, but it was not: %v", testCase.handling, err)
+		}
+		if !testCase.isValid && err == nil {
+			t.Errorf("Expected %+v to be invalid, but it was not", testCase.handling)
+		}
+	}
+
+	if port := (SpotInterruptionHandling{}).MetricsPortOrDefault(); port != 9253 {
+		t.Errorf("Expected the default metrics port to be 9253, but was %d", port)
+	}
+}
+
+func TestWorkerNodePoolRunsSpotInstances(t *testing.T) {
+	onDemand := NewDefaultNodePoolConfig()
+	if onDemand.RunsSpotInstances() {
+		t.Error("Expected the default node pool to run on-demand instances")
+	}
+
+	spotPrice := NewDefaultNodePoolConfig()
+	spotPrice.SpotPrice = "0.05"
+	if !spotPrice.RunsSpotInstances() {
+		t.Error("Expected a node pool with spotPrice to run spot instances")
+	}
+
+	mixed := NewDefaultNodePoolConfig()
+	mixed.AutoScalingGroup.MixedInstances = MixedInstances{Enabled: true, OnDemandPercentageAboveBaseCapacity: 100}
+	if mixed.RunsSpotInstances() {
+		t.Error("Expected a node pool of 100% on-demand mixed instances to run on-demand instances")
+	}
+	mixed.AutoScalingGroup.MixedInstances.OnDemandPercentageAboveBaseCapacity = 50
+	if !mixed.RunsSpotInstances() {
+		t.Error("Expected a node pool of 50% on-demand mixed instances to run spot instances")
+	}
+}
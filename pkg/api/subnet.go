@@ -16,6 +16,9 @@ type Subnet struct {
 	NATGateway       NATGatewayConfig `yaml:"natGateway,omitempty"`
 	Private          bool             `yaml:"private,omitempty"`
 	RouteTable       RouteTable       `yaml:"routeTable,omitempty"`
+	// IPv6CIDRIndex is the index of the /64 block assigned to this subnet out of the IPv6 CIDR block of the VPC.
+	// Defaults to the position of the subnet in `subnets` when `ipv6.enabled` is true
+	IPv6CIDRIndex *int `yaml:"ipv6CIDRIndex,omitempty"`
 }
 
 func NewPublicSubnet(az string, cidr string) Subnet {
@@ -262,6 +265,14 @@ func (s *Subnet) NATGatewayRouteLogicalName() string {
 	return s.subnetSpecificResourceLogicalName("RouteToNatGateway")
 }
 
+func (s *Subnet) IPv6InternetGatewayRouteLogicalName() string {
+	return s.subnetSpecificResourceLogicalName("IPv6RouteToInternet")
+}
+
+func (s *Subnet) EgressOnlyInternetGatewayRouteLogicalName() string {
+	return s.subnetSpecificResourceLogicalName("IPv6RouteToEgressOnlyInternetGateway")
+}
+
 func (s *Subnet) subnetSpecificResourceLogicalName(resourceName string) string {
 	return fmt.Sprintf("%s%s", s.LogicalName(), resourceName)
 }
@@ -0,0 +1,353 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/netutil"
+)
+
+const (
+	// AWS doesn't allow subnets smaller than /28 nor larger than /16
+	minSubnetPrefixLength = 16
+	maxSubnetPrefixLength = 28
+)
+
+var subnetTierNamePattern = regexp.MustCompile("^[a-zA-Z][a-zA-Z0-9]*$")
+
+// SubnetTier is a group of subnets, one per availability zone, sharing the same visibility and size
+type SubnetTier struct {
+	Name         string
+	Private      bool
+	PrefixLength int
+}
+
+// ParseSubnetTier parses a tier in the `<public|private>:<prefix length>` or `<name>=<public|private>:<prefix length>` notation
+// e.g. `public:24`, `private:20` or `Ingress=public:26`
+func ParseSubnetTier(s string) (SubnetTier, error) {
+	tier := SubnetTier{}
+
+	spec := s
+	if i := strings.Index(s, "="); i >= 0 {
+		tier.Name = s[:i]
+		spec = s[i+1:]
+	}
+
+	parts := strings.Split(spec, ":")
+	if len(parts) != 2 {
+		return tier, fmt.Errorf("invalid subnet tier \"%s\": expected <public|private>:<prefix length>", s)
+	}
+
+	switch parts[0] {
+	case "public":
+		tier.Private = false
+	case "private":
+		tier.Private = true
+	default:
+		return tier, fmt.Errorf("invalid subnet tier \"%s\": visibility must be either public or private but was %s", s, parts[0])
+	}
+
+	prefixLength, err := strconv.Atoi(strings.TrimPrefix(parts[1], "/"))
+	if err != nil {
+		return tier, fmt.Errorf("invalid subnet tier \"%s\": %v", s, err)
+	}
+	tier.PrefixLength = prefixLength
+
+	if tier.Name == "" {
+		tier.Name = strings.Title(parts[0])
+	}
+
+	return tier, nil
+}
+
+// SubnetPlan describes the subnets to be carved out of the VPC CIDR
+type SubnetPlan struct {
+	VPCCIDR           string
+	AvailabilityZones []string
+	Tiers             []SubnetTier
+	// ReservedPerTier is the number of extra CIDR blocks of each tier's size kept free for future node pools or availability zones
+	ReservedPerTier int
+	// Reserved are the CIDR blocks reserved by a previous plan, per tier name.
+	// Subnets of a tier are allocated from its reserved blocks first
+	Reserved map[string][]string
+}
+
+// SubnetAllocation is the result of a subnet plan
+type SubnetAllocation struct {
+	// Subnets are the newly planned subnets, ordered by tier and then availability zone
+	Subnets []Subnet
+	// Reserved are the CIDR blocks kept free for the future, per tier name
+	Reserved map[string][]string
+	// Free is the unused address space of the VPC which is neither allocated nor reserved
+	Free []string
+}
+
+func (p SubnetPlan) Validate() error {
+	if len(p.AvailabilityZones) == 0 {
+		return errors.New("at least one availability zone must be specified to plan subnets")
+	}
+	if len(p.Tiers) == 0 {
+		return errors.New("at least one subnet tier must be specified to plan subnets")
+	}
+	if p.ReservedPerTier < 0 {
+		return fmt.Errorf("the number of reserved blocks per tier must not be negative but was %d", p.ReservedPerTier)
+	}
+
+	vpcNet, err := netutil.ParseIPv4CIDR(p.VPCCIDR)
+	if err != nil {
+		return fmt.Errorf("invalid vpcCIDR: %v", err)
+	}
+	vpcOnes, _ := vpcNet.Mask.Size()
+
+	seenAZs := map[string]bool{}
+	for _, az := range p.AvailabilityZones {
+		if seenAZs[az] {
+			return fmt.Errorf("availability zone %s is specified more than once", az)
+		}
+		seenAZs[az] = true
+	}
+
+	seenTiers := map[string]bool{}
+	hasPublic := false
+	hasPrivate := false
+	for _, t := range p.Tiers {
+		if !subnetTierNamePattern.MatchString(t.Name) {
+			return fmt.Errorf("subnet tier name \"%s\" must start with a letter and consist only of alphanumeric characters", t.Name)
+		}
+		if seenTiers[t.Name] {
+			return fmt.Errorf("subnet tier %s is specified more than once", t.Name)
+		}
+		seenTiers[t.Name] = true
+		if t.PrefixLength < minSubnetPrefixLength || t.PrefixLength > maxSubnetPrefixLength {
+			return fmt.Errorf("prefix length of subnet tier %s must be between %d and %d but was %d", t.Name, minSubnetPrefixLength, maxSubnetPrefixLength, t.PrefixLength)
+		}
+		if t.PrefixLength < vpcOnes {
+			return fmt.Errorf("subnets of tier %s (/%d) are larger than the VPC (%s)", t.Name, t.PrefixLength, p.VPCCIDR)
+		}
+		hasPublic = hasPublic || !t.Private
+		hasPrivate = hasPrivate || t.Private
+	}
+
+	// NAT gateways for private subnets are placed in public subnets in the same availability zones
+	if hasPrivate && !hasPublic {
+		return errors.New("a public subnet tier is required to host NAT gateways for private subnets")
+	}
+
+	return nil
+}
+
+type subnetRequest struct {
+	tier   int
+	az     int
+	length int
+}
+
+// Allocate computes non-overlapping CIDRs for the planned subnets, avoiding the CIDRs of the existing subnets
+func (p SubnetPlan) Allocate(existing Subnets) (*SubnetAllocation, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	vpcNet, _ := netutil.ParseIPv4CIDR(p.VPCCIDR)
+	allocator, err := netutil.NewAllocator(vpcNet)
+	if err != nil {
+		return nil, err
+	}
+
+	existingNames := map[string]bool{}
+	for _, s := range existing {
+		existingNames[s.Name] = true
+		if s.InstanceCIDR == "" {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(s.InstanceCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instanceCIDR of existing subnet %s: %v", s.Name, err)
+		}
+		if err := allocator.Reserve(cidr); err != nil {
+			return nil, fmt.Errorf("existing subnet %s can't be kept: %v", s.Name, err)
+		}
+	}
+
+	// Blocks reserved by a previous plan are kept away from other tiers
+	pools := map[string][]*net.IPNet{}
+	for tier, cidrs := range p.Reserved {
+		for _, c := range cidrs {
+			_, cidr, err := net.ParseCIDR(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR block reserved for subnet tier %s: %v", tier, err)
+			}
+			if err := allocator.Reserve(cidr); err != nil {
+				return nil, fmt.Errorf("CIDR block reserved for subnet tier %s is no longer available: %v", tier, err)
+			}
+			pools[tier] = append(pools[tier], cidr)
+		}
+	}
+
+	requests := []subnetRequest{}
+	for i, t := range p.Tiers {
+		for j := range p.AvailabilityZones {
+			requests = append(requests, subnetRequest{tier: i, az: j, length: t.PrefixLength})
+		}
+	}
+
+	// Allocating larger blocks first avoids fragmenting the VPC CIDR
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].length < requests[j].length
+	})
+
+	subnets := make([][]Subnet, len(p.Tiers))
+	for i := range subnets {
+		subnets[i] = make([]Subnet, len(p.AvailabilityZones))
+	}
+
+	for _, r := range requests {
+		tier := p.Tiers[r.tier]
+
+		cidr := takeReservedBlock(pools, tier)
+		if cidr == nil {
+			if cidr, err = allocator.Allocate(r.length); err != nil {
+				return nil, fmt.Errorf("failed to allocate subnets of tier %s: %v", tier.Name, err)
+			}
+		}
+
+		if tier.Private {
+			subnets[r.tier][r.az] = NewPrivateSubnet(p.AvailabilityZones[r.az], cidr.String())
+		} else {
+			subnets[r.tier][r.az] = NewPublicSubnet(p.AvailabilityZones[r.az], cidr.String())
+		}
+	}
+
+	// Subnets are named after their tiers and numbered in the order of availability zones, skipping names already in use
+	for i, t := range p.Tiers {
+		n := 1
+		for j := range subnets[i] {
+			for existingNames[fmt.Sprintf("%s%d", t.Name, n)] {
+				n++
+			}
+			subnets[i][j].Name = fmt.Sprintf("%s%d", t.Name, n)
+			n++
+		}
+	}
+
+	// Top up the reserved blocks of each tier after all the subnets are packed at the beginning of the VPC CIDR
+	for _, t := range p.Tiers {
+		for len(pools[t.Name]) < p.ReservedPerTier {
+			cidr, err := allocator.Allocate(t.PrefixLength)
+			if err != nil {
+				return nil, fmt.Errorf("failed to reserve CIDR blocks for subnet tier %s: %v", t.Name, err)
+			}
+			pools[t.Name] = append(pools[t.Name], cidr)
+		}
+	}
+
+	result := &SubnetAllocation{Subnets: []Subnet{}, Reserved: map[string][]string{}, Free: []string{}}
+	for _, ss := range subnets {
+		result.Subnets = append(result.Subnets, ss...)
+	}
+	for tier, cidrs := range pools {
+		if len(cidrs) == 0 {
+			continue
+		}
+		strs := []string{}
+		for _, c := range cidrs {
+			strs = append(strs, c.String())
+		}
+		sort.Slice(strs, func(i, j int) bool { return compareCIDRs(strs[i], strs[j]) })
+		result.Reserved[tier] = strs
+	}
+	for _, f := range allocator.Free() {
+		result.Free = append(result.Free, f.String())
+	}
+
+	return result, nil
+}
+
+// takeReservedBlock removes and returns the lowest block reserved for the tier which matches the tier's subnet size
+func takeReservedBlock(pools map[string][]*net.IPNet, tier SubnetTier) *net.IPNet {
+	pool := pools[tier.Name]
+	best := -1
+	for i, c := range pool {
+		if ones, _ := c.Mask.Size(); ones != tier.PrefixLength {
+			continue
+		}
+		if best < 0 || bytes.Compare(c.IP.To16(), pool[best].IP.To16()) < 0 {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	cidr := pool[best]
+	pools[tier.Name] = append(pool[:best:best], pool[best+1:]...)
+	return cidr
+}
+
+func compareCIDRs(a, b string) bool {
+	_, aNet, _ := net.ParseCIDR(a)
+	_, bNet, _ := net.ParseCIDR(b)
+	return bytes.Compare(aNet.IP.To16(), bNet.IP.To16()) < 0
+}
+
+// SubnetUsage summarizes how the address space of the VPC is used by the managed subnets
+type SubnetUsage struct {
+	VPCCIDR   string
+	Total     uint64
+	Allocated uint64
+	Subnets   []string
+	Free      []string
+}
+
+func (u SubnetUsage) String() string {
+	largest := "none"
+	if len(u.Free) > 0 {
+		largestOnes := 33
+		for _, f := range u.Free {
+			_, n, _ := net.ParseCIDR(f)
+			if ones, _ := n.Mask.Size(); ones < largestOnes {
+				largestOnes = ones
+				largest = f
+			}
+		}
+	}
+	return fmt.Sprintf("vpcCIDR %s: %d subnet(s) use %d of %d addresses (%.1f%%). Largest free block: %s",
+		u.VPCCIDR, len(u.Subnets), u.Allocated, u.Total, float64(u.Allocated)*100/float64(u.Total), largest)
+}
+
+// SubnetUsage computes the address space of the VPC used by the subnets managed by kube-aws
+func (c DeploymentSettings) SubnetUsage() (*SubnetUsage, error) {
+	vpcNet, err := netutil.ParseIPv4CIDR(c.VPCCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vpcCIDR: %v", err)
+	}
+	allocator, err := netutil.NewAllocator(vpcNet)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &SubnetUsage{VPCCIDR: c.VPCCIDR, Total: netutil.Size(vpcNet), Subnets: []string{}, Free: []string{}}
+	for _, s := range c.Subnets {
+		if !s.ManageSubnet() {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(s.InstanceCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instanceCIDR of subnet %s: %v", s.Name, err)
+		}
+		if err := allocator.Reserve(cidr); err != nil {
+			return nil, fmt.Errorf("invalid instanceCIDR of subnet %s: %v", s.Name, err)
+		}
+		usage.Subnets = append(usage.Subnets, cidr.String())
+		usage.Allocated += netutil.Size(cidr)
+	}
+	for _, f := range allocator.Free() {
+		usage.Free = append(usage.Free, f.String())
+	}
+	return usage, nil
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubnetTier(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected SubnetTier
+		err      bool
+	}{
+		{input: "public:24", expected: SubnetTier{Name: "Public", PrefixLength: 24}},
+		{input: "private:/20", expected: SubnetTier{Name: "Private", Private: true, PrefixLength: 20}},
+		{input: "Ingress=public:26", expected: SubnetTier{Name: "Ingress", PrefixLength: 26}},
+		{input: "public", err: true},
+		{input: "internal:24", err: true},
+		{input: "private:big", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, err := ParseSubnetTier(tc.input)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSubnetPlanAllocate(t *testing.T) {
+	plan := SubnetPlan{
+		VPCCIDR:           "10.0.0.0/16",
+		AvailabilityZones: []string{"us-west-2a", "us-west-2b"},
+		Tiers: []SubnetTier{
+			{Name: "Public", PrefixLength: 24},
+			{Name: "Private", Private: true, PrefixLength: 20},
+		},
+		ReservedPerTier: 1,
+	}
+
+	t.Run("Fresh", func(t *testing.T) {
+		allocation, err := plan.Allocate(Subnets{})
+		require.NoError(t, err)
+
+		actual := map[string]string{}
+		for _, s := range allocation.Subnets {
+			actual[s.Name] = s.AvailabilityZone + " " + s.InstanceCIDR
+		}
+		assert.Equal(t, map[string]string{
+			"Public1":  "us-west-2a 10.0.32.0/24",
+			"Public2":  "us-west-2b 10.0.33.0/24",
+			"Private1": "us-west-2a 10.0.0.0/20",
+			"Private2": "us-west-2b 10.0.16.0/20",
+		}, actual)
+		assert.True(t, allocation.Subnets[2].Private)
+		assert.Equal(t, "Public1", allocation.Subnets[0].Name, "subnets must be ordered by tier and then availability zone")
+
+		assert.Equal(t, map[string][]string{
+			"Public":  {"10.0.34.0/24"},
+			"Private": {"10.0.48.0/20"},
+		}, allocation.Reserved)
+		assert.Equal(t, []string{"10.0.35.0/24", "10.0.36.0/22", "10.0.40.0/21", "10.0.64.0/18", "10.0.128.0/17"}, allocation.Free)
+	})
+
+	t.Run("ExistingSubnetsAndReservedBlocks", func(t *testing.T) {
+		p := plan
+		p.AvailabilityZones = []string{"us-west-2c"}
+		p.Reserved = map[string][]string{
+			"Private": {"10.0.48.0/20"},
+			"Public":  {"10.0.34.0/24"},
+		}
+		existing := Subnets{
+			NewPublicSubnet("us-west-2a", "10.0.32.0/24"),
+			NewPrivateSubnet("us-west-2a", "10.0.0.0/20"),
+		}
+		existing[0].Name = "Public1"
+		existing[1].Name = "Private1"
+
+		p.Tiers = []SubnetTier{
+			{Name: "Edge", PrefixLength: 24},
+			{Name: "Private", Private: true, PrefixLength: 20},
+		}
+		allocation, err := p.Allocate(existing)
+		require.NoError(t, err)
+
+		require.Len(t, allocation.Subnets, 2)
+		assert.Equal(t, "Edge1", allocation.Subnets[0].Name)
+		assert.Equal(t, "10.0.16.0/24", allocation.Subnets[0].InstanceCIDR, "a new tier must not take blocks reserved for other tiers")
+		assert.Equal(t, "Private2", allocation.Subnets[1].Name)
+		assert.Equal(t, "10.0.48.0/20", allocation.Subnets[1].InstanceCIDR, "the block reserved for the tier must be used first")
+		assert.Equal(t, map[string][]string{
+			"Edge":    {"10.0.17.0/24"},
+			"Private": {"10.0.64.0/20"},
+			"Public":  {"10.0.34.0/24"},
+		}, allocation.Reserved)
+	})
+
+	invalidPlans := []struct {
+		context string
+		plan    SubnetPlan
+		err     string
+	}{
+		{
+			context: "NoAZ",
+			plan:    SubnetPlan{VPCCIDR: "10.0.0.0/16", Tiers: plan.Tiers},
+			err:     "at least one availability zone",
+		},
+		{
+			context: "PrivateOnly",
+			plan:    SubnetPlan{VPCCIDR: "10.0.0.0/16", AvailabilityZones: []string{"a"}, Tiers: []SubnetTier{{Name: "Private", Private: true, PrefixLength: 20}}},
+			err:     "a public subnet tier is required",
+		},
+		{
+			context: "TooLarge",
+			plan:    SubnetPlan{VPCCIDR: "10.0.0.0/20", AvailabilityZones: []string{"a"}, Tiers: []SubnetTier{{Name: "Public", PrefixLength: 18}}},
+			err:     "larger than the VPC",
+		},
+		{
+			context: "Exhausted",
+			plan:    SubnetPlan{VPCCIDR: "10.0.0.0/22", AvailabilityZones: []string{"a", "b", "c"}, Tiers: []SubnetTier{{Name: "Public", PrefixLength: 23}}},
+			err:     "no free /23 subnet left",
+		},
+		{
+			context: "IPv6",
+			plan:    SubnetPlan{VPCCIDR: "fd00::/56", AvailabilityZones: []string{"a"}, Tiers: []SubnetTier{{Name: "Public", PrefixLength: 24}}},
+			err:     "is not an IPv4 CIDR",
+		},
+	}
+
+	for _, tc := range invalidPlans {
+		t.Run(tc.context, func(t *testing.T) {
+			_, err := tc.plan.Allocate(Subnets{})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestSubnetUsage(t *testing.T) {
+	c := DeploymentSettings{
+		VPCCIDR: "10.0.0.0/16",
+		Subnets: Subnets{
+			NewPublicSubnet("us-west-2a", "10.0.0.0/24"),
+			NewPublicSubnet("us-west-2b", "10.0.1.0/24"),
+			NewExistingPrivateSubnet("us-west-2a", "subnet-1"),
+		},
+	}
+
+	usage, err := c.SubnetUsage()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(512), usage.Allocated)
+	assert.Equal(t, uint64(65536), usage.Total)
+	assert.Equal(t, "vpcCIDR 10.0.0.0/16: 2 subnet(s) use 512 of 65536 addresses (0.8%). Largest free block: 10.0.128.0/17", usage.String())
+}
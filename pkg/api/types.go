@@ -4,7 +4,9 @@ type Worker struct {
 	APIEndpointName         string           `yaml:"apiEndpointName,omitempty"`
 	NodePools               []WorkerNodePool `yaml:"nodePools,omitempty"`
 	NodePoolRollingStrategy string           `yaml:"nodePoolRollingStrategy,omitempty"`
-	UnknownKeys             `yaml:",inline"`
+	// SecurityGroupRules are the rules of the security group shared by all the node pools
+	SecurityGroupRules SecurityGroupRules `yaml:"securityGroupRules,omitempty"`
+	UnknownKeys        `yaml:",inline"`
 }
 
 // Kubelet options
@@ -111,6 +113,8 @@ type AwsNodeLabels struct {
 
 type EncryptionAtRest struct {
 	Enabled bool `yaml:"enabled"`
+	// KMS runs the KMS provider plugin on controller nodes so that `kube-aws rotate encryption-key --provider kms` can switch secrets to envelope encryption with a KMS key
+	KMS EncryptionAtRestKMS `yaml:"kms,omitempty"`
 }
 
 type PodAutoscalerUseRestClient struct {
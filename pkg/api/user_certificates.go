@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// UserCertificates configures client certificates issued for users by `kube-aws credentials issue-user`
+type UserCertificates struct {
+	// Organizations maps each group accepted by `--groups` to the organizations set to the certificate,
+	// which kube-apiserver takes as the Kubernetes groups of the user. Groups missing here are rejected
+	Organizations map[string][]string `yaml:"organizations,omitempty"`
+}
+
+func (c UserCertificates) Validate() error {
+	for group, orgs := range c.Organizations {
+		if group == "" {
+			return errors.New("`kubernetes.authentication.userCertificates.organizations` must not contain an empty group")
+		}
+		if len(orgs) == 0 {
+			return fmt.Errorf("`kubernetes.authentication.userCertificates.organizations.%s` must list one or more organizations", group)
+		}
+		for _, o := range orgs {
+			if o == "" {
+				return fmt.Errorf("`kubernetes.authentication.userCertificates.organizations.%s` must not contain an empty organization", group)
+			}
+		}
+	}
+	return nil
+}
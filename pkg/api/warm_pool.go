@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+)
+
+var warmPoolStates = []string{"Stopped", "Running"}
+
+// WarmPool keeps instances launched in advance next to the auto scaling group, so that scale-outs and rollouts don't wait for
+// instances to boot from scratch.
+// Instances in the warm pool don't register themselves as nodes until they leave the warm pool and go in service
+type WarmPool struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// MinSize is the minimum number of instances kept in the warm pool. Defaults to 0
+	MinSize *int `yaml:"minSize,omitempty"`
+	// MaxGroupPreparedCapacity is the maximum number of instances in the warm pool and the group combined.
+	// Defaults to the max size of the group
+	MaxGroupPreparedCapacity *int `yaml:"maxGroupPreparedCapacity,omitempty"`
+	// PoolState is the state of instances in the warm pool. One of "Stopped" and "Running". Defaults to "Stopped"
+	PoolState   string `yaml:"poolState,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+func (p WarmPool) Validate() error {
+	if !p.Enabled {
+		return nil
+	}
+	if p.MinSize != nil && *p.MinSize < 0 {
+		return fmt.Errorf("`autoScalingGroup.warmPool.minSize` must be zero or greater if specified")
+	}
+	if p.MaxGroupPreparedCapacity != nil && *p.MaxGroupPreparedCapacity < 0 {
+		return fmt.Errorf("`autoScalingGroup.warmPool.maxGroupPreparedCapacity` must be zero or greater if specified")
+	}
+	if p.MinSize != nil && p.MaxGroupPreparedCapacity != nil && *p.MinSize > *p.MaxGroupPreparedCapacity {
+		return fmt.Errorf("`autoScalingGroup.warmPool.minSize` (%d) must be less than or equal to `autoScalingGroup.warmPool.maxGroupPreparedCapacity` (%d)",
+			*p.MinSize, *p.MaxGroupPreparedCapacity)
+	}
+	if p.PoolState != "" && !containsString(warmPoolStates, p.PoolState) {
+		return fmt.Errorf("`autoScalingGroup.warmPool.poolState` must be one of %s if specified", quoteAll(warmPoolStates))
+	}
+	return nil
+}
+
+func (p WarmPool) PoolStateOrDefault() string {
+	if p.PoolState != "" {
+		return p.PoolState
+	}
+	return "Stopped"
+}
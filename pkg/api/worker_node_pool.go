@@ -24,15 +24,20 @@ type WorkerNodePool struct {
 	IAMConfig                 IAMConfig              `yaml:"iam,omitempty"`
 	SpotPrice                 string                 `yaml:"spotPrice,omitempty"`
 	SecurityGroupIds          []string               `yaml:"securityGroupIds,omitempty"`
+	SecurityGroupRules        SecurityGroupRules     `yaml:"securityGroupRules,omitempty"`
 	CustomSettings            map[string]interface{} `yaml:"customSettings,omitempty"`
 	VolumeMounts              []NodeVolumeMount      `yaml:"volumeMounts,omitempty"`
 	Raid0Mounts               []Raid0Mount           `yaml:"raid0Mounts,omitempty"`
 	NodeSettings              `yaml:",inline"`
-	NodeStatusUpdateFrequency string              `yaml:"nodeStatusUpdateFrequency"`
-	CustomFiles               []CustomFile        `yaml:"customFiles,omitempty"`
-	CustomSystemdUnits        []CustomSystemdUnit `yaml:"customSystemdUnits,omitempty"`
-	Gpu                       Gpu                 `yaml:"gpu"`
-	NodePoolRollingStrategy   string              `yaml:"nodePoolRollingStrategy,omitempty"`
+	NodeStatusUpdateFrequency string                   `yaml:"nodeStatusUpdateFrequency"`
+	CustomFiles               []CustomFile             `yaml:"customFiles,omitempty"`
+	CustomSystemdUnits        []CustomSystemdUnit      `yaml:"customSystemdUnits,omitempty"`
+	Gpu                       Gpu                      `yaml:"gpu"`
+	NodePoolRollingStrategy   string                   `yaml:"nodePoolRollingStrategy,omitempty"`
+	Canary                    Canary                   `yaml:"canary,omitempty"`
+	SpotInterruptionHandling  SpotInterruptionHandling `yaml:"spotInterruptionHandling,omitempty"`
+	MigratedFromSpotFleet     bool                     `yaml:"migratedFromSpotFleet,omitempty"`
+	ClusterAutoscaler         ClusterAutoscalerOptions `yaml:"clusterAutoscaler,omitempty"`
 	UnknownKeys               `yaml:",inline"`
 }
 
@@ -79,16 +84,32 @@ func newDefaultSpotFleet() SpotFleet {
 	}
 }
 
+const (
+	workersLogicalName = "Workers"
+	// migratedWorkersLogicalName is the logical name of the auto scaling group replacing a spot fleet.
+	// CloudFormation can't change the type of a resource in place, hence the spot fleet keeps workersLogicalName until it is removed
+	migratedWorkersLogicalName = "WorkersASG"
+)
+
+// LogicalName returns the logical name of the spot fleet or the auto scaling group managing worker nodes
 func (c WorkerNodePool) LogicalName() string {
-	return "Workers"
+	if c.MigratedFromSpotFleet && !c.SpotFleet.Enabled() {
+		return migratedWorkersLogicalName
+	}
+	return workersLogicalName
+}
+
+// SpotFleetLogicalName returns the logical name of the spot fleet, which is still deployed while the node pool is being migrated to an auto scaling group
+func (c WorkerNodePool) SpotFleetLogicalName() string {
+	return workersLogicalName
 }
 
 func (c WorkerNodePool) LaunchConfigurationLogicalName() string {
-	return c.LogicalName() + "LC"
+	return workersLogicalName + "LC"
 }
 
 func (c WorkerNodePool) LaunchTemplateLogicalName() string {
-	return c.LogicalName() + "LT"
+	return workersLogicalName + "LT"
 }
 
 // NodePoolLogicalName returns a sanitized name of this pool which is usable as a valid cloudformation nested stack name
@@ -122,6 +143,60 @@ func (c WorkerNodePool) validate(experimentalGpuSupportEnabled bool) error {
 		return err
 	}
 
+	if c.SpotFleet.Enabled() {
+		logger.Warnf("`worker.nodePools[name=%s].spotFleet` is deprecated. Run `kube-aws migrate spotfleet --pool %s` to replace it with an auto scaling group of mixed instances", c.NodePoolName, c.NodePoolName)
+	}
+
+	if err := c.MetadataOptions.Validate(fmt.Sprintf("worker.nodePools[name=%s]", c.NodePoolName)); err != nil {
+		return err
+	}
+
+	if asg := c.AutoScalingGroup; (asg.WarmPool.Enabled || asg.InstanceRefresh.Enabled) && c.SpotFleet.Enabled() {
+		return fmt.Errorf("`worker.nodePools[name=%s].autoScalingGroup.warmPool` and `instanceRefresh` are incompatible with spot fleet", c.NodePoolName)
+	}
+
+	if c.AutoScalingGroup.HasScalingSettings() && c.SpotFleet.Enabled() {
+		return fmt.Errorf("`worker.nodePools[name=%s].autoScalingGroup.scheduledActions` and `targetTrackingPolicies` are incompatible with spot fleet", c.NodePoolName)
+	}
+
+	if c.AutoScalingGroup.WarmPool.Enabled && c.SpotPrice != "" {
+		return fmt.Errorf("`worker.nodePools[name=%s].autoScalingGroup.warmPool` is incompatible with spot instances", c.NodePoolName)
+	}
+
+	if c.MigratedFromSpotFleet && c.SpotFleet.Enabled() {
+		return fmt.Errorf("`worker.nodePools[name=%s].migratedFromSpotFleet` can't be true while `spotFleet` is still configured. Run `kube-aws migrate spotfleet --pool %s` for the configuration replacing the spot fleet", c.NodePoolName, c.NodePoolName)
+	}
+
+	if c.MetadataOptions.HasOptions() && c.SpotFleet.Enabled() {
+		return fmt.Errorf("`worker.nodePools[name=%s].metadataOptions` is incompatible with spot fleet because spot fleet doesn't launch instances from a launch template", c.NodePoolName)
+	}
+
+	if err := c.SpotInterruptionHandling.Validate(fmt.Sprintf("worker.nodePools[name=%s]", c.NodePoolName)); err != nil {
+		return err
+	}
+
+	if c.SpotInterruptionHandling.Enabled && c.MetadataOptions.TokensRequired() && c.MetadataOptions.HttpPutResponseHopLimit < 2 {
+		// The handler doesn't run in the host network, so that a response to its session token request takes two hops
+		return fmt.Errorf("`worker.nodePools[name=%s].spotInterruptionHandling` requires `metadataOptions.httpPutResponseHopLimit` to be 2 or greater when `metadataOptions.httpTokens` is \"%s\", "+
+			"because the handler runs outside the host network and obtains session tokens for IMDSv2 from there", c.NodePoolName, HttpTokensRequired)
+	}
+
+	if c.SpotInterruptionHandling.Enabled && !c.RunsSpotInstances() {
+		logger.Warnf("`worker.nodePools[name=%s].spotInterruptionHandling` has no effect because the node pool doesn't run spot instances", c.NodePoolName)
+	}
+
+	if err := c.ClusterAutoscaler.Validate(fmt.Sprintf("worker.nodePools[name=%s]", c.NodePoolName)); err != nil {
+		return err
+	}
+
+	if err := c.validateRollingStrategy(); err != nil {
+		return err
+	}
+
+	if err := c.validateInstanceTypeCapabilities(); err != nil {
+		return err
+	}
+
 	if err := ValidateVolumeMounts(c.VolumeMounts); err != nil {
 		return err
 	}
@@ -131,6 +206,10 @@ func (c WorkerNodePool) validate(experimentalGpuSupportEnabled bool) error {
 		return err
 	}
 
+	if err := c.SecurityGroupRules.Validate(fmt.Sprintf("worker.nodePools[name=%s].securityGroupRules", c.NodePoolName)); err != nil {
+		return err
+	}
+
 	if c.InstanceType == "t2.micro" || c.InstanceType == "t2.nano" {
 		logger.Warnf(`instance types "t2.nano" and "t2.micro" are not recommended. See https://github.com/kubernetes-incubator/kube-aws/issues/258 for more information`)
 	}
@@ -153,12 +232,20 @@ func (c WorkerNodePool) validate(experimentalGpuSupportEnabled bool) error {
 	// * InstanceCIDR
 	// * MapPublicIPs
 	// * ElasticFileSystemID
+	// * PrivateLinks
+	// * PrivateHostedZone
 	if c.VPC.HasIdentifier() {
 		return fmt.Errorf("although you can't customize VPC per node pool but you did specify \"%v\" in your cluster.yaml", c.VPC)
 	}
 	if c.InternetGateway.HasIdentifier() {
 		return fmt.Errorf("although you can't customize internet gateway per node pool but you did specify \"%v\" in your cluster.yaml", c.InternetGateway)
 	}
+	if c.PrivateLinks.Enabled {
+		return fmt.Errorf("although you can't customize `privateLinks` per node pool but you did specify \"%+v\" in your cluster.yaml", c.PrivateLinks)
+	}
+	if c.PrivateHostedZone.Enabled {
+		return fmt.Errorf("although you can't customize `privateHostedZone` per node pool but you did specify \"%+v\" in your cluster.yaml", c.PrivateHostedZone)
+	}
 	if c.VPCCIDR != "" {
 		return fmt.Errorf("although you can't customize `vpcCIDR` per node pool but you did specify \"%s\" in your cluster.yaml", c.VPCCIDR)
 	}
@@ -215,6 +302,31 @@ func (c WorkerNodePool) RollingUpdateMinInstancesInService() int {
 	return *c.AutoScalingGroup.RollingUpdateMinInstancesInService
 }
 
+func (c WorkerNodePool) validateRollingStrategy() error {
+	path := fmt.Sprintf("worker.nodePools[name=%s]", c.NodePoolName)
+
+	switch c.NodePoolRollingStrategy {
+	case "Canary":
+		return c.Canary.Validate(path)
+	case "Surge":
+		if c.SpotFleet.Enabled() {
+			return fmt.Errorf("`%s.nodePoolRollingStrategy` can't be Surge for a spot fleet", path)
+		}
+		if c.AutoScalingGroup.InstanceRefresh.Enabled {
+			return fmt.Errorf("`%s.nodePoolRollingStrategy` can't be Surge when `autoScalingGroup.instanceRefresh` is enabled", path)
+		}
+		// the replacement auto scaling group is considered ready only once its instances signal CloudFormation
+		if !c.WaitSignal.Enabled() {
+			return fmt.Errorf("`%s.nodePoolRollingStrategy` can't be Surge when `waitSignal` is disabled", path)
+		}
+	}
+
+	if len(c.Canary.HealthChecks) > 0 && c.NodePoolRollingStrategy != "Canary" {
+		return fmt.Errorf("`%s.canary` can be specified only with the Canary nodePoolRollingStrategy", path)
+	}
+	return nil
+}
+
 func (c WorkerNodePool) Validate(experimental Experimental) error {
 	return c.validate(experimental.GpuSupport.Enabled)
 }
@@ -224,7 +336,8 @@ func (c WorkerNodePool) WithDefaultsFrom(main DefaultWorkerSettings) WorkerNodeP
 		c.RootVolume.Type = main.WorkerRootVolumeType
 	}
 
-	if c.RootVolume.IOPS == 0 && c.RootVolume.Type == "io1" {
+	// IOPS are inherited only for the same volume type, as io1 and gp3 accept different ranges of IOPS
+	if c.RootVolume.IOPS == 0 && c.RootVolume.Type == main.WorkerRootVolumeType && (c.RootVolume.Type == "io1" || c.RootVolume.Type == "gp3") {
 		c.RootVolume.IOPS = main.WorkerRootVolumeIOPS
 	}
 
@@ -1,6 +1,11 @@
 package model
 
-import "github.com/kubernetes-incubator/kube-aws/pkg/api"
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+)
 
 // APIEndpoint represents a Kubernetes API endpoint
 type APIEndpoint struct {
@@ -9,3 +14,10 @@ type APIEndpoint struct {
 	// LoadBalancer is the load balancer serving this API endpoint if any
 	LoadBalancer APIEndpointLB
 }
+
+var unitNameUnsafeChars = regexp.MustCompile(`[^a-z0-9-]`)
+
+// DNSRoundRobinUnitName returns the name of the systemd unit registering the IP of a controller node to the DNS round-robin of this endpoint
+func (e APIEndpoint) DNSRoundRobinUnitName() string {
+	return "kube-apiserver-dns-register-" + unitNameUnsafeChars.ReplaceAllString(strings.ToLower(e.Name), "-") + ".service"
+}
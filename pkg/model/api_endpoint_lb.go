@@ -14,6 +14,36 @@ type APIEndpointLB struct {
 	api.APIEndpoint
 	// Subnets contains all the subnets assigned to this load-balancer. Specified only when this load balancer is not reused but managed one
 	Subnets api.Subnets
+	// legacy is true when this is the load balancer the endpoint is being migrated from
+	legacy bool
+}
+
+// MigrationSource returns the load balancer which served the API endpoint before its type was changed, or nil when the endpoint isn't being migrated.
+// It is kept as it was before the migration, under its original logical name, so that CloudFormation doesn't replace it
+func (b APIEndpointLB) MigrationSource() *APIEndpointLB {
+	if b.MigrateFrom == "" || !b.ManageELB() {
+		return nil
+	}
+	from := b.MigrateFrom
+	source := b
+	source.Type = &from
+	source.MigrateFrom = ""
+	source.SecurityGroupsEnabled = false
+	source.TLS = api.APIEndpointLBTLS{}
+	source.AccessLogs = api.APIEndpointLBAccessLogs{}
+	source.legacy = true
+	return &source
+}
+
+// Legacy returns true when this is the load balancer the endpoint is being migrated from, or a network load balancer without security groups.
+// A legacy network load balancer has neither security groups nor any of the load balancer attributes
+func (b APIEndpointLB) Legacy() bool {
+	return b.legacy || b.LegacyNetworkLoadBalancer()
+}
+
+// HealthzProxyPort is the port controller nodes serve the health of kube-apiserver for load balancer health checks
+func (b APIEndpointLB) HealthzProxyPort() int {
+	return api.APIServerHealthzProxyPort
 }
 
 // DNSNameRef returns a CloudFormation ref for the Amazon-provided DNS name of this load balancer, which is typically used
@@ -23,18 +53,28 @@ func (b APIEndpointLB) DNSNameRef() string {
 }
 
 // RecordSetLogicalName returns the logical name of a record set created for this load balancer
-// A logical name is an unique name of an AWS resource inside a CloudFormation stack template
+// A logical name is an unique name of an AWS resource inside a CloudFormation stack template.
+// It doesn't depend on the type of the load balancer, so that changing the type updates the record set in-place
+// rather than creating another record set for the same DNS name
 func (b APIEndpointLB) RecordSetLogicalName() string {
-	return fmt.Sprintf("%sRecordSet", b.LogicalName())
+	return fmt.Sprintf("APIEndpoint%sELBRecordSet", strings.Title(b.Name))
 }
 
 // HostedZoneRef returns a CloudFormation ref for the hosted zone the record set for this load balancer is created in
-func (b APIEndpointLB) HostedZoneRef() string {
-	return b.HostedZone.Identifier.ID
+func (b APIEndpointLB) HostedZoneRef() (string, error) {
+	return b.HostedZone.Identifier.RefOrError(func() (string, error) {
+		return "", fmt.Errorf("[bug] HostedZoneRef called for the API endpoint LB \"%s\" without a hosted zone", b.Name)
+	})
 }
 
-// LogicalName returns the unique resource name of the load balancer
+// LogicalName returns the unique resource name of the load balancer.
+// Network load balancers with security groups have their own suffix because a security group can't be added to a network load balancer
+// created without one, and hence a load balancer can't be changed in-place to a network load balancer with security groups.
+// Network load balancers without security groups keep the name older versions of kube-aws gave them, so that they aren't replaced
 func (b APIEndpointLB) LogicalName() string {
+	if b.NetworkLoadBalancer() && !b.Legacy() {
+		return fmt.Sprintf("APIEndpoint%sNLB", strings.Title(b.Name))
+	}
 	return fmt.Sprintf("APIEndpoint%sELB", strings.Title(b.Name))
 }
 
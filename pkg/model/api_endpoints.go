@@ -95,6 +95,9 @@ func (e APIEndpoints) ELBClassicRefs() []string {
 		if endpoint.LoadBalancer.Enabled() && endpoint.LoadBalancer.ClassicLoadBalancer() {
 			refs = append(refs, endpoint.LoadBalancer.Ref())
 		}
+		if source := endpoint.LoadBalancer.MigrationSource(); source != nil && source.ClassicLoadBalancer() {
+			refs = append(refs, source.Ref())
+		}
 	}
 	return refs
 }
@@ -106,6 +109,9 @@ func (e APIEndpoints) ELBV2TargetGroupRefs() []string {
 		if endpoint.LoadBalancer.Enabled() && endpoint.LoadBalancer.LoadBalancerV2() {
 			refs = append(refs, endpoint.LoadBalancer.TargetGroupRef())
 		}
+		if source := endpoint.LoadBalancer.MigrationSource(); source != nil && source.LoadBalancerV2() {
+			refs = append(refs, source.TargetGroupRef())
+		}
 	}
 	return refs
 }
@@ -117,11 +123,26 @@ func (e APIEndpoints) ManagedELBLogicalNames() []string {
 		if endpoint.LoadBalancer.ManageELB() {
 			logicalNames = append(logicalNames, endpoint.LoadBalancer.LogicalName())
 		}
+		if source := endpoint.LoadBalancer.MigrationSource(); source != nil {
+			logicalNames = append(logicalNames, source.LogicalName())
+		}
 	}
 	sort.Strings(logicalNames)
 	return logicalNames
 }
 
+// DNSRoundRobins returns all the API endpoints served by DNS round-robins of controller nodes, sorted by their names
+func (e APIEndpoints) DNSRoundRobins() []APIEndpoint {
+	endpoints := []APIEndpoint{}
+	for _, endpoint := range e {
+		if endpoint.DNSRoundRobinEnabled() {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].Name < endpoints[j].Name })
+	return endpoints
+}
+
 // GetDefault returns the default API endpoint identified by its name.
 // The name is defined as DefaultAPIEndpointName
 func (e APIEndpoints) GetDefault() APIEndpoint {
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+)
+
+const clusterAutoscalerPluginKey = "clusterAutoscaler"
+
+// ValidateClusterAutoscalerConflicts returns an error when a node pool managed by cluster-autoscaler is also resized by its own scaling settings.
+// Target tracking policies and scheduled changes of the desired capacity fight with cluster-autoscaler over the desired capacity,
+// whereas scheduled changes of the min/max sizes only move the bounds cluster-autoscaler works within
+func (c *Config) ValidateClusterAutoscalerConflicts(nps []*NodePoolConfig) error {
+	if !c.PluginConfigs.PluginIsEnabled(clusterAutoscalerPluginKey) {
+		return nil
+	}
+
+	for _, np := range nps {
+		if np.SpotFleet.Enabled() {
+			continue
+		}
+		if len(np.AutoScalingGroup.TargetTrackingPolicies) > 0 {
+			return fmt.Errorf("`worker.nodePools[name=%s].autoScalingGroup.targetTrackingPolicies` conflicts with cluster-autoscaler, which manages the desired capacity of the node pool. "+
+				"Disable the clusterAutoscaler plugin or remove the policies", np.NodePoolName)
+		}
+		for _, a := range np.AutoScalingGroup.ScheduledActions {
+			if a.DesiredCapacity != nil {
+				return fmt.Errorf("`worker.nodePools[name=%s].autoScalingGroup.scheduledActions[name=%s].desiredCapacity` conflicts with cluster-autoscaler, which manages the desired capacity of the node pool. "+
+					"Schedule `minSize` and `maxSize` instead", np.NodePoolName, a.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// SetClusterAutoscalerValues renders the priorities of node pools into the `expanderPriorities` value of the cluster-autoscaler plugin, if enabled.
+// It maps each priority to the patterns of names of auto scaling groups, in the format of the priority expander config.
+// Min/max sizes of node pools aren't rendered, as cluster-autoscaler auto-discovers them from the tagged auto scaling groups
+func (c *Config) SetClusterAutoscalerValues(nps []*NodePoolConfig) {
+	if !c.PluginConfigs.PluginIsEnabled(clusterAutoscalerPluginKey) {
+		return
+	}
+
+	priorities := map[string]interface{}{}
+	for _, np := range nps {
+		// Spot fleets aren't auto-discovered by cluster-autoscaler
+		if np.SpotFleet.Enabled() || np.ClusterAutoscaler.Priority == nil {
+			continue
+		}
+		// Names of auto scaling groups are generated by CloudFormation from the names of the root stack and the nested stack
+		pattern := fmt.Sprintf("^%s-%s-.*", c.ClusterName, np.NestedStackName())
+		key := strconv.Itoa(*np.ClusterAutoscaler.Priority)
+		patterns, _ := priorities[key].([]interface{})
+		priorities[key] = append(patterns, pattern)
+	}
+
+	pc := c.PluginConfigs[clusterAutoscalerPluginKey]
+	values := api.Values{}
+	for k, v := range pc.Values {
+		values[k] = v
+	}
+	values["expanderPriorities"] = priorities
+	pc.Values = values
+	c.PluginConfigs[clusterAutoscalerPluginKey] = pc
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kubernetes-incubator/kube-aws/pkg/api"
+)
+
+func TestSetClusterAutoscalerValues(t *testing.T) {
+	priority := 10
+	minSize := 0
+
+	pool1 := api.NewDefaultNodePoolConfig()
+	pool1.NodePoolName = "pool1"
+	pool1.Count = 0
+	pool1.AutoScalingGroup.MinSize = &minSize
+	pool1.AutoScalingGroup.MaxSize = 5
+	pool1.ClusterAutoscaler = api.ClusterAutoscalerOptions{Priority: &priority, ScaleDownDisabled: true}
+
+	pool2 := api.NewDefaultNodePoolConfig()
+	pool2.NodePoolName = "pool-2"
+
+	spotFleet := api.NewDefaultNodePoolConfig()
+	spotFleet.NodePoolName = "spotfleet"
+	spotFleet.SpotFleet.TargetCapacity = 3
+	spotFleet.ClusterAutoscaler = api.ClusterAutoscalerOptions{Priority: &priority}
+
+	nps := []*NodePoolConfig{{WorkerNodePool: pool1}, {WorkerNodePool: pool2}, {WorkerNodePool: spotFleet}}
+
+	c := &Config{Cluster: &api.Cluster{}}
+	c.ClusterName = "mycluster"
+	c.PluginConfigs = api.PluginConfigs{"clusterAutoscaler": {Enabled: true, Values: api.Values{"replicas": 1}}}
+	c.SetClusterAutoscalerValues(nps)
+
+	values := c.PluginConfigs["clusterAutoscaler"].Values
+	if _, ok := values["nodePools"]; ok {
+		t.Errorf("expected no nodePools value, as cluster-autoscaler auto-discovers node pools, but was %+v", values)
+	}
+	expectedPriorities := map[string]interface{}{"10": []interface{}{"^mycluster-Pool1-.*"}}
+	if !reflect.DeepEqual(values["expanderPriorities"], expectedPriorities) {
+		t.Errorf("expected expanderPriorities %+v, but was %+v", expectedPriorities, values["expanderPriorities"])
+	}
+	if values["replicas"] != 1 {
+		t.Errorf("expected values in cluster.yaml to be kept, but was %+v", values)
+	}
+
+	disabled := &Config{Cluster: &api.Cluster{}}
+	disabled.PluginConfigs = api.PluginConfigs{"clusterAutoscaler": {Enabled: false}}
+	disabled.SetClusterAutoscalerValues(nps)
+	if _, ok := disabled.PluginConfigs["clusterAutoscaler"].Values["expanderPriorities"]; ok {
+		t.Error("expected no values to be rendered when the cluster-autoscaler plugin is disabled")
+	}
+}
+
+func TestValidateClusterAutoscalerConflicts(t *testing.T) {
+	one := 1
+
+	scheduledSizes := api.NewDefaultNodePoolConfig()
+	scheduledSizes.NodePoolName = "scheduled-sizes"
+	scheduledSizes.AutoScalingGroup.ScheduledActions = []api.ScheduledAction{{Name: "nights", Recurrence: "0 20 * * *", MinSize: &one, MaxSize: &one}}
+
+	scheduledCapacity := api.NewDefaultNodePoolConfig()
+	scheduledCapacity.NodePoolName = "scheduled-capacity"
+	scheduledCapacity.AutoScalingGroup.ScheduledActions = []api.ScheduledAction{{Name: "nights", Recurrence: "0 20 * * *", DesiredCapacity: &one}}
+
+	targetTracking := api.NewDefaultNodePoolConfig()
+	targetTracking.NodePoolName = "target-tracking"
+	targetTracking.AutoScalingGroup.TargetTrackingPolicies = []api.TargetTrackingPolicy{{Name: "cpu", PredefinedMetric: "ASGAverageCPUUtilization", TargetValue: 60}}
+
+	enabled := &Config{Cluster: &api.Cluster{}}
+	enabled.PluginConfigs = api.PluginConfigs{"clusterAutoscaler": {Enabled: true}}
+	disabled := &Config{Cluster: &api.Cluster{}}
+	disabled.PluginConfigs = api.PluginConfigs{"clusterAutoscaler": {Enabled: false}}
+
+	if err := enabled.ValidateClusterAutoscalerConflicts([]*NodePoolConfig{{WorkerNodePool: scheduledSizes}}); err != nil {
+		t.Errorf("expected scheduled min/max sizes not to conflict with cluster-autoscaler, but they did: %v", err)
+	}
+	for _, np := range []api.WorkerNodePool{scheduledCapacity, targetTracking} {
+		nps := []*NodePoolConfig{{WorkerNodePool: np}}
+		if err := enabled.ValidateClusterAutoscalerConflicts(nps); err == nil {
+			t.Errorf("expected node pool %s to conflict with cluster-autoscaler, but it did not", np.NodePoolName)
+		}
+		if err := disabled.ValidateClusterAutoscalerConflicts(nps); err != nil {
+			t.Errorf("expected node pool %s not to conflict when the cluster-autoscaler plugin is disabled, but it did: %v", np.NodePoolName, err)
+		}
+	}
+}
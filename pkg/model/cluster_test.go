@@ -145,6 +145,52 @@ apiEndpoints:
     recordSetManaged: false
     securityGroupIds: []
     apiAccessAllowedSourceCIDRs: []
+`, `
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    type: network
+    securityGroupsEnabled: true
+    hostedZone:
+      id: hostedzone-xxxxxx
+    securityGroupIds:
+      - sg-1234
+    migrateFrom: classic
+    crossZone: false
+    healthCheck:
+      healthyThreshold: 2
+      unhealthyThreshold: 5
+      intervalSeconds: 30
+      timeoutSeconds: 10
+    tls:
+      certificateArn: arn:aws:acm:us-west-2:123456789012:certificate/abcd
+      sslPolicy: ELBSecurityPolicy-TLS-1-2-2017-01
+    accessLogs:
+      enabled: true
+      s3Bucket: access-logs
+      s3Prefix: api
+`, `
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+    accessLogs:
+      enabled: true
+      s3Bucket: access-logs
+      emitInterval: 5
+`, `
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  dnsRoundRobin:
+    hostedZone:
+      id: hostedzone-xxxxxx
+    recordSetTTL: 30
+    healthCheck:
+      intervalSeconds: 5
+      timeoutSeconds: 3
 `,
 }
 
@@ -200,15 +246,136 @@ apiEndpoints:
       id: hostedzone-xxxxxx
     recordSetTTL: 0
 `, `
-# cannot set security groups for a network load balancer
+# migrateFrom is only for network load balancers
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+    migrateFrom: network
+`, `
+# migrateFrom must be a load balancer type
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    type: network
+    securityGroupsEnabled: true
+    recordSetManaged: false
+    migrateFrom: application
+`, `
+# tls is only for network load balancers
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+    tls:
+      certificateArn: arn:aws:acm:us-west-2:123456789012:certificate/abcd
+`, `
+# tls.sslPolicy requires tls.certificateArn
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    type: network
+    securityGroupsEnabled: true
+    recordSetManaged: false
+    tls:
+      sslPolicy: ELBSecurityPolicy-TLS-1-2-2017-01
+`, `
+# access logs of network load balancers require tls
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    type: network
+    securityGroupsEnabled: true
+    recordSetManaged: false
+    accessLogs:
+      enabled: true
+      s3Bucket: access-logs
+`, `
+# access logs require s3Bucket
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+    accessLogs:
+      enabled: true
+`, `
+# securityGroupsEnabled is only for network load balancers
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+    securityGroupsEnabled: true
+`, `
+# crossZone of a network load balancer requires securityGroupsEnabled
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    type: network
+    recordSetManaged: false
+    crossZone: false
+`, `
+# migrateFrom requires securityGroupsEnabled, or the new network load balancer would have the same name as the previous one
 apiEndpoints:
 - name: public
+  dnsName: test.staging.core-os.net
   loadBalancer:
     type: network
+    recordSetManaged: false
+    migrateFrom: classic
+`, `
+# emitInterval must be either 5 or 60
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+    accessLogs:
+      enabled: true
+      s3Bucket: access-logs
+      emitInterval: 10
+`, `
+# health check timeout must be less than the interval
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+    healthCheck:
+      intervalSeconds: 5
+`, `
+# healthyThreshold must be between 2 and 10
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+    healthCheck:
+      healthyThreshold: 11
+`, `
+# dnsRoundRobin requires hostedZone.id
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  dnsRoundRobin:
+    private: true
+`, `
+# loadBalancer must be omitted for dnsRoundRobin
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+  dnsRoundRobin:
     hostedZone:
       id: hostedzone-xxxxxx
-    securityGroupIds:
-      - sg-1234
 `, `
 # must specify either securityGroupIds or apiAccessAllowedSourceCIDRs for classic ELBs
 apiEndpoints:
@@ -295,6 +462,176 @@ func TestMinimalChinaConfig(t *testing.T) {
 	}
 }
 
+func TestAPIEndpointLBMigration(t *testing.T) {
+	testCases := []struct {
+		migrateFrom   string
+		logicalNames  []string
+		classicRefs   int
+		targetGroups  int
+		sourceClassic bool
+	}{
+		{
+			migrateFrom:  "",
+			logicalNames: []string{"APIEndpointPublicNLB"},
+			classicRefs:  0,
+			targetGroups: 1,
+		},
+		{
+			migrateFrom:   "classic",
+			logicalNames:  []string{"APIEndpointPublicELB", "APIEndpointPublicNLB"},
+			classicRefs:   1,
+			targetGroups:  1,
+			sourceClassic: true,
+		},
+		{
+			migrateFrom:  "network",
+			logicalNames: []string{"APIEndpointPublicELB", "APIEndpointPublicNLB"},
+			classicRefs:  0,
+			targetGroups: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.migrateFrom, func(t *testing.T) {
+			conf := apiEndpointMinimalConfigYaml + availabilityZoneConfig + `
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    type: network
+    securityGroupsEnabled: true
+    hostedZone:
+      id: hostedzone-xxxxxx
+`
+			if tc.migrateFrom != "" {
+				conf += "    migrateFrom: " + tc.migrateFrom + "\n"
+			}
+			c, err := ClusterFromBytes([]byte(conf))
+			if err != nil {
+				t.Fatalf("failed to parse config: %v", err)
+			}
+			endpoints, err := NewAPIEndpoints(c.APIEndpointConfigs, c.Subnets)
+			if err != nil {
+				t.Fatalf("failed to create API endpoints: %v", err)
+			}
+
+			if actual := endpoints.ManagedELBLogicalNames(); !reflect.DeepEqual(actual, tc.logicalNames) {
+				t.Errorf("unexpected logical names: expected=%v, actual=%v", tc.logicalNames, actual)
+			}
+			if actual := len(endpoints.ELBClassicRefs()); actual != tc.classicRefs {
+				t.Errorf("unexpected number of classic ELBs: expected=%d, actual=%d", tc.classicRefs, actual)
+			}
+			if actual := len(endpoints.ELBV2TargetGroupRefs()); actual != tc.targetGroups {
+				t.Errorf("unexpected number of target groups: expected=%d, actual=%d", tc.targetGroups, actual)
+			}
+
+			endpoint, err := endpoints.FindByName("public")
+			if err != nil {
+				t.Fatalf("failed to find the API endpoint: %v", err)
+			}
+			lb := endpoint.LoadBalancer
+			if !lb.ManageSecurityGroup() {
+				t.Error("expected a security group to be managed for the network load balancer")
+			}
+			source := lb.MigrationSource()
+			if tc.migrateFrom == "" {
+				if source != nil {
+					t.Errorf("expected no migration source, but got %+v", source)
+				}
+				return
+			}
+			if !source.Legacy() || source.LogicalName() != "APIEndpointPublicELB" || source.ClassicLoadBalancer() != tc.sourceClassic {
+				t.Errorf("unexpected migration source: %+v", source)
+			}
+		})
+	}
+}
+
+func TestAPIEndpointLegacyNetworkLoadBalancer(t *testing.T) {
+	conf := apiEndpointMinimalConfigYaml + availabilityZoneConfig + `
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    type: network
+    hostedZone:
+      id: hostedzone-xxxxxx
+`
+	c, err := ClusterFromBytes([]byte(conf))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if c.APIEndpointConfigs.HasHealthzProxy() {
+		t.Error("expected no /healthz proxy for a network load balancer without security groups")
+	}
+	endpoints, err := NewAPIEndpoints(c.APIEndpointConfigs, c.Subnets)
+	if err != nil {
+		t.Fatalf("failed to create API endpoints: %v", err)
+	}
+
+	// The network load balancer keeps the name given by older versions of kube-aws so that it isn't replaced
+	expected := []string{"APIEndpointPublicELB"}
+	if actual := endpoints.ManagedELBLogicalNames(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected logical names: expected=%v, actual=%v", expected, actual)
+	}
+	endpoint, err := endpoints.FindByName("public")
+	if err != nil {
+		t.Fatalf("failed to find the API endpoint: %v", err)
+	}
+	if lb := endpoint.LoadBalancer; !lb.Legacy() || lb.ManageSecurityGroup() {
+		t.Errorf("expected a legacy network load balancer without security groups, but got %+v", lb)
+	}
+}
+
+func TestAPIEndpointLBTerminatingTLS(t *testing.T) {
+	endpoints := `
+apiEndpoints:
+- name: public
+  dnsName: test.staging.core-os.net
+  loadBalancer:
+    type: network
+    securityGroupsEnabled: true
+    recordSetManaged: false
+    tls:
+      certificateArn: arn:aws:acm:us-west-1:123456789012:certificate/abcd
+- name: private
+  dnsName: test-private.staging.core-os.net
+  loadBalancer:
+    recordSetManaged: false
+`
+	testCases := []struct {
+		conf  string
+		valid bool
+	}{
+		{
+			conf:  "adminAPIEndpointName: private\nworker:\n  apiEndpointName: private\n",
+			valid: true,
+		},
+		{
+			conf:  "adminAPIEndpointName: public\nworker:\n  apiEndpointName: private\n",
+			valid: false,
+		},
+		{
+			conf:  "adminAPIEndpointName: private\nworker:\n  apiEndpointName: public\n  nodePools:\n  - name: pool1\n",
+			valid: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		c, err := ClusterFromBytes([]byte(apiEndpointMinimalConfigYaml + availabilityZoneConfig + "amiId: ami-12345678\n" + endpoints + tc.conf))
+		if err != nil {
+			t.Fatalf("failed to parse config at index %d: %v", i, err)
+		}
+		_, err = Compile(c, api.ClusterOptions{S3URI: c.S3URI})
+		if tc.valid && err != nil {
+			t.Errorf("expected config at index %d to be valid, but got: %v", i, err)
+		}
+		if !tc.valid && (err == nil || !strings.Contains(err.Error(), "terminate")) {
+			t.Errorf("expected config at index %d to be invalid due to the TLS termination, but got: %v", i, err)
+		}
+	}
+}
+
 func TestAPIAccessAllowedSourceCIDRsForControllerSG(t *testing.T) {
 	testCases := []struct {
 		conf  string
@@ -380,6 +717,19 @@ apiEndpoints:
 `,
 			cidrs: []string{"0.0.0.0/0", "127.0.0.1/32", "192.168.0.0/24"},
 		},
+		{
+			conf: `
+apiEndpoints:
+- name: endpoint-1
+  dnsName: test-1.staging.core-os.net
+  dnsRoundRobin:
+    hostedZone:
+      id: hostedzone-xxxxxx
+    apiAccessAllowedSourceCIDRs:
+      - 10.0.0.0/8
+`,
+			cidrs: []string{"10.0.0.0/8"},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -768,6 +1118,16 @@ controller:
 			volumeType: "io1",
 			iops:       20000,
 		},
+		{
+			conf: `
+controller:
+  rootVolume:
+    type: gp3
+    iops: 6000
+`,
+			volumeType: "gp3",
+			iops:       6000,
+		},
 	}
 
 	invalidConfigs := []string{
@@ -804,6 +1164,13 @@ controller:
   rootVolume:
     type: io1
     iops: 20001
+`,
+		`
+# IOPS greater than the maximum of gp3 (16000)
+controller:
+  rootVolume:
+    type: gp3
+    iops: 16001
 `,
 	}
 
@@ -875,6 +1242,21 @@ workerRootVolumeIOPS: 20000
 			volumeType: "io1",
 			iops:       20000,
 		},
+		{
+			conf: `
+workerRootVolumeType: gp3
+`,
+			volumeType: "gp3",
+			iops:       0,
+		},
+		{
+			conf: `
+workerRootVolumeType: gp3
+workerRootVolumeIOPS: 16000
+`,
+			volumeType: "gp3",
+			iops:       16000,
+		},
 	}
 
 	invalidConfigs := []string{
@@ -901,6 +1283,11 @@ workerRootVolumeIOPS: 99
 # IOPS greater than the maximum (20000)
 workerRootVolumeType: io1
 workerRootVolumeIOPS: 20001
+`,
+		`
+# IOPS smaller than the baseline of gp3 (3000)
+workerRootVolumeType: gp3
+workerRootVolumeIOPS: 2000
 `,
 	}
 
@@ -991,6 +1378,50 @@ experimental:
 
 }
 
+func TestNodeDrainerImage(t *testing.T) {
+	original := VERSION
+	defer func() { VERSION = original }()
+	VERSION = "master/0123abcd+dirty"
+
+	testCases := []struct {
+		conf     string
+		expected string
+	}{
+		{
+			conf: `
+experimental:
+  nodeDrainer:
+    enabled: true
+`,
+			expected: "quay.io/kube-aws/kube-aws:master-0123abcd-dirty",
+		},
+		{
+			conf: `
+experimental:
+  nodeDrainer:
+    enabled: true
+    image:
+      repo: example.com/kube-aws
+      tag: v0.16.0
+`,
+			expected: "example.com/kube-aws:v0.16.0",
+		},
+	}
+
+	for _, testCase := range testCases {
+		confBody := singleAzConfigYaml + testCase.conf
+		c, err := ClusterFromBytes([]byte(confBody))
+		if err != nil {
+			t.Errorf("failed to parse config %s: %v", confBody, err)
+			continue
+		}
+		actual := Config{Cluster: c}.NodeDrainerImage().RepoWithTag()
+		if actual != testCase.expected {
+			t.Errorf("expected node drainer image to be %s, but was %s", testCase.expected, actual)
+		}
+	}
+}
+
 func TestEncryptionAtRestConfig(t *testing.T) {
 
 	validConfigs := []struct {
@@ -1059,11 +1490,274 @@ encryptionAtRest:
 	}
 }
 
-func TestPodAutoscalerUseRestClientConfig(t *testing.T) {
-	validConfigs := []struct {
-		conf                       string
-		podAutoscalerUseRestClient api.PodAutoscalerUseRestClient
-	}{
+func TestEncryptionAtRestKMSConfig(t *testing.T) {
+	c, err := ClusterFromBytes([]byte(singleAzConfigYaml + `
+kubernetes:
+  encryptionAtRest:
+    enabled: true
+    kms:
+      enabled: true
+      image:
+        repo: example.com/aws-encryption-provider
+        tag: v0.0.1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if arn := c.EncryptionAtRestKMSKeyARN(); arn != c.KMSKeyARN {
+		t.Errorf("expected the KMS key to default to kmsKeyArn but was %s", arn)
+	}
+	if size := c.Kubernetes.EncryptionAtRest.KMS.CacheSizeOrDefault(); size != 1000 {
+		t.Errorf("unexpected default cache size: %d", size)
+	}
+
+	invalidConfigs := []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: singleAzConfigYaml + `
+kubernetes:
+  encryptionAtRest:
+    kms:
+      enabled: true
+      image:
+        repo: example.com/aws-encryption-provider
+        tag: v0.0.1
+`,
+			err: "requires `kubernetes.encryptionAtRest.enabled` to be true",
+		},
+		{
+			conf: singleAzConfigYaml + `
+kubernetes:
+  encryptionAtRest:
+    enabled: true
+    kms:
+      enabled: true
+`,
+			err: "`kubernetes.encryptionAtRest.kms.image.repo` and `kubernetes.encryptionAtRest.kms.image.tag` must be specified",
+		},
+	}
+
+	for _, tc := range invalidConfigs {
+		_, err := ClusterFromBytes([]byte(tc.conf))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" but was: %v\n%s", tc.err, err, tc.conf)
+		}
+	}
+}
+
+func TestSecurityGroupRules(t *testing.T) {
+	c, err := ClusterFromBytes([]byte(singleAzConfigYaml + `
+controller:
+  securityGroupRules:
+    ingress:
+    - description: node-exporter
+      protocol: tcp
+      fromPort: 9100
+      cidr: 10.1.0.0/16
+    egress:
+    - protocol: tcp
+      fromPort: 2379
+      securityGroup: etcd
+    - protocol: tcp
+      fromPort: 0
+      toPort: 65535
+      cidr: 0.0.0.0/0
+worker:
+  nodePools:
+  - name: pool1
+    securityGroupRules:
+      ingress:
+      - protocol: udp
+        fromPort: 53
+        securityGroup: controller
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rules := c.Controller.SecurityGroupRules
+	if !rules.RestrictsEgress() {
+		t.Errorf("expected controller egress to be restricted")
+	}
+	resources, err := rules.Resources("SecurityGroupController", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resources) != 3 {
+		t.Fatalf("expected 3 rules but was %+v", resources)
+	}
+	if resources[0].Type != "AWS::EC2::SecurityGroupIngress" || resources[0].Properties != `{"CidrIp":"10.1.0.0/16","Description":"node-exporter","FromPort":9100,"GroupId":{"Ref":"SecurityGroupController"},"IpProtocol":"tcp","ToPort":9100}` {
+		t.Errorf("unexpected ingress rule: %+v", resources[0])
+	}
+	if resources[1].Type != "AWS::EC2::SecurityGroupEgress" || resources[1].Properties != `{"DestinationSecurityGroupId":{"Ref":"SecurityGroupEtcd"},"FromPort":2379,"GroupId":{"Ref":"SecurityGroupController"},"IpProtocol":"tcp","ToPort":2379}` {
+		t.Errorf("unexpected egress rule: %+v", resources[1])
+	}
+
+	// Reordering rules or updating descriptions doesn't rename them
+	reordered := api.SecurityGroupRules{Egress: []api.SecurityGroupRule{rules.Egress[1], rules.Egress[0]}, Ingress: rules.Ingress}
+	reordered.Ingress[0].Description = "updated"
+	renamed, err := reordered.Resources("SecurityGroupController", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if renamed[0].LogicalName != resources[0].LogicalName || renamed[1].LogicalName != resources[2].LogicalName || renamed[2].LogicalName != resources[1].LogicalName {
+		t.Errorf("expected logical names not to change: %+v %+v", resources, renamed)
+	}
+
+	np := NodePoolConfig{WorkerNodePool: c.Worker.NodePools[0]}
+	if refs := np.SecurityGroupRefs(); refs[len(refs)-1] != `{"Ref":"SecurityGroupNodePool"}` {
+		t.Errorf("expected the node pool security group to be attached: %v", refs)
+	}
+	npResources, err := np.SecurityGroupRules.Resources("SecurityGroupNodePool", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(npResources) != 1 || !strings.Contains(npResources[0].Properties, `"SourceSecurityGroupId":{"Fn::ImportValue":{"Fn::Sub":"${NetworkStackName}-ControllerSecurityGroup"}}`) {
+		t.Errorf("expected the controller security group to be imported from the network stack: %+v", npResources)
+	}
+
+	invalidConfigs := []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: singleAzConfigYaml + `
+controller:
+  securityGroupRules:
+    egress:
+    - protocol: tcp
+      fromPort: 443
+      cidr: 0.0.0.0/0
+`,
+			err: "`controller.securityGroupRules.egress` must allow tcp port 2379 to the etcd security group",
+		},
+		{
+			conf: singleAzConfigYaml + `
+controller:
+  securityGroupRules:
+    egress:
+    - protocol: tcp
+      fromPort: 2379
+      securityGroup: etcd
+    - protocol: tcp
+      fromPort: 443
+      cidr: 10.0.0.0/8
+`,
+			err: "`controller.securityGroupRules.egress` must allow tcp port 10250 to the controller security group",
+		},
+		{
+			conf: singleAzConfigYaml + `
+etcd:
+  securityGroupRules:
+    egress:
+    - protocol: tcp
+      fromPort: 2379
+      securityGroup: etcd
+`,
+			err: "must allow tcp port 2380 to the etcd security group or the VPC CIDR, which is required for etcd peer traffic",
+		},
+		{
+			conf: singleAzConfigYaml + `
+worker:
+  securityGroupRules:
+    egress:
+    - protocol: udp
+      fromPort: 443
+      securityGroup: controller
+`,
+			err: "`worker.securityGroupRules.egress` must allow tcp port 443 to the controller security group",
+		},
+		{
+			conf: singleAzConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    securityGroupRules:
+      ingress:
+      - protocol: tcp
+        fromPort: 80
+        securityGroup: bastion
+`,
+			err: "invalid worker.nodePools[name=pool1].securityGroupRules.ingress[0]: unknown securityGroup \"bastion\"",
+		},
+		{
+			conf: singleAzConfigYaml + `
+controller:
+  securityGroupRules:
+    ingress:
+    - protocol: tcp
+      fromPort: 80
+      cidr: 10.0.0.0/8
+      securityGroup: worker
+`,
+			err: "exactly one of cidr, cidrIPv6 or securityGroup must be specified",
+		},
+		{
+			conf: singleAzConfigYaml + `
+controller:
+  securityGroupRules:
+    ingress:
+    - protocol: tcp
+      fromPort: 8080
+      toPort: 80
+      cidr: 10.0.0.0/8
+`,
+			err: "invalid port range 8080-80",
+		},
+		{
+			conf: singleAzConfigYaml + `
+controller:
+  securityGroupRules:
+    ingress:
+    - protocol: icmp
+      fromPort: 8
+      cidr: 10.0.0.0/8
+`,
+			err: "fromPort and toPort can't be specified for the protocol icmp",
+		},
+		{
+			conf: singleAzConfigYaml + `
+etcd:
+  securityGroupRules:
+    ingress:
+    - protocol: tcp
+      fromPort: 2379
+      securityGroup: sg-1234abcd
+    - description: duplicate
+      protocol: tcp
+      fromPort: 2379
+      securityGroup: sg-1234abcd
+`,
+			err: "etcd.securityGroupRules.ingress[1] duplicates etcd.securityGroupRules.ingress[0]",
+		},
+		{
+			conf: singleAzConfigYaml + `
+controller:
+  securityGroupRules:
+    ingress:
+    - protocol: tcp
+      port: 80
+      cidr: 10.0.0.0/8
+`,
+			err: "unknown keys found in controller.securityGroupRules.ingress[0]: port",
+		},
+	}
+
+	for _, tc := range invalidConfigs {
+		_, err := ClusterFromBytes([]byte(tc.conf))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" but was: %v\n%s", tc.err, err, tc.conf)
+		}
+	}
+}
+
+func TestPodAutoscalerUseRestClientConfig(t *testing.T) {
+	validConfigs := []struct {
+		conf                       string
+		podAutoscalerUseRestClient api.PodAutoscalerUseRestClient
+	}{
 		{
 			conf: `
 `,
@@ -1899,3 +2593,626 @@ kmsKeyArn: "arn:aws:kms:eu-west-1:xxxxxxxxx:key/xxxxxxxxxxxxxxxxxxx"
 		t.Errorf("Expecting validation error for mismatching KMS key ARN and region config: %s\n%s", err, confBody)
 	}
 }
+
+func TestIPv6(t *testing.T) {
+	dualStack := `
+ipv6:
+  enabled: true
+  podCIDR: fd00:10:2::/56
+  serviceCIDR: fd00:10:3::/112
+`
+	subnets := `
+subnets:
+- name: Public0
+  availabilityZone: us-west-1a
+  instanceCIDR: 10.0.0.0/24
+- name: Private1
+  availabilityZone: us-west-1a
+  instanceCIDR: 10.0.1.0/24
+  private: true
+  ipv6CIDRIndex: 10
+`
+
+	t.Run("Valid", func(t *testing.T) {
+		cluster, err := ClusterFromBytes([]byte(minimalConfigYaml + subnets + dualStack))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if cluster.PodCIDRs() != "10.2.0.0/16,fd00:10:2::/56" {
+			t.Errorf("unexpected pod CIDRs: %s", cluster.PodCIDRs())
+		}
+		if cluster.ServiceCIDRs() != "10.3.0.0/24,fd00:10:3::/112" {
+			t.Errorf("unexpected service CIDRs: %s", cluster.ServiceCIDRs())
+		}
+		if cluster.ControllerFeatureGates()["IPv6DualStack"] != "true" {
+			t.Errorf("IPv6DualStack feature gate should be enabled: %v", cluster.ControllerFeatureGates())
+		}
+
+		c := Config{Cluster: cluster}
+		expectedRefs := []string{
+			`{ "Fn::Select" : [0, { "Fn::Cidr" : [{ "Fn::Select" : [0, { "Fn::GetAtt" : ["VPC", "Ipv6CidrBlocks"] }] }, 256, "64"] }] }`,
+			`{ "Fn::Select" : [10, { "Fn::Cidr" : [{ "Fn::Select" : [0, { "Fn::GetAtt" : ["VPC", "Ipv6CidrBlocks"] }] }, 256, "64"] }] }`,
+		}
+		for i, s := range cluster.Subnets {
+			ref, err := c.IPv6SubnetCIDRRef(s)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				continue
+			}
+			if ref != expectedRefs[i] {
+				t.Errorf("unexpected ipv6 cidr of subnet %d: expected=%s, actual=%s", i, expectedRefs[i], ref)
+			}
+		}
+		if !c.ManageEgressOnlyInternetGateway() {
+			t.Errorf("an egress-only internet gateway should be managed for the private subnet")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		cluster, err := ClusterFromBytes([]byte(minimalConfigYaml + subnets[:len(subnets)-len("  ipv6CIDRIndex: 10\n")]))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cluster.PodCIDRs() != "10.2.0.0/16" || cluster.ServiceCIDRs() != "10.3.0.0/24" {
+			t.Errorf("unexpected pod/service CIDRs: %s %s", cluster.PodCIDRs(), cluster.ServiceCIDRs())
+		}
+		if cluster.Subnets[0].IPv6CIDRIndex != nil {
+			t.Errorf("ipv6CIDRIndex should not be defaulted when ipv6 is disabled")
+		}
+	})
+
+	invalidConfigs := []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: singleAzConfigYaml + `
+ipv6:
+  podCIDR: fd00:10:2::/56
+`,
+			err: "can't be specified unless `ipv6.enabled` is true",
+		},
+		{
+			conf: singleAzConfigYaml + dualStack + `
+kubernetesVersion: v1.15.3
+`,
+			err: "requires kubernetesVersion 1.16 or greater",
+		},
+		{
+			conf: singleAzConfigYaml + `
+ipv6:
+  enabled: true
+  podCIDR: 10.10.0.0/16
+  serviceCIDR: fd00:10:3::/112
+`,
+			err: "is not an IPv6 CIDR",
+		},
+		{
+			conf: singleAzConfigYaml + `
+ipv6:
+  enabled: true
+  podCIDR: fd00:10:2::/56
+  serviceCIDR: fd00:10:3::/64
+`,
+			err: "ipv6.serviceCIDR (fd00:10:3::/64) is too large",
+		},
+		{
+			conf: singleAzConfigYaml + `
+ipv6:
+  enabled: true
+  podCIDR: fd00:10::/32
+  serviceCIDR: fd00:10:3::/112
+`,
+			err: "overlaps with ipv6.podCIDR",
+		},
+		{
+			conf: singleAzConfigYaml + dualStack + `
+vpc:
+  id: vpc-1a2b3c4d
+internetGateway:
+  id: igw-1a2b3c4d
+`,
+			err: "`ipv6.vpcCIDR` must be set",
+		},
+		{
+			conf: singleAzConfigYaml + dualStack + `
+vpc:
+  id: vpc-1a2b3c4d
+internetGateway:
+  id: igw-1a2b3c4d
+ipv6:
+  enabled: true
+  vpcCIDR: fd00:10:2::/64
+  podCIDR: fd00:10:2::/56
+  serviceCIDR: fd00:10:3::/112
+`,
+			err: "must be a /56 or larger",
+		},
+		{
+			conf: singleAzConfigYaml + `
+ipv6:
+  enabled: true
+  vpcCIDR: 2600:1f14:abc:de00::/56
+  podCIDR: fd00:10:2::/56
+  serviceCIDR: fd00:10:3::/112
+`,
+			err: "can be specified only for an existing VPC",
+		},
+		{
+			conf: minimalConfigYaml + subnets + `- name: Public2
+  availabilityZone: us-west-1c
+  instanceCIDR: 10.0.2.0/24
+  ipv6CIDRIndex: 10
+` + dualStack,
+			err: "are assigned the same ipv6CIDRIndex 10",
+		},
+		{
+			conf: minimalConfigYaml + subnets + `- name: Public2
+  availabilityZone: us-west-1c
+  instanceCIDR: 10.0.2.0/24
+  ipv6CIDRIndex: 256
+` + dualStack,
+			err: "must be between 0 and 255",
+		},
+		{
+			conf: minimalConfigYaml + `
+vpcCIDR: fd00::/56
+`,
+			err: "is not an IPv4 CIDR",
+		},
+	}
+
+	for _, tc := range invalidConfigs {
+		_, err := ClusterFromBytes([]byte(tc.conf))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" but was: %v\n%s", tc.err, err, tc.conf)
+		}
+	}
+}
+
+func TestPrivateLinks(t *testing.T) {
+	fullyPrivate := `
+amiId: ami-1234567
+hostedZoneId: "XXXXXXXXXXX"
+subnets:
+- name: Private1
+  availabilityZone: us-west-1a
+  instanceCIDR: 10.0.1.0/24
+  private: true
+- name: Private2
+  availabilityZone: us-west-1b
+  instanceCIDR: 10.0.2.0/24
+  private: true
+controller:
+  subnets:
+  - name: Private1
+  - name: Private2
+  loadBalancer:
+    private: true
+etcd:
+  subnets:
+  - name: Private1
+  - name: Private2
+worker:
+  nodePools:
+  - name: pool1
+    amiId: ami-1234567
+    subnets:
+    - name: Private2
+privateLinks:
+  enabled: true
+  disableInternetEgress: true
+`
+
+	t.Run("FullyPrivate", func(t *testing.T) {
+		c, err := ConfigFromBytes([]byte(minimalConfigYaml + fullyPrivate))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if c.ManageInternetGateway() {
+			t.Errorf("an internet gateway should not be managed while internet egress is disabled")
+		}
+		if n := len(c.NATGateways()); n != 0 {
+			t.Errorf("expected no NAT gateway but was %d", n)
+		}
+		if n := len(c.EtcdNodes); n != 1 {
+			t.Errorf("expected an etcd node but was %d", n)
+		}
+
+		expectedSubnetRefs := []string{`{ "Ref" : "Private1" }`, `{ "Ref" : "Private2" }`}
+		if refs := c.VPCEndpointSubnetRefs(); !reflect.DeepEqual(refs, expectedSubnetRefs) {
+			t.Errorf("unexpected subnets for interface endpoints: expected=%v, actual=%v", expectedSubnetRefs, refs)
+		}
+		rtbRefs, err := c.VPCEndpointRouteTableRefs()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectedRtbRefs := []string{`{ "Ref" : "Private1RouteTable" }`, `{ "Ref" : "Private2RouteTable" }`}
+		if !reflect.DeepEqual(rtbRefs, expectedRtbRefs) {
+			t.Errorf("unexpected route tables for gateway endpoints: expected=%v, actual=%v", expectedRtbRefs, rtbRefs)
+		}
+
+		np, err := NodePoolCompile(c.Worker.NodePools[0], c)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !np.PrivateLinks.InternetEgressDisabled() {
+			t.Errorf("node pools should inherit privateLinks: %+v", np.PrivateLinks)
+		}
+	})
+
+	t.Run("PreferPrivateSubnets", func(t *testing.T) {
+		c, err := ConfigFromBytes([]byte(minimalConfigYaml + `
+amiId: ami-1234567
+subnets:
+- name: Public1
+  availabilityZone: us-west-1a
+  instanceCIDR: 10.0.0.0/24
+- name: Private1
+  availabilityZone: us-west-1a
+  instanceCIDR: 10.0.1.0/24
+  private: true
+- name: Public2
+  availabilityZone: us-west-1b
+  instanceCIDR: 10.0.2.0/24
+privateLinks:
+  enabled: true
+  services:
+  - sts
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !c.ManageInternetGateway() {
+			t.Errorf("an internet gateway should be managed unless internet egress is disabled")
+		}
+		expected := []string{`{ "Ref" : "Private1" }`, `{ "Ref" : "Public2" }`}
+		if refs := c.VPCEndpointSubnetRefs(); !reflect.DeepEqual(refs, expected) {
+			t.Errorf("unexpected subnets for interface endpoints: expected=%v, actual=%v", expected, refs)
+		}
+	})
+
+	invalidConfigs := []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: singleAzConfigYaml + `
+privateLinks:
+  disableInternetEgress: true
+`,
+			err: "can't be specified unless `privateLinks.enabled` is true",
+		},
+		{
+			conf: singleAzConfigYaml + `
+controller:
+  loadBalancer:
+    private: true
+privateLinks:
+  enabled: true
+  disableInternetEgress: true
+`,
+			err: "all of them must be private",
+		},
+		{
+			conf: minimalConfigYaml + strings.Replace(fullyPrivate, "    private: true\n", "", 1),
+			err:  "`controller.loadBalancer.private` must be true",
+		},
+		{
+			conf: minimalConfigYaml + fullyPrivate + `  services:
+  - s3
+  - elb
+`,
+			err: "unsupported service \"elb\"",
+		},
+		{
+			conf: minimalConfigYaml + fullyPrivate + `  services:
+  - s3
+  - ec2
+  - sts
+  - cloudformation
+`,
+			err: "`privateLinks.services` must include autoscaling, kms when `privateLinks.disableInternetEgress` is true",
+		},
+	}
+
+	for _, tc := range invalidConfigs {
+		_, err := ClusterFromBytes([]byte(tc.conf))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" but was: %v\n%s", tc.err, err, tc.conf)
+		}
+	}
+}
+
+func TestPrivateHostedZone(t *testing.T) {
+	privateHostedZone := `
+privateHostedZone:
+  enabled: true
+  name: mycluster.internal
+`
+	apiEndpoints := `
+apiEndpoints:
+- name: internal
+  dnsName: api.mycluster.internal
+  loadBalancer:
+    private: true
+- name: public
+  dnsName: api.example.com
+  loadBalancer:
+    hostedZone:
+      id: hostedzone-xxxx
+`
+
+	t.Run("EtcdAndAPIEndpoints", func(t *testing.T) {
+		c, err := ClusterFromBytes([]byte(apiEndpointMinimalConfigYaml + availabilityZoneConfig + privateHostedZone + apiEndpoints + `
+etcd:
+  memberIdentityProvider: eni
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ref := `{"Fn::ImportValue" : {"Fn::Sub" : "${NetworkStackName}-PrivateHostedZone"}}`
+
+		if c.Etcd.Cluster.InternalDomainName != "mycluster.internal" {
+			t.Errorf("etcd.internalDomainName should default to the name of the private hosted zone but was \"%s\"", c.Etcd.Cluster.InternalDomainName)
+		}
+		if c.Etcd.HostedZoneManaged() {
+			t.Error("the etcd stack should not create a hosted zone when etcd nodes use the private hosted zone")
+		}
+		if !c.Etcd.Cluster.RecordSetsManaged() {
+			t.Error("record sets for etcd nodes should be managed in the private hosted zone")
+		}
+		if actual, err := c.Etcd.HostedZoneRef(); err != nil || actual != ref {
+			t.Errorf("unexpected hosted zone ref for etcd: expected=%s, actual=%s, err=%v", ref, actual, err)
+		}
+
+		internal := c.APIEndpointConfigs[0].LoadBalancer
+		if !internal.ManageELBRecordSet() || internal.HostedZone.IDFromFn != ref {
+			t.Errorf("the record set for the API endpoint in the private hosted zone should be created in it: %+v", internal.HostedZone)
+		}
+		public := c.APIEndpointConfigs[1].LoadBalancer
+		if public.HostedZone.IDFromFn != "" || public.HostedZone.ID != "hostedzone-xxxx" {
+			t.Errorf("the hosted zone of the API endpoint outside the private hosted zone should not be changed: %+v", public.HostedZone)
+		}
+	})
+
+	t.Run("ExistingEtcdHostedZone", func(t *testing.T) {
+		c, err := ClusterFromBytes([]byte(apiEndpointMinimalConfigYaml + availabilityZoneConfig + privateHostedZone + apiEndpoints + `
+etcd:
+  memberIdentityProvider: eni
+  internalDomainName: etcd.example.com
+  hostedZone:
+    id: hostedzone-yyyy
+`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual, err := c.Etcd.HostedZoneRef(); err != nil || actual != `"hostedzone-yyyy"` {
+			t.Errorf("etcd nodes should keep using the specified hosted zone but was %s: %v", actual, err)
+		}
+	})
+
+	invalidConfigs := []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: singleAzConfigYaml + `
+privateHostedZone:
+  name: mycluster.internal
+`,
+			err: "can't be specified unless `privateHostedZone.enabled` is true",
+		},
+		{
+			conf: singleAzConfigYaml + `
+privateHostedZone:
+  enabled: true
+  additionalVPCs:
+  - id: vpc-1
+`,
+			err: "`privateHostedZone.name` must be specified",
+		},
+		{
+			conf: apiEndpointMinimalConfigYaml + availabilityZoneConfig + privateHostedZone + `
+apiEndpoints:
+- name: public
+  dnsName: api.example.com
+  loadBalancer:
+    private: true
+`,
+			err: "missing hostedZone.id",
+		},
+	}
+
+	for _, tc := range invalidConfigs {
+		_, err := ClusterFromBytes([]byte(tc.conf))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" but was: %v\n%s", tc.err, err, tc.conf)
+		}
+	}
+}
+
+func TestControllerMixedInstances(t *testing.T) {
+	c, err := ClusterFromBytes([]byte(singleAzConfigYaml + `
+controller:
+  autoScalingGroup:
+    mixedInstances:
+      enabled: true
+      onDemandAllocationStrategy: prioritized
+      instanceTypes:
+      - t3.medium
+      - t2.medium
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := c.Controller.AutoScalingGroup.MixedInstances.InstanceTypes; !reflect.DeepEqual(actual, []string{"t3.medium", "t2.medium"}) {
+		t.Errorf("unexpected instance types: %v", actual)
+	}
+	if actual := c.Controller.LaunchTemplateLogicalName(); actual != "ControllersLT" {
+		t.Errorf("unexpected logical name of the launch template: %s", actual)
+	}
+
+	invalidConfigs := []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: `
+controller:
+  autoScalingGroup:
+    mixedInstances:
+      enabled: true
+      spotInstancePools: 2
+      instanceTypes:
+      - t3.medium
+`,
+			err: "controller nodes are always on-demand instances",
+		},
+		{
+			conf: `
+controller:
+  autoScalingGroup:
+    mixedInstances:
+      enabled: true
+`,
+			err: "`controller.autoScalingGroup.mixedInstances.instanceTypes` must be specified",
+		},
+	}
+
+	for _, tc := range invalidConfigs {
+		_, err := ClusterFromBytes([]byte(singleAzConfigYaml + tc.conf))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" but was: %v\n%s", tc.err, err, tc.conf)
+		}
+	}
+}
+
+func TestMetadataOptions(t *testing.T) {
+	c, err := ClusterFromBytes([]byte(singleAzConfigYaml + `
+awsCliImage:
+  repo: quay.io/coreos/awscli
+  tag: 1.18.69
+controller:
+  metadataOptions:
+    httpTokens: required
+    httpPutResponseHopLimit: 2
+etcd:
+  metadataOptions:
+    httpTokens: required
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := c.Controller.MetadataOptions; actual.HttpTokens != "required" || actual.HttpPutResponseHopLimit != 2 || actual.HttpEndpoint != "" {
+		t.Errorf("unexpected controller metadata options: %+v", actual)
+	}
+	if !c.Etcd.MetadataOptions.TokensRequired() {
+		t.Errorf("unexpected etcd metadata options: %+v", c.Etcd.MetadataOptions)
+	}
+
+	invalidConfigs := []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: `
+controller:
+  metadataOptions:
+    httpPutResponseHopLimit: 100
+`,
+			err: "`controller.metadataOptions.httpPutResponseHopLimit` must be between 1 and 64",
+		},
+		{
+			conf: `
+etcd:
+  metadataOptions:
+    httpEndpoint: disabled
+`,
+			err: "`etcd.metadataOptions.httpEndpoint` can't be \"disabled\"",
+		},
+		{
+			conf: `
+etcd:
+  metadataOptions:
+    httpTokens: required
+`,
+			err: "`etcd.metadataOptions.httpTokens` can't be \"required\" with the default `awsCliImage`",
+		},
+	}
+
+	for _, tc := range invalidConfigs {
+		_, err := ClusterFromBytes([]byte(singleAzConfigYaml + tc.conf))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" but was: %v\n%s", tc.err, err, tc.conf)
+		}
+	}
+}
+
+func TestSecretBackend(t *testing.T) {
+	c, err := ClusterFromBytes([]byte(singleAzConfigYaml + `
+secretBackend:
+  type: ssmParameterStore
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prefix := c.SecretBackend.SecretPrefix(c.ClusterName); prefix != "/kube-aws/test-cluster-name/" {
+		t.Errorf("unexpected default prefix: %s", prefix)
+	}
+
+	invalidConfigs := []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: singleAzConfigYaml + `
+secretBackend:
+  type: file
+`,
+			err: "unsupported `secretBackend.type` \"file\"",
+		},
+		{
+			conf: singleAzConfigYaml + `
+secretBackend:
+  prefix: /kube-aws/
+`,
+			err: "`secretBackend.type` must be specified",
+		},
+		{
+			conf: singleAzConfigYaml + `
+secretBackend:
+  type: ssmParameterStore
+  prefix: kube-aws/
+`,
+			err: "must start with \"/\"",
+		},
+		{
+			conf: singleAzConfigYaml + `
+secretBackend:
+  type: secretsManager
+  vault:
+    address: https://vault.example.com:8200
+`,
+			err: "`secretBackend.vault` can't be specified",
+		},
+		{
+			conf: singleAzConfigYaml + `
+manageCertificates: false
+secretBackend:
+  type: vault
+`,
+			err: "`secretBackend` requires `manageCertificates` to be true",
+		},
+	}
+
+	for _, tc := range invalidConfigs {
+		_, err := ClusterFromBytes([]byte(tc.conf))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing \"%s\" but was: %v\n%s", tc.err, err, tc.conf)
+		}
+	}
+}
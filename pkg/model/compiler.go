@@ -73,6 +73,9 @@ func Compile(cfgRef *api.Cluster, opts api.ClusterOptions) (*Config, error) {
 		}
 		adminAPIEndpoint = apiEndpoints.GetDefault()
 	}
+	if adminAPIEndpoint.LoadBalancer.TerminatesTLS() {
+		return nil, fmt.Errorf("the admin API endpoint \"%s\" must not terminate TLS at its load balancer, as the admin authenticates with a client certificate", adminAPIEndpoint.Name)
+	}
 	config.AdminAPIEndpoint = adminAPIEndpoint
 
 	if opts.S3URI != "" {
@@ -123,8 +126,12 @@ func Compile(cfgRef *api.Cluster, opts api.ClusterOptions) (*Config, error) {
 			}
 		}
 
-		if np.NodePoolRollingStrategy != "Parallel" && np.NodePoolRollingStrategy != "Sequential" && np.NodePoolRollingStrategy != "AvailabilityZone" {
-			if c.Worker.NodePoolRollingStrategy == "Sequential" || c.Worker.NodePoolRollingStrategy == "Parallel" || c.Worker.NodePoolRollingStrategy == "AvailabilityZone" {
+		if e, err := config.APIEndpoints.FindByName(np.APIEndpointName); err == nil && e.LoadBalancer.TerminatesTLS() {
+			return nil, fmt.Errorf("node pool \"%s\" can't use the API endpoint \"%s\" which terminates TLS at its load balancer, as kubelets authenticate with client certificates", np.NodePoolName, e.Name)
+		}
+
+		if np.NodePoolRollingStrategy != "Parallel" && np.NodePoolRollingStrategy != "Sequential" && np.NodePoolRollingStrategy != "AvailabilityZone" && np.NodePoolRollingStrategy != "Canary" && np.NodePoolRollingStrategy != "Surge" {
+			if c.Worker.NodePoolRollingStrategy == "Sequential" || c.Worker.NodePoolRollingStrategy == "Parallel" || c.Worker.NodePoolRollingStrategy == "AvailabilityZone" || c.Worker.NodePoolRollingStrategy == "Surge" {
 				np.NodePoolRollingStrategy = c.Worker.NodePoolRollingStrategy
 			} else {
 				np.NodePoolRollingStrategy = "AvailabilityZone"
@@ -14,8 +14,12 @@ import (
 )
 
 const (
-	vpcLogicalName             = "VPC"
-	internetGatewayLogicalName = "InternetGateway"
+	vpcLogicalName                       = "VPC"
+	internetGatewayLogicalName           = "InternetGateway"
+	ipv6VPCCIDRBlockLogicalName          = "VPCIPv6CidrBlock"
+	egressOnlyInternetGatewayLogicalName = "EgressOnlyInternetGateway"
+	ipv6SubnetCIDRBits                   = 64
+	vpcEndpointSecurityGroupLogicalName  = "SecurityGroupVPCEndpoint"
 )
 
 // Config contains configuration parameters available when rendering userdata injected into a controller or an etcd node from golang text templates
@@ -120,6 +124,108 @@ func (c Config) InternetGatewayRef() string {
 	return c.InternetGateway.Ref(c.InternetGatewayLogicalName)
 }
 
+// ManageInternetGateway returns true if kube-aws must create an IGW and attach it to the VPC managed by kube-aws
+func (c Config) ManageInternetGateway() bool {
+	return c.VPCManaged() && !c.PrivateLinks.InternetEgressDisabled()
+}
+
+func (c Config) IPv6VPCCIDRBlockLogicalName() string {
+	return ipv6VPCCIDRBlockLogicalName
+}
+
+func (c Config) EgressOnlyInternetGatewayLogicalName() string {
+	return egressOnlyInternetGatewayLogicalName
+}
+
+// ManageEgressOnlyInternetGateway returns true if kube-aws must create an egress-only IGW to route IPv6 traffic from private subnets to the internet
+func (c Config) ManageEgressOnlyInternetGateway() bool {
+	if !c.IPv6.Enabled {
+		return false
+	}
+	for _, s := range c.Subnets {
+		if s.Private && s.ManageRouteTable() {
+			return true
+		}
+	}
+	return false
+}
+
+// IPv6VPCCIDRRef returns the IPv6 CIDR block of the VPC, which is the Amazon-provided one when the VPC is managed by kube-aws
+func (c Config) IPv6VPCCIDRRef() (string, error) {
+	if !c.IPv6.Enabled {
+		return "", fmt.Errorf("[BUG] .IPv6VPCCIDRRef should not be called in stack template when ipv6 is disabled")
+	}
+	if c.VPCManaged() {
+		logicalName, err := c.VPCLogicalName()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`{ "Fn::Select" : [0, { "Fn::GetAtt" : [%q, "Ipv6CidrBlocks"] }] }`, logicalName), nil
+	}
+	return fmt.Sprintf(`"%s"`, c.IPv6.VPCCIDR), nil
+}
+
+// IPv6SubnetCIDRRef returns the /64 IPv6 CIDR block assigned to the subnet out of the IPv6 CIDR block of the VPC
+func (c Config) IPv6SubnetCIDRRef(s api.Subnet) (string, error) {
+	if s.IPv6CIDRIndex == nil {
+		return "", fmt.Errorf("[BUG] ipv6CIDRIndex of subnet %s is not defaulted", s.Name)
+	}
+	vpcCIDR, err := c.IPv6VPCCIDRRef()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`{ "Fn::Select" : [%d, { "Fn::Cidr" : [%s, 256, "%d"] }] }`, *s.IPv6CIDRIndex, vpcCIDR, ipv6SubnetCIDRBits), nil
+}
+
+func (c Config) VPCEndpointSecurityGroupLogicalName() string {
+	return vpcEndpointSecurityGroupLogicalName
+}
+
+// VPCEndpointSubnetRefs returns the subnets ENIs of interface VPC endpoints are created in.
+// An interface endpoint accepts at most one subnet per availability zone, for which a private subnet is preferred
+func (c Config) VPCEndpointSubnetRefs() []string {
+	chosen := map[string]api.Subnet{}
+	zones := []string{}
+	for _, s := range c.Subnets {
+		current, ok := chosen[s.AvailabilityZone]
+		if !ok {
+			zones = append(zones, s.AvailabilityZone)
+		}
+		if !ok || (s.Private && !current.Private) {
+			chosen[s.AvailabilityZone] = s
+		}
+	}
+
+	refs := []string{}
+	for _, z := range zones {
+		s := chosen[z]
+		refs = append(refs, s.Ref())
+	}
+	return refs
+}
+
+// VPCEndpointRouteTableRefs returns the route tables gateway VPC endpoints are associated to.
+// Existing subnets are skipped unless their route tables are specified, as kube-aws doesn't know which route tables they use
+func (c Config) VPCEndpointRouteTableRefs() ([]string, error) {
+	refs := []string{}
+	seen := map[string]bool{}
+	for _, s := range c.Subnets {
+		if !s.ManageRouteTable() && !s.RouteTable.HasIdentifier() {
+			continue
+		}
+		ref, err := s.RouteTableRef()
+		if err != nil {
+			return nil, err
+		}
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
 // Etcdadm returns the content of the etcdadm script to be embedded into cloud-config-etcd
 func (c *Config) Etcdadm() (string, error) {
 	return gzipcompressor.BytesToGzippedBase64String(builtin.Bytes("etcdadm/etcdadm"))
@@ -210,3 +316,26 @@ func (c Config) EtcdStackName() string {
 	}
 	return etcdStackName
 }
+
+// NodeDrainerImage returns the kube-aws image the node drainer and the spot interruption handler run, which defaults to the image of this version of kube-aws
+func (c Config) NodeDrainerImage() *api.Image {
+	return kubeAwsImage(c.Experimental.NodeDrainer)
+}
+
+// KubeAwsImage returns the kube-aws image controller and etcd nodes decrypt envelope-encrypted assets with
+func (c Config) KubeAwsImage() *api.Image {
+	return kubeAwsImage(c.Experimental.NodeDrainer)
+}
+
+// kubeAwsImage returns the image configured via `experimental.nodeDrainer.image`, which defaults to the image of this version of kube-aws
+func kubeAwsImage(nd api.NodeDrainer) *api.Image {
+	image := nd.Image
+	if image.Repo == "" {
+		image.Repo = api.DefaultNodeDrainerImageRepo
+	}
+	if image.Tag == "" {
+		// VERSION of development builds looks like `master/0123abcd+dirty`, which isn't a valid image tag
+		image.Tag = strings.NewReplacer("/", "-", "+", "-").Replace(VERSION)
+	}
+	return &image
+}
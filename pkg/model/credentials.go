@@ -1,7 +1,12 @@
 package model
 
 import (
+	"errors"
+	"os"
+
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/kubernetes-incubator/kube-aws/credential"
 	"github.com/kubernetes-incubator/kube-aws/pkg/api"
 )
@@ -14,6 +19,11 @@ func LoadCredentials(sess *session.Session, cfg *Config, opts api.StackTemplateO
 func (s *Context) LoadCredentials(cfg *Config, opts api.StackTemplateOptions) (*credential.CompactAssets, error) {
 	if cfg.AssetsEncryptionEnabled() {
 		kmsConfig := credential.NewKMSConfig(cfg.KMSKeyARN, s.ProvidedEncryptService, s.Session)
+		backend, err := s.SecretBackend(cfg)
+		if err != nil {
+			return nil, err
+		}
+		kmsConfig.SecretBackend = backend
 		compactAssets, err := credential.ReadOrCreateCompactAssets(opts.AssetsDir, cfg.ManageCertificates, true, kmsConfig)
 		if err != nil {
 			return nil, err
@@ -30,6 +40,38 @@ func (s *Context) LoadCredentials(cfg *Config, opts api.StackTemplateOptions) (*
 	}
 }
 
+// SecretBackend returns the backend configured via `secretBackend` in cluster.yaml, or nil if credentials are kept in the credentials directory
+func (s *Context) SecretBackend(cfg *Config) (credential.SecretBackend, error) {
+	b := cfg.SecretBackend
+	if !b.Enabled() {
+		return nil, nil
+	}
+	if s.ProvidedSecretBackend != nil {
+		return s.ProvidedSecretBackend, nil
+	}
+	prefix := b.SecretPrefix(cfg.ClusterName)
+	switch b.Type {
+	case api.SecretBackendTypeSecretsManager:
+		return credential.SecretsManagerBackend{Svc: secretsmanager.New(s.Session), Prefix: prefix, KMSKeyID: cfg.KMSKeyARN}, nil
+	case api.SecretBackendTypeSSMParameterStore:
+		return credential.SSMParameterBackend{Svc: ssm.New(s.Session), Prefix: prefix, KMSKeyID: cfg.KMSKeyARN}, nil
+	case api.SecretBackendTypeVault:
+		address := b.Vault.Address
+		if address == "" {
+			address = os.Getenv("VAULT_ADDR")
+		}
+		if address == "" {
+			return nil, errors.New("either `secretBackend.vault.address` or the VAULT_ADDR environment variable must be set")
+		}
+		token := os.Getenv("VAULT_TOKEN")
+		if token == "" {
+			return nil, errors.New("the VAULT_TOKEN environment variable must be set to read and write credentials in vault")
+		}
+		return credential.VaultBackend{Address: address, Token: token, MountPath: b.Vault.MountPathOrDefault(), Prefix: prefix}, nil
+	}
+	return nil, nil
+}
+
 func NewCredentialGenerator(c *Config) *credential.Generator {
 	r := &credential.Generator{
 		TLSCADurationDays:                c.TLSCADurationDays,
@@ -43,6 +85,10 @@ func NewCredentialGenerator(c *Config) *credential.Generator {
 		ServiceCIDR:                      c.ServiceCIDR,
 	}
 
+	if c.IPv6.Enabled {
+		r.ServiceIPv6CIDR = c.IPv6.ServiceCIDR
+	}
+
 	return r
 }
 
@@ -53,5 +99,12 @@ func GenerateAssetsOnDisk(sess *session.Session, c *Config, dir string, opts cre
 
 func (s *Context) GenerateAssetsOnDisk(c *Config, dir string, opts credential.GeneratorOptions) (*credential.RawAssetsOnDisk, error) {
 	r := NewCredentialGenerator(c)
+	backend, err := s.SecretBackend(c)
+	if err != nil {
+		return nil, err
+	}
+	if backend != nil {
+		return r.GenerateAssetsToBackend(dir, opts, backend)
+	}
 	return r.GenerateAssetsOnDisk(dir, opts)
 }
@@ -183,11 +183,17 @@ func (i EtcdNode) NetworkInterfacePrivateIPLogicalName() string {
 	return fmt.Sprintf("%sPrivateIP", i.LogicalName())
 }
 
-// LaunchConfigurationLogicalName returns the logical name of the launch configuration specific to this etcd node
+// LaunchConfigurationLogicalName returns the logical name of the launch configuration this etcd node was launched from before launch templates
 func (i EtcdNode) LaunchConfigurationLogicalName() string {
 	return fmt.Sprintf("%sLC", i.LogicalName())
 }
 
+// LaunchTemplateLogicalName returns the logical name of the launch template specific to this etcd node.
+// Switching from the launch configuration replaces the instance only. The data volume is kept as is and attached to the new instance
+func (i EtcdNode) LaunchTemplateLogicalName() string {
+	return fmt.Sprintf("%sLT", i.LogicalName())
+}
+
 func (i EtcdNode) LogicalName() string {
 	return fmt.Sprintf("%si%d", i.cluster.LogicalName(), i.index)
 }
@@ -21,7 +21,8 @@ func NewEtcdNodes(nodeConfigs []api.EtcdNode, cluster EtcdCluster) ([]EtcdNode,
 			nodeConfig = nodeConfigs[etcdIndex]
 		}
 
-		if subnet.ManageNATGateway() {
+		// No NAT gateway exists when internet egress is disabled via `privateLinks.disableInternetEgress`
+		if subnet.ManageNATGateway() && len(cluster.NATGateways()) > 0 {
 			ngw, err := cluster.NATGatewayForSubnet(subnet)
 
 			if err != nil {
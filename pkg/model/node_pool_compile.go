@@ -10,7 +10,8 @@ import (
 )
 
 func nodePoolPreprocess(c api.WorkerNodePool, main *Config) (*api.WorkerNodePool, error) {
-	if c.SpotFleet.Enabled() {
+	// cfn-signal is sent per instance while capacity is counted in units when instances are weighted
+	if mi := c.AutoScalingGroup.MixedInstances; c.SpotFleet.Enabled() || mi.Enabled && mi.HasWeightedCapacity() {
 		enabled := false
 		c.WaitSignal.EnabledOverride = &enabled
 	}
@@ -29,6 +29,11 @@ type MainClusterSettings struct {
 	KubeResourcesAutosave api.KubeResourcesAutosave
 }
 
+// KubeAwsImage returns the kube-aws image worker nodes decrypt envelope-encrypted assets with
+func (c NodePoolConfig) KubeAwsImage() *api.Image {
+	return kubeAwsImage(c.Experimental.NodeDrainer)
+}
+
 // NestedStackName returns a sanitized name of this node pool which is usable as a valid cloudformation nested stack name
 func (c NodePoolConfig) NestedStackName() string {
 	// Convert stack name into something valid as a cfn resource name or
@@ -83,6 +88,9 @@ func (c NodePoolConfig) FeatureGates() api.FeatureGates {
 		gates["CSIMigration"] = "true"
 		gates["CSIMigrationAWS"] = "true"
 	}
+	if c.IPv6.Enabled {
+		gates["IPv6DualStack"] = "true"
+	}
 	return gates
 }
 
@@ -175,5 +183,10 @@ func (c NodePoolConfig) SecurityGroupRefs() []string {
 		`{"Fn::ImportValue" : {"Fn::Sub" : "${NetworkStackName}-WorkerSecurityGroup"}}`,
 	)
 
+	if c.SecurityGroupRules.HasRules() {
+		// The security group the rules specific to this node pool are added to
+		refs = append(refs, `{"Ref":"SecurityGroupNodePool"}`)
+	}
+
 	return refs
 }
@@ -74,6 +74,26 @@ func (c *Stack) Assets() cfnstack.Assets {
 	return c.assets
 }
 
+// SetExtraCfnResource adds the resource to the stack template besides the ones rendered from the config, or removes it when resource is nil.
+// Assets are rebuilt so that the next update of the stack deploys the resulting template
+func (c *Stack) SetExtraCfnResource(name string, resource interface{}) error {
+	if resource == nil {
+		delete(c.ExtraCfnResources, name)
+	} else {
+		if c.ExtraCfnResources == nil {
+			c.ExtraCfnResources = map[string]interface{}{}
+		}
+		c.ExtraCfnResources[name] = resource
+	}
+
+	assets, err := c.buildAssets()
+	if err != nil {
+		return err
+	}
+	c.assets = assets
+	return nil
+}
+
 func (c *Stack) buildAssets() (cfnstack.Assets, error) {
 	logger.Debugf("buildAssets: Building assets for %s", c.StackName)
 
@@ -197,6 +217,16 @@ func (c *Stack) validateCertsAgainstSettings() error {
 	if !kubeAPIServerCert.ContainsIPAddress(kubernetesServiceIPAddr) {
 		return fmt.Errorf("the api server cert does not contain the kubernetes service ip address %v, please regenerate or resolve", kubernetesServiceIPAddr)
 	}
+
+	if c.Config.IPv6.Enabled {
+		kubernetesServiceIPv6Addr, err := c.Config.IPv6.KubernetesServiceIP()
+		if err != nil {
+			return err
+		}
+		if !kubeAPIServerCert.ContainsIPAddress(kubernetesServiceIPv6Addr) {
+			return fmt.Errorf("the api server cert does not contain the kubernetes service ipv6 address %v, please regenerate or resolve", kubernetesServiceIPv6Addr)
+		}
+	}
 	return nil
 }
 
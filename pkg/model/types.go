@@ -52,6 +52,7 @@ type Context struct {
 	Session *session.Session
 
 	ProvidedEncryptService  credential.KMSEncryptionService
+	ProvidedSecretBackend   credential.SecretBackend
 	ProvidedCFInterrogator  cfnstack.CFInterrogator
 	ProvidedEC2Interrogator cfnstack.EC2Interrogator
 	StackTemplateGetter     StackTemplateGetter
@@ -97,7 +97,19 @@ func NewSignedServerCertificate(cfg ServerCertConfig, key *rsa.PrivateKey, caCer
 	return x509.ParseCertificate(certDERBytes)
 }
 
+// NewSignedClientCertificate signs a client certificate whose organizations are those of the CA followed by cfg.Organization
 func NewSignedClientCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCert *x509.Certificate, caKey *rsa.PrivateKey) (*x509.Certificate, error) {
+	orgs := append(append([]string{}, caCert.Subject.Organization...), cfg.Organization...)
+	return newSignedClientCertificate(cfg, orgs, key, caCert, caKey)
+}
+
+// NewSignedUserCertificate signs a client certificate for a user whose organizations are only cfg.Organization.
+// kube-apiserver takes organizations as the groups of the user, so that the organization of the CA must not leak into them
+func NewSignedUserCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCert *x509.Certificate, caKey *rsa.PrivateKey) (*x509.Certificate, error) {
+	return newSignedClientCertificate(cfg, cfg.Organization, key, caCert, caKey)
+}
+
+func newSignedClientCertificate(cfg ClientCertConfig, orgs []string, key *rsa.PrivateKey, caCert *x509.Certificate, caKey *rsa.PrivateKey) (*x509.Certificate, error) {
 	ips := make([]net.IP, len(cfg.IPAddresses))
 	for i, ipStr := range cfg.IPAddresses {
 		ips[i] = net.ParseIP(ipStr)
@@ -115,7 +127,7 @@ func NewSignedClientCertificate(cfg ClientCertConfig, key *rsa.PrivateKey, caCer
 	certTmpl := x509.Certificate{
 		Subject: pkix.Name{
 			CommonName:   cfg.CommonName,
-			Organization: append(caCert.Subject.Organization, cfg.Organization...),
+			Organization: orgs,
 		},
 		DNSNames:     cfg.DNSNames,
 		IPAddresses:  ips,
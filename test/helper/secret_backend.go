@@ -0,0 +1,140 @@
+package helper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// FakeSecretsManager is an in-memory AWS Secrets Manager keeping binary secrets
+type FakeSecretsManager struct {
+	Secrets map[string][]byte
+}
+
+func NewFakeSecretsManager() *FakeSecretsManager {
+	return &FakeSecretsManager{Secrets: map[string][]byte{}}
+}
+
+func (s *FakeSecretsManager) GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	v, ok := s.Secrets[*input.SecretId]
+	if !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret.", nil)
+	}
+	return &secretsmanager.GetSecretValueOutput{Name: input.SecretId, SecretBinary: v}, nil
+}
+
+func (s *FakeSecretsManager) CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+	if _, ok := s.Secrets[*input.Name]; ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceExistsException, "The operation failed because the secret already exists.", nil)
+	}
+	s.Secrets[*input.Name] = input.SecretBinary
+	return &secretsmanager.CreateSecretOutput{Name: input.Name}, nil
+}
+
+func (s *FakeSecretsManager) PutSecretValue(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	if _, ok := s.Secrets[*input.SecretId]; !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret.", nil)
+	}
+	s.Secrets[*input.SecretId] = input.SecretBinary
+	return &secretsmanager.PutSecretValueOutput{Name: input.SecretId}, nil
+}
+
+// FakeSSMParameterStore is an in-memory SSM Parameter Store which records the type of each parameter
+type FakeSSMParameterStore struct {
+	Parameters map[string]string
+	Types      map[string]string
+}
+
+func NewFakeSSMParameterStore() *FakeSSMParameterStore {
+	return &FakeSSMParameterStore{Parameters: map[string]string{}, Types: map[string]string{}}
+}
+
+func (s *FakeSSMParameterStore) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	v, ok := s.Parameters[*input.Name]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "", nil)
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Name: input.Name, Value: aws.String(v), Type: aws.String(s.Types[*input.Name])}}, nil
+}
+
+func (s *FakeSSMParameterStore) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	if _, ok := s.Parameters[*input.Name]; ok && !aws.BoolValue(input.Overwrite) {
+		return nil, awserr.New(ssm.ErrCodeParameterAlreadyExists, "", nil)
+	}
+	s.Parameters[*input.Name] = *input.Value
+	s.Types[*input.Name] = aws.StringValue(input.Type)
+	return &ssm.PutParameterOutput{Version: aws.Int64(1)}, nil
+}
+
+// FakeVault is an in-memory HashiCorp Vault serving the KV version 2 secrets engine mounted at `secret`
+type FakeVault struct {
+	*httptest.Server
+	Token string
+
+	mu      sync.Mutex
+	secrets map[string]map[string]string
+}
+
+// NewFakeVault starts a fake vault accepting the token. Close it once done
+func NewFakeVault(token string) *FakeVault {
+	v := &FakeVault{Token: token, secrets: map[string]map[string]string{}}
+	v.Server = httptest.NewServer(http.HandlerFunc(v.serve))
+	return v
+}
+
+// Secret returns the data of the secret at the path under the mount
+func (v *FakeVault) Secret(path string) (map[string]string, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	d, ok := v.secrets[path]
+	return d, ok
+}
+
+func (v *FakeVault) serve(w http.ResponseWriter, r *http.Request) {
+	writeErrors := func(status int, errs ...string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string][]string{"errors": errs})
+	}
+
+	if r.Header.Get("X-Vault-Token") != v.Token {
+		writeErrors(http.StatusForbidden, "permission denied")
+		return
+	}
+	const prefix = "/v1/secret/data/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeErrors(http.StatusNotFound, "no handler for route")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch r.Method {
+	case "GET":
+		d, ok := v.secrets[path]
+		if !ok {
+			writeErrors(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": d}})
+	case "POST", "PUT":
+		body := struct {
+			Data map[string]string `json:"data"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeErrors(http.StatusBadRequest, err.Error())
+			return
+		}
+		v.secrets[path] = body.Data
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"version": 1}})
+	default:
+		writeErrors(http.StatusMethodNotAllowed)
+	}
+}
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -521,6 +522,692 @@ apiEndpoints:
 				},
 			},
 		},
+		{
+			context: "WithAPIEndpointNetworkLoadBalancerMigratedFromClassic",
+			configYaml: configYamlWithoutExernalDNSName + `
+apiEndpoints:
+- name: default
+  dnsName: k8s.example.com
+  loadBalancer:
+    type: network
+    securityGroupsEnabled: true
+    migrateFrom: classic
+    apiAccessAllowedSourceCIDRs:
+    - 1.2.3.255/32
+    healthCheck:
+      healthyThreshold: 2
+      intervalSeconds: 30
+    hostedZone:
+      id: a1b2c4
+`,
+			assertConfig: []ConfigTester{
+				func(c *config.Config, t *testing.T) {
+					expected := []string{"APIEndpointDefaultELB", "APIEndpointDefaultNLB"}
+					if actual := c.ManagedELBLogicalNames(); !reflect.DeepEqual(actual, expected) {
+						t.Errorf("unexpected managed ELB logical names: expected=%v, actual=%v", expected, actual)
+					}
+				},
+			},
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					cp, err := c.ControlPlane().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the control plane stack template: %v", err)
+					}
+					var resources struct {
+						Resources map[string]struct {
+							Type       string
+							Properties map[string]interface{}
+						}
+					}
+					if err := json.Unmarshal([]byte(cp), &resources); err != nil {
+						t.Fatalf("failed to parse the control plane stack template: %v", err)
+					}
+					for name, typ := range map[string]string{
+						"APIEndpointDefaultELB":            "AWS::ElasticLoadBalancing::LoadBalancer",
+						"APIEndpointDefaultNLB":            "AWS::ElasticLoadBalancingV2::LoadBalancer",
+						"APIEndpointDefaultNLBTargetGroup": "AWS::ElasticLoadBalancingV2::TargetGroup",
+						"APIEndpointDefaultELBRecordSet":   "AWS::Route53::RecordSet",
+						"APIEndpointDefaultSG":             "AWS::EC2::SecurityGroup",
+					} {
+						if r, ok := resources.Resources[name]; !ok || r.Type != typ {
+							t.Errorf("expected the control plane stack template to contain %s of type %s", name, typ)
+						}
+					}
+					nlb := resources.Resources["APIEndpointDefaultNLB"].Properties
+					if sgs, ok := nlb["SecurityGroups"].([]interface{}); !ok || len(sgs) != 2 {
+						t.Errorf("expected the network load balancer to be associated with 2 security groups, but got %v", nlb["SecurityGroups"])
+					}
+					tg := resources.Resources["APIEndpointDefaultNLBTargetGroup"].Properties
+					if tg["HealthCheckPath"] != "/healthz" || tg["HealthCheckPort"] != "10280" || tg["HealthyThresholdCount"] != "2" || tg["HealthCheckIntervalSeconds"] != "30" {
+						t.Errorf("unexpected health check of the target group: %v", tg)
+					}
+
+					userdata, err := c.ControlPlane().GetUserData("Controller").Parts["s3"].Template()
+					if err != nil {
+						t.Fatalf("failed to render the controller userdata: %v", err)
+					}
+					// The /healthz proxy authenticates with its own token rather than credentials of a control plane component
+					for _, expected := range []string{
+						"- path: /etc/kubernetes/manifests/kube-apiserver-healthz.yaml",
+						"- --kubeconfig=/etc/kubernetes/apiserver-healthz/kubeconfig.yaml",
+						"tokenFile: /etc/kubernetes/apiserver-healthz/token",
+						`echo "${healthz_token},kube-aws:apiserver-healthz,kube-aws:apiserver-healthz" >> $authDir/tokens.csv`,
+					} {
+						if !strings.Contains(userdata, expected) {
+							t.Errorf("expected the controller userdata to contain %s", expected)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithAPIEndpointDNSRoundRobin",
+			configYaml: configYamlWithoutExernalDNSName + `
+apiEndpoints:
+- name: default
+  dnsName: k8s.example.com
+  dnsRoundRobin:
+    hostedZone:
+      id: a1b2c4
+    apiAccessAllowedSourceCIDRs:
+    - 1.2.3.255/32
+`,
+			assertConfig: []ConfigTester{
+				func(c *config.Config, t *testing.T) {
+					if names := c.ManagedELBLogicalNames(); len(names) != 0 {
+						t.Errorf("expected no load balancers to be managed, but got %v", names)
+					}
+				},
+			},
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					cp, err := c.ControlPlane().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the control plane stack template: %v", err)
+					}
+					if !strings.Contains(cp, `"Resource":"arn:aws:route53:::hostedzone/a1b2c4"`) {
+						t.Error("expected controller nodes to be allowed to change record sets in the hosted zone")
+					}
+
+					userdata, err := c.ControlPlane().GetUserData("Controller").Parts["s3"].Template()
+					if err != nil {
+						t.Fatalf("failed to render the controller userdata: %v", err)
+					}
+					for _, expected := range []string{
+						"- name: kube-apiserver-dns-register-default.service",
+						"ExecStart=/opt/bin/kube-apiserver-dns-register a1b2c4 k8s.example.com 60 false 10 8 3 3",
+						"- path: /opt/bin/kube-apiserver-dns-register",
+					} {
+						if !strings.Contains(userdata, expected) {
+							t.Errorf("expected the controller userdata to contain %s", expected)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithNodeDrainer",
+			configYaml: minimalValidConfigYaml + `
+experimental:
+  nodeDrainer:
+    enabled: true
+    drainTimeout: 3
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					cp, err := c.ControlPlane().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the control plane stack template: %v", err)
+					}
+					var resources struct {
+						Resources map[string]struct {
+							Type       string
+							Properties map[string]interface{}
+						}
+					}
+					if err := json.Unmarshal([]byte(cp), &resources); err != nil {
+						t.Fatalf("failed to parse the control plane stack template: %v", err)
+					}
+					for name, typ := range map[string]string{
+						"NodeDrainerQueue":                "AWS::SQS::Queue",
+						"NodeDrainerQueuePolicy":          "AWS::SQS::QueuePolicy",
+						"NodeDrainerLifecycleRule":        "AWS::Events::Rule",
+						"NodeDrainerSpotInterruptionRule": "AWS::Events::Rule",
+					} {
+						if r, ok := resources.Resources[name]; !ok || r.Type != typ {
+							t.Errorf("expected the control plane stack template to contain %s of type %s", name, typ)
+						}
+					}
+					queue := resources.Resources["NodeDrainerQueue"].Properties
+					if queue["QueueName"] != kubeAwsSettings.clusterName+"-node-drainer" || queue["MessageRetentionPeriod"] != float64(180) {
+						t.Errorf("unexpected node drainer queue: %v", queue)
+					}
+					// The prefix also matches auto scaling groups of clusters named `<clusterName>-*`, whose lifecycle actions the drainer
+					// ignores as they aren't tagged with the cluster name
+					pattern, _ := json.Marshal(resources.Resources["NodeDrainerLifecycleRule"].Properties["EventPattern"])
+					if expected := fmt.Sprintf(`"detail":{"AutoScalingGroupName":[{"prefix":"%s-"}]}`, kubeAwsSettings.clusterName); !strings.Contains(string(pattern), expected) {
+						t.Errorf("expected the event pattern of the lifecycle rule to contain %s, but was %s", expected, pattern)
+					}
+
+					userdata, err := c.ControlPlane().GetUserData("Controller").Parts["s3"].Template()
+					if err != nil {
+						t.Fatalf("failed to render the controller userdata: %v", err)
+					}
+					for _, expected := range []string{
+						"- path: /srv/kubernetes/manifests/kube-node-drainer.yaml",
+						fmt.Sprintf("- --cluster-name=%s", kubeAwsSettings.clusterName),
+						fmt.Sprintf("- --queue-name=%s-node-drainer", kubeAwsSettings.clusterName),
+						"- --drain-timeout=180s",
+						"remove_object DaemonSet kube-system/kube-node-drainer-ds",
+					} {
+						if !strings.Contains(userdata, expected) {
+							t.Errorf("expected the controller userdata to contain %s", expected)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithSpotInterruptionHandling",
+			configYaml: minimalValidConfigYaml + `
+awsCliImage:
+  repo: quay.io/coreos/awscli
+  tag: 1.18.69
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      mixedInstances:
+        enabled: true
+        instanceTypes:
+        - c5.large
+        - m5.large
+    spotInterruptionHandling:
+      enabled: true
+      rebalanceRecommendation:
+        drain: true
+        launchReplacement: true
+      metricsPort: 9999
+    metadataOptions:
+      httpTokens: required
+      httpPutResponseHopLimit: 2
+  - name: pool2
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					np, err := c.NodePools()[0].RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the node pool stack template: %v", err)
+					}
+					if !strings.Contains(np, `"CapacityRebalance":true`) {
+						t.Error("expected capacity rebalancing to be enabled for the auto scaling group of pool1")
+					}
+
+					worker, err := c.NodePools()[0].GetUserData("Worker").Parts["s3"].Template()
+					if err != nil {
+						t.Fatalf("failed to render the worker userdata: %v", err)
+					}
+					for _, expected := range []string{
+						`kube-aws.coreos.com/spot-interruption-handler="pool1"`,
+						"name: kube-spot-interruption-handler-credentials.path",
+						"PathChanged=/etc/kubernetes/ssl/kubelet-client-current.pem",
+						"client-certificate: /etc/kubernetes/spot-interruption-handler/kubelet-client.pem",
+					} {
+						if !strings.Contains(worker, expected) {
+							t.Errorf("expected the worker userdata to contain %s", expected)
+						}
+					}
+
+					worker2, err := c.NodePools()[1].GetUserData("Worker").Parts["s3"].Template()
+					if err != nil {
+						t.Fatalf("failed to render the worker userdata: %v", err)
+					}
+					if strings.Contains(worker2, "kube-spot-interruption-handler-credentials") {
+						t.Error("expected the kubelet client credentials not to be copied for pool2")
+					}
+
+					controller, err := c.ControlPlane().GetUserData("Controller").Parts["s3"].Template()
+					if err != nil {
+						t.Fatalf("failed to render the controller userdata: %v", err)
+					}
+					for _, expected := range []string{
+						`deploy "${mfdir}/kube-spot-interruption-handler.yaml"`,
+						"name: kube-spot-interruption-handler-pool1",
+						"- --metrics-address=:9999",
+						"- --drain-on-rebalance-recommendation",
+						"- --kubeconfig=/etc/kubernetes/spot-interruption-handler/kubeconfig.yaml",
+						"automountServiceAccountToken: false",
+						"remove_object DaemonSet kube-system/kube-spot-interruption-handler-pool2",
+						"remove_object ClusterRoleBinding kube-aws:spot-interruption-handler",
+						"remove_object ClusterRole kube-aws:spot-interruption-handler",
+					} {
+						if !strings.Contains(controller, expected) {
+							t.Errorf("expected the controller userdata to contain %s", expected)
+						}
+					}
+					handler := controller[strings.Index(controller, "/srv/kubernetes/manifests/kube-spot-interruption-handler.yaml"):]
+					handler = handler[:strings.Index(handler, "  - path:")]
+					for _, unexpected := range []string{"hostNetwork", "kind: ClusterRole", "serviceAccountName", "/etc/kubernetes/ssl"} {
+						if strings.Contains(handler, unexpected) {
+							t.Errorf("expected the spot interruption handler manifest not to contain %s", unexpected)
+						}
+					}
+					if strings.Contains(controller, "name: kube-spot-interruption-handler-pool2") {
+						t.Error("expected no spot interruption handler to be deployed to pool2")
+					}
+				},
+			},
+		},
+		{
+			context: "WithSpotFleetMigratedToMixedInstances",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    migratedFromSpotFleet: true
+    count: 10
+    instanceType: c5.large
+    autoScalingGroup:
+      mixedInstances:
+        enabled: true
+        spotAllocationStrategy: capacity-optimized
+        overrides:
+        - instanceType: c5.large
+          weightedCapacity: 1
+        - instanceType: c5.xlarge
+          weightedCapacity: 2
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					np, err := c.NodePools()[0].RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the node pool stack template: %v", err)
+					}
+					for _, expected := range []string{
+						`"WorkersASG":{"DependsOn":"WorkersLT"`,
+						`"SpotAllocationStrategy":"capacity-optimized"`,
+						`{"WeightedCapacity":"2","InstanceType":"c5.xlarge"}`,
+						// Instances of the spot fleet carried over during the migration keep tagging themselves
+						`{"Action":"ec2:CreateTags","Effect":"Allow","Resource":"*"}`,
+					} {
+						if !strings.Contains(np, expected) {
+							t.Errorf("expected the node pool stack template to contain %s", expected)
+						}
+					}
+					for _, unexpected := range []string{`"Workers":`, "SpotInstancePools", "CreationPolicy"} {
+						if strings.Contains(np, unexpected) {
+							t.Errorf("expected the node pool stack template not to contain %s", unexpected)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithClusterAutoscalerOptions",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      minSize: 0
+      maxSize: 5
+    clusterAutoscaler:
+      priority: 10
+      scaleDownDisabled: true
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					userdata := c.NodePools()[0].UserData["Worker"].Parts[api.USERDATA_S3].Asset.Content
+					if !strings.Contains(userdata, "annotate --overwrite nodes/$(hostname) cluster-autoscaler.kubernetes.io/scale-down-disabled=true") {
+						t.Errorf("expected nodes in the node pool to be annotated to disable scale down: %s", userdata)
+					}
+				},
+			},
+		},
+		{
+			context: "WithWarmPool",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      minSize: 1
+      maxSize: 5
+      warmPool:
+        enabled: true
+        minSize: 0
+        maxGroupPreparedCapacity: 4
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					np, err := c.NodePools()[0].RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the node pool stack template: %v", err)
+					}
+					expected := `"WorkersWarmPool":{"Type":"AWS::AutoScaling::WarmPool","Properties":{"AutoScalingGroupName":{"Ref":"Workers"},"MinSize":0,"MaxGroupPreparedCapacity":4,"PoolState":"Stopped"}}`
+					if !strings.Contains(np, expected) {
+						t.Errorf("expected the node pool stack template to contain %s: %s", expected, np)
+					}
+					userdata := c.NodePools()[0].UserData["Worker"].Parts[api.USERDATA_S3].Asset.Content
+					for _, expected := range []string{
+						"Requires=wait-for-warm-pool-exit.service",
+						"meta-data/autoscaling/target-lifecycle-state",
+					} {
+						if !strings.Contains(userdata, expected) {
+							t.Errorf("expected the worker userdata to contain %s", expected)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithInstanceRefresh",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      instanceRefresh:
+        enabled: true
+        minHealthyPercentage: 50
+        checkpointPercentages: [20, 100]
+        checkpointDelay: 600
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					np, err := c.NodePools()[0].RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the node pool stack template: %v", err)
+					}
+					if !strings.Contains(np, `"UpdatePolicy":{"AutoScalingScheduledAction":{"IgnoreUnmodifiedGroupSizeProperties":true}}`) {
+						t.Errorf("expected instances not to be replaced by the rolling update of CloudFormation: %s", np)
+					}
+					for _, unexpected := range []string{"AutoScalingRollingUpdate", "WarmPool"} {
+						if strings.Contains(np, unexpected) {
+							t.Errorf("expected the node pool stack template not to contain %s", unexpected)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithScheduledActionsAndTargetTrackingPolicies",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      minSize: 1
+      maxSize: 10
+      scheduledActions:
+      - name: business-hours
+        recurrence: "0 8 * * 1-5"
+        timeZone: Europe/London
+        minSize: 3
+        desiredCapacity: 5
+      - name: nights
+        recurrence: "0 20 * * *"
+        minSize: 1
+      targetTrackingPolicies:
+      - name: cpu
+        predefinedMetric: ASGAverageCPUUtilization
+        targetValue: 60
+        estimatedInstanceWarmup: 300
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					np, err := c.NodePools()[0].RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the node pool stack template: %v", err)
+					}
+					for _, expected := range []string{
+						`"WorkersScheduledBusinessHours":{"Type":"AWS::AutoScaling::ScheduledAction","Properties":{"AutoScalingGroupName":{"Ref":"Workers"},"TimeZone":"Europe/London","MinSize":3,"DesiredCapacity":5,"Recurrence":"0 8 * * 1-5"}}`,
+						`"WorkersScheduledNights":{"Type":"AWS::AutoScaling::ScheduledAction","Properties":{"AutoScalingGroupName":{"Ref":"Workers"},"MinSize":1,"Recurrence":"0 20 * * *"}}`,
+						`"WorkersTargetTrackingCpu":{"Type":"AWS::AutoScaling::ScalingPolicy","Properties":{"AutoScalingGroupName":{"Ref":"Workers"},"PolicyType":"TargetTrackingScaling","EstimatedInstanceWarmup":300,"TargetTrackingConfiguration":{"PredefinedMetricSpecification":{"PredefinedMetricType":"ASGAverageCPUUtilization"},"TargetValue":60,"DisableScaleIn":false}}}`,
+					} {
+						if !strings.Contains(np, expected) {
+							t.Errorf("expected the node pool stack template to contain %s: %s", expected, np)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithPrivateHostedZone",
+			configYaml: kubeAwsSettings.mainClusterYamlWithoutAPIEndpoint() + `  memberIdentityProvider: eni
+availabilityZone: us-west-1c
+apiEndpoints:
+- name: internal
+  dnsName: api.mycluster.internal
+  loadBalancer:
+    private: false
+privateHostedZone:
+  enabled: true
+  name: mycluster.internal
+  additionalVPCs:
+  - id: vpc-shared
+    region: us-east-1
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					ref := `{"Fn::ImportValue":{"Fn::Sub":"${NetworkStackName}-PrivateHostedZone"}}`
+
+					network, err := c.Network().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the network stack template: %v", err)
+					}
+					for _, expected := range []string{
+						`"Type":"AWS::Route53::HostedZone"`,
+						`"Name":"mycluster.internal"`,
+						`{"VPCId":"vpc-shared","VPCRegion":"us-east-1"}`,
+						`"Export":{"Name":{"Fn::Sub":"${AWS::StackName}-PrivateHostedZone"}}`,
+					} {
+						if !strings.Contains(network, expected) {
+							t.Errorf("expected the network stack template to contain %s", expected)
+						}
+					}
+
+					etcd, err := c.Etcd().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the etcd stack template: %v", err)
+					}
+					if strings.Contains(etcd, `"AWS::Route53::HostedZone"`) {
+						t.Error("expected the etcd stack not to create its own hosted zone")
+					}
+					if !strings.Contains(etcd, `"HostedZoneId":`+ref) {
+						t.Error("expected the records of etcd nodes to be created in the private hosted zone")
+					}
+
+					cp, err := c.ControlPlane().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the control plane stack template: %v", err)
+					}
+					if !strings.Contains(cp, `"HostedZoneId":`+ref+`,"Name":"api.mycluster.internal"`) {
+						t.Error("expected the record of the API endpoint to be created in the private hosted zone")
+					}
+
+					userdata, err := c.ControlPlane().GetUserData("Controller").Parts["s3"].Template()
+					if err != nil {
+						t.Fatalf("failed to render the controller userdata: %v", err)
+					}
+					for _, expected := range []string{
+						"- name: kube-apiserver-dns-register-private-hosted-zone.service",
+						"ExecStart=/opt/bin/kube-apiserver-dns-register private:mycluster.internal controllers.mycluster.internal 60 true 10 8 3 3",
+						"- path: /opt/bin/kube-apiserver-dns-register",
+					} {
+						if !strings.Contains(userdata, expected) {
+							t.Errorf("expected the controller userdata to contain %s", expected)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithLaunchTemplates",
+			configYaml: kubeAwsSettings.mainClusterYaml() + `  memberIdentityProvider: eni
+  internalDomainName: internal.example.com
+availabilityZone: us-west-1c
+controller:
+  autoScalingGroup:
+    mixedInstances:
+      enabled: true
+      instanceTypes:
+      - t3.medium
+      - t2.medium
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					cp, err := c.ControlPlane().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the control plane stack template: %v", err)
+					}
+					if strings.Contains(cp, "AWS::AutoScaling::LaunchConfiguration") {
+						t.Error("expected controller nodes not to be launched from a launch configuration")
+					}
+					for _, expected := range []string{
+						`"ControllersLT":{"Properties":{"LaunchTemplateData":`,
+						`"OnDemandPercentageAboveBaseCapacity":100`,
+						`"LaunchTemplateSpecification":{"LaunchTemplateId":{"Ref":"ControllersLT"}`,
+						`"Overrides":[{"InstanceType":"t3.medium"},{"InstanceType":"t2.medium"}]`,
+					} {
+						if !strings.Contains(cp, expected) {
+							t.Errorf("expected the control plane stack template to contain %s", expected)
+						}
+					}
+
+					etcd, err := c.Etcd().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the etcd stack template: %v", err)
+					}
+					if strings.Contains(etcd, "AWS::AutoScaling::LaunchConfiguration") {
+						t.Error("expected etcd nodes not to be launched from a launch configuration")
+					}
+					for _, expected := range []string{
+						`"LaunchTemplate":{"LaunchTemplateId":{"Ref":"Etcdv3dot3i0LT"}`,
+						`"Etcdv3dot3i0LT":{"Properties":{"LaunchTemplateData":`,
+						// Data volumes keep their logical names so that switching to launch templates doesn't replace them
+						`"Etcdv3dot3i0EBS":{"Properties":`,
+					} {
+						if !strings.Contains(etcd, expected) {
+							t.Errorf("expected the etcd stack template to contain %s", expected)
+						}
+					}
+				},
+			},
+		},
+		{
+			context: "WithMetadataOptions",
+			configYaml: kubeAwsSettings.mainClusterYaml() + `  metadataOptions:
+    httpTokens: required
+availabilityZone: us-west-1c
+awsCliImage:
+  repo: quay.io/coreos/awscli
+  tag: 1.18.69
+controller:
+  metadataOptions:
+    httpTokens: required
+    httpPutResponseHopLimit: 2
+    httpEndpoint: enabled
+worker:
+  nodePools:
+  - name: pool1
+    metadataOptions:
+      httpTokens: optional
+      httpPutResponseHopLimit: 3
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					cp, err := c.ControlPlane().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the control plane stack template: %v", err)
+					}
+					for _, expected := range []string{
+						`"MetadataOptions":{"HttpEndpoint":"enabled","HttpPutResponseHopLimit":2,"HttpTokens":"required"}`,
+						// The instance script retrieves the instance metadata with a session token
+						`latest/api/token`,
+					} {
+						if !strings.Contains(cp, expected) {
+							t.Errorf("expected the control plane stack template to contain %s", expected)
+						}
+					}
+
+					etcd, err := c.Etcd().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the etcd stack template: %v", err)
+					}
+					if expected := `"MetadataOptions":{"HttpTokens":"required"}`; !strings.Contains(etcd, expected) {
+						t.Errorf("expected the etcd stack template to contain %s", expected)
+					}
+
+					np, err := c.NodePools()[0].RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the node pool stack template: %v", err)
+					}
+					if expected := `"MetadataOptions":{"HttpPutResponseHopLimit":3,"HttpTokens":"optional"}`; !strings.Contains(np, expected) {
+						t.Errorf("expected the node pool stack template to contain %s", expected)
+					}
+				},
+			},
+		},
+		{
+			context: "WithMetadataOptionsEnforcingIMDSv2AndEnvelopeEncryptedAssets",
+			configYaml: kubeAwsSettings.mainClusterYaml() + `  metadataOptions:
+    httpTokens: required
+availabilityZone: us-west-1c
+awsCliImage:
+  repo: quay.io/coreos/awscli
+  tag: 1.18.69
+controller:
+  metadataOptions:
+    httpTokens: required
+worker:
+  nodePools:
+  - name: pool1
+    metadataOptions:
+      httpTokens: required
+`,
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					// Nodes decrypt their envelope-encrypted assets with the IAM credentials obtained from the instance metadata.
+					// The kube-aws image of this version obtains them with session tokens, as IMDSv2 requires
+					userdata := map[string]*api.UserData{
+						"controller": c.ControlPlane().GetUserData("Controller"),
+						"etcd":       c.Etcd().GetUserData("Etcd"),
+						"worker":     c.NodePools()[0].GetUserData("Worker"),
+					}
+					for role, ud := range userdata {
+						content, err := ud.Parts["s3"].Template()
+						if err != nil {
+							t.Fatalf("failed to render the %s userdata: %v", role, err)
+						}
+						for _, expected := range []string{
+							"/opt/bin/decrypt-envelope-assets",
+							"decrypt-envelope-assets --region us-west-1",
+							api.DefaultNodeDrainerImageRepo,
+						} {
+							if !strings.Contains(content, expected) {
+								t.Errorf("expected the %s userdata to contain %s", role, expected)
+							}
+						}
+					}
+
+					for role, render := range map[string]func() (string, error){
+						"control plane": c.ControlPlane().RenderStackTemplateAsString,
+						"etcd":          c.Etcd().RenderStackTemplateAsString,
+						"node pool":     c.NodePools()[0].RenderStackTemplateAsString,
+					} {
+						template, err := render()
+						if err != nil {
+							t.Fatalf("failed to render the %s stack template: %v", role, err)
+						}
+						if expected := `"MetadataOptions":{"HttpTokens":"required"}`; !strings.Contains(template, expected) {
+							t.Errorf("expected the %s stack template to contain %s", role, expected)
+						}
+					}
+				},
+			},
+		},
 		{
 			context: "WithAPIEndpointLBAPIAccessAllowedSourceCIDRsOmitted",
 			configYaml: configYamlWithoutExernalDNSName + `
@@ -605,6 +1292,103 @@ kubeProxy:
 				},
 			},
 		},
+		{
+			context: "WithIPv6DualStack",
+			configYaml: mainClusterYaml + `
+subnets:
+- name: Public
+  availabilityZone: us-west-1c
+  instanceCIDR: 10.0.0.0/24
+- name: Private
+  availabilityZone: us-west-1c
+  instanceCIDR: 10.0.1.0/24
+  private: true
+ipv6:
+  enabled: true
+  podCIDR: fd00:10:2::/56
+  serviceCIDR: fd00:10:3::/112
+`,
+			assertConfig: []ConfigTester{
+				func(c *config.Config, t *testing.T) {
+					if c.ServiceCIDRs() != "10.3.0.0/24,fd00:10:3::/112" {
+						t.Errorf("unexpected service CIDRs: %s", c.ServiceCIDRs())
+					}
+					if c.Subnets[1].IPv6CIDRIndex == nil || *c.Subnets[1].IPv6CIDRIndex != 1 {
+						t.Errorf("ipv6CIDRIndex of the second subnet should default to 1: %v", c.Subnets[1].IPv6CIDRIndex)
+					}
+					if !c.ManageEgressOnlyInternetGateway() {
+						t.Errorf("an egress-only internet gateway should be created for the private subnet")
+					}
+				},
+			},
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					network, err := c.Network().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the network stack template: %v", err)
+					}
+					// Routes to ::/0 fail unless the VPC has its IPv6 CIDR block associated
+					for _, route := range []string{"PublicIPv6RouteToInternet", "PrivateIPv6RouteToEgressOnlyInternetGateway"} {
+						if !strings.Contains(network, fmt.Sprintf(`"%s":{"DependsOn":["VPCIPv6CidrBlock"]`, route)) {
+							t.Errorf("expected %s to depend on the IPv6 CIDR block of the VPC", route)
+						}
+					}
+
+					controlPlane, err := c.ControlPlane().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the control-plane stack template: %v", err)
+					}
+					if !strings.Contains(controlPlane, `{"CidrIpv6":"::/0","FromPort":0,"IpProtocol":"tcp","ToPort":65535}`) {
+						t.Errorf("expected worker nodes to be allowed IPv6 egress")
+					}
+				},
+			},
+		},
+		{
+			context: "WithPrivateLinksWithoutInternetEgress",
+			configYaml: kubeAwsSettings.mainClusterYamlWithoutAPIEndpoint() + `
+apiEndpoints:
+- name: private
+  dnsName: "` + kubeAwsSettings.externalDNSName + `"
+  loadBalancer:
+    private: true
+    hostedZone:
+      id: hostedzone-xxxx
+subnets:
+- name: Private1
+  availabilityZone: us-west-1a
+  instanceCIDR: 10.0.1.0/24
+  private: true
+- name: Private2
+  availabilityZone: us-west-1b
+  instanceCIDR: 10.0.2.0/24
+  private: true
+controller:
+  subnets:
+  - name: Private1
+  - name: Private2
+etcd:
+  subnets:
+  - name: Private1
+  - name: Private2
+privateLinks:
+  enabled: true
+  disableInternetEgress: true
+`,
+			assertConfig: []ConfigTester{
+				func(c *config.Config, t *testing.T) {
+					if c.ManageInternetGateway() {
+						t.Errorf("an internet gateway should not be created while internet egress is disabled")
+					}
+					if n := len(c.NATGateways()); n != 0 {
+						t.Errorf("expected no NAT gateway but was %d", n)
+					}
+					if n := len(c.PrivateLinks.Endpoints()); n != 13 {
+						t.Errorf("expected VPC endpoints for all the default services but was %d", n)
+					}
+				},
+			},
+		},
 		{
 			// See https://github.com/kubernetes-incubator/kube-aws/issues/365
 			context:    "WithClusterNameContainsHyphens",
@@ -1447,6 +2231,7 @@ worker:
 worker:
   nodePools:
   - name: pool1
+    instanceType: m3.medium
     auditLog:
       enabled: true
       maxage: 100
@@ -1702,6 +2487,61 @@ worker:
 				hasSpecificNodePoolRollingStrategy("Sequential"),
 			},
 		},
+		{
+			context: "WithCanaryNodePoolRollingStrategy",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    nodePoolRollingStrategy: Canary
+    canary:
+      timeout: 300
+      healthChecks:
+      - name: nodes-ready
+        command: kubectl --kubeconfig=kubeconfig wait --for=condition=Ready nodes --all
+      - name: ingress
+        httpGet:
+          url: https://example.com/healthz
+`,
+			assertConfig: []ConfigTester{
+				hasSpecificNodePoolRollingStrategy("Canary"),
+				func(c *config.Config, t *testing.T) {
+					canary := c.NodePools[0].Canary
+					if canary.TimeoutOrDefault() != 300 || canary.IntervalOrDefault() != 10 {
+						t.Errorf("unexpected canary timeout and interval: %+v", canary)
+					}
+					if len(canary.HealthChecks) != 2 || canary.HealthChecks[1].HTTPGet.ExpectedStatusOrDefault() != 200 {
+						t.Errorf("unexpected canary health checks: %+v", canary.HealthChecks)
+					}
+				},
+			},
+		},
+		{
+			context: "WithSurgeNodePoolRollingStrategy",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePoolRollingStrategy: Surge
+  nodePools:
+  - name: pool1
+`,
+			assertConfig: []ConfigTester{
+				hasSpecificNodePoolRollingStrategy("Surge"),
+			},
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					np, err := c.NodePools()[0].RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the node pool stack template: %v", err)
+					}
+					if !strings.Contains(np, `"AutoScalingReplacingUpdate":{"WillReplace":"true"}`) {
+						t.Errorf("expected the auto scaling group to be replaced as a whole: %s", np)
+					}
+					if strings.Contains(np, "AutoScalingRollingUpdate") {
+						t.Error("expected the node pool stack template not to contain AutoScalingRollingUpdate")
+					}
+				},
+			},
+		},
 		{
 			context: "WithSpecificWorkerRollingStrategy",
 			configYaml: minimalValidConfigYaml + `
@@ -3272,6 +4112,71 @@ worker:
 				},
 			},
 		},
+		{
+			context: "WithSecurityGroupRules",
+			configYaml: minimalValidConfigYaml + `
+controller:
+  securityGroupRules:
+    ingress:
+    - protocol: tcp
+      fromPort: 9100
+      cidr: 10.1.0.0/16
+etcd:
+  securityGroupRules:
+    egress:
+    - protocol: tcp
+      fromPort: 2379
+      toPort: 2380
+      securityGroup: etcd
+    - protocol: tcp
+      fromPort: 443
+      cidr: 0.0.0.0/0
+worker:
+  nodePools:
+  - name: pool1
+    securityGroupRules:
+      ingress:
+      - protocol: tcp
+        fromPort: 8080
+        securityGroup: controller
+`,
+			assertConfig: []ConfigTester{
+				func(c *config.Config, t *testing.T) {
+					expectedWorkerSecurityGroupRefs := []string{
+						`{"Fn::ImportValue" : {"Fn::Sub" : "${NetworkStackName}-WorkerSecurityGroup"}}`,
+						`{"Ref":"SecurityGroupNodePool"}`,
+					}
+					if !reflect.DeepEqual(c.NodePools[0].SecurityGroupRefs(), expectedWorkerSecurityGroupRefs) {
+						t.Errorf("SecurityGroupRefs didn't match: expected=%v actual=%v", expectedWorkerSecurityGroupRefs, c.NodePools[0].SecurityGroupRefs())
+					}
+				},
+			},
+			assertCluster: []ClusterTester{
+				func(c *root.Cluster, t *testing.T) {
+					network, err := c.Network().RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the network stack template: %v", err)
+					}
+					for _, expected := range []string{
+						`{"CidrIp":"10.1.0.0/16","FromPort":9100,"GroupId":{"Ref":"SecurityGroupController"},"IpProtocol":"tcp","ToPort":9100}`,
+						`{"DestinationSecurityGroupId":{"Ref":"SecurityGroupEtcd"},"FromPort":2379,"GroupId":{"Ref":"SecurityGroupEtcd"},"IpProtocol":"tcp","ToPort":2380}`,
+					} {
+						if !strings.Contains(network, expected) {
+							t.Errorf("expected the network stack template to contain %s", expected)
+						}
+					}
+
+					nodePool, err := c.NodePools()[0].RenderStackTemplateAsString()
+					if err != nil {
+						t.Fatalf("failed to render the node pool stack template: %v", err)
+					}
+					expected := `{"FromPort":8080,"GroupId":{"Ref":"SecurityGroupNodePool"},"IpProtocol":"tcp","SourceSecurityGroupId":{"Fn::ImportValue":{"Fn::Sub":"${NetworkStackName}-ControllerSecurityGroup"}},"ToPort":8080}`
+					if !strings.Contains(nodePool, expected) {
+						t.Errorf("expected the node pool stack template to contain %s", expected)
+					}
+				},
+			},
+		},
 		{
 			context: "WithWorkerAndALBSecurityGroupIds",
 			configYaml: minimalValidConfigYaml + `
@@ -4235,13 +5140,47 @@ etcd:
 		{
 			context: "WithUnknownKeyInWorkerNodePool",
 			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoscaling:
+      clusterAutoscaler:
+        enabled: true
+`,
+			expectedErrorMessage: "unknown keys found in worker.nodePools[0]: autoscaling",
+		},
+		{
+			context: "WithUserCertificateGroupMappedToNoOrganizations",
+			configYaml: minimalValidConfigYaml + `
+kubernetes:
+  authentication:
+    userCertificates:
+      organizations:
+        devs: []
+`,
+			expectedErrorMessage: "`kubernetes.authentication.userCertificates.organizations.devs` must list one or more organizations",
+		},
+		{
+			context: "WithNegativeClusterAutoscalerPriority",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    clusterAutoscaler:
+      priority: -1
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].clusterAutoscaler.priority` must be zero or greater but was -1",
+		},
+		{
+			context: "WithUnknownKeyInWorkerNodePoolClusterAutoscaler",
+			configYaml: minimalValidConfigYaml + `
 worker:
   nodePools:
   - name: pool1
     clusterAutoscaler:
       enabled: true
 `,
-			expectedErrorMessage: "unknown keys found in worker.nodePools[0]: clusterAutoscaler",
+			expectedErrorMessage: "unknown keys found in worker.nodePools[0].clusterAutoscaler: enabled",
 		},
 		{
 			context: "WithUnknownKeyInWorkerNodePoolASG",
@@ -4254,6 +5193,242 @@ worker:
 `,
 			expectedErrorMessage: "unknown keys found in worker.nodePools[0].autoScalingGroup: foo",
 		},
+		{
+			context: "WithUnknownKeyInWorkerNodePoolWarmPool",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      warmPool:
+        instanceReusePolicy: {}
+`,
+			expectedErrorMessage: "unknown keys found in worker.nodePools[0].autoScalingGroup.warmPool: instanceReusePolicy",
+		},
+		{
+			context: "WithWarmPoolAndSpotPrice",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    spotPrice: "0.05"
+    autoScalingGroup:
+      warmPool:
+        enabled: true
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].autoScalingGroup.warmPool` is incompatible with spot instances",
+		},
+		{
+			context: "WithInstanceRefreshAndSpotFleet",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    spotFleet:
+      targetCapacity: 3
+    autoScalingGroup:
+      instanceRefresh:
+        enabled: true
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].autoScalingGroup.warmPool` and `instanceRefresh` are incompatible with spot fleet",
+		},
+		{
+			context: "WithControllerInstanceRefresh",
+			configYaml: minimalValidConfigYaml + `
+controller:
+  autoScalingGroup:
+    instanceRefresh:
+      enabled: true
+`,
+			expectedErrorMessage: "`controller.autoScalingGroup.warmPool` and `controller.autoScalingGroup.instanceRefresh` are supported only by worker node pools",
+		},
+		{
+			context: "WithScheduledActionWithoutSizes",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      scheduledActions:
+      - name: nights
+        recurrence: "0 20 * * *"
+`,
+			expectedErrorMessage: "`autoScalingGroup.scheduledActions[name=nights]` must specify at least one of `minSize`, `maxSize` and `desiredCapacity`",
+		},
+		{
+			context: "WithTargetTrackingPolicyAndClusterAutoscaler",
+			configYaml: minimalValidConfigYaml + `
+kubeAwsPlugins:
+  clusterAutoscaler:
+    enabled: true
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      targetTrackingPolicies:
+      - name: cpu
+        predefinedMetric: ASGAverageCPUUtilization
+        targetValue: 60
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].autoScalingGroup.targetTrackingPolicies` conflicts with cluster-autoscaler",
+		},
+		{
+			context: "WithScheduledDesiredCapacityAndClusterAutoscaler",
+			configYaml: minimalValidConfigYaml + `
+kubeAwsPlugins:
+  clusterAutoscaler:
+    enabled: true
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      scheduledActions:
+      - name: business-hours
+        recurrence: "0 8 * * 1-5"
+        desiredCapacity: 3
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].autoScalingGroup.scheduledActions[name=business-hours].desiredCapacity` conflicts with cluster-autoscaler",
+		},
+		{
+			context: "WithUnknownKeyInScheduledAction",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      scheduledActions:
+      - name: nights
+        recurrence: "0 20 * * *"
+        minSize: 1
+        startTime: "2020-01-01T00:00:00Z"
+`,
+			expectedErrorMessage: "unknown keys found in worker.nodePools[0].autoScalingGroup.scheduledActions[0]: startTime",
+		},
+		{
+			context: "WithNvmeDeviceInRaid0Mounts",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    instanceType: r5.large
+    raid0Mounts:
+    - type: gp2
+      size: 100
+      path: "/ebs"
+      devices:
+      - "/dev/nvme1n1"
+      - "/dev/nvme2n1"
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].raid0Mounts[0].devices[0]` \"/dev/nvme1n1\" can't be specified as the names of NVMe devices depend on the order EBS volumes are attached. " +
+				"Specify \"/dev/xvdf\" instead, which kube-aws links to the NVMe device of the volume on Nitro instances",
+		},
+		{
+			context: "WithEphemeralImageStorageWithoutInstanceStore",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    instanceType: c5.2xlarge
+    ephemeralImageStorage:
+      enabled: true
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].ephemeralImageStorage` requires an instance store volume but instance type c5.2xlarge has none. Use e.g. c5d.2xlarge instead",
+		},
+		{
+			context: "WithMixedInstancesOfDifferentArchitectures",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    instanceType: m5.large
+    autoScalingGroup:
+      mixedInstances:
+        enabled: true
+        instanceTypes:
+        - m5.large
+        - m6g.large
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].autoScalingGroup.mixedInstances` mixes instance types of architectures arm64 (m6g.large) and x86_64 (m5.large) but all the instances boot the same AMI",
+		},
+		{
+			context: "WithInvalidGp3RootVolumeIOPS",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    rootVolume:
+      type: gp3
+      iops: 20000
+`,
+			expectedErrorMessage: "rootVolumeIOPS must be between 3000 and 16000 when rootVolumeType is \"gp3\"",
+		},
+		{
+			context: "WithCanaryNodePoolRollingStrategyWithoutHealthChecks",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    nodePoolRollingStrategy: Canary
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].canary.healthChecks` must contain at least one health check for the Canary nodePoolRollingStrategy",
+		},
+		{
+			context: "WithMultipleCanaryNodePools",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    nodePoolRollingStrategy: Canary
+    canary:
+      healthChecks:
+      - name: api
+        command: "true"
+  - name: pool2
+    nodePoolRollingStrategy: Canary
+    canary:
+      healthChecks:
+      - name: api
+        command: "true"
+`,
+			expectedErrorMessage: "only one node pool can use the Canary nodePoolRollingStrategy but pool1, pool2 do",
+		},
+		{
+			context: "WithCanaryWorkerRollingStrategy",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePoolRollingStrategy: Canary
+  nodePools:
+  - name: pool1
+`,
+			expectedErrorMessage: "`worker.nodePoolRollingStrategy` can't be Canary. Set `nodePoolRollingStrategy: Canary` to the node pool rolled first instead",
+		},
+		{
+			context: "WithSurgeNodePoolRollingStrategyAndWaitSignalDisabled",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    nodePoolRollingStrategy: Surge
+    waitSignal:
+      enabled: false
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].nodePoolRollingStrategy` can't be Surge when `waitSignal` is disabled",
+		},
+		{
+			context: "WithUnknownKeyInCanaryHealthCheck",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    nodePoolRollingStrategy: Canary
+    canary:
+      healthChecks:
+      - name: api
+        command: "true"
+        exec: "true"
+`,
+			expectedErrorMessage: "unknown keys found in worker.nodePools[0].canary.healthChecks[0]: exec",
+		},
 		{
 			context: "WithUnknownKeyInWorkerNodePoolSpotFleet",
 			configYaml: minimalValidConfigYaml + `
@@ -4265,6 +5440,121 @@ worker:
 `,
 			expectedErrorMessage: "unknown keys found in worker.nodePools[0].spotFleet: bar",
 		},
+		{
+			context: "WithUnknownKeyInControllerMetadataOptions",
+			configYaml: minimalValidConfigYaml + `
+controller:
+  metadataOptions:
+    httpProtocolIpv6: enabled
+`,
+			expectedErrorMessage: "unknown keys found in controller.metadataOptions: httpProtocolIpv6",
+		},
+		{
+			context: "WithRebalanceRecommendationWithoutSpotInterruptionHandling",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    spotInterruptionHandling:
+      rebalanceRecommendation:
+        launchReplacement: true
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].spotInterruptionHandling.rebalanceRecommendation` requires `worker.nodePools[name=pool1].spotInterruptionHandling.enabled` to be true",
+		},
+		{
+			context: "WithMigratedFromSpotFleetAndSpotFleet",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    migratedFromSpotFleet: true
+    spotFleet:
+      targetCapacity: 10
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].migratedFromSpotFleet` can't be true while `spotFleet` is still configured",
+		},
+		{
+			context: "WithMixedInstancesSpotInstancePoolsAndCapacityOptimized",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    autoScalingGroup:
+      mixedInstances:
+        enabled: true
+        spotAllocationStrategy: capacity-optimized
+        spotInstancePools: 2
+        instanceTypes:
+        - c5.large
+`,
+			expectedErrorMessage: "`mixedInstances.spotInstancePools` can be specified only when `mixedInstances.spotAllocationStrategy` is 'lowest-price'",
+		},
+		{
+			context: "WithUnknownKeyInSpotInterruptionHandling",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    spotInterruptionHandling:
+      enabled: true
+      rebalanceRecommendations:
+        drain: true
+`,
+			expectedErrorMessage: "unknown keys found in worker.nodePools[0].spotInterruptionHandling: rebalanceRecommendations",
+		},
+		{
+			context: "WithMetadataOptionsForSpotFleet",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    spotFleet:
+      targetCapacity: 10
+    metadataOptions:
+      httpTokens: required
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].metadataOptions` is incompatible with spot fleet",
+		},
+		{
+			context: "WithSpotInterruptionHandlingAndDefaultHopLimitWithIMDSv2Enforced",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    spotPrice: "0.06"
+    spotInterruptionHandling:
+      enabled: true
+    metadataOptions:
+      httpTokens: required
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].spotInterruptionHandling` requires `metadataOptions.httpPutResponseHopLimit` to be 2 or greater",
+		},
+		{
+			context: "WithIMDSv2EnforcedOnControllersWithDefaultAWSCliImage",
+			configYaml: minimalValidConfigYaml + `
+controller:
+  metadataOptions:
+    httpTokens: required
+`,
+			expectedErrorMessage: "`controller.metadataOptions.httpTokens` can't be \"required\" with the default `awsCliImage` quay.io/coreos/awscli:master",
+		},
+		{
+			context: "WithIMDSv2EnforcedOnNodePoolWithDefaultAWSCliImage",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    metadataOptions:
+      httpTokens: required
+  - name: pool2
+    awsCliImage:
+      repo: quay.io/coreos/awscli
+      tag: 1.18.69
+    metadataOptions:
+      httpTokens: required
+`,
+			expectedErrorMessage: "`worker.nodePools[name=pool1].metadataOptions.httpTokens` can't be \"required\" with the default `awsCliImage`",
+		},
 		{
 			context: "WithUnknownKeyInAddons",
 			configYaml: minimalValidConfigYaml + `
@@ -4376,7 +5666,7 @@ worker:
         enabled: true
         version: ""
 `,
-			expectedErrorMessage: `instance type t2.medium doesn't support GPU. You can enable Nvidia driver intallation support only when use [p2 p3 g2 g3] instance family.`,
+			expectedErrorMessage: `instance type t2.medium doesn't support GPU. You can enable Nvidia driver intallation support only on instance types with GPUs.`,
 		},
 	}
 